@@ -15,10 +15,12 @@ DBPort=$2
 ToDoAddr=$3
 ToDoPort=$4
 export PGPASSWORD="todo123"
+export TODOD_DB_PASSWORD=$PGPASSWORD
 
 
-echo "Create table"
-psql -h $DBAddr -p $DBPort -U todo todo -f sql/createtables.sql -a
+echo "Recreate tables"
+go run ./src/cmd/todod migrate -dbhost $DBAddr -dbport $DBPort down all
+go run ./src/cmd/todod migrate -dbhost $DBAddr -dbport $DBPort up
 echo ""
 echo "Pre-populate table with test data"
 psql -h $DBAddr -p $DBPort -U todo todo -f sql/testdata.sql -a
@@ -26,37 +28,40 @@ psql -h $DBAddr -p $DBPort -U todo todo -f sql/testdata.sql -a
 echo ""
 echo "curl http://"$ToDoAddr":"$ToDoPort"todos"
 echo "Get To Do List"
-curl http://$ToDoAddr:$ToDoPort/todos | jq "."
+curl -u demo:password123 http://$ToDoAddr:$ToDoPort/todos | jq "."
 echo ""
 echo "Get To Do item 3"
-curl http://$ToDoAddr:$ToDoPort/todos/3 | jq "."
+curl -u demo:password123 http://$ToDoAddr:$ToDoPort/todos/3 | jq "."
 echo ""
 echo "Add a To Do item for working out. Should get a 201 and the 'Location' header should have '/todos/4'"
-curl -i -X POST http://$ToDoAddr:$ToDoPort/todos -H "Content-Type: application/json" -d "{\"note\":\"work out\",\"duedate\":\"2020-04-01T00:00:00Z\",\"repeat\":true,\"completed\":false}"
+curl -u demo:password123 -i -X POST http://$ToDoAddr:$ToDoPort/todos -H "Content-Type: application/json" -d "{\"note\":\"work out\",\"duedate\":\"2020-04-01T00:00:00Z\",\"repeat\":true,\"completed\":false}"
 echo ""
 echo "Get new To Do item"
-curl http://$ToDoAddr:$ToDoPort/todos/4 | jq "."
+curl -u demo:password123 http://$ToDoAddr:$ToDoPort/todos/4 | jq "."
 echo ""
 echo "Update To Do to 'workout extra hard'"
-curl -i -X PUT http://$ToDoAddr:$ToDoPort/todos/4 -H "Content-Type: application/json" -d "{\"id\":4,\"note\":\"workout extra hard\",\"duedate\":\"2525-04-02T13:13:13Z\",\"repeat\":true,\"completed\":true}"
+curl -u demo:password123 -i -X PUT http://$ToDoAddr:$ToDoPort/todos/4 -H "Content-Type: application/json" -d "{\"id\":4,\"note\":\"workout extra hard\",\"duedate\":\"2525-04-02T13:13:13Z\",\"repeat\":true,\"completed\":true}"
 echo ""
 echo "Look at the update"
-curl http://$ToDoAddr:$ToDoPort/todos/4 | jq "."
+curl -u demo:password123 http://$ToDoAddr:$ToDoPort/todos/4 | jq "."
+echo ""
+echo "Mark the To Do item not completed using a merge patch. Should get a 200 and the updated item"
+curl -u demo:password123 -i -X PATCH http://$ToDoAddr:$ToDoPort/todos/4 -H "Content-Type: application/merge-patch+json" -d "{\"completed\":false}"
 echo ""
 echo "Delete the newly added To Do item. Should get a 200."
-curl -i -X DELETE http://$ToDoAddr:$ToDoPort/todos/4
+curl -u demo:password123 -i -X DELETE http://$ToDoAddr:$ToDoPort/todos/4
 echo ""
 echo "Is the deleted item still there? Should get a 404"
-curl -i http://$ToDoAddr:$ToDoPort/todos/4
+curl -u demo:password123 -i http://$ToDoAddr:$ToDoPort/todos/4
 echo ""
 echo "Back to the original To Do List"
-curl http://$ToDoAddr:$ToDoPort/todos | jq "."
+curl -u demo:password123 http://$ToDoAddr:$ToDoPort/todos | jq "."
 echo ""
 echo "Do a bulk POST with results of operation in body of response"
-curl -X POST http://$ToDoAddr:$ToDoPort/todos?bulk=true -H "Content-Type: application/json" -d "{\"todolist\": [{\"note\": \"get groceries\",\"duedate\": \"2020-04-01T00:00:00Z\",\"repeat\": false,\"completed\": false},{\"note\": \"pay bills\",\"duedate\": \"2020-04-02T00:00:00Z\",\"repeat\": false,\"completed\": false},{\"note\": \"walk dog\",\"duedate\": \"2020-04-03T12:00:00Z\",\"repeat\": true,\"completed\": false}]}" | jq "."
+curl -u demo:password123 -X POST http://$ToDoAddr:$ToDoPort/todos?bulk=true -H "Content-Type: application/json" -d "{\"todolist\": [{\"note\": \"get groceries\",\"duedate\": \"2020-04-01T00:00:00Z\",\"repeat\": false,\"completed\": false},{\"note\": \"pay bills\",\"duedate\": \"2020-04-02T00:00:00Z\",\"repeat\": false,\"completed\": false},{\"note\": \"walk dog\",\"duedate\": \"2020-04-03T12:00:00Z\",\"repeat\": true,\"completed\": false}]}" | jq "."
 echo ""
 echo "Should now have 6 To Do items in the list"
-curl http://$ToDoAddr:$ToDoPort/todos | jq "."
+curl -u demo:password123 http://$ToDoAddr:$ToDoPort/todos | jq "."
 
 
 echo ""
@@ -67,25 +72,31 @@ echo ""
 echo ""
 echo ""
 echo "POST an item with an invalid date, should return a 400"
-curl -i -X POST http://$ToDoAddr:$ToDoPort/todos -H "Content-Type: application/json" -d "{\"note\":\"work out\",\"duedate\":\"JULY 5TH\",\"repeat\":true,\"completed\":false}"
+curl -u demo:password123 -i -X POST http://$ToDoAddr:$ToDoPort/todos -H "Content-Type: application/json" -d "{\"note\":\"work out\",\"duedate\":\"JULY 5TH\",\"repeat\":true,\"completed\":false}"
 echo ""
 echo "POST an item with an invalid bool value, should return a 400"
-curl -i -X POST http://$ToDoAddr:$ToDoPort/todos -H "Content-Type: application/json" -d "{\"note\":\"work out\",\"duedate\":\"2020-04-02T13:13:13Z\",\"repeat\":true,\"completed\":NUITSNUT}"
+curl -u demo:password123 -i -X POST http://$ToDoAddr:$ToDoPort/todos -H "Content-Type: application/json" -d "{\"note\":\"work out\",\"duedate\":\"2020-04-02T13:13:13Z\",\"repeat\":true,\"completed\":NUITSNUT}"
 echo ""
 echo "POST an item with an invalid JSON tag, should return a 400"
-curl -i -X POST http://$ToDoAddr:$ToDoPort/todos -H "Content-Type: application/json" -d "{\"BADNOTE\":\"work out\",\"duedate\":\"2020-04-02T13:13:13Z\",\"repeat\":true,\"completed\":false}"
+curl -u demo:password123 -i -X POST http://$ToDoAddr:$ToDoPort/todos -H "Content-Type: application/json" -d "{\"BADNOTE\":\"work out\",\"duedate\":\"2020-04-02T13:13:13Z\",\"repeat\":true,\"completed\":false}"
 echo ""
 echo "DELETE an item with no {ID}, should return a 400"
-curl -i -X DELETE http://$ToDoAddr:$ToDoPort/todos/
+curl -u demo:password123 -i -X DELETE http://$ToDoAddr:$ToDoPort/todos/
 echo ""
 echo "DELETE an item with an invalid {ID}, should return a 400"
-curl -i -X DELETE http://$ToDoAddr:$ToDoPort/todos/BADID
+curl -u demo:password123 -i -X DELETE http://$ToDoAddr:$ToDoPort/todos/BADID
+echo ""
+echo "DELETE a non-existing item, should return a 404"
+curl -u demo:password123 -i -X DELETE http://$ToDoAddr:$ToDoPort/todos/2001
 echo ""
-echo "DELETE a non-existing item. Will return a 200 OK mirroring Postgres behavior"
-curl -i -X DELETE http://$ToDoAddr:$ToDoPort/todos/2001
+echo "PUT a non-existing item, should return a 404"
+curl -u demo:password123 -i -X PUT http://$ToDoAddr:$ToDoPort/todos/2001 -H "Content-Type: application/json" -d "{\"id\":2001,\"note\":\"work out\",\"duedate\":\"2020-04-02T13:13:13Z\",\"repeat\":true,\"completed\":false}"
 echo ""
 echo "Send a request with an unsupported HTTP Verb, should return a 501"
-curl -i -X PATCH http://$ToDoAddr:$ToDoPort/todos/2
+curl -u demo:password123 -i -X OPTIONS http://$ToDoAddr:$ToDoPort/todos/2
+echo ""
+echo "PATCH an item with the wrong Content-Type, should return a 415"
+curl -u demo:password123 -i -X PATCH http://$ToDoAddr:$ToDoPort/todos/2 -H "Content-Type: application/json" -d "{\"completed\":true}"
 echo ""
 echo "POST a bulk request with an invalided sub-request. Should return a 409 with the problem clearly identified in the response."
-curl -i -X POST http://$ToDoAddr:$ToDoPort/todos?bulk=true -H "Content-Type: application/json" -d "{\"todolist\": [{\"id\":1,\"note\": \"get groceries\",\"duedate\": \"2020-04-01T00:00:00Z\",\"repeat\": false,\"completed\": false},{\"note\": \"pay bills\",\"duedate\": \"2020-04-02T00:00:00Z\",\"repeat\": false,\"completed\": false},{\"note\": \"walk dog\",\"duedate\": \"2020-04-03T12:00:00Z\",\"repeat\": true,\"completed\": false}]}"
+curl -u demo:password123 -i -X POST http://$ToDoAddr:$ToDoPort/todos?bulk=true -H "Content-Type: application/json" -d "{\"todolist\": [{\"id\":1,\"note\": \"get groceries\",\"duedate\": \"2020-04-01T00:00:00Z\",\"repeat\": false,\"completed\": false},{\"note\": \"pay bills\",\"duedate\": \"2020-04-02T00:00:00Z\",\"repeat\": false,\"completed\": false},{\"note\": \"walk dog\",\"duedate\": \"2020-04-03T12:00:00Z\",\"repeat\": true,\"completed\": false}]}"
@@ -1,3 +1,5 @@
-INSERT INTO todo (note, duedate, repeat, completed) VALUES ('get groceries', '2020-04-01 00:00:00+0', false, false);
-INSERT INTO todo (note, duedate, repeat, completed) VALUES ('pay bills', '2020-04-02 00:00:00+0', false, false);
-INSERT INTO todo (note, duedate, repeat, completed) VALUES ('walk dog', '2020-04-03 12:00:00+0', true, false);
\ No newline at end of file
+-- 'demo' user's password is 'password123'
+INSERT INTO users (username, password_hash) VALUES ('demo', '$2a$10$v8vRCHVb4SfuqBlhpKJzL.FcF.zlLd7ozKBPlOJuKJksxYYUV8LKe');
+INSERT INTO todo (owner, note, duedate, repeat, completed) VALUES ('demo', 'get groceries', '2020-04-01 00:00:00+0', false, false);
+INSERT INTO todo (owner, note, duedate, repeat, completed) VALUES ('demo', 'pay bills', '2020-04-02 00:00:00+0', false, false);
+INSERT INTO todo (owner, note, duedate, repeat, completed) VALUES ('demo', 'walk dog', '2020-04-03 12:00:00+0', true, false);
\ No newline at end of file
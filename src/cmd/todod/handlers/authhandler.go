@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/auth"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/user"
+)
+
+// authRealm is the protection space reported in the WWW-Authenticate header
+const authRealm = "todod"
+
+// authHandler authenticates requests before passing them to 'next'
+type authHandler struct {
+	users  user.Store
+	tokens auth.TokenVerifier
+	next   http.Handler
+	logger *log.Entry
+}
+
+// NewAuthHandler returns a *http.Handler that requires either HTTP Basic authentication
+// against the users in 'users' or, if 'tokens' isn't nil, a bearer token verified by
+// 'tokens'. Authenticated requests are passed to 'next' with the principal, the user's
+// name or the token's prefixed principal, e.g., 'svc:reporting', in their context, see
+// user.FromContext. Other requests are rejected with a 401.
+func NewAuthHandler(users user.Store, tokens auth.TokenVerifier, next http.Handler, logger *log.Entry) (http.Handler, error) {
+	if users == nil {
+		return nil, errors.New("non-nil user.Store required")
+	}
+	if next == nil {
+		return nil, errors.New("non-nil http.Handler required")
+	}
+	if logger == nil {
+		return nil, errors.New("non-nil log.Entry  required")
+	}
+
+	return authHandler{users: users, tokens: tokens, next: next, logger: logger}, nil
+}
+
+// ServeHTTP authenticates the request and, if successful, passes it on
+func (h authHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.logger = requestLogger(r, h.logger)
+	if token, ok := bearerToken(r); ok {
+		h.serveBearer(w, r, token)
+		return
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		h.writeUnauthorized(w, r, "", "expected credentials", false)
+		return
+	}
+
+	u, err := user.Authenticate(r.Context(), h.users, username, password)
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.DBQueryErrorCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.Path:        r.URL.Path,
+			constants.User:        username,
+			constants.ErrorDetail: err.Error(),
+		}).Error(constants.DBQueryError)
+		writeProblem(w, r, httpStatus, constants.DBQueryErrorCode, "")
+		return
+	}
+	if u == nil {
+		h.writeUnauthorized(w, r, username, "invalid username or password", false)
+		return
+	}
+
+	h.next.ServeHTTP(w, r.WithContext(user.NewContext(r.Context(), u.Username)))
+}
+
+// serveBearer authenticates a request with the bearer 'token' and, if successful, passes
+// it on
+func (h authHandler) serveBearer(w http.ResponseWriter, r *http.Request, token string) {
+	if h.tokens == nil {
+		h.writeUnauthorized(w, r, "", "bearer tokens aren't accepted", false)
+		return
+	}
+	principal, err := h.tokens.Verify(token)
+	if err != nil {
+		h.writeUnauthorized(w, r, "", err.Error(), true)
+		return
+	}
+
+	h.next.ServeHTTP(w, r.WithContext(user.NewContext(r.Context(), principal)))
+}
+
+// bearerToken returns the token in the request's 'Authorization: Bearer' header, if it has one
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "bearer "
+	authz := r.Header.Get("Authorization")
+	if len(authz) <= len(prefix) || !strings.EqualFold(authz[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(authz[len(prefix):]), true
+}
+
+// writeUnauthorized logs, and responds to, a request that couldn't be authenticated. The
+// response challenges the client to use one of the accepted schemes, 'invalidToken' is
+// true if the request's bearer token was rejected.
+func (h authHandler) writeUnauthorized(w http.ResponseWriter, r *http.Request, username string, detail string, invalidToken bool) {
+	httpStatus := http.StatusUnauthorized
+	h.logger.WithFields(log.Fields{
+		constants.ErrorCode:   constants.UserAuthenticationErrorCode,
+		constants.HTTPStatus:  httpStatus,
+		constants.Path:        r.URL.Path,
+		constants.RemoteAddr:  r.RemoteAddr,
+		constants.User:        username,
+		constants.ErrorDetail: detail,
+	}).Warn(constants.UserAuthenticationError)
+	w.Header().Add("WWW-Authenticate", fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", authRealm))
+	if h.tokens != nil {
+		challenge := fmt.Sprintf("Bearer realm=%q", authRealm)
+		if invalidToken {
+			challenge += `, error="invalid_token"`
+		}
+		w.Header().Add("WWW-Authenticate", challenge)
+	}
+	writeProblem(w, r, httpStatus, constants.UserAuthenticationErrorCode, detail)
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/auth"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+	"github.com/youngkin/todoshaleapps/src/internal/user"
+)
+
+// withRequester returns a handler that passes requests to 'h' as if 'username' had
+// authenticated them
+func withRequester(h http.Handler, username string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.ServeHTTP(w, r.WithContext(user.NewContext(r.Context(), username)))
+	})
+}
+
+// newTestUsers returns a user.Store containing 'usernames', each with the password
+// 'password123'
+func newTestUsers(t *testing.T, usernames ...string) user.Store {
+	users := user.NewMemStore()
+	for _, username := range usernames {
+		u, err := user.NewUser(user.Credentials{Username: username, Password: "password123"})
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected creating user %s", err, username)
+		}
+		_, _, err = users.InsertUser(context.Background(), u)
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected inserting user %s", err, username)
+		}
+	}
+	return users
+}
+
+func TestAuthHandler(t *testing.T) {
+	basicChallenge := `Basic realm="todod", charset="UTF-8"`
+	bearerChallenge := `Bearer realm="todod"`
+
+	tcs := []struct {
+		testName           string
+		username           string
+		password           string
+		bearer             string
+		noCredentials      bool
+		noTokens           bool
+		expectedHTTPStatus int
+		expectedRequester  string
+		expectedChallenges []string
+	}{
+		{
+			testName:           "testAuthenticated",
+			username:           "ryoungkin",
+			password:           "password123",
+			expectedHTTPStatus: http.StatusOK,
+			expectedRequester:  "ryoungkin",
+		},
+		{
+			testName:           "testNoCredentials",
+			noCredentials:      true,
+			expectedHTTPStatus: http.StatusUnauthorized,
+			expectedChallenges: []string{basicChallenge, bearerChallenge},
+		},
+		{
+			testName:           "testWrongPassword",
+			username:           "ryoungkin",
+			password:           "password456",
+			expectedHTTPStatus: http.StatusUnauthorized,
+			expectedChallenges: []string{basicChallenge, bearerChallenge},
+		},
+		{
+			testName:           "testUnknownUser",
+			username:           "jdoe",
+			password:           "password123",
+			expectedHTTPStatus: http.StatusUnauthorized,
+			expectedChallenges: []string{basicChallenge, bearerChallenge},
+		},
+		{
+			testName:           "testAPIKey",
+			bearer:             "reporting-key-0123456789abcdef0123",
+			expectedHTTPStatus: http.StatusOK,
+			expectedRequester:  "svc:reporting",
+		},
+		{
+			testName:           "testUserNamedForServiceAccount",
+			username:           "reporting",
+			password:           "password123",
+			expectedHTTPStatus: http.StatusOK,
+			expectedRequester:  "reporting",
+		},
+		{
+			testName:           "testUnknownAPIKey",
+			bearer:             "unknown-key-0123456789abcdef012345",
+			expectedHTTPStatus: http.StatusUnauthorized,
+			expectedChallenges: []string{basicChallenge, bearerChallenge + `, error="invalid_token"`},
+		},
+		{
+			testName:           "testJWTNotAccepted",
+			bearer:             "eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiJyeW91bmdraW4ifQ.c2ln",
+			expectedHTTPStatus: http.StatusUnauthorized,
+			expectedChallenges: []string{basicChallenge, bearerChallenge + `, error="invalid_token"`},
+		},
+		{
+			testName:           "testBearerWithoutTokenVerifier",
+			bearer:             "reporting-key-0123456789abcdef0123",
+			noTokens:           true,
+			expectedHTTPStatus: http.StatusUnauthorized,
+			expectedChallenges: []string{basicChallenge},
+		},
+	}
+
+	users := newTestUsers(t, "ryoungkin", "reporting")
+	apiKeys, err := auth.ParseAPIKeys([]byte("reporting reporting-key-0123456789abcdef0123"))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected parsing API keys", err)
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			requester := ""
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requester = user.FromContext(r.Context())
+			})
+			var tokens auth.TokenVerifier
+			if !tc.noTokens {
+				tokens = auth.BearerVerifier{APIKeys: apiKeys}
+			}
+			srvHandler, err := NewAuthHandler(users, tokens, next, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting an auth handler", err)
+			}
+
+			testSrv := httptest.NewServer(srvHandler)
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(http.MethodGet, testSrv.URL+"/todos", nil)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			switch {
+			case len(tc.bearer) > 0:
+				req.Header.Set("Authorization", "Bearer "+tc.bearer)
+			case !tc.noCredentials:
+				req.SetBasicAuth(tc.username, tc.password)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, resp.StatusCode)
+			}
+			if requester != tc.expectedRequester {
+				t.Errorf("expected requester %q, got %q", tc.expectedRequester, requester)
+			}
+			if tc.expectedHTTPStatus != http.StatusUnauthorized {
+				return
+			}
+
+			challenges := resp.Header["Www-Authenticate"]
+			if !reflect.DeepEqual(challenges, tc.expectedChallenges) {
+				t.Errorf("expected WWW-Authenticate challenges %q, got %q", tc.expectedChallenges, challenges)
+			}
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(resp.Body)
+			p := problem{}
+			err = json.Unmarshal(buf.Bytes(), &p)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected decoding the problem details", err)
+			}
+			if p.ErrCode != constants.UserAuthenticationErrorCode {
+				t.Errorf("expected errcode %d, got %+v", constants.UserAuthenticationErrorCode, p)
+			}
+		})
+	}
+}
+
+// TestPrincipalTakeover verifies that a user registered with a service account's name
+// can't act as the service account
+func TestPrincipalTakeover(t *testing.T) {
+	tcs := []struct {
+		testName           string
+		username           string
+		bearer             string
+		method             string
+		expectedHTTPStatus int
+	}{
+		{
+			testName:           "testServiceAccountGet",
+			bearer:             "reporting-key-0123456789abcdef0123",
+			method:             http.MethodGet,
+			expectedHTTPStatus: http.StatusOK,
+		},
+		{
+			testName:           "testUserGet",
+			username:           "reporting",
+			method:             http.MethodGet,
+			expectedHTTPStatus: http.StatusNotFound,
+		},
+		{
+			testName:           "testUserDelete",
+			username:           "reporting",
+			method:             http.MethodDelete,
+			expectedHTTPStatus: http.StatusNotFound,
+		},
+	}
+
+	users := newTestUsers(t, "reporting")
+	apiKeys, err := auth.ParseAPIKeys([]byte("reporting reporting-key-0123456789abcdef0123"))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected parsing API keys", err)
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := todo.NewMemStore()
+			_, err := store.InsertToDo(context.Background(), todo.Item{
+				Note:    "send weekly report",
+				DueDate: time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC),
+				Owner:   auth.APIKeyPrincipalPrefix + "reporting",
+			})
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected populating the todo store", err)
+			}
+			todoHandler, err := NewToDoHandler(store, nil, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
+			}
+			srvHandler, err := NewAuthHandler(users, auth.BearerVerifier{APIKeys: apiKeys}, todoHandler, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting an auth handler", err)
+			}
+
+			testSrv := httptest.NewServer(srvHandler)
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(tc.method, testSrv.URL+"/todos/1", nil)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			if len(tc.bearer) > 0 {
+				req.Header.Set("Authorization", "Bearer "+tc.bearer)
+			} else {
+				req.SetBasicAuth(tc.username, "password123")
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, resp.StatusCode)
+			}
+			td, err := store.GetToDoItem(context.Background(), auth.APIKeyPrincipalPrefix+"reporting", 1)
+			if err != nil || td == nil {
+				t.Errorf("expected the service account's todo to be unchanged, got %+v, %v", td, err)
+			}
+		})
+	}
+}
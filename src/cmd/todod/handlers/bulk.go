@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+	"github.com/youngkin/todoshaleapps/src/internal/user"
+	"github.com/youngkin/todoshaleapps/src/internal/webhook"
+)
+
+// bulkDeleteRqst is the body of a bulk delete request, it identifies the todos to delete
+type bulkDeleteRqst struct {
+	IDs []int64 `json:"ids"`
+}
+
+// bulkStatus returns the overall status of a bulk request whose sub-requests all
+// succeeded with 'okStatus'. If any of them failed the status is http.StatusConflict, the
+// response body contains more detail regarding the actual errors.
+// See https://www.w3.org/Protocols/rfc2616/rfc2616-sec10.html
+func bulkStatus(responses []insertTodoResponse, okStatus int) int {
+	for _, resp := range responses {
+		if resp.HTTPStatus != okStatus {
+			return http.StatusConflict
+		}
+	}
+	return okStatus
+}
+
+// failedResponse logs, and returns the response for, a failed bulk sub-request
+func (h handler) failedResponse(r *http.Request, td todo.Item, httpStatus int, errCode constants.ErrCode, err error) insertTodoResponse {
+	h.logger.WithFields(log.Fields{
+		constants.ErrorCode:   errCode,
+		constants.HTTPStatus:  httpStatus,
+		constants.Path:        r.URL.Path,
+		constants.ErrorDetail: err.Error(),
+	}).Error(errCode.Message())
+
+	return insertTodoResponse{
+		Item:       td,
+		HTTPStatus: httpStatus,
+		Err:        err.Error(),
+		Problem:    newProblem(r, httpStatus, errCode, err.Error()),
+	}
+}
+
+// handleBulkPut replaces each of the todos in the request body. Each todo is updated
+// independently, if some updates fail the others are still done. A todo with a non-zero
+// version is only updated if the stored todo has the same version.
+func (h handler) handleBulkPut(w http.ResponseWriter, r *http.Request) {
+	tdl, pathNodes, errCode, err := parseBulkRqst(r, h.logger)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, errCode, err.Error())
+		return
+	}
+	if len(pathNodes) != 1 {
+		h.writeBulkPathProblem(w, r, pathNodes)
+		return
+	}
+
+	responses := make([]insertTodoResponse, 0, len(tdl.Items))
+	for _, td := range tdl.Items {
+		responses = append(responses, h.updateItem(r, *td, pathNodes))
+	}
+
+	h.writeInsertResponses(w, r, bulkStatus(responses, http.StatusOK), responses)
+}
+
+func (h handler) updateItem(r *http.Request, td todo.Item, pathNodes []string) insertTodoResponse {
+	if td.ID == nilToDoID {
+		return h.failedResponse(r, td, http.StatusBadRequest, constants.ToDoRqstErrorCode,
+			errors.New("expected Item.ID > 0, got Item.ID = 0"))
+	}
+	td.SelfRef = "/" + pathNodes[0] + "/" + strconv.FormatInt(td.ID, 10)
+
+	err := todo.ValidateToDo(td)
+	if err != nil {
+		return h.failedResponse(r, td, http.StatusBadRequest, constants.ToDoValidationErrorCode, err)
+	}
+
+	// Todos in lists shared with the requester are changed on behalf of the list's owner,
+	// as for PUT /todos/{id}
+	owner, cur, errCode, err := h.changeOwner(r.Context(), int(td.ID))
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		if errCode == constants.UserForbiddenErrorCode {
+			httpStatus = http.StatusForbidden
+		}
+		return h.failedResponse(r, td, httpStatus, errCode, err)
+	}
+	if cur != nil && owner != td.Owner && td.ListID != 0 && td.ListID != cur.ListID {
+		return h.failedResponse(r, td, http.StatusForbidden, constants.UserForbiddenErrorCode,
+			errors.New("only the list's owner can move its todos to another list"))
+	}
+	td.Owner = owner
+
+	errCode, err = h.store.UpdateToDo(r.Context(), td)
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		switch errCode {
+		case constants.ToDoVersionConflictErrorCode:
+			httpStatus = http.StatusPreconditionFailed
+		case constants.DBInvalidRequestCode:
+			// The todo doesn't exist
+			httpStatus = http.StatusNotFound
+		case constants.ListNotFoundErrorCode:
+			httpStatus = http.StatusBadRequest
+		}
+		return h.failedResponse(r, td, httpStatus, errCode, err)
+	}
+
+	// The response, and any events, contain the todo as stored, e.g., with its new version
+	stored := h.storedAfterUpdate(r.Context(), td)
+	h.publishUpdate(stored, completes(cur, td.Completed))
+	return insertTodoResponse{Item: stored, HTTPStatus: http.StatusOK}
+}
+
+// handleBulkDelete deletes the todos identified by the 'ids' in the request body or, if
+// there isn't a body, the todos selected by the 'completed', 'repeat', 'due_before', and
+// 'due_after' query parameters. At least one of them is required so that the whole list
+// isn't deleted by accident. Todos selected by the query parameters are only deleted if
+// they haven't changed since they were selected.
+func (h handler) handleBulkDelete(w http.ResponseWriter, r *http.Request) {
+	pathNodes, err := getURLPathNodes(r.URL.Path)
+	if err != nil {
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.MalformedURLErrorCode,
+			constants.HTTPStatus:  http.StatusBadRequest,
+			constants.Path:        r.URL.Path,
+			constants.ErrorDetail: err,
+		}).Error(constants.MalformedURL)
+		writeProblem(w, r, http.StatusBadRequest, constants.MalformedURLErrorCode, err.Error())
+		return
+	}
+	if len(pathNodes) != 1 {
+		h.writeBulkPathProblem(w, r, pathNodes)
+		return
+	}
+
+	rqst := bulkDeleteRqst{}
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	err = d.Decode(&rqst)
+	if err != nil && err != io.EOF {
+		httpStatus := http.StatusBadRequest
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.JSONDecodingErrorCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.ErrorDetail: err.Error(),
+		}).Error(constants.JSONDecodingError)
+		writeProblem(w, r, httpStatus, constants.JSONDecodingErrorCode, err.Error())
+		return
+	}
+
+	var tds []todo.Item
+	if err == io.EOF {
+		// No body, the todos are selected by the query parameters
+		var errCode constants.ErrCode
+		tds, errCode, err = h.selectToDos(r)
+		if err != nil {
+			httpStatus := http.StatusInternalServerError
+			if errCode == constants.MalformedURLErrorCode {
+				httpStatus = http.StatusBadRequest
+			}
+			h.logger.WithFields(log.Fields{
+				constants.ErrorCode:   errCode,
+				constants.HTTPStatus:  httpStatus,
+				constants.Path:        r.URL.Path,
+				constants.ErrorDetail: err.Error(),
+			}).Error(errCode.Message())
+			writeProblem(w, r, httpStatus, errCode, err.Error())
+			return
+		}
+	} else {
+		for _, id := range rqst.IDs {
+			tds = append(tds, todo.Item{ID: id})
+		}
+	}
+
+	h.deleteItems(w, r, tds, pathNodes)
+}
+
+// selectToDos returns all of the todos matching the filters in the request's query parameters
+func (h handler) selectToDos(r *http.Request) ([]todo.Item, constants.ErrCode, error) {
+	opts, err := parseListOptions(r.URL.Query())
+	if err != nil {
+		return nil, constants.MalformedURLErrorCode, err
+	}
+	if opts.Completed == nil && opts.Repeat == nil && opts.DueBefore.IsZero() && opts.DueAfter.IsZero() {
+		return nil, constants.MalformedURLErrorCode, errors.New("expected a request body containing 'ids', or at least one of the 'completed', 'repeat', 'due_before', or 'due_after' query parameters")
+	}
+
+	// Page through the requester's matching todos, in the default list, in ID order
+	opts.Owner = user.FromContext(r.Context())
+	opts.ListID = todo.DefaultListID
+	opts.After = 0
+	opts.Limit = todo.MaxPageLimit
+	opts.SortBy = todo.SortByID
+	opts.SortDesc = false
+
+	var tds []todo.Item
+	for {
+		tdl, more, err := h.store.GetToDoListPage(r.Context(), opts)
+		if err != nil {
+			return nil, constants.DBQueryErrorCode, errors.Annotate(err, "error selecting todos to delete")
+		}
+		for _, td := range tdl.Items {
+			tds = append(tds, *td)
+		}
+		if !more || len(tdl.Items) == 0 {
+			return tds, constants.NoErrorCode, nil
+		}
+		opts.After = tdl.Items[len(tdl.Items)-1].ID
+	}
+}
+
+func (h handler) deleteItems(w http.ResponseWriter, r *http.Request, tds []todo.Item, pathNodes []string) {
+	responses := make([]insertTodoResponse, 0, len(tds))
+	for _, td := range tds {
+		td.SelfRef = "/" + pathNodes[0] + "/" + strconv.FormatInt(td.ID, 10)
+
+		// The todo may be in a list shared with the requester, delete it on its owner's behalf
+		owner, _, errCode, err := h.changeOwner(r.Context(), int(td.ID))
+		if err != nil {
+			httpStatus := http.StatusInternalServerError
+			if errCode == constants.UserForbiddenErrorCode {
+				httpStatus = http.StatusForbidden
+			}
+			responses = append(responses, h.failedResponse(r, td, httpStatus, errCode, err))
+			continue
+		}
+		td.Owner = owner
+
+		deleted := h.beforeDelete(r.Context(), td)
+		errCode, err = h.store.DeleteToDo(r.Context(), td.Owner, int(td.ID), td.Version)
+		if err != nil {
+			httpStatus := http.StatusInternalServerError
+			switch errCode {
+			case constants.ToDoVersionConflictErrorCode:
+				httpStatus = http.StatusPreconditionFailed
+			case constants.DBInvalidRequestCode:
+				// The todo doesn't exist
+				httpStatus = http.StatusNotFound
+			}
+			responses = append(responses, h.failedResponse(r, td, httpStatus, errCode, err))
+			continue
+		}
+		responses = append(responses, insertTodoResponse{Item: td, HTTPStatus: http.StatusOK})
+		h.publish(webhook.ToDoDeleted, deleted)
+	}
+
+	h.logger.WithFields(log.Fields{
+		constants.Method:        http.MethodDelete,
+		constants.MessageDetail: fmt.Sprintf("Responses: %+v", responses),
+	}).Debugf("handleBulkDelete exit")
+	h.writeInsertResponses(w, r, bulkStatus(responses, http.StatusOK), responses)
+}
+
+// writeBulkPathProblem responds to a bulk request that wasn't for '/todos'
+func (h handler) writeBulkPathProblem(w http.ResponseWriter, r *http.Request, pathNodes []string) {
+	httpStatus := http.StatusBadRequest
+	errMsg := fmt.Sprintf("expected '/todos', got %s", pathNodes)
+	h.logger.WithFields(log.Fields{
+		constants.ErrorCode:   constants.MalformedURLErrorCode,
+		constants.HTTPStatus:  httpStatus,
+		constants.Path:        r.URL.Path,
+		constants.ErrorDetail: errMsg,
+	}).Error(constants.MalformedURL)
+	writeProblem(w, r, httpStatus, constants.MalformedURLErrorCode, errMsg)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+)
+
+// itemETag returns the entity tag for the representation of 'td'. The tag is derived
+// from td.Version, which changes each time the todo is changed.
+func itemETag(td *todo.Item) string {
+	return `"` + strconv.FormatInt(td.Version, 10) + `"`
+}
+
+// parseETags splits an If-Match or If-None-Match header value into its entity tags
+func parseETags(header string) []string {
+	var etags []string
+	for _, etag := range strings.Split(header, ",") {
+		etag = strings.TrimSpace(etag)
+		if len(etag) > 0 {
+			etags = append(etags, etag)
+		}
+	}
+	return etags
+}
+
+// parseIfMatch returns the todo version required by an If-Match header value. A version
+// of 0 means there is no requirement, either because there is no header or because it's
+// '*'. An error is returned if the header can't identify a single todo version, e.g.,
+// because it contains a weak or unrecognized entity tag.
+func parseIfMatch(header string) (int64, error) {
+	etags := parseETags(header)
+	if len(etags) == 0 || (len(etags) == 1 && etags[0] == "*") {
+		return 0, nil
+	}
+	if len(etags) > 1 {
+		return 0, errors.Errorf("expected a single entity tag in If-Match, got %q", header)
+	}
+
+	// If-Match requires a strong comparison so weak tags, 'W/"1"', never match
+	etag := etags[0]
+	if len(etag) < 2 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		return 0, errors.Errorf("expected a strong entity tag in If-Match, got %q", header)
+	}
+	version, err := strconv.ParseInt(etag[1:len(etag)-1], 10, 64)
+	if err != nil || version < 1 {
+		return 0, errors.Errorf("unrecognized entity tag in If-Match, got %q", header)
+	}
+
+	return version, nil
+}
+
+// noneMatch evaluates an If-None-Match header value against 'etag'. It returns false if
+// any of the header's entity tags, compared weakly, match 'etag', or if the header is '*'.
+func noneMatch(header string, etag string) bool {
+	for _, t := range parseETags(header) {
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(etag, "W/") {
+			return false
+		}
+	}
+	return true
+}
+
+// ifMatchVersion returns the todo version required by the request's If-Match header,
+// 0 if there is no requirement. If the header can't be satisfied a 412 response is
+// written and 'ok' is false.
+func (h handler) ifMatchVersion(w http.ResponseWriter, r *http.Request) (version int64, ok bool) {
+	version, err := parseIfMatch(r.Header.Get("If-Match"))
+	if err != nil {
+		h.writeVersionConflict(w, r, err)
+		return 0, false
+	}
+	return version, true
+}
+
+// writeVersionConflict logs, and responds to, a request whose If-Match precondition failed
+func (h handler) writeVersionConflict(w http.ResponseWriter, r *http.Request, err error) {
+	httpStatus := http.StatusPreconditionFailed
+	h.logger.WithFields(log.Fields{
+		constants.ErrorCode:   constants.ToDoVersionConflictErrorCode,
+		constants.HTTPStatus:  httpStatus,
+		constants.Path:        r.URL.Path,
+		constants.ErrorDetail: err,
+	}).Error(constants.ToDoVersionConflictError)
+	writeProblem(w, r, httpStatus, constants.ToDoVersionConflictErrorCode, err.Error())
+}
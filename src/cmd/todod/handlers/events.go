@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+	"github.com/youngkin/todoshaleapps/src/internal/webhook"
+)
+
+// EventPublisher is notified when todos are created, updated, completed, or deleted.
+// Publish must not block, it's called while the request is being handled.
+type EventPublisher interface {
+	Publish(e webhook.Event)
+}
+
+// Publishers is an EventPublisher that publishes each event to all of its EventPublishers
+type Publishers []EventPublisher
+
+// Publish publishes 'e' to each of the EventPublishers in 'ps'
+func (ps Publishers) Publish(e webhook.Event) {
+	for _, p := range ps {
+		p.Publish(e)
+	}
+}
+
+// publish notifies the handler's EventPublisher, if it has one, that 'et' happened to 'td'
+func (h handler) publish(et webhook.EventType, td todo.Item) {
+	if h.publisher == nil {
+		return
+	}
+	if len(td.SelfRef) == 0 {
+		td.SelfRef = itemSelfRef(td.ID)
+	}
+	h.publisher.Publish(webhook.NewEvent(et, td))
+}
+
+// publishUpdate publishes a ToDoUpdated event for 'td', and a ToDoCompleted event if the
+// update completed it, see completes. 'td' should be the todo as stored after the update,
+// e.g., with its new version, see storedAfterUpdate.
+func (h handler) publishUpdate(td todo.Item, completed bool) {
+	h.publish(webhook.ToDoUpdated, td)
+	if completed {
+		h.publish(webhook.ToDoCompleted, td)
+	}
+}
+
+// storedAfterUpdate returns the todo identified by td.ID as stored after it was updated by
+// 'td', e.g., with its new version or, if it's repeating and was completed, its next due
+// date. If it can't be retrieved 'td' is returned.
+func (h handler) storedAfterUpdate(ctx context.Context, td todo.Item) todo.Item {
+	stored, err := h.store.GetToDoItem(ctx, td.Owner, int(td.ID))
+	if err != nil {
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.DBQueryErrorCode,
+			constants.ToDoID:      td.ID,
+			constants.ErrorDetail: err.Error(),
+		}).Warn(constants.DBQueryError)
+	}
+	if stored == nil {
+		return td
+	}
+	stored.SelfRef = itemSelfRef(stored.ID)
+	return *stored
+}
+
+// beforeDelete returns 'td', the todo about to be deleted, for its ToDoDeleted event. If
+// only its ID is known, i.e., it has no version, the stored todo is returned if it can be
+// retrieved.
+func (h handler) beforeDelete(ctx context.Context, td todo.Item) todo.Item {
+	if h.publisher == nil || td.Version != 0 {
+		return td
+	}
+
+	stored, err := h.store.GetToDoItem(ctx, td.Owner, int(td.ID))
+	if err != nil {
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.DBQueryErrorCode,
+			constants.ToDoID:      td.ID,
+			constants.ErrorDetail: err.Error(),
+		}).Warn(constants.DBQueryError)
+	}
+	if stored == nil {
+		return td
+	}
+	stored.SelfRef = td.SelfRef
+	return *stored
+}
+
+// completes returns true if an update that marks a todo 'completed' completes it, i.e.,
+// 'prev', the todo before the update, wasn't already completed. Updates of todos that were
+// already completed, e.g., to change their notes, don't complete them again.
+func completes(prev *todo.Item, completed bool) bool {
+	return completed && prev != nil && !prev.Completed
+}
+
+// patchCompletes returns true if the JSON Merge Patch 'patch' marks a todo completed
+func patchCompletes(patch []byte) bool {
+	var p struct {
+		Completed *bool `json:"completed"`
+	}
+	err := json.Unmarshal(patch, &p)
+	return err == nil && p.Completed != nil && *p.Completed
+}
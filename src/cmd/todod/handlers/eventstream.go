@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/stream"
+	"github.com/youngkin/todoshaleapps/src/internal/user"
+)
+
+const (
+	// eventStreamContentType is the media type of a Server-Sent Events stream
+	eventStreamContentType = "text/event-stream"
+	// resetEvent tells the client that events were missed, e.g., because its Last-Event-ID
+	// is no longer in the log, so it should re-fetch the todos it's interested in
+	resetEvent = "reset"
+	// defaultKeepAlive is how often a comment is sent on an idle stream so that proxies
+	// don't close it
+	defaultKeepAlive = 15 * time.Second
+	// reconnectDelay is the time, in milliseconds, clients wait before reconnecting
+	reconnectDelay = 3000
+)
+
+type eventStreamHandler struct {
+	log       *stream.Log
+	done      chan interface{}
+	keepAlive time.Duration
+	logger    *log.Entry
+}
+
+// NewEventStreamHandler returns a *http.Handler that streams the events published to 'l'
+// as Server-Sent Events. Streams are ended when 'done' is closed so they don't prevent
+// the server from shutting down.
+func NewEventStreamHandler(l *stream.Log, done chan interface{}, logger *log.Entry) (http.Handler, error) {
+	if l == nil {
+		return nil, errors.New("non-nil stream.Log required")
+	}
+	if done == nil {
+		return nil, errors.New("non-nil done channel required")
+	}
+	if logger == nil {
+		return nil, errors.New("non-nil log.Entry  required")
+	}
+
+	return eventStreamHandler{log: l, done: done, keepAlive: defaultKeepAlive, logger: logger}, nil
+}
+
+// ServeHTTP streams events, for the requester's todos, until the client disconnects, the
+// server shuts down, or the client falls too far behind. In the last case the client can
+// reconnect with the Last-Event-ID header to resume where it left off.
+func (h eventStreamHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.logger = requestLogger(r, h.logger)
+	logRqstRcvd(r, h.logger)
+
+	if r.Method != http.MethodGet {
+		httpStatus := http.StatusMethodNotAllowed
+		errMsg := fmt.Sprintf("expected GET, got %s", r.Method)
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnsupportedMethodErrorCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.Path:        r.URL.Path,
+			constants.ErrorDetail: errMsg,
+		}).Error(constants.UnsupportedMethod)
+		writeProblem(w, r, httpStatus, constants.UnsupportedMethodErrorCode, errMsg)
+		return
+	}
+
+	var lastID stream.ID
+	if v := r.Header.Get("Last-Event-ID"); len(v) > 0 {
+		var err error
+		lastID, err = stream.ParseID(v)
+		if err != nil {
+			httpStatus := http.StatusBadRequest
+			errMsg := fmt.Sprintf("invalid Last-Event-ID, must be an event's ID, got %s", v)
+			h.logger.WithFields(log.Fields{
+				constants.ErrorCode:   constants.RqstParsingErrorCode,
+				constants.HTTPStatus:  httpStatus,
+				constants.Path:        r.URL.Path,
+				constants.ErrorDetail: errMsg,
+			}).Error(constants.RqstParsingError)
+			writeProblem(w, r, httpStatus, constants.RqstParsingErrorCode, errMsg)
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		httpStatus := http.StatusInternalServerError
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.HTTPWriteErrorCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.Path:        r.URL.Path,
+			constants.ErrorDetail: "http.ResponseWriter doesn't support streaming",
+		}).Error(constants.HTTPWriteError)
+		writeProblem(w, r, httpStatus, constants.HTTPWriteErrorCode, "")
+		return
+	}
+
+	owner := user.FromContext(r.Context())
+	backlog, entries, complete, cancel := h.log.Subscribe(lastID)
+	defer cancel()
+
+	w.Header().Set("Content-Type", eventStreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	// Tell nginx, and similar proxies, not to buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	_, err := fmt.Fprintf(w, "retry: %d\n\n", reconnectDelay)
+	if err == nil && !complete {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: {}\n\n", resetEvent)
+	}
+	for _, e := range backlog {
+		if err != nil {
+			break
+		}
+		if e.Event.ToDo.Owner == owner {
+			err = writeEvent(w, e)
+		}
+	}
+	if err != nil {
+		h.logStreamEnd(r, err)
+		return
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(h.keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-entries:
+			if !ok {
+				h.logStreamEnd(r, errors.New("client fell too far behind"))
+				return
+			}
+			if e.Event.ToDo.Owner != owner {
+				continue
+			}
+			err = writeEvent(w, e)
+		case <-ticker.C:
+			_, err = fmt.Fprint(w, ": keepalive\n\n")
+		case <-r.Context().Done():
+			h.logStreamEnd(r, r.Context().Err())
+			return
+		case <-h.done:
+			h.logStreamEnd(r, errors.New("server shutting down"))
+			return
+		}
+		if err != nil {
+			h.logStreamEnd(r, err)
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes 'e' in the Server-Sent Events format. The event's data is a single
+// line of JSON so it doesn't need to be split across 'data' fields.
+func writeEvent(w http.ResponseWriter, e stream.Entry) error {
+	data, err := json.Marshal(e.Event)
+	if err != nil {
+		return errors.Annotate(err, "error marshaling event")
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Event.Type, data)
+	return err
+}
+
+func (h eventStreamHandler) logStreamEnd(r *http.Request, reason error) {
+	h.logger.WithFields(log.Fields{
+		constants.Path:          r.URL.Path,
+		constants.RemoteAddr:    r.RemoteAddr,
+		constants.MessageDetail: reason.Error(),
+	}).Info("event stream ended")
+}
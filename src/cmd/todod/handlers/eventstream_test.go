@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/stream"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+	"github.com/youngkin/todoshaleapps/src/internal/webhook"
+)
+
+// sseEvent is an event read from a Server-Sent Events stream
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// readEvent reads the next event, skipping comments and the 'retry' field
+func readEvent(t *testing.T, r *bufio.Reader) sseEvent {
+	e := sseEvent{}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected reading the event stream", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			if e.event != "" {
+				return e
+			}
+		case strings.HasPrefix(line, "id: "):
+			e.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	tcs := []struct {
+		testName           string
+		requester          string
+		lastEventID        string
+		lastEventSeq       int64
+		expectedHTTPStatus int
+		expectedEvents     []string
+		expectedSeqs       []int64
+	}{
+		{
+			testName:           "testNewEventsOnly",
+			expectedHTTPStatus: http.StatusOK,
+			expectedEvents:     []string{"todo.updated"},
+			expectedSeqs:       []int64{3},
+		},
+		{
+			testName:           "testResume",
+			lastEventSeq:       1,
+			expectedHTTPStatus: http.StatusOK,
+			expectedEvents:     []string{"todo.created", "todo.updated"},
+			expectedSeqs:       []int64{2, 3},
+		},
+		{
+			testName:           "testResumeUnknownID",
+			lastEventSeq:       100,
+			expectedHTTPStatus: http.StatusOK,
+			expectedEvents:     []string{resetEvent, "todo.created", "todo.created", "todo.updated"},
+			expectedSeqs:       []int64{0, 1, 2, 3},
+		},
+		{
+			// The ID is from before a restart, its epoch isn't the log's
+			testName:           "testResumeOtherEpoch",
+			lastEventID:        "1-1",
+			expectedHTTPStatus: http.StatusOK,
+			expectedEvents:     []string{resetEvent, "todo.created", "todo.created", "todo.updated"},
+			expectedSeqs:       []int64{0, 1, 2, 3},
+		},
+		{
+			testName:           "testResumeNoEpoch",
+			lastEventID:        "1",
+			expectedHTTPStatus: http.StatusOK,
+			expectedEvents:     []string{resetEvent, "todo.created", "todo.created", "todo.updated"},
+			expectedSeqs:       []int64{0, 1, 2, 3},
+		},
+		{
+			// Only jdoe's todo, created after ryoungkin's update, is streamed to jdoe
+			testName:           "testOtherUsersEventsFiltered",
+			requester:          "jdoe",
+			lastEventSeq:       1,
+			expectedHTTPStatus: http.StatusOK,
+			expectedEvents:     []string{"todo.created"},
+			expectedSeqs:       []int64{4},
+		},
+		{
+			testName:           "testInvalidLastEventID",
+			lastEventID:        "one",
+			expectedHTTPStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := todo.NewMemStore()
+			eventLog := stream.NewLog(10)
+			todoHandler, err := NewToDoHandler(store, eventLog, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
+			}
+			done := make(chan interface{})
+			streamHandler, err := NewEventStreamHandler(eventLog, done, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting an event stream handler", err)
+			}
+
+			mux := http.NewServeMux()
+			mux.Handle("/todos", todoHandler)
+			mux.Handle("/todos/", todoHandler)
+			mux.Handle("/todos/events", streamHandler)
+			authHandler, err := NewAuthHandler(newTestUsers(t, "ryoungkin", "jdoe"), nil, mux, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting an auth handler", err)
+			}
+			testSrv := httptest.NewServer(authHandler)
+			defer testSrv.Close()
+
+			requester := tc.requester
+			if len(requester) == 0 {
+				requester = "ryoungkin"
+			}
+
+			// Two todos are created before the stream starts, they're events 1 and 2
+			for _, note := range []string{"walk the dog", "get groceries"} {
+				td := todo.Item{Note: note, DueDate: date}
+				body, _ := json.Marshal(td)
+				req, _ := http.NewRequest(http.MethodPost, testSrv.URL+"/todos", bytes.NewBuffer(body))
+				req.SetBasicAuth("ryoungkin", "password123")
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected creating a todo", err)
+				}
+				resp.Body.Close()
+			}
+
+			req, err := http.NewRequest(http.MethodGet, testSrv.URL+"/todos/events", nil)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			req.SetBasicAuth(requester, "password123")
+			if len(tc.lastEventID) > 0 {
+				req.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+			if tc.lastEventSeq > 0 {
+				req.Header.Set("Last-Event-ID", stream.ID{Epoch: eventLog.Epoch(), Seq: tc.lastEventSeq}.String())
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedHTTPStatus {
+				t.Fatalf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, resp.StatusCode)
+			}
+			if resp.StatusCode != http.StatusOK {
+				return
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != eventStreamContentType {
+				t.Errorf("expected Content-Type %s, got %s", eventStreamContentType, ct)
+			}
+
+			// Event 3 is published while the stream is open
+			req, _ = http.NewRequest(http.MethodPut, testSrv.URL+"/todos/1",
+				strings.NewReader(`{"id":1,"note":"walk the cat","duedate":"2020-04-02T13:13:00Z"}`))
+			req.SetBasicAuth("ryoungkin", "password123")
+			putResp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected updating a todo", err)
+			}
+			putResp.Body.Close()
+
+			// Event 4 is another user's todo
+			req, _ = http.NewRequest(http.MethodPost, testSrv.URL+"/todos",
+				strings.NewReader(`{"note":"feed the cat","duedate":"2020-04-02T13:13:00Z"}`))
+			req.SetBasicAuth("jdoe", "password123")
+			postResp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating a todo", err)
+			}
+			postResp.Body.Close()
+
+			r := bufio.NewReader(resp.Body)
+			for i, expected := range tc.expectedEvents {
+				e := readEvent(t, r)
+				expectedID := ""
+				if tc.expectedSeqs[i] > 0 {
+					expectedID = stream.ID{Epoch: eventLog.Epoch(), Seq: tc.expectedSeqs[i]}.String()
+				}
+				if e.event != expected || e.id != expectedID {
+					t.Errorf("expected event %s with id %q, got %+v", expected, expectedID, e)
+				}
+				if e.event == resetEvent {
+					continue
+				}
+				ev := webhook.Event{}
+				err = json.Unmarshal([]byte(e.data), &ev)
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected decoding event data %s", err, e.data)
+				}
+				if ev.ToDo.SelfRef == "" || ev.ToDo.Note == "" {
+					t.Errorf("expected the full todo in the event, got %+v", ev.ToDo)
+				}
+			}
+
+			// Closing 'done' ends the stream
+			close(done)
+			_, err = r.ReadString('\n')
+			for err == nil {
+				_, err = r.ReadString('\n')
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+const (
+	// checkOK is the status of a check that passed
+	checkOK = "ok"
+	// checkFailed is the status of a check that failed or didn't finish in time
+	checkFailed = "failed"
+)
+
+// launcherRunning is 1 while PostRequestLauncher is running, bulk POSTs can't be handled
+// without it
+var launcherRunning int32
+
+// ReadinessCheck is a dependency todod needs to be able to handle requests. 'Check' returns
+// an error if the dependency is unavailable, it should give up when 'ctx' is done.
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// CheckBulkLauncher is a ReadinessCheck function that fails if PostRequestLauncher isn't
+// running
+func CheckBulkLauncher(ctx context.Context) error {
+	if atomic.LoadInt32(&launcherRunning) == 0 {
+		return errors.New("bulk POST launcher isn't running")
+	}
+	return nil
+}
+
+// checkResult is the outcome of a ReadinessCheck
+type checkResult struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// readiness is the body of a /readyz response
+type readiness struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks"`
+}
+
+// NewLivenessHandler returns a http.Handler that reports that todod is running. It doesn't
+// check todod's dependencies, restarting todod won't fix them.
+func NewLivenessHandler(logger *log.Entry) (http.Handler, error) {
+	if logger == nil {
+		return nil, errors.New("non-nil log.Entry  required")
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestLogger(r, logger).WithFields(log.Fields{
+			constants.ServiceName: "livez",
+		}).Debug("handling request")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"ok"}` + "\n"))
+	}), nil
+}
+
+// ReadinessHandler reports whether todod can handle requests, i.e., whether its dependencies
+// are available and it isn't shutting down
+type ReadinessHandler struct {
+	checks       []ReadinessCheck
+	timeout      time.Duration
+	shuttingDown int32
+	logger       *log.Entry
+}
+
+// NewReadinessHandler returns a *ReadinessHandler that runs 'checks' for each request. A check
+// that takes longer than 'timeout' fails.
+func NewReadinessHandler(checks []ReadinessCheck, timeout time.Duration, logger *log.Entry) (*ReadinessHandler, error) {
+	if timeout <= 0 {
+		return nil, errors.New("positive timeout required")
+	}
+	if logger == nil {
+		return nil, errors.New("non-nil log.Entry  required")
+	}
+	for _, c := range checks {
+		if len(c.Name) == 0 || c.Check == nil {
+			return nil, errors.New("ReadinessChecks require a name and a Check function")
+		}
+	}
+	return &ReadinessHandler{checks: checks, timeout: timeout, logger: logger}, nil
+}
+
+// ShutDown makes 'h' report that todod isn't ready, so that it stops receiving new
+// requests while it's shutting down
+func (h *ReadinessHandler) ShutDown() {
+	atomic.StoreInt32(&h.shuttingDown, 1)
+}
+
+// ServeHTTP runs the checks concurrently and responds with each one's result. The status is
+// 200 if they all passed, 503 otherwise.
+func (h *ReadinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := requestLogger(r, h.logger)
+	logger.WithFields(log.Fields{
+		constants.ServiceName: "readyz",
+	}).Debug("handling request")
+
+	results := h.runChecks(r.Context())
+	if atomic.LoadInt32(&h.shuttingDown) == 1 {
+		results["shutdown"] = checkResult{Status: checkFailed, Error: "todod is shutting down"}
+	}
+
+	body := readiness{Status: checkOK, Checks: results}
+	httpStatus := http.StatusOK
+	var failed []string
+	for name, result := range results {
+		if result.Status != checkOK {
+			failed = append(failed, fmt.Sprintf("%s: %s", name, result.Error))
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		body.Status = checkFailed
+		httpStatus = http.StatusServiceUnavailable
+		logger.WithFields(log.Fields{
+			constants.ServiceName: "readyz",
+			constants.HTTPStatus:  httpStatus,
+			constants.ErrorDetail: strings.Join(failed, ", "),
+		}).Warn("todod isn't ready")
+	}
+
+	marshBody, err := json.Marshal(body)
+	if err != nil {
+		writeProblem(w, r, http.StatusInternalServerError, constants.JSONMarshalingErrorCode, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	w.Write(marshBody)
+}
+
+// runChecks runs the checks concurrently and returns their results by name. Checks that
+// haven't finished when the timeout expires fail, even if they ignore the context.
+func (h *ReadinessHandler) runChecks(ctx context.Context) map[string]checkResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	results := make(map[string]checkResult, len(h.checks))
+	for _, c := range h.checks {
+		results[c.Name] = checkResult{Status: checkFailed, Error: "timed out after " + h.timeout.String()}
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range h.checks {
+		wg.Add(1)
+		done := make(chan error, 1)
+		go func(c ReadinessCheck) {
+			done <- c.Check(ctx)
+		}(c)
+		go func(name string) {
+			defer wg.Done()
+			select {
+			case err := <-done:
+				result := checkResult{Status: checkOK}
+				if err != nil {
+					result = checkResult{Status: checkFailed, Error: err.Error()}
+				}
+				mu.Lock()
+				results[name] = result
+				mu.Unlock()
+			case <-ctx.Done():
+			}
+		}(c.Name)
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	return results
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+	hung := func(context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}
+
+	tcs := []struct {
+		testName           string
+		checks             []ReadinessCheck
+		shutDown           bool
+		expectedHTTPStatus int
+		expectedBody       readiness
+	}{
+		{
+			testName:           "testReady",
+			checks:             []ReadinessCheck{{Name: "database", Check: ok}, {Name: "schema", Check: ok}},
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody: readiness{Status: checkOK, Checks: map[string]checkResult{
+				"database": {Status: checkOK},
+				"schema":   {Status: checkOK},
+			}},
+		},
+		{
+			testName:           "testCheckFailed",
+			checks:             []ReadinessCheck{{Name: "database", Check: down}, {Name: "schema", Check: ok}},
+			expectedHTTPStatus: http.StatusServiceUnavailable,
+			expectedBody: readiness{Status: checkFailed, Checks: map[string]checkResult{
+				"database": {Status: checkFailed, Error: "connection refused"},
+				"schema":   {Status: checkOK},
+			}},
+		},
+		{
+			testName:           "testCheckTimedOut",
+			checks:             []ReadinessCheck{{Name: "database", Check: hung}},
+			expectedHTTPStatus: http.StatusServiceUnavailable,
+			expectedBody: readiness{Status: checkFailed, Checks: map[string]checkResult{
+				"database": {Status: checkFailed, Error: "timed out after 50ms"},
+			}},
+		},
+		{
+			testName:           "testShuttingDown",
+			checks:             []ReadinessCheck{{Name: "database", Check: ok}},
+			shutDown:           true,
+			expectedHTTPStatus: http.StatusServiceUnavailable,
+			expectedBody: readiness{Status: checkFailed, Checks: map[string]checkResult{
+				"database": {Status: checkOK},
+				"shutdown": {Status: checkFailed, Error: "todod is shutting down"},
+			}},
+		},
+		{
+			testName:           "testBulkLauncherStopped",
+			checks:             []ReadinessCheck{{Name: "bulkLauncher", Check: CheckBulkLauncher}},
+			expectedHTTPStatus: http.StatusServiceUnavailable,
+			expectedBody: readiness{Status: checkFailed, Checks: map[string]checkResult{
+				"bulkLauncher": {Status: checkFailed, Error: "bulk POST launcher isn't running"},
+			}},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			h, err := NewReadinessHandler(tc.checks, 50*time.Millisecond, log.NewEntry(log.New()))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating the readiness handler", err)
+			}
+			if tc.shutDown {
+				h.ShutDown()
+			}
+
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rr.Code != tc.expectedHTTPStatus {
+				t.Errorf("expected HTTP status %d, got %d", tc.expectedHTTPStatus, rr.Code)
+			}
+			var body readiness
+			err = json.Unmarshal(rr.Body.Bytes(), &body)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected unmarshaling %s", err, rr.Body.String())
+			}
+			if !reflect.DeepEqual(tc.expectedBody, body) {
+				t.Errorf("expected body %+v, got %+v", tc.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestLivenessHandler(t *testing.T) {
+	h, err := NewLivenessHandler(log.NewEntry(log.New()))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected creating the liveness handler", err)
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected HTTP status %d, got %d", http.StatusOK, rr.Code)
+	}
+}
@@ -186,7 +186,7 @@ func TestParseBulkRqst(t *testing.T) {
 				URL:    u,
 			}
 
-			tdl, _, err := parseBulkRqst(&r, logger)
+			tdl, _, _, err := parseBulkRqst(&r, logger)
 			if err != nil {
 				t.Errorf("unexpected error calling parseBulkRequest: %s", err)
 			}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+	"github.com/youngkin/todoshaleapps/src/internal/webhook"
+)
+
+func TestListHandler(t *testing.T) {
+	client := &http.Client{}
+
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	tcs := []struct {
+		testName           string
+		requester          string
+		method             string
+		url                string
+		data               string
+		expectedHTTPStatus int
+		expectedErrCode    constants.ErrCode
+		expectedBody       string
+		expectedLocation   string
+		expectedEvents     []webhook.EventType
+	}{
+		{
+			testName:           "testGetLists",
+			method:             http.MethodGet,
+			url:                "/lists",
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody:       `{"lists":[{"id":1,"selfref":"/lists/1","name":"default","description":"","owner":""},{"id":2,"selfref":"/lists/2","name":"sprint","description":"current sprint","owner":"ryoungkin"}]}`,
+		},
+		{
+			testName:           "testGetList",
+			method:             http.MethodGet,
+			url:                "/lists/2",
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody:       `{"id":2,"selfref":"/lists/2","name":"sprint","description":"current sprint","owner":"ryoungkin"}`,
+		},
+		{
+			testName:           "testGetListNotFound",
+			method:             http.MethodGet,
+			url:                "/lists/100",
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.ListNotFoundErrorCode,
+		},
+		{
+			testName:           "testGetOtherUsersList",
+			requester:          "jdoe",
+			method:             http.MethodGet,
+			url:                "/lists/2",
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.ListNotFoundErrorCode,
+		},
+		{
+			testName:           "testGetListsOtherUser",
+			requester:          "jdoe",
+			method:             http.MethodGet,
+			url:                "/lists",
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody:       `{"lists":[{"id":1,"selfref":"/lists/1","name":"default","description":"","owner":""}]}`,
+		},
+		{
+			testName:           "testGetListBadID",
+			method:             http.MethodGet,
+			url:                "/lists/one",
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.MalformedURLErrorCode,
+		},
+		{
+			testName:           "testPostList",
+			method:             http.MethodPost,
+			url:                "/lists",
+			data:               `{"name":"personal","owner":"jdoe"}`,
+			expectedHTTPStatus: http.StatusCreated,
+			expectedBody:       `{"id":3,"selfref":"/lists/3","name":"personal","description":"","owner":"ryoungkin"}`,
+			expectedLocation:   "/lists/3",
+		},
+		{
+			testName:           "testPostListInvalid",
+			method:             http.MethodPost,
+			url:                "/lists",
+			data:               `{"description":"no name"}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.ListValidationErrorCode,
+		},
+		{
+			testName:           "testPutList",
+			method:             http.MethodPut,
+			url:                "/lists/2",
+			data:               `{"name":"next sprint"}`,
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody:       `{"id":2,"selfref":"/lists/2","name":"next sprint","description":"","owner":"ryoungkin"}`,
+		},
+		{
+			testName:           "testPutOtherUsersList",
+			requester:          "jdoe",
+			method:             http.MethodPut,
+			url:                "/lists/2",
+			data:               `{"name":"next sprint"}`,
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.ListNotFoundErrorCode,
+		},
+		{
+			testName:           "testPutListNotFound",
+			method:             http.MethodPut,
+			url:                "/lists/100",
+			data:               `{"name":"missing"}`,
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.ListNotFoundErrorCode,
+		},
+		{
+			testName:           "testDeleteList",
+			method:             http.MethodDelete,
+			url:                "/lists/2",
+			expectedHTTPStatus: http.StatusOK,
+			expectedEvents:     []webhook.EventType{webhook.ToDoDeleted},
+		},
+		{
+			testName:           "testDeleteOtherUsersList",
+			requester:          "jdoe",
+			method:             http.MethodDelete,
+			url:                "/lists/2",
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.ListNotFoundErrorCode,
+		},
+		{
+			testName:           "testDeleteDefaultList",
+			method:             http.MethodDelete,
+			url:                "/lists/1",
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.ListValidationErrorCode,
+		},
+		{
+			testName:           "testGetListToDos",
+			method:             http.MethodGet,
+			url:                "/lists/2/todos",
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody:       `{"todolist":[{"id":2,"selfref":"/todos/2","listid":2,"owner":"ryoungkin","note":"fix bug","duedate":"2020-04-02T13:13:00Z","repeat":false,"completed":false,"version":1}]}`,
+		},
+		{
+			testName:           "testGetListToDosNotFound",
+			method:             http.MethodGet,
+			url:                "/lists/100/todos",
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.ListNotFoundErrorCode,
+		},
+		{
+			testName:           "testPostListToDo",
+			method:             http.MethodPost,
+			url:                "/lists/2/todos",
+			data:               `{"note":"write tests","duedate":"2020-04-02T13:13:00Z"}`,
+			expectedHTTPStatus: http.StatusCreated,
+			expectedLocation:   "/todos/3",
+			expectedEvents:     []webhook.EventType{webhook.ToDoCreated},
+		},
+		{
+			testName:           "testPostOtherUsersListToDo",
+			requester:          "jdoe",
+			method:             http.MethodPost,
+			url:                "/lists/2/todos",
+			data:               `{"note":"write tests","duedate":"2020-04-02T13:13:00Z"}`,
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.ListNotFoundErrorCode,
+		},
+		{
+			testName:           "testPostListToDoListMismatch",
+			method:             http.MethodPost,
+			url:                "/lists/2/todos",
+			data:               `{"note":"write tests","duedate":"2020-04-02T13:13:00Z","listid":1}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.MalformedURLErrorCode,
+		},
+		{
+			testName:           "testUnsupportedMethod",
+			method:             http.MethodPatch,
+			url:                "/lists/2",
+			expectedHTTPStatus: http.StatusMethodNotAllowed,
+			expectedErrCode:    constants.UnsupportedMethodErrorCode,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := todo.NewMemStore()
+			_, err := store.InsertList(context.Background(), todo.ListInfo{Name: "sprint", Description: "current sprint", Owner: "ryoungkin"})
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected populating the list store", err)
+			}
+			_, err = store.InsertToDos(context.Background(), []todo.Item{
+				{Note: "walk the dog", DueDate: date, Owner: "ryoungkin"},
+				{Note: "fix bug", DueDate: date, ListID: 2, Owner: "ryoungkin"},
+			})
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected populating the todo store", err)
+			}
+
+			publisher := &testPublisher{}
+			srvHandler, err := NewListHandler(store, publisher, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a list handler", err)
+			}
+
+			requester := tc.requester
+			if len(requester) == 0 {
+				requester = "ryoungkin"
+			}
+			testSrv := httptest.NewServer(withRequester(srvHandler, requester))
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(tc.method, testSrv.URL+tc.url, bytes.NewBuffer([]byte(tc.data)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, resp.StatusCode)
+			}
+			if loc := resp.Header.Get("Location"); loc != tc.expectedLocation {
+				t.Errorf("expected Location %q, got %q", tc.expectedLocation, loc)
+			}
+			if len(publisher.events) != len(tc.expectedEvents) {
+				t.Fatalf("expected events %v, got %+v", tc.expectedEvents, publisher.events)
+			}
+			for i, e := range publisher.events {
+				if e.Type != tc.expectedEvents[i] || e.ToDo.ListID != 2 {
+					t.Errorf("expected %s event for a todo in list 2, got %+v", tc.expectedEvents[i], e)
+				}
+			}
+
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(resp.Body)
+			if tc.expectedHTTPStatus >= http.StatusBadRequest {
+				p := problem{}
+				err = json.Unmarshal(buf.Bytes(), &p)
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected decoding the problem details", err)
+				}
+				if p.ErrCode != tc.expectedErrCode {
+					t.Errorf("expected errcode %d, got %+v", tc.expectedErrCode, p)
+				}
+				return
+			}
+			if len(tc.expectedBody) > 0 && buf.String() != tc.expectedBody {
+				t.Errorf("expected body %s, got %s", tc.expectedBody, buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+	"github.com/youngkin/todoshaleapps/src/internal/user"
+	"github.com/youngkin/todoshaleapps/src/internal/webhook"
+)
+
+// listHandler handles requests for lists and their shares. Requests for a list's todos are
+// handled by 'todos', the same handler that serves '/todos'. Requesters see their own lists,
+// the default list, and the lists shared with them. Only a list's owner can change the list
+// or its shares, editors can change its todos.
+type listHandler struct {
+	todos handler
+}
+
+// listList is the response body of GET /lists
+type listList struct {
+	Lists []todo.ListInfo `json:"lists"`
+}
+
+// NewListHandler returns a *http.Handler that manages the lists in 'store', and the todos
+// in each list. If 'publisher' isn't nil it's notified of each change to the store's todos.
+func NewListHandler(store todo.Store, publisher EventPublisher, logger *log.Entry) (http.Handler, error) {
+	if store == nil {
+		return nil, errors.New("non-nil todo.Store required")
+	}
+	if logger == nil {
+		return nil, errors.New("non-nil log.Entry  required")
+	}
+
+	return listHandler{todos: handler{store: store, publisher: publisher, logger: logger}}, nil
+}
+
+// ServeHTTP handles requests for '/lists', '/lists/{id}', '/lists/{id}/todos',
+// '/lists/{id}/shares', and '/lists/{id}/shares/{username}'
+func (h listHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.todos.logger = requestLogger(r, h.todos.logger)
+	logRqstRcvd(r, h.todos.logger)
+
+	pathNodes, err := getURLPathNodes(r.URL.Path)
+	if err != nil || len(pathNodes) < 1 || len(pathNodes) > 4 ||
+		(len(pathNodes) == 3 && pathNodes[2] != "todos" && pathNodes[2] != "shares") ||
+		(len(pathNodes) == 4 && pathNodes[2] != "shares") {
+		h.writeError(w, r, http.StatusBadRequest, constants.MalformedURLErrorCode,
+			fmt.Sprintf("expected '/lists', '/lists/{id}', '/lists/{id}/todos', '/lists/{id}/shares', or '/lists/{id}/shares/{username}', got %s",
+				r.URL.Path))
+		return
+	}
+
+	var id int64
+	if len(pathNodes) > 1 {
+		id, err = strconv.ParseInt(pathNodes[1], 10, 64)
+		if err != nil {
+			h.writeError(w, r, http.StatusBadRequest, constants.MalformedURLErrorCode,
+				fmt.Sprintf("Invalid resource ID, must be int, got %v", pathNodes[1]))
+			return
+		}
+	}
+
+	switch {
+	case len(pathNodes) == 1 && r.Method == http.MethodGet:
+		h.handleGetLists(w, r)
+	case len(pathNodes) == 1 && r.Method == http.MethodPost:
+		h.handlePost(w, r)
+	case len(pathNodes) == 2 && r.Method == http.MethodGet:
+		h.handleGet(w, r, id)
+	case len(pathNodes) == 2 && r.Method == http.MethodPut:
+		h.handlePut(w, r, id)
+	case len(pathNodes) == 2 && r.Method == http.MethodDelete:
+		h.handleDelete(w, r, id)
+	case len(pathNodes) == 3 && pathNodes[2] == "todos" && r.Method == http.MethodGet:
+		h.handleGetToDos(w, r, id)
+	case len(pathNodes) == 3 && pathNodes[2] == "todos" && r.Method == http.MethodPost:
+		h.handlePostToDo(w, r, id)
+	case len(pathNodes) == 3 && r.Method == http.MethodGet:
+		h.handleGetShares(w, r, id)
+	case len(pathNodes) == 4 && r.Method == http.MethodPut:
+		h.handlePutShare(w, r, id, pathNodes[3])
+	case len(pathNodes) == 4 && r.Method == http.MethodDelete:
+		h.handleDeleteShare(w, r, id, pathNodes[3])
+	default:
+		h.writeError(w, r, http.StatusMethodNotAllowed, constants.UnsupportedMethodErrorCode,
+			fmt.Sprintf("%s isn't supported for %s", r.Method, r.URL.Path))
+	}
+}
+
+func (h listHandler) handleGetLists(w http.ResponseWriter, r *http.Request) {
+	lists, err := h.todos.store.GetLists(r.Context(), user.FromContext(r.Context()))
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, constants.DBQueryErrorCode, err.Error())
+		return
+	}
+
+	for i := range lists {
+		lists[i].SelfRef = listSelfRef(lists[i].ID)
+	}
+	h.writeJSON(w, r, http.StatusOK, listList{Lists: lists})
+}
+
+func (h listHandler) handlePost(w http.ResponseWriter, r *http.Request) {
+	l, ok := h.parseList(w, r)
+	if !ok {
+		return
+	}
+	if l.ID != 0 {
+		h.writeError(w, r, http.StatusBadRequest, constants.InvalidInsertErrorCode,
+			fmt.Sprintf("expected unpopulated list ID, got ID = %d", l.ID))
+		return
+	}
+
+	err := todo.ValidateList(l)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, constants.ListValidationErrorCode, err.Error())
+		return
+	}
+
+	id, err := h.todos.store.InsertList(r.Context(), l)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, constants.DBUpSertErrorCode, err.Error())
+		return
+	}
+
+	l.ID = id
+	l.SelfRef = listSelfRef(id)
+	w.Header().Set("Location", l.SelfRef)
+	h.writeJSON(w, r, http.StatusCreated, l)
+}
+
+func (h listHandler) handleGet(w http.ResponseWriter, r *http.Request, id int64) {
+	l, _, ok := h.getList(w, r, id)
+	if !ok {
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, l)
+}
+
+func (h listHandler) handlePut(w http.ResponseWriter, r *http.Request, id int64) {
+	l, ok := h.parseList(w, r)
+	if !ok {
+		return
+	}
+	if l.ID != 0 && l.ID != id {
+		h.writeError(w, r, http.StatusBadRequest, constants.MalformedURLErrorCode,
+			fmt.Sprintf("resource ID in url (%d) doesn't match resource ID in request body (%d)", id, l.ID))
+		return
+	}
+	l.ID = id
+	if _, ok := h.getOwnList(w, r, id); !ok {
+		return
+	}
+
+	errCode, err := h.todos.store.UpdateList(r.Context(), l)
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		switch errCode {
+		case constants.ListValidationErrorCode:
+			httpStatus = http.StatusBadRequest
+		case constants.ListNotFoundErrorCode:
+			httpStatus = http.StatusNotFound
+		}
+		h.writeError(w, r, httpStatus, errCode, err.Error())
+		return
+	}
+
+	l.SelfRef = listSelfRef(id)
+	h.writeJSON(w, r, http.StatusOK, l)
+}
+
+// handleDelete deletes a list and all of its todos. A ToDoDeleted event is published for
+// each of the todos.
+func (h listHandler) handleDelete(w http.ResponseWriter, r *http.Request, id int64) {
+	if _, ok := h.getOwnList(w, r, id); !ok {
+		return
+	}
+
+	var deleted []todo.Item
+	if h.todos.publisher != nil && id != todo.DefaultListID {
+		var err error
+		deleted, err = h.listToDos(r.Context(), user.FromContext(r.Context()), id)
+		if err != nil {
+			h.writeError(w, r, http.StatusInternalServerError, constants.DBQueryErrorCode, err.Error())
+			return
+		}
+	}
+
+	errCode, err := h.todos.store.DeleteList(r.Context(), user.FromContext(r.Context()), id)
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		switch errCode {
+		case constants.ListValidationErrorCode:
+			// The default list can't be deleted
+			httpStatus = http.StatusBadRequest
+		case constants.ListNotFoundErrorCode:
+			httpStatus = http.StatusNotFound
+		}
+		h.writeError(w, r, httpStatus, errCode, err.Error())
+		return
+	}
+	for _, td := range deleted {
+		h.todos.publish(webhook.ToDoDeleted, td)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleGetToDos returns a page of the list's todos. It accepts the same query parameters
+// as '/todos'.
+func (h listHandler) handleGetToDos(w http.ResponseWriter, r *http.Request, id int64) {
+	l, _, ok := h.getList(w, r, id)
+	if !ok {
+		return
+	}
+
+	path := "lists/" + strconv.FormatInt(id, 10) + "/todos"
+	owner := l.ItemOwner(user.FromContext(r.Context()))
+	payload, errCode, err := h.todos.handleGetToDoList(r.Context(), owner, path, id, r.URL.Query())
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		if errCode == constants.MalformedURLErrorCode {
+			httpStatus = http.StatusBadRequest
+		}
+		h.writeError(w, r, httpStatus, errCode, err.Error())
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, payload)
+}
+
+// handlePostToDo creates a todo in the list. The todo's list ID, if any, must match the
+// list in the URL. A todo created by an editor of a shared list belongs to the list's owner.
+func (h listHandler) handlePostToDo(w http.ResponseWriter, r *http.Request, id int64) {
+	td, _, errCode, err := parseRqst(r, h.todos.logger)
+	if err != nil {
+		writeProblem(w, r, http.StatusBadRequest, errCode, err.Error())
+		return
+	}
+	if td.ListID != 0 && td.ListID != id {
+		h.writeError(w, r, http.StatusBadRequest, constants.MalformedURLErrorCode,
+			fmt.Sprintf("list ID in url (%d) doesn't match list ID in request body (%d)", id, td.ListID))
+		return
+	}
+	l, role, ok := h.getList(w, r, id)
+	if !ok {
+		return
+	}
+	if !role.CanEdit() {
+		h.writeError(w, r, http.StatusForbidden, constants.UserForbiddenErrorCode,
+			fmt.Sprintf("the %s role can't add todos to list %d", role, id))
+		return
+	}
+
+	td.ListID = id
+	td.Owner = l.ItemOwner(td.Owner)
+	h.todos.handlePost(w, r, td, []string{"todos"})
+}
+
+// listToDos returns all of 'owner's todos in the list identified by 'id'
+func (h listHandler) listToDos(ctx context.Context, owner string, id int64) ([]todo.Item, error) {
+	opts := todo.ListOptions{Owner: owner, ListID: id, Limit: todo.MaxPageLimit}
+
+	var tds []todo.Item
+	for {
+		tdl, more, err := h.todos.store.GetToDoListPage(ctx, opts)
+		if err != nil {
+			return nil, errors.Annotate(err, "error retrieving the list's todos")
+		}
+		for _, td := range tdl.Items {
+			td.SelfRef = itemSelfRef(td.ID)
+			tds = append(tds, *td)
+		}
+		if !more || len(tdl.Items) == 0 {
+			return tds, nil
+		}
+		opts.After = tdl.Items[len(tdl.Items)-1].ID
+	}
+}
+
+// getList returns the list identified by 'id', and the requester's role on it, if it's
+// visible to the requester. If it can't be returned the error response has been written
+// and false is returned.
+func (h listHandler) getList(w http.ResponseWriter, r *http.Request, id int64) (*todo.ListInfo, todo.Role, bool) {
+	l, role, err := h.todos.store.GetListRole(r.Context(), user.FromContext(r.Context()), id)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, constants.DBQueryErrorCode, err.Error())
+		return nil, "", false
+	}
+	if l == nil {
+		h.writeError(w, r, http.StatusNotFound, constants.ListNotFoundErrorCode,
+			fmt.Sprintf("list %d doesn't exist", id))
+		return nil, "", false
+	}
+
+	l.SelfRef = listSelfRef(id)
+	return l, role, true
+}
+
+// getOwnList returns the list identified by 'id' if the requester owns it. If it isn't
+// visible to the requester a 404 response has been written, if it's only shared with
+// them a 403, and false is returned.
+func (h listHandler) getOwnList(w http.ResponseWriter, r *http.Request, id int64) (*todo.ListInfo, bool) {
+	l, role, ok := h.getList(w, r, id)
+	if !ok {
+		return nil, false
+	}
+	if role != todo.RoleOwner {
+		h.writeError(w, r, http.StatusForbidden, constants.UserForbiddenErrorCode,
+			fmt.Sprintf("only the owner of list %d can change it or its shares, the requester is a %s", id, role))
+		return nil, false
+	}
+	return l, true
+}
+
+// parseList decodes the list in the request body, the list's owner is always the requester.
+// If it can't be decoded the error response has been written and false is returned.
+func (h listHandler) parseList(w http.ResponseWriter, r *http.Request) (todo.ListInfo, bool) {
+	l := todo.ListInfo{}
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	err := d.Decode(&l)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, constants.JSONDecodingErrorCode,
+			errors.Annotate(err, "error occurred while unmarshaling request body").Error())
+		return todo.ListInfo{}, false
+	}
+	l.Owner = user.FromContext(r.Context())
+	return l, true
+}
+
+// writeJSON writes 'body', JSON encoded, with 'httpStatus'
+func (h listHandler) writeJSON(w http.ResponseWriter, r *http.Request, httpStatus int, body interface{}) {
+	marshBody, err := json.Marshal(body)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, constants.JSONMarshalingErrorCode, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	w.Write(marshBody)
+}
+
+// writeError logs, and writes the problem details response for, a failed request
+func (h listHandler) writeError(w http.ResponseWriter, r *http.Request, httpStatus int, errCode constants.ErrCode, detail string) {
+	h.todos.logger.WithFields(log.Fields{
+		constants.ErrorCode:   errCode,
+		constants.HTTPStatus:  httpStatus,
+		constants.Path:        r.URL.Path,
+		constants.ErrorDetail: detail,
+	}).Error(errCode.Message())
+	writeProblem(w, r, httpStatus, errCode, detail)
+}
+
+// listSelfRef returns the resource path of the list identified by 'id'
+func listSelfRef(id int64) string {
+	return "/lists/" + strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/metrics"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+var (
+	httpRequests = metrics.DefaultRegistry.NewCounterVec("todod_http_requests_total",
+		"Total number of HTTP requests by method, route, and response status.", "method", "route", "status")
+	httpRequestDuration = metrics.DefaultRegistry.NewHistogramVec("todod_http_request_duration_seconds",
+		"HTTP request latency by method, route, and response status.", metrics.DefaultBuckets, "method", "route", "status")
+	errorResponses = metrics.DefaultRegistry.NewCounterVec("todod_errors_total",
+		"Total number of errors reported to clients by error code, including bulk sub-request errors.", "errcode")
+	bulkInsertsInFlight = metrics.DefaultRegistry.NewGauge("todod_bulk_insert_in_flight",
+		"Number of bulk POST item inserts currently being processed.")
+)
+
+func init() {
+	metrics.DefaultRegistry.NewGaugeFunc("todod_bulk_insert_queue_depth",
+		"Number of bulk POST item inserts waiting to be processed.",
+		func() float64 { return float64(len(InsertToDoRqstChan)) })
+}
+
+// routeNodes are the URL path nodes that are part of a route, other nodes are identifiers
+var routeNodes = map[string]bool{
+	"todos": true, "events": true, "lists": true, "shares": true, "webhooks": true,
+	"deliveries": true, "users": true, "health": true, "livez": true, "readyz": true, "metrics": true,
+}
+
+// metricsHandler records the count and latency of the requests handled by 'next'
+type metricsHandler struct {
+	next   http.Handler
+	logger *log.Entry
+}
+
+// NewMetricsHandler returns a handler that records the count and latency of each request
+// handled by 'next', by method, route, and response status
+func NewMetricsHandler(next http.Handler, logger *log.Entry) (http.Handler, error) {
+	if next == nil {
+		return nil, errors.New("non-nil http.Handler required")
+	}
+	if logger == nil {
+		return nil, errors.New("non-nil log.Entry  required")
+	}
+	return metricsHandler{next: next, logger: logger}, nil
+}
+
+// ServeHTTP passes the request to 'next' and records it once it's been handled
+func (h metricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	h.next.ServeHTTP(sw, r)
+
+	method, route, status := metricsMethod(r.Method), metricsRoute(r.URL.Path), strconv.Itoa(sw.status)
+	httpRequests.Inc(method, route, status)
+	httpRequestDuration.Observe(time.Since(start).Seconds(), method, route, status)
+}
+
+// metricsMethod returns the method label for 'method'. Nonstandard methods are reported as
+// OTHER so clients can't create an unbounded number of series.
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions:
+		return method
+	}
+	return "OTHER"
+}
+
+// metricsRoute returns the route label for 'path', the path with its identifiers replaced
+// by placeholders, e.g., '/lists/{id}/shares/{username}' for '/lists/2/shares/jdoe'.
+// Unknown paths are reported as 'other' so clients can't create an unbounded number of
+// series.
+func metricsRoute(path string) string {
+	pathNodes, err := getURLPathNodes(path)
+	if err != nil || len(pathNodes) == 0 || !routeNodes[pathNodes[0]] {
+		return "other"
+	}
+	for i, node := range pathNodes {
+		switch {
+		case routeNodes[node]:
+		case i > 0 && pathNodes[i-1] == "shares":
+			pathNodes[i] = "{username}"
+		default:
+			pathNodes[i] = "{id}"
+		}
+	}
+	return "/" + strings.Join(pathNodes, "/")
+}
+
+// countError records that an error identified by 'errCode' was reported to a client
+func countError(errCode constants.ErrCode) {
+	errorResponses.Inc(strconv.Itoa(int(errCode)))
+}
+
+// statusWriter records the status of the response it writes. It's an http.Flusher if the
+// http.ResponseWriter it wraps is, so streaming responses can be recorded.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader records 'status' and writes it
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write writes 'b', the status is 200 if it hasn't been written
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush flushes the wrapped http.ResponseWriter if it's an http.Flusher
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/metrics"
+)
+
+func TestMetricsRoute(t *testing.T) {
+	tcs := []struct {
+		path          string
+		expectedRoute string
+	}{
+		{path: "/todos", expectedRoute: "/todos"},
+		{path: "/todos/", expectedRoute: "/todos"},
+		{path: "/todos/12", expectedRoute: "/todos/{id}"},
+		{path: "/todos/events", expectedRoute: "/todos/events"},
+		{path: "/lists/2/todos", expectedRoute: "/lists/{id}/todos"},
+		{path: "/lists/2/shares/jdoe", expectedRoute: "/lists/{id}/shares/{username}"},
+		{path: "/webhooks/3/deliveries", expectedRoute: "/webhooks/{id}/deliveries"},
+		{path: "/metrics", expectedRoute: "/metrics"},
+		{path: "/", expectedRoute: "other"},
+		{path: "/unknown/1", expectedRoute: "other"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.path, func(t *testing.T) {
+			route := metricsRoute(tc.path)
+			if route != tc.expectedRoute {
+				t.Errorf("expected route %s, got %s", tc.expectedRoute, route)
+			}
+		})
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Errorf("expected the http.ResponseWriter to be an http.Flusher")
+		}
+		if r.URL.Path == "/todos/404" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte("done"))
+	})
+	h, err := NewMetricsHandler(next, log.NewEntry(log.New()))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected creating the metrics handler", err)
+	}
+	testSrv := httptest.NewServer(h)
+	defer testSrv.Close()
+
+	for _, path := range []string{"/todos/1", "/todos/2", "/todos/404"} {
+		resp, err := http.Get(testSrv.URL + path)
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected calling todod server", err)
+		}
+		resp.Body.Close()
+	}
+
+	rr := httptest.NewRecorder()
+	metrics.DefaultRegistry.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := ioutil.ReadAll(rr.Body)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected reading the metrics", err)
+	}
+
+	for _, expected := range []string{
+		`todod_http_requests_total{method="GET",route="/todos/{id}",status="200"} 2`,
+		`todod_http_requests_total{method="GET",route="/todos/{id}",status="404"} 1`,
+		`todod_http_request_duration_seconds_count{method="GET",route="/todos/{id}",status="200"} 2`,
+		"# TYPE todod_bulk_insert_queue_depth gauge",
+		"# TYPE todod_bulk_insert_in_flight gauge",
+	} {
+		if !strings.Contains(string(body), expected) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", expected, body)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+const (
+	// problemContentType is the media type of a problem details response body
+	problemContentType = "application/problem+json"
+	// problemTypePrefix is combined with an ErrCode to identify the type of a problem
+	problemTypePrefix = "urn:todoshaleapps:errcode:"
+)
+
+// problem is the body of an error response as described by RFC 7807, "Problem Details
+// for HTTP APIs". ErrCode is an extension member containing the application's error code.
+type problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	ErrCode  constants.ErrCode `json:"errcode"`
+}
+
+// newProblem returns the problem details for a request that failed with 'httpStatus' for
+// the reason identified by 'errCode'. 'detail' explains this particular failure. It's
+// omitted for internal server errors since it can expose implementation details, e.g.,
+// database errors, that aren't useful to the client. The problem is counted in the
+// todod_errors_total metric.
+func newProblem(r *http.Request, httpStatus int, errCode constants.ErrCode, detail string) *problem {
+	countError(errCode)
+	p := &problem{
+		Type:     problemTypePrefix + strconv.Itoa(int(errCode)),
+		Title:    errCode.Message(),
+		Status:   httpStatus,
+		Instance: r.URL.Path,
+		ErrCode:  errCode,
+	}
+	if httpStatus != http.StatusInternalServerError {
+		p.Detail = detail
+	}
+	return p
+}
+
+// writeProblem writes an RFC 7807 problem details response. See newProblem.
+func writeProblem(w http.ResponseWriter, r *http.Request, httpStatus int, errCode constants.ErrCode, detail string) {
+	body, err := json.Marshal(newProblem(r, httpStatus, errCode, detail))
+	if err != nil {
+		// Shouldn't happen, but the status is more important than the body
+		w.WriteHeader(httpStatus)
+		return
+	}
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(httpStatus)
+	w.Write(body)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/logging"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+const (
+	// RequestIDHeader is the header that identifies a request, in the request and its response
+	RequestIDHeader = "X-Request-ID"
+	// maxRequestIDLen is the length of the longest request ID accepted from a client
+	maxRequestIDLen = 128
+)
+
+// requestIDHandler identifies the requests handled by 'next'
+type requestIDHandler struct {
+	next   http.Handler
+	logger *log.Entry
+}
+
+// NewRequestIDHandler returns a handler that identifies each request with its X-Request-ID
+// header, generating one if the client didn't provide a valid one. The ID is echoed in the
+// response and is added to the messages logged while handling the request, see
+// requestLogger.
+func NewRequestIDHandler(next http.Handler, logger *log.Entry) (http.Handler, error) {
+	if next == nil {
+		return nil, errors.New("non-nil http.Handler required")
+	}
+	if logger == nil {
+		return nil, errors.New("non-nil log.Entry  required")
+	}
+	return requestIDHandler{next: next, logger: logger}, nil
+}
+
+// ServeHTTP passes the request, carrying a logger with its ID, to 'next'
+func (h requestIDHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id := r.Header.Get(RequestIDHeader)
+	if !validRequestID(id) {
+		id = newRequestID()
+		r.Header.Set(RequestIDHeader, id)
+	}
+	w.Header().Set(RequestIDHeader, id)
+
+	logger := h.logger.WithField(constants.RequestID, id)
+	h.next.ServeHTTP(w, r.WithContext(logging.NewContext(r.Context(), logger)))
+}
+
+// requestLogger returns the logger for 'r's log messages, 'logger' with the request's ID,
+// or 'logger' if the request doesn't have one
+func requestLogger(r *http.Request, logger *log.Entry) *log.Entry {
+	return logging.FromContext(r.Context(), logger)
+}
+
+// validRequestID returns true if 'id' can be used as a request ID. IDs are logged and
+// echoed so they're limited to a reasonable length of printable ASCII characters.
+func validRequestID(id string) bool {
+	if len(id) == 0 || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random request ID, 32 hex digits
+func newRequestID() string {
+	b := make([]byte, 16)
+	// crypto/rand only fails if the OS's random source is unavailable, an all zero ID
+	// is better than failing the request
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
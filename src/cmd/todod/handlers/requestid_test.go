@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+func TestRequestIDHandler(t *testing.T) {
+	tcs := []struct {
+		testName    string
+		requestID   string
+		expectedID  string
+		expectNewID bool
+	}{
+		{
+			testName:   "testProvidedID",
+			requestID:  "3f2c9a1e-7d4b-4c8e-9a6f-1b2d3e4f5a6b",
+			expectedID: "3f2c9a1e-7d4b-4c8e-9a6f-1b2d3e4f5a6b",
+		},
+		{
+			testName:    "testMissingID",
+			expectNewID: true,
+		},
+		{
+			testName:    "testInvalidID",
+			requestID:   "has spaces",
+			expectNewID: true,
+		},
+		{
+			testName:    "testTooLongID",
+			requestID:   strings.Repeat("a", maxRequestIDLen+1),
+			expectNewID: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			var logged bytes.Buffer
+			l := log.New()
+			l.SetFormatter(&log.JSONFormatter{})
+			l.SetOutput(&logged)
+			logger := log.NewEntry(l)
+
+			var rqstID string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				rqstID = r.Header.Get(RequestIDHeader)
+				requestLogger(r, log.NewEntry(log.New())).Info("handling request")
+			})
+			h, err := NewRequestIDHandler(next, logger)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating the request ID handler", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/todos", nil)
+			if len(tc.requestID) > 0 {
+				req.Header.Set(RequestIDHeader, tc.requestID)
+			}
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+
+			respID := rr.Header().Get(RequestIDHeader)
+			if tc.expectNewID {
+				if len(respID) != 32 || respID == tc.requestID {
+					t.Errorf("expected a generated request ID, got %q", respID)
+				}
+			} else if respID != tc.expectedID {
+				t.Errorf("expected request ID %q, got %q", tc.expectedID, respID)
+			}
+			if rqstID != respID {
+				t.Errorf("expected the request to have ID %q, got %q", respID, rqstID)
+			}
+
+			var entry map[string]interface{}
+			err = json.Unmarshal(logged.Bytes(), &entry)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected unmarshaling log message %q", err, logged.String())
+			}
+			if entry[constants.RequestID] != respID {
+				t.Errorf("expected the log message to have request ID %q, got %v", respID, entry[constants.RequestID])
+			}
+		})
+	}
+}
@@ -0,0 +1,424 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+)
+
+// newSharedStore returns a MemStore containing ryoungkin's list 2, shared with 'editor' and
+// 'viewer', and ryoungkin's todos 1, in the default list, and 2, in list 2
+func newSharedStore(t *testing.T) *todo.MemStore {
+	store := todo.NewMemStore()
+	_, err := store.InsertList(context.Background(), todo.ListInfo{Name: "sprint", Description: "current sprint", Owner: "ryoungkin"})
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected populating the list store", err)
+	}
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+	_, err = store.InsertToDos(context.Background(), []todo.Item{
+		{Note: "walk the dog", DueDate: date, Owner: "ryoungkin"},
+		{Note: "fix bug", DueDate: date, ListID: 2, Owner: "ryoungkin"},
+	})
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected populating the todo store", err)
+	}
+	for _, sh := range []todo.Share{
+		{ListID: 2, Username: "editor", Role: todo.RoleEditor},
+		{ListID: 2, Username: "viewer", Role: todo.RoleViewer},
+	} {
+		_, _, err = store.PutShare(context.Background(), sh)
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected populating the share store", err)
+		}
+	}
+	return store
+}
+
+func TestListSharing(t *testing.T) {
+	client := &http.Client{}
+
+	tcs := []struct {
+		testName           string
+		requester          string
+		method             string
+		url                string
+		data               string
+		expectedHTTPStatus int
+		expectedErrCode    constants.ErrCode
+		expectedBody       string
+	}{
+		{
+			testName:           "testGetShares",
+			requester:          "ryoungkin",
+			method:             http.MethodGet,
+			url:                "/lists/2/shares",
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody:       `{"shares":[{"listid":2,"username":"editor","role":"editor"},{"listid":2,"username":"viewer","role":"viewer"}]}`,
+		},
+		{
+			testName:           "testGetSharesEditor",
+			requester:          "editor",
+			method:             http.MethodGet,
+			url:                "/lists/2/shares",
+			expectedHTTPStatus: http.StatusForbidden,
+			expectedErrCode:    constants.UserForbiddenErrorCode,
+		},
+		{
+			testName:           "testGetSharesOtherUser",
+			requester:          "jdoe",
+			method:             http.MethodGet,
+			url:                "/lists/2/shares",
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.ListNotFoundErrorCode,
+		},
+		{
+			testName:           "testPutShareCreated",
+			requester:          "ryoungkin",
+			method:             http.MethodPut,
+			url:                "/lists/2/shares/jdoe",
+			data:               `{"role":"viewer"}`,
+			expectedHTTPStatus: http.StatusCreated,
+			expectedBody:       `{"listid":2,"username":"jdoe","role":"viewer"}`,
+		},
+		{
+			testName:           "testPutShareUpdated",
+			requester:          "ryoungkin",
+			method:             http.MethodPut,
+			url:                "/lists/2/shares/viewer",
+			data:               `{"role":"editor"}`,
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody:       `{"listid":2,"username":"viewer","role":"editor"}`,
+		},
+		{
+			testName:           "testPutShareInvalidRole",
+			requester:          "ryoungkin",
+			method:             http.MethodPut,
+			url:                "/lists/2/shares/jdoe",
+			data:               `{"role":"owner"}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.ListValidationErrorCode,
+		},
+		{
+			testName:           "testPutShareWithOwner",
+			requester:          "ryoungkin",
+			method:             http.MethodPut,
+			url:                "/lists/2/shares/ryoungkin",
+			data:               `{"role":"viewer"}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.ListValidationErrorCode,
+		},
+		{
+			testName:           "testPutShareDefaultList",
+			requester:          "ryoungkin",
+			method:             http.MethodPut,
+			url:                "/lists/1/shares/jdoe",
+			data:               `{"role":"viewer"}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.ListValidationErrorCode,
+		},
+		{
+			testName:           "testPutShareEditor",
+			requester:          "editor",
+			method:             http.MethodPut,
+			url:                "/lists/2/shares/jdoe",
+			data:               `{"role":"editor"}`,
+			expectedHTTPStatus: http.StatusForbidden,
+			expectedErrCode:    constants.UserForbiddenErrorCode,
+		},
+		{
+			testName:           "testDeleteShare",
+			requester:          "ryoungkin",
+			method:             http.MethodDelete,
+			url:                "/lists/2/shares/viewer",
+			expectedHTTPStatus: http.StatusOK,
+		},
+		{
+			testName:           "testDeleteOwnShare",
+			requester:          "viewer",
+			method:             http.MethodDelete,
+			url:                "/lists/2/shares/viewer",
+			expectedHTTPStatus: http.StatusOK,
+		},
+		{
+			testName:           "testDeleteOtherUsersShare",
+			requester:          "viewer",
+			method:             http.MethodDelete,
+			url:                "/lists/2/shares/editor",
+			expectedHTTPStatus: http.StatusForbidden,
+			expectedErrCode:    constants.UserForbiddenErrorCode,
+		},
+		{
+			testName:           "testDeleteShareNotFound",
+			requester:          "ryoungkin",
+			method:             http.MethodDelete,
+			url:                "/lists/2/shares/jdoe",
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.ListShareNotFoundErrorCode,
+		},
+		{
+			testName:           "testGetSharedLists",
+			requester:          "viewer",
+			method:             http.MethodGet,
+			url:                "/lists",
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody:       `{"lists":[{"id":1,"selfref":"/lists/1","name":"default","description":"","owner":""},{"id":2,"selfref":"/lists/2","name":"sprint","description":"current sprint","owner":"ryoungkin"}]}`,
+		},
+		{
+			testName:           "testGetSharedListToDos",
+			requester:          "viewer",
+			method:             http.MethodGet,
+			url:                "/lists/2/todos",
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody:       `{"todolist":[{"id":2,"selfref":"/todos/2","listid":2,"owner":"ryoungkin","note":"fix bug","duedate":"2020-04-02T13:13:00Z","repeat":false,"completed":false,"version":1}]}`,
+		},
+		{
+			testName:           "testPostSharedListToDoEditor",
+			requester:          "editor",
+			method:             http.MethodPost,
+			url:                "/lists/2/todos",
+			data:               `{"note":"write tests","duedate":"2020-04-02T13:13:00Z"}`,
+			expectedHTTPStatus: http.StatusCreated,
+		},
+		{
+			testName:           "testPostSharedListToDoViewer",
+			requester:          "viewer",
+			method:             http.MethodPost,
+			url:                "/lists/2/todos",
+			data:               `{"note":"write tests","duedate":"2020-04-02T13:13:00Z"}`,
+			expectedHTTPStatus: http.StatusForbidden,
+			expectedErrCode:    constants.UserForbiddenErrorCode,
+		},
+		{
+			testName:           "testPutSharedListEditor",
+			requester:          "editor",
+			method:             http.MethodPut,
+			url:                "/lists/2",
+			data:               `{"name":"next sprint"}`,
+			expectedHTTPStatus: http.StatusForbidden,
+			expectedErrCode:    constants.UserForbiddenErrorCode,
+		},
+		{
+			testName:           "testDeleteSharedListEditor",
+			requester:          "editor",
+			method:             http.MethodDelete,
+			url:                "/lists/2",
+			expectedHTTPStatus: http.StatusForbidden,
+			expectedErrCode:    constants.UserForbiddenErrorCode,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := newSharedStore(t)
+			srvHandler, err := NewListHandler(store, nil, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a list handler", err)
+			}
+
+			testSrv := httptest.NewServer(withRequester(srvHandler, tc.requester))
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(tc.method, testSrv.URL+tc.url, bytes.NewBuffer([]byte(tc.data)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, resp.StatusCode)
+			}
+
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(resp.Body)
+			if tc.expectedHTTPStatus >= http.StatusBadRequest {
+				p := problem{}
+				err = json.Unmarshal(buf.Bytes(), &p)
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected decoding the problem details", err)
+				}
+				if p.ErrCode != tc.expectedErrCode {
+					t.Errorf("expected errcode %d, got %+v", tc.expectedErrCode, p)
+				}
+				return
+			}
+			if len(tc.expectedBody) > 0 && buf.String() != tc.expectedBody {
+				t.Errorf("expected body %s, got %s", tc.expectedBody, buf.String())
+			}
+		})
+	}
+}
+
+func TestToDoSharing(t *testing.T) {
+	client := &http.Client{}
+
+	tcs := []struct {
+		testName           string
+		requester          string
+		method             string
+		url                string
+		data               string
+		contentType        string
+		expectedHTTPStatus int
+		expectedErrCode    constants.ErrCode
+		expectChanged      bool
+	}{
+		{
+			testName:           "testGetSharedToDoViewer",
+			requester:          "viewer",
+			method:             http.MethodGet,
+			url:                "/todos/2",
+			expectedHTTPStatus: http.StatusOK,
+		},
+		{
+			testName:           "testGetUnsharedToDoViewer",
+			requester:          "viewer",
+			method:             http.MethodGet,
+			url:                "/todos/1",
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.ToDoNotFoundErrorCode,
+		},
+		{
+			testName:           "testPutSharedToDoViewer",
+			requester:          "viewer",
+			method:             http.MethodPut,
+			url:                "/todos/2",
+			data:               `{"id":2,"listid":2,"note":"fix another bug","duedate":"2020-04-02T13:13:00Z"}`,
+			contentType:        "application/json",
+			expectedHTTPStatus: http.StatusForbidden,
+			expectedErrCode:    constants.UserForbiddenErrorCode,
+		},
+		{
+			testName:           "testPatchSharedToDoViewer",
+			requester:          "viewer",
+			method:             http.MethodPatch,
+			url:                "/todos/2",
+			data:               `{"completed":true}`,
+			contentType:        todo.MergePatchContentType,
+			expectedHTTPStatus: http.StatusForbidden,
+			expectedErrCode:    constants.UserForbiddenErrorCode,
+		},
+		{
+			testName:           "testDeleteSharedToDoViewer",
+			requester:          "viewer",
+			method:             http.MethodDelete,
+			url:                "/todos/2",
+			expectedHTTPStatus: http.StatusForbidden,
+			expectedErrCode:    constants.UserForbiddenErrorCode,
+		},
+		{
+			testName:           "testPutSharedToDoEditor",
+			requester:          "editor",
+			method:             http.MethodPut,
+			url:                "/todos/2",
+			data:               `{"id":2,"listid":2,"note":"fix another bug","duedate":"2020-04-02T13:13:00Z"}`,
+			contentType:        "application/json",
+			expectedHTTPStatus: http.StatusOK,
+			expectChanged:      true,
+		},
+		{
+			testName:           "testPatchSharedToDoEditor",
+			requester:          "editor",
+			method:             http.MethodPatch,
+			url:                "/todos/2",
+			data:               `{"completed":true}`,
+			contentType:        todo.MergePatchContentType,
+			expectedHTTPStatus: http.StatusOK,
+			expectChanged:      true,
+		},
+		{
+			testName:           "testPatchSharedToDoEditorMove",
+			requester:          "editor",
+			method:             http.MethodPatch,
+			url:                "/todos/2",
+			data:               `{"listid":1}`,
+			contentType:        todo.MergePatchContentType,
+			expectedHTTPStatus: http.StatusForbidden,
+			expectedErrCode:    constants.UserForbiddenErrorCode,
+		},
+		{
+			// A listid of 0 leaves the todo in its list, it isn't a move
+			testName:           "testPatchSharedToDoEditorZeroList",
+			requester:          "editor",
+			method:             http.MethodPatch,
+			url:                "/todos/2",
+			data:               `{"listid":0,"completed":true}`,
+			contentType:        todo.MergePatchContentType,
+			expectedHTTPStatus: http.StatusOK,
+			expectChanged:      true,
+		},
+		{
+			testName:           "testDeleteSharedToDoEditor",
+			requester:          "editor",
+			method:             http.MethodDelete,
+			url:                "/todos/2",
+			expectedHTTPStatus: http.StatusOK,
+			expectChanged:      true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := newSharedStore(t)
+			srvHandler, err := NewToDoHandler(store, nil, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
+			}
+
+			testSrv := httptest.NewServer(withRequester(srvHandler, tc.requester))
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(tc.method, testSrv.URL+tc.url, bytes.NewBuffer([]byte(tc.data)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			if len(tc.contentType) > 0 {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, resp.StatusCode)
+			}
+			if tc.expectedHTTPStatus >= http.StatusBadRequest {
+				p := problem{}
+				err = json.NewDecoder(resp.Body).Decode(&p)
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected decoding the problem details", err)
+				}
+				if p.ErrCode != tc.expectedErrCode {
+					t.Errorf("expected errcode %d, got %+v", tc.expectedErrCode, p)
+				}
+			}
+
+			td, err := store.GetToDoItem(context.Background(), "ryoungkin", 2)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected getting the todo", err)
+			}
+			unchanged := td != nil && td.Note == "fix bug" && !td.Completed && td.ListID == 2
+			if unchanged == tc.expectChanged {
+				t.Errorf("expected the todo changed = %t, got %+v", tc.expectChanged, td)
+			}
+			if td != nil && td.Owner != "ryoungkin" {
+				t.Errorf("expected the todo to still belong to ryoungkin, got %+v", td)
+			}
+		})
+	}
+}
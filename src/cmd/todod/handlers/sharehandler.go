@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/juju/errors"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+	"github.com/youngkin/todoshaleapps/src/internal/user"
+)
+
+// shareList is the response body of GET /lists/{id}/shares
+type shareList struct {
+	Shares []todo.Share `json:"shares"`
+}
+
+// handleGetShares returns the list's shares, only the list's owner can see them
+func (h listHandler) handleGetShares(w http.ResponseWriter, r *http.Request, id int64) {
+	if _, ok := h.getOwnList(w, r, id); !ok {
+		return
+	}
+
+	shares, err := h.todos.store.GetShares(r.Context(), id)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, constants.DBQueryErrorCode, err.Error())
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, shareList{Shares: shares})
+}
+
+// handlePutShare grants 'username' the role in the request body, '{"role":"editor"}' or
+// '{"role":"viewer"}', on the list. It replaces the role they have, if any.
+func (h listHandler) handlePutShare(w http.ResponseWriter, r *http.Request, id int64, username string) {
+	body := struct {
+		Role todo.Role `json:"role"`
+	}{}
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	err := d.Decode(&body)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, constants.JSONDecodingErrorCode,
+			errors.Annotate(err, "error occurred while unmarshaling request body").Error())
+		return
+	}
+
+	l, ok := h.getOwnList(w, r, id)
+	if !ok {
+		return
+	}
+	if username == l.Owner || (l.Owner == "" && username == user.FromContext(r.Context())) {
+		h.writeError(w, r, http.StatusBadRequest, constants.ListValidationErrorCode,
+			fmt.Sprintf("list %d can't be shared with its owner", id))
+		return
+	}
+
+	share := todo.Share{ListID: id, Username: username, Role: body.Role}
+	created, errCode, err := h.todos.store.PutShare(r.Context(), share)
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		switch errCode {
+		case constants.ListValidationErrorCode:
+			httpStatus = http.StatusBadRequest
+		case constants.ListNotFoundErrorCode:
+			httpStatus = http.StatusNotFound
+		}
+		h.writeError(w, r, httpStatus, errCode, err.Error())
+		return
+	}
+
+	httpStatus := http.StatusOK
+	if created {
+		httpStatus = http.StatusCreated
+	}
+	h.writeJSON(w, r, httpStatus, share)
+}
+
+// handleDeleteShare revokes 'username's access to the list. The list's owner can revoke
+// anyone's access, other users can only give up their own.
+func (h listHandler) handleDeleteShare(w http.ResponseWriter, r *http.Request, id int64, username string) {
+	_, role, ok := h.getList(w, r, id)
+	if !ok {
+		return
+	}
+	if role != todo.RoleOwner && username != user.FromContext(r.Context()) {
+		h.writeError(w, r, http.StatusForbidden, constants.UserForbiddenErrorCode,
+			fmt.Sprintf("only the owner of list %d can revoke other users' access", id))
+		return
+	}
+
+	errCode, err := h.todos.store.DeleteShare(r.Context(), id, username)
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		if errCode == constants.ListShareNotFoundErrorCode {
+			httpStatus = http.StatusNotFound
+		}
+		h.writeError(w, r, httpStatus, errCode, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,240 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+)
+
+func TestBulkDELETEToDos(t *testing.T) {
+	client := &http.Client{}
+
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	tcs := []struct {
+		testName             string
+		url                  string
+		deleteData           string
+		expectedHTTPStatus   int
+		expectedItemStatuses []int
+		expectedRemaining    []int64
+	}{
+		{
+			testName:             "testBulkDeleteByID",
+			url:                  "/todos?bulk=true",
+			deleteData:           `{"ids":[1,3]}`,
+			expectedHTTPStatus:   http.StatusOK,
+			expectedItemStatuses: []int{http.StatusOK, http.StatusOK},
+			expectedRemaining:    []int64{2},
+		},
+		{
+			testName:             "testBulkDeleteByIDNotFound",
+			url:                  "/todos?bulk=true",
+			deleteData:           `{"ids":[1,100]}`,
+			expectedHTTPStatus:   http.StatusConflict,
+			expectedItemStatuses: []int{http.StatusOK, http.StatusNotFound},
+			expectedRemaining:    []int64{2, 3},
+		},
+		{
+			testName:             "testBulkDeleteCompleted",
+			url:                  "/todos?bulk=true&completed=true",
+			expectedHTTPStatus:   http.StatusOK,
+			expectedItemStatuses: []int{http.StatusOK, http.StatusOK},
+			expectedRemaining:    []int64{2},
+		},
+		{
+			testName:           "testBulkDeleteNoFilter",
+			url:                "/todos?bulk=true",
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedRemaining:  []int64{1, 2, 3},
+		},
+		{
+			testName:           "testBulkDeleteInvalidFilter",
+			url:                "/todos?bulk=true&completed=maybe",
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedRemaining:  []int64{1, 2, 3},
+		},
+		{
+			testName:           "testBulkDeleteMalformedJSON",
+			url:                "/todos?bulk=true",
+			deleteData:         `{"ids":[1,`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedRemaining:  []int64{1, 2, 3},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := todo.NewMemStore()
+			_, err := store.InsertToDos(context.Background(), []todo.Item{
+				{Note: "walk the dog", DueDate: date, Completed: true},
+				{Note: "get groceries", DueDate: date},
+				{Note: "pay bills", DueDate: date, Completed: true},
+			})
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected populating the todo store", err)
+			}
+
+			srvHandler, err := NewToDoHandler(store, nil, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
+			}
+
+			testSrv := httptest.NewServer(http.HandlerFunc(srvHandler.ServeHTTP))
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(http.MethodDelete, testSrv.URL+tc.url, bytes.NewBuffer([]byte(tc.deleteData)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, resp.StatusCode)
+			}
+
+			if len(tc.expectedItemStatuses) > 0 {
+				resps := insertTodoResponses{}
+				err = json.NewDecoder(resp.Body).Decode(&resps)
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected decoding the response body", err)
+				}
+				if len(resps.Responses) != len(tc.expectedItemStatuses) {
+					t.Fatalf("expected %d responses, got %+v", len(tc.expectedItemStatuses), resps.Responses)
+				}
+				for i, r := range resps.Responses {
+					if r.HTTPStatus != tc.expectedItemStatuses[i] {
+						t.Errorf("expected response %d status %d, got %d", i, tc.expectedItemStatuses[i], r.HTTPStatus)
+					}
+				}
+			}
+
+			tdl, err := store.GetToDoList(context.Background(), "")
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected getting the todo list", err)
+			}
+			remaining := []int64{}
+			for _, td := range tdl.Items {
+				remaining = append(remaining, td.ID)
+			}
+			if len(remaining) != len(tc.expectedRemaining) {
+				t.Fatalf("expected remaining todos %v, got %v", tc.expectedRemaining, remaining)
+			}
+			for i := range remaining {
+				if remaining[i] != tc.expectedRemaining[i] {
+					t.Errorf("expected remaining todos %v, got %v", tc.expectedRemaining, remaining)
+				}
+			}
+		})
+	}
+}
+
+func TestBulkDELETESharedToDos(t *testing.T) {
+	client := &http.Client{}
+
+	tcs := []struct {
+		testName             string
+		requester            string
+		deleteData           string
+		expectedHTTPStatus   int
+		expectedItemStatuses []int
+		expectedRemaining    []int64
+	}{
+		{
+			testName:             "testBulkDeleteSharedEditor",
+			requester:            "editor",
+			deleteData:           `{"ids":[2]}`,
+			expectedHTTPStatus:   http.StatusOK,
+			expectedItemStatuses: []int{http.StatusOK},
+			expectedRemaining:    []int64{1},
+		},
+		{
+			testName:             "testBulkDeleteSharedViewer",
+			requester:            "viewer",
+			deleteData:           `{"ids":[2]}`,
+			expectedHTTPStatus:   http.StatusConflict,
+			expectedItemStatuses: []int{http.StatusForbidden},
+			expectedRemaining:    []int64{1, 2},
+		},
+		{
+			testName:             "testBulkDeleteUnsharedEditor",
+			requester:            "editor",
+			deleteData:           `{"ids":[1,2]}`,
+			expectedHTTPStatus:   http.StatusConflict,
+			expectedItemStatuses: []int{http.StatusNotFound, http.StatusOK},
+			expectedRemaining:    []int64{1},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := newSharedStore(t)
+			srvHandler, err := NewToDoHandler(store, nil, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
+			}
+
+			testSrv := httptest.NewServer(withRequester(srvHandler, tc.requester))
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(http.MethodDelete, testSrv.URL+"/todos?bulk=true", bytes.NewBuffer([]byte(tc.deleteData)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, resp.StatusCode)
+			}
+
+			resps := insertTodoResponses{}
+			err = json.NewDecoder(resp.Body).Decode(&resps)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected decoding the response body", err)
+			}
+			if len(resps.Responses) != len(tc.expectedItemStatuses) {
+				t.Fatalf("expected %d responses, got %+v", len(tc.expectedItemStatuses), resps.Responses)
+			}
+			for i, r := range resps.Responses {
+				if r.HTTPStatus != tc.expectedItemStatuses[i] {
+					t.Errorf("expected response %d status %d, got %d", i, tc.expectedItemStatuses[i], r.HTTPStatus)
+				}
+			}
+
+			remaining := []int64{}
+			for _, id := range []int64{1, 2} {
+				td, err := store.GetToDoItem(context.Background(), "ryoungkin", int(id))
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected getting todo %d", err, id)
+				}
+				if td != nil {
+					remaining = append(remaining, id)
+				}
+			}
+			if len(remaining) != len(tc.expectedRemaining) {
+				t.Fatalf("expected remaining todos %v, got %v", tc.expectedRemaining, remaining)
+			}
+			for i := range remaining {
+				if remaining[i] != tc.expectedRemaining[i] {
+					t.Errorf("expected remaining todos %v, got %v", tc.expectedRemaining, remaining)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+)
+
+func TestAtomicBulkPOSTToDos(t *testing.T) {
+	client := &http.Client{}
+
+	tcs := []struct {
+		testName             string
+		url                  string
+		postData             string
+		expectedHTTPStatus   int
+		expectedItemStatuses []int
+		expectedStoreSize    int
+	}{
+		{
+			testName:             "testAtomicBulkInsertSuccess",
+			url:                  "/todos?bulk=true&atomic=true",
+			postData:             `{"todolist":[{"note":"walk the dog","duedate":"2020-04-02T13:13:00Z"},{"note":"get groceries","duedate":"2020-04-03T13:13:00Z"}]}`,
+			expectedHTTPStatus:   http.StatusCreated,
+			expectedItemStatuses: []int{http.StatusCreated, http.StatusCreated},
+			expectedStoreSize:    2,
+		},
+		{
+			testName:             "testAtomicBulkInsertInvalidToDo",
+			url:                  "/todos?bulk=true&atomic=true",
+			postData:             `{"todolist":[{"note":"walk the dog","duedate":"2020-04-02T13:13:00Z"},{"note":""},{"id":5,"note":"pay bills"}]}`,
+			expectedHTTPStatus:   http.StatusBadRequest,
+			expectedItemStatuses: []int{http.StatusFailedDependency, http.StatusBadRequest, http.StatusBadRequest},
+			expectedStoreSize:    0,
+		},
+		{
+			testName:           "testAtomicBulkInsertInvalidAtomicParam",
+			url:                "/todos?bulk=true&atomic=maybe",
+			postData:           `{"todolist":[{"note":"walk the dog","duedate":"2020-04-02T13:13:00Z"}]}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedStoreSize:  0,
+		},
+		{
+			testName:           "testAtomicBulkInsertBadURL",
+			url:                "/todos/1?bulk=true&atomic=true",
+			postData:           `{"todolist":[{"note":"walk the dog","duedate":"2020-04-02T13:13:00Z"}]}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedStoreSize:  0,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := todo.NewMemStore()
+			srvHandler, err := NewToDoHandler(store, nil, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
+			}
+
+			testSrv := httptest.NewServer(http.HandlerFunc(srvHandler.ServeHTTP))
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(http.MethodPost, testSrv.URL+tc.url, bytes.NewBuffer([]byte(tc.postData)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, resp.StatusCode)
+			}
+
+			if len(tc.expectedItemStatuses) > 0 {
+				resps := insertTodoResponses{}
+				err = json.NewDecoder(resp.Body).Decode(&resps)
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected decoding the response body", err)
+				}
+				if len(resps.Responses) != len(tc.expectedItemStatuses) {
+					t.Fatalf("expected %d responses, got %+v", len(tc.expectedItemStatuses), resps.Responses)
+				}
+				for i, r := range resps.Responses {
+					if r.HTTPStatus != tc.expectedItemStatuses[i] {
+						t.Errorf("expected response %d status %d, got %d", i, tc.expectedItemStatuses[i], r.HTTPStatus)
+					}
+					if r.HTTPStatus == http.StatusCreated && (r.Item.ID == 0 || len(r.Item.SelfRef) == 0) {
+						t.Errorf("expected response %d to have an ID and selfref, got %+v", i, r.Item)
+					}
+					if r.HTTPStatus != http.StatusCreated && r.Problem == nil {
+						t.Errorf("expected response %d to have problem details", i)
+					}
+				}
+			}
+
+			tdl, err := store.GetToDoList(context.Background(), "")
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected getting the todo list", err)
+			}
+			if len(tdl.Items) != tc.expectedStoreSize {
+				t.Errorf("expected %d todos in the store, got %d", tc.expectedStoreSize, len(tdl.Items))
+			}
+		})
+	}
+}
+
+// TestBulkPOSTAbandoned verifies that a bulk POST doesn't wait forever for inserts that
+// can't be launched, e.g., because PostRequestLauncher has stopped during shutdown, or that
+// never finish, once its request is cancelled
+func TestBulkPOSTAbandoned(t *testing.T) {
+	tcs := []struct {
+		testName string
+		// launched is true if the inserts are received, but never done
+		launched bool
+	}{
+		{
+			testName: "testBulkPOSTNotLaunched",
+		},
+		{
+			testName: "testBulkPOSTNotInserted",
+			launched: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			rqstChan := make(chan insertTodoRequest)
+			defer func(c chan insertTodoRequest) { InsertToDoRqstChan = c }(InsertToDoRqstChan)
+			InsertToDoRqstChan = rqstChan
+			if tc.launched {
+				defer close(rqstChan)
+				go func() {
+					for range rqstChan {
+					}
+				}()
+			}
+
+			srvHandler, err := NewToDoHandler(todo.NewMemStore(), nil, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			postData := `{"todolist":[{"note":"walk the dog","duedate":"2020-04-02T13:13:00Z"},{"note":"get groceries","duedate":"2020-04-03T13:13:00Z"}]}`
+			req := httptest.NewRequest(http.MethodPost, "/todos?bulk=true", bytes.NewBuffer([]byte(postData))).WithContext(ctx)
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+
+			done := make(chan struct{})
+			go func() {
+				srvHandler.ServeHTTP(w, req)
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("expected the bulk POST to give up on its inserts when its request was cancelled")
+			}
+
+			if w.Code != http.StatusConflict {
+				t.Errorf("expected StatusCode = %d, got %d", http.StatusConflict, w.Code)
+			}
+			resps := insertTodoResponses{}
+			err = json.NewDecoder(w.Body).Decode(&resps)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected decoding the response body", err)
+			}
+			if len(resps.Responses) != 2 {
+				t.Fatalf("expected 2 responses, got %+v", resps.Responses)
+			}
+			for i, r := range resps.Responses {
+				if r.HTTPStatus != http.StatusServiceUnavailable || r.Problem == nil ||
+					r.Problem.ErrCode != constants.ToDoInsertAbandonedErrorCode {
+					t.Errorf("expected response %d to report the insert was abandoned, got %+v", i, r)
+				}
+			}
+		})
+	}
+}
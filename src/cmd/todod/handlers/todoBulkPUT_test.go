@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+)
+
+func TestBulkPUTToDos(t *testing.T) {
+	client := &http.Client{}
+
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	tcs := []struct {
+		testName             string
+		url                  string
+		putData              string
+		expectedHTTPStatus   int
+		expectedItemStatuses []int
+		// expectedVersions are the versions of the todos in the responses
+		expectedVersions []int64
+		expectedNotes    []string
+		// rolledForward is true if the first todo is repeating and was completed, its
+		// response must contain it rolled forward to its next due date
+		rolledForward bool
+	}{
+		{
+			testName:             "testBulkUpdateSuccess",
+			url:                  "/todos?bulk=true",
+			putData:              `{"todolist":[{"id":1,"note":"walk the cat","duedate":"2020-04-02T13:13:00Z"},{"id":2,"note":"get more groceries","duedate":"2020-04-02T13:13:00Z","version":1}]}`,
+			expectedHTTPStatus:   http.StatusOK,
+			expectedItemStatuses: []int{http.StatusOK, http.StatusOK},
+			expectedVersions:     []int64{2, 2},
+			expectedNotes:        []string{"walk the cat", "get more groceries"},
+		},
+		{
+			testName:             "testBulkUpdateRepeatingCompleted",
+			url:                  "/todos?bulk=true",
+			putData:              `{"todolist":[{"id":1,"note":"walk the dog","duedate":"2020-04-02T13:13:00Z","repeat":true,"completed":true}]}`,
+			expectedHTTPStatus:   http.StatusOK,
+			expectedItemStatuses: []int{http.StatusOK},
+			expectedVersions:     []int64{2},
+			expectedNotes:        []string{"walk the dog"},
+			rolledForward:        true,
+		},
+		{
+			testName: "testBulkUpdatePartialFailure",
+			url:      "/todos?bulk=true",
+			putData: `{"todolist":[{"id":1,"note":"walk the cat","duedate":"2020-04-02T13:13:00Z"},` +
+				`{"id":2,"note":"get more groceries","duedate":"2020-04-02T13:13:00Z","version":5},` +
+				`{"id":100,"note":"pay bills","duedate":"2020-04-02T13:13:00Z"},` +
+				`{"id":2,"note":""},` +
+				`{"note":"no id"}]}`,
+			expectedHTTPStatus: http.StatusConflict,
+			expectedItemStatuses: []int{http.StatusOK, http.StatusPreconditionFailed, http.StatusNotFound,
+				http.StatusBadRequest, http.StatusBadRequest},
+			expectedNotes: []string{"walk the cat", "get groceries"},
+		},
+		{
+			testName:           "testBulkUpdateBadURL",
+			url:                "/todos/1?bulk=true",
+			putData:            `{"todolist":[{"id":1,"note":"walk the cat","duedate":"2020-04-02T13:13:00Z"}]}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedNotes:      []string{"walk the dog", "get groceries"},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := todo.NewMemStore()
+			_, err := store.InsertToDos(context.Background(), []todo.Item{{Note: "walk the dog", DueDate: date}, {Note: "get groceries", DueDate: date}})
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected populating the todo store", err)
+			}
+
+			srvHandler, err := NewToDoHandler(store, nil, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
+			}
+
+			testSrv := httptest.NewServer(http.HandlerFunc(srvHandler.ServeHTTP))
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(http.MethodPut, testSrv.URL+tc.url, bytes.NewBuffer([]byte(tc.putData)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, resp.StatusCode)
+			}
+
+			if len(tc.expectedItemStatuses) > 0 {
+				resps := insertTodoResponses{}
+				err = json.NewDecoder(resp.Body).Decode(&resps)
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected decoding the response body", err)
+				}
+				if len(resps.Responses) != len(tc.expectedItemStatuses) {
+					t.Fatalf("expected %d responses, got %+v", len(tc.expectedItemStatuses), resps.Responses)
+				}
+				for i, r := range resps.Responses {
+					if r.HTTPStatus != tc.expectedItemStatuses[i] {
+						t.Errorf("expected response %d status %d, got %d", i, tc.expectedItemStatuses[i], r.HTTPStatus)
+					}
+					if i < len(tc.expectedVersions) && r.Item.Version != tc.expectedVersions[i] {
+						t.Errorf("expected response %d to contain version %d, got %+v", i, tc.expectedVersions[i], r.Item)
+					}
+				}
+				if tc.rolledForward {
+					if td := resps.Responses[0].Item; td.Completed || !td.DueDate.After(date) {
+						t.Errorf("expected the response to contain the todo rolled forward, got %+v", td)
+					}
+				}
+			}
+
+			tdl, err := store.GetToDoList(context.Background(), "")
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected getting the todo list", err)
+			}
+			for i, note := range tc.expectedNotes {
+				if tdl.Items[i].Note != note {
+					t.Errorf("expected todo %d note %q, got %q", tdl.Items[i].ID, note, tdl.Items[i].Note)
+				}
+			}
+		})
+	}
+}
+
+// TestBulkPUTSharedToDos verifies that bulk updates of todos in shared lists are authorized
+// the same way as PUT /todos/{id}
+func TestBulkPUTSharedToDos(t *testing.T) {
+	client := &http.Client{}
+
+	tcs := []struct {
+		testName           string
+		requester          string
+		putData            string
+		expectedItemStatus int
+		expectChanged      bool
+	}{
+		{
+			testName:           "testBulkUpdateSharedToDoViewer",
+			requester:          "viewer",
+			putData:            `{"todolist":[{"id":2,"listid":2,"note":"fix another bug","duedate":"2020-04-02T13:13:00Z"}]}`,
+			expectedItemStatus: http.StatusForbidden,
+		},
+		{
+			testName:           "testBulkUpdateSharedToDoEditorMove",
+			requester:          "editor",
+			putData:            `{"todolist":[{"id":2,"listid":1,"note":"fix another bug","duedate":"2020-04-02T13:13:00Z"}]}`,
+			expectedItemStatus: http.StatusForbidden,
+		},
+		{
+			testName:           "testBulkUpdateSharedToDoEditor",
+			requester:          "editor",
+			putData:            `{"todolist":[{"id":2,"listid":2,"note":"fix another bug","duedate":"2020-04-02T13:13:00Z"}]}`,
+			expectedItemStatus: http.StatusOK,
+			expectChanged:      true,
+		},
+		{
+			testName:           "testBulkUpdateUnsharedToDo",
+			requester:          "jdoe",
+			putData:            `{"todolist":[{"id":2,"listid":2,"note":"fix another bug","duedate":"2020-04-02T13:13:00Z"}]}`,
+			expectedItemStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := newSharedStore(t)
+			srvHandler, err := NewToDoHandler(store, nil, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
+			}
+
+			testSrv := httptest.NewServer(withRequester(srvHandler, tc.requester))
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(http.MethodPut, testSrv.URL+"/todos?bulk=true", bytes.NewBuffer([]byte(tc.putData)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			resps := insertTodoResponses{}
+			err = json.NewDecoder(resp.Body).Decode(&resps)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected decoding the response body", err)
+			}
+			if len(resps.Responses) != 1 || resps.Responses[0].HTTPStatus != tc.expectedItemStatus {
+				t.Fatalf("expected a response with status %d, got %+v", tc.expectedItemStatus, resps.Responses)
+			}
+			if tc.expectChanged && resps.Responses[0].Item.Owner != "ryoungkin" {
+				t.Errorf("expected the response to contain ryoungkin's todo, got %+v", resps.Responses[0].Item)
+			}
+
+			td, err := store.GetToDoItem(context.Background(), "ryoungkin", 2)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected getting the todo", err)
+			}
+			changed := td == nil || td.Note != "fix bug" || td.ListID != 2
+			if changed != tc.expectChanged {
+				t.Errorf("expected the todo changed = %t, got %+v", tc.expectChanged, td)
+			}
+		})
+	}
+}
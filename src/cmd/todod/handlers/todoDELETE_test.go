@@ -12,45 +12,43 @@ import (
 func TestDeleteToDo(t *testing.T) {
 	client := &http.Client{}
 
-	// date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
-
 	tcs := []TestCase{
-		// {
-		// 	testName:           "testDeleteTODOSuccess",
-		// 	shouldPass:         true,
-		// 	url:                "/todos/100",
-		// 	expectedHTTPStatus: http.StatusOK,
-		// 	updateResourceID:   "todos/100",
-		// 	expectedResourceID: "",
-		// 	postData:           `{"id":100,"note":"walk the dog","duedate":"2020-04-02T13:13:13Z","repeat":true,"completed":false}`,
-		// 	todo: todo.Item{
-		// 		ID:        100,
-		// 		Note:      "walk the dog",
-		// 		DueDate:   date,
-		// 		Repeat:    true,
-		// 		Completed: false,
-		// 	},
-		// 	setupFunc:    todo.DBDeleteSetupHelper,
-		// 	teardownFunc: todo.DBCallTeardownHelper,
-		// },
-		// {
-		// 	testName:           "testUpdateDBError",
-		// 	shouldPass:         false,
-		// 	url:                "/todos/100",
-		// 	expectedHTTPStatus: http.StatusInternalServerError,
-		// 	updateResourceID:   "todos/100",
-		// 	expectedResourceID: "",
-		// 	postData:           "{\"id\":100,\"note\":\"walk the dog\",\"duedate\":\"2020-04-02T13:13:13Z\",\"repeat\":true,\"completed\":false}",
-		// 	todo: todo.Item{
-		// 		ID:        100,
-		// 		Note:      "walk the dog",
-		// 		DueDate:   date,
-		// 		Repeat:    true,
-		// 		Completed: false,
-		// 	},
-		// 	setupFunc:    todo.DBUpdateErrorSetupHelper,
-		// 	teardownFunc: todo.DBCallTeardownHelper,
-		// },
+		{
+			testName:           "testDeleteTODOSuccess",
+			shouldPass:         true,
+			url:                "/todos/100",
+			expectedHTTPStatus: http.StatusOK,
+			updateResourceID:   "todos/100",
+			expectedResourceID: "",
+			postData:           "",
+			todo:               todo.Item{ID: 100},
+			setupFunc:          todo.DBDeleteSetupHelper,
+			teardownFunc:       todo.DBCallTeardownHelper,
+		},
+		{
+			testName:           "testDeleteTODONotFound",
+			shouldPass:         false,
+			url:                "/todos/100",
+			expectedHTTPStatus: http.StatusNotFound,
+			updateResourceID:   "todos/100",
+			expectedResourceID: "",
+			postData:           "",
+			todo:               todo.Item{ID: 100},
+			setupFunc:          todo.DBDeleteNotFoundSetupHelper,
+			teardownFunc:       todo.DBCallTeardownHelper,
+		},
+		{
+			testName:           "testDeleteDBError",
+			shouldPass:         false,
+			url:                "/todos/100",
+			expectedHTTPStatus: http.StatusInternalServerError,
+			updateResourceID:   "todos/100",
+			expectedResourceID: "",
+			postData:           "",
+			todo:               todo.Item{ID: 100},
+			setupFunc:          todo.DBDeleteErrorSetupHelper,
+			teardownFunc:       todo.DBCallTeardownHelper,
+		},
 		{
 			testName:           "testPUTInvalidURLMissingResourceID",
 			shouldPass:         false,
@@ -81,7 +79,12 @@ func TestDeleteToDo(t *testing.T) {
 		t.Run(tc.testName, func(t *testing.T) {
 			db, mock := tc.setupFunc(t, tc.todo)
 
-			srvHandler, err := NewToDoHandler(db, logger)
+			store, err := todo.NewPGStore(db, 0)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo store", err)
+			}
+
+			srvHandler, err := NewToDoHandler(store, nil, logger)
 			if err != nil {
 				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
 			}
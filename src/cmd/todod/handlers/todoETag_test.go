@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	client := &http.Client{}
+
+	tcs := []struct {
+		testName           string
+		method             string
+		url                string
+		header             string
+		headerValue        string
+		contentType        string
+		body               string
+		expectedHTTPStatus int
+		expectedETag       string
+	}{
+		{
+			testName:           "testGetReturnsETag",
+			method:             http.MethodGet,
+			url:                "/todos/1",
+			expectedHTTPStatus: http.StatusOK,
+			expectedETag:       `"1"`,
+		},
+		{
+			testName:           "testGetIfNoneMatchCurrent",
+			method:             http.MethodGet,
+			url:                "/todos/1",
+			header:             "If-None-Match",
+			headerValue:        `W/"7", "1"`,
+			expectedHTTPStatus: http.StatusNotModified,
+			expectedETag:       `"1"`,
+		},
+		{
+			testName:           "testGetIfNoneMatchStale",
+			method:             http.MethodGet,
+			url:                "/todos/1",
+			header:             "If-None-Match",
+			headerValue:        `"7"`,
+			expectedHTTPStatus: http.StatusOK,
+			expectedETag:       `"1"`,
+		},
+		{
+			testName:           "testPutIfMatchCurrent",
+			method:             http.MethodPut,
+			url:                "/todos/1",
+			header:             "If-Match",
+			headerValue:        `"1"`,
+			contentType:        "application/json",
+			body:               `{"id":1,"note":"walk the cat","duedate":"2020-04-02T13:13:13Z","repeat":true,"completed":false}`,
+			expectedHTTPStatus: http.StatusOK,
+		},
+		{
+			testName:           "testPutIfMatchStale",
+			method:             http.MethodPut,
+			url:                "/todos/1",
+			header:             "If-Match",
+			headerValue:        `"7"`,
+			contentType:        "application/json",
+			body:               `{"id":1,"note":"walk the cat","duedate":"2020-04-02T13:13:13Z","repeat":true,"completed":false}`,
+			expectedHTTPStatus: http.StatusPreconditionFailed,
+		},
+		{
+			testName:           "testPutIfMatchWeak",
+			method:             http.MethodPut,
+			url:                "/todos/1",
+			header:             "If-Match",
+			headerValue:        `W/"1"`,
+			contentType:        "application/json",
+			body:               `{"id":1,"note":"walk the cat","duedate":"2020-04-02T13:13:13Z","repeat":true,"completed":false}`,
+			expectedHTTPStatus: http.StatusPreconditionFailed,
+		},
+		{
+			testName:           "testPatchIfMatchCurrent",
+			method:             http.MethodPatch,
+			url:                "/todos/1",
+			header:             "If-Match",
+			headerValue:        `"1"`,
+			contentType:        todo.MergePatchContentType,
+			body:               `{"completed":true}`,
+			expectedHTTPStatus: http.StatusOK,
+			expectedETag:       `"2"`,
+		},
+		{
+			testName:           "testPatchIfMatchStale",
+			method:             http.MethodPatch,
+			url:                "/todos/1",
+			header:             "If-Match",
+			headerValue:        `"7"`,
+			contentType:        todo.MergePatchContentType,
+			body:               `{"completed":true}`,
+			expectedHTTPStatus: http.StatusPreconditionFailed,
+		},
+		{
+			testName:           "testDeleteIfMatchAny",
+			method:             http.MethodDelete,
+			url:                "/todos/1",
+			header:             "If-Match",
+			headerValue:        "*",
+			expectedHTTPStatus: http.StatusOK,
+		},
+		{
+			testName:           "testDeleteIfMatchStale",
+			method:             http.MethodDelete,
+			url:                "/todos/1",
+			header:             "If-Match",
+			headerValue:        `"7"`,
+			expectedHTTPStatus: http.StatusPreconditionFailed,
+		},
+		{
+			testName:           "testDeleteIfMatchNonExistent",
+			method:             http.MethodDelete,
+			url:                "/todos/100",
+			header:             "If-Match",
+			headerValue:        `"1"`,
+			expectedHTTPStatus: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := todo.NewMemStore()
+			_, err := store.InsertToDo(context.Background(), todo.Item{Note: "walk the dog", DueDate: time.Now(), Repeat: true})
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected populating the todo store", err)
+			}
+
+			srvHandler, err := NewToDoHandler(store, nil, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
+			}
+
+			testSrv := httptest.NewServer(http.HandlerFunc(srvHandler.ServeHTTP))
+			defer testSrv.Close()
+
+			url := testSrv.URL + tc.url
+			req, err := http.NewRequest(tc.method, url, bytes.NewBuffer([]byte(tc.body)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			if len(tc.header) > 0 {
+				req.Header.Set(tc.header, tc.headerValue)
+			}
+			if len(tc.contentType) > 0 {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			status := resp.StatusCode
+			if status != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, status)
+			}
+
+			etag := resp.Header.Get("ETag")
+			if len(tc.expectedETag) > 0 && etag != tc.expectedETag {
+				t.Errorf("expected ETag %s, got %s", tc.expectedETag, etag)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+	"github.com/youngkin/todoshaleapps/src/internal/webhook"
+)
+
+// testPublisher records the events it's published
+type testPublisher struct {
+	events []webhook.Event
+}
+
+func (p *testPublisher) Publish(e webhook.Event) {
+	p.events = append(p.events, e)
+}
+
+func TestToDoEvents(t *testing.T) {
+	client := &http.Client{}
+
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	tcs := []struct {
+		testName       string
+		method         string
+		url            string
+		contentType    string
+		data           string
+		expectedEvents []webhook.EventType
+		expectedIDs    []int64
+	}{
+		{
+			testName:       "testPOSTPublishesCreated",
+			method:         http.MethodPost,
+			url:            "/todos",
+			data:           `{"note":"wash the car","duedate":"2020-04-02T13:13:00Z"}`,
+			expectedEvents: []webhook.EventType{webhook.ToDoCreated},
+			expectedIDs:    []int64{4},
+		},
+		{
+			testName:       "testAtomicBulkPOSTPublishesCreated",
+			method:         http.MethodPost,
+			url:            "/todos?bulk=true&atomic=true",
+			data:           `{"todolist":[{"note":"wash the car","duedate":"2020-04-02T13:13:00Z"},{"note":"mow the lawn","duedate":"2020-04-02T13:13:00Z"}]}`,
+			expectedEvents: []webhook.EventType{webhook.ToDoCreated, webhook.ToDoCreated},
+			expectedIDs:    []int64{4, 5},
+		},
+		{
+			testName:       "testPUTPublishesUpdated",
+			method:         http.MethodPut,
+			url:            "/todos/1",
+			data:           `{"id":1,"note":"walk the cat","duedate":"2020-04-02T13:13:00Z"}`,
+			expectedEvents: []webhook.EventType{webhook.ToDoUpdated},
+			expectedIDs:    []int64{1},
+		},
+		{
+			testName:       "testPUTPublishesCompleted",
+			method:         http.MethodPut,
+			url:            "/todos/1",
+			data:           `{"id":1,"note":"walk the dog","duedate":"2020-04-02T13:13:00Z","completed":true}`,
+			expectedEvents: []webhook.EventType{webhook.ToDoUpdated, webhook.ToDoCompleted},
+			expectedIDs:    []int64{1, 1},
+		},
+		{
+			testName:       "testPATCHPublishesCompleted",
+			method:         http.MethodPatch,
+			url:            "/todos/2",
+			contentType:    todo.MergePatchContentType,
+			data:           `{"completed":true}`,
+			expectedEvents: []webhook.EventType{webhook.ToDoUpdated, webhook.ToDoCompleted},
+			expectedIDs:    []int64{2, 2},
+		},
+		{
+			testName:       "testPUTCompletedPublishesUpdated",
+			method:         http.MethodPut,
+			url:            "/todos/3",
+			data:           `{"id":3,"note":"pay more bills","duedate":"2020-04-02T13:13:00Z","completed":true}`,
+			expectedEvents: []webhook.EventType{webhook.ToDoUpdated},
+			expectedIDs:    []int64{3},
+		},
+		{
+			testName:       "testPATCHCompletedPublishesUpdated",
+			method:         http.MethodPatch,
+			url:            "/todos/3",
+			contentType:    todo.MergePatchContentType,
+			data:           `{"note":"pay more bills","completed":true}`,
+			expectedEvents: []webhook.EventType{webhook.ToDoUpdated},
+			expectedIDs:    []int64{3},
+		},
+		{
+			testName:       "testBulkPUTPublishesCompleted",
+			method:         http.MethodPut,
+			url:            "/todos?bulk=true",
+			data:           `{"todolist":[{"id":1,"note":"walk the dog","duedate":"2020-04-02T13:13:00Z","completed":true},{"id":3,"note":"pay more bills","duedate":"2020-04-02T13:13:00Z","completed":true}]}`,
+			expectedEvents: []webhook.EventType{webhook.ToDoUpdated, webhook.ToDoCompleted, webhook.ToDoUpdated},
+			expectedIDs:    []int64{1, 1, 3},
+		},
+		{
+			testName:       "testBulkPUTPublishesUpdated",
+			method:         http.MethodPut,
+			url:            "/todos?bulk=true",
+			data:           `{"todolist":[{"id":1,"note":"walk the cat","duedate":"2020-04-02T13:13:00Z"},{"id":100,"note":"missing","duedate":"2020-04-02T13:13:00Z"}]}`,
+			expectedEvents: []webhook.EventType{webhook.ToDoUpdated},
+			expectedIDs:    []int64{1},
+		},
+		{
+			testName:       "testDELETEPublishesDeleted",
+			method:         http.MethodDelete,
+			url:            "/todos/2",
+			expectedEvents: []webhook.EventType{webhook.ToDoDeleted},
+			expectedIDs:    []int64{2},
+		},
+		{
+			testName:       "testBulkDELETEPublishesDeleted",
+			method:         http.MethodDelete,
+			url:            "/todos?bulk=true",
+			data:           `{"ids":[1,2,100]}`,
+			expectedEvents: []webhook.EventType{webhook.ToDoDeleted, webhook.ToDoDeleted},
+			expectedIDs:    []int64{1, 2},
+		},
+		{
+			testName: "testFailedPUTPublishesNothing",
+			method:   http.MethodPut,
+			url:      "/todos/100",
+			data:     `{"id":100,"note":"missing","duedate":"2020-04-02T13:13:00Z"}`,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := todo.NewMemStore()
+			_, err := store.InsertToDos(context.Background(), []todo.Item{
+				{Note: "walk the dog", DueDate: date},
+				{Note: "get groceries", DueDate: date},
+				{Note: "pay bills", DueDate: date, Completed: true},
+			})
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected populating the todo store", err)
+			}
+
+			publisher := &testPublisher{}
+			srvHandler, err := NewToDoHandler(store, publisher, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
+			}
+
+			testSrv := httptest.NewServer(http.HandlerFunc(srvHandler.ServeHTTP))
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(tc.method, testSrv.URL+tc.url, bytes.NewBuffer([]byte(tc.data)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			contentType := "application/json"
+			if len(tc.contentType) > 0 {
+				contentType = tc.contentType
+			}
+			req.Header.Set("Content-Type", contentType)
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			resp.Body.Close()
+
+			if len(publisher.events) != len(tc.expectedEvents) {
+				t.Fatalf("expected events %v, got %+v", tc.expectedEvents, publisher.events)
+			}
+			for i, e := range publisher.events {
+				if e.Type != tc.expectedEvents[i] || e.ToDo.ID != tc.expectedIDs[i] {
+					t.Errorf("expected %s event for todo %d, got %s event for todo %d", tc.expectedEvents[i], tc.expectedIDs[i], e.Type, e.ToDo.ID)
+				}
+				if e.ToDo.SelfRef == "" || e.ToDo.Note == "" || e.ID == "" {
+					t.Errorf("expected event with an ID and the full todo, got %+v", e)
+				}
+			}
+		})
+	}
+}
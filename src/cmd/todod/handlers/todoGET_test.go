@@ -86,6 +86,86 @@ func TestGetAllItems(t *testing.T) {
 			teardownFunc:       todo.DBCallTeardownHelper,
 			expectedHTTPStatus: http.StatusInternalServerError,
 		},
+		{
+			testName:           "testGetToDoListFirstPage",
+			url:                "/todos?limit=2",
+			shouldPass:         true,
+			setupFunc:          todo.DBCallPageSetupHelper,
+			teardownFunc:       todo.DBCallTeardownHelper,
+			expectedHTTPStatus: http.StatusOK,
+		},
+		{
+			testName:           "testGetToDoListInvalidLimit",
+			url:                "/todos?limit=0",
+			shouldPass:         false,
+			setupFunc:          todo.DBCallNoListExpectationsSetupHelper,
+			teardownFunc:       todo.DBCallTeardownHelper,
+			expectedHTTPStatus: http.StatusBadRequest,
+		},
+		{
+			testName:           "testGetToDoListFiltered",
+			url:                "/todos?completed=false&due_before=2020-04-03",
+			shouldPass:         true,
+			setupFunc:          todo.DBCallFilterSetupHelper,
+			teardownFunc:       todo.DBCallTeardownHelper,
+			expectedHTTPStatus: http.StatusOK,
+		},
+		{
+			testName:           "testGetToDoListFilteredEmpty",
+			url:                "/todos?completed=true",
+			shouldPass:         true,
+			setupFunc:          todo.DBCallEmptyFilterSetupHelper,
+			teardownFunc:       todo.DBCallTeardownHelper,
+			expectedHTTPStatus: http.StatusOK,
+		},
+		{
+			testName:           "testGetToDoListInvalidCompleted",
+			url:                "/todos?completed=maybe",
+			shouldPass:         false,
+			setupFunc:          todo.DBCallNoListExpectationsSetupHelper,
+			teardownFunc:       todo.DBCallTeardownHelper,
+			expectedHTTPStatus: http.StatusBadRequest,
+		},
+		{
+			testName:           "testGetToDoListInvalidDueAfter",
+			url:                "/todos?due_after=JULY5TH",
+			shouldPass:         false,
+			setupFunc:          todo.DBCallNoListExpectationsSetupHelper,
+			teardownFunc:       todo.DBCallTeardownHelper,
+			expectedHTTPStatus: http.StatusBadRequest,
+		},
+		{
+			testName:           "testGetToDoListSortedNextPage",
+			url:                "/todos?sort=-duedate&after=1&limit=2",
+			shouldPass:         true,
+			setupFunc:          todo.DBCallSortSetupHelper,
+			teardownFunc:       todo.DBCallTeardownHelper,
+			expectedHTTPStatus: http.StatusOK,
+		},
+		{
+			testName:           "testGetToDoListUnknownAfter",
+			url:                "/todos?sort=-duedate&after=4&limit=2",
+			shouldPass:         false,
+			setupFunc:          todo.DBCallUnknownCursorSetupHelper,
+			teardownFunc:       todo.DBCallTeardownHelper,
+			expectedHTTPStatus: http.StatusBadRequest,
+		},
+		{
+			testName:           "testGetToDoListInvalidSort",
+			url:                "/todos?sort=repeat",
+			shouldPass:         false,
+			setupFunc:          todo.DBCallNoListExpectationsSetupHelper,
+			teardownFunc:       todo.DBCallTeardownHelper,
+			expectedHTTPStatus: http.StatusBadRequest,
+		},
+		{
+			testName:           "testGetToDoListNonNumericAfter",
+			url:                "/todos?after=abc",
+			shouldPass:         false,
+			setupFunc:          todo.DBCallNoListExpectationsSetupHelper,
+			teardownFunc:       todo.DBCallTeardownHelper,
+			expectedHTTPStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tc := range tcs {
@@ -98,7 +178,12 @@ func TestGetAllItems(t *testing.T) {
 				todo.SelfRef = "/todos/" + strconv.FormatInt(todo.ID, 10)
 			}
 
-			todoHandler, err := NewToDoHandler(db, logger)
+			store, err := todo.NewPGStore(db, 0)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo store", err)
+			}
+
+			todoHandler, err := NewToDoHandler(store, nil, logger)
 			if err != nil {
 				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
 			}
@@ -185,7 +270,12 @@ func TestGetOneItem(t *testing.T) {
 				expected.SelfRef = tc.url
 			}
 
-			todoHandler, err := NewToDoHandler(db, logger)
+			store, err := todo.NewPGStore(db, 0)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo store", err)
+			}
+
+			todoHandler, err := NewToDoHandler(store, nil, logger)
 			if err != nil {
 				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
 			}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+)
+
+func TestToDoOwnership(t *testing.T) {
+	client := &http.Client{}
+
+	tcs := []struct {
+		testName           string
+		requester          string
+		method             string
+		url                string
+		data               string
+		contentType        string
+		expectedHTTPStatus int
+	}{
+		{
+			testName:           "testGetOwnToDo",
+			requester:          "ryoungkin",
+			method:             http.MethodGet,
+			url:                "/todos/1",
+			expectedHTTPStatus: http.StatusOK,
+		},
+		{
+			testName:           "testGetOtherUsersToDo",
+			requester:          "jdoe",
+			method:             http.MethodGet,
+			url:                "/todos/1",
+			expectedHTTPStatus: http.StatusNotFound,
+		},
+		{
+			testName:           "testGetOtherUsersToDoList",
+			requester:          "jdoe",
+			method:             http.MethodGet,
+			url:                "/todos",
+			expectedHTTPStatus: http.StatusOK,
+		},
+		{
+			testName:           "testPageAfterOtherUsersToDo",
+			requester:          "jdoe",
+			method:             http.MethodGet,
+			url:                "/todos?after=1",
+			expectedHTTPStatus: http.StatusBadRequest,
+		},
+		{
+			testName:           "testPutOtherUsersToDo",
+			requester:          "jdoe",
+			method:             http.MethodPut,
+			url:                "/todos/1",
+			data:               `{"id":1,"note":"walk the cat","duedate":"2020-04-02T13:13:00Z"}`,
+			contentType:        "application/json",
+			expectedHTTPStatus: http.StatusNotFound,
+		},
+		{
+			testName:           "testPatchOtherUsersToDo",
+			requester:          "jdoe",
+			method:             http.MethodPatch,
+			url:                "/todos/1",
+			data:               `{"completed":true}`,
+			contentType:        todo.MergePatchContentType,
+			expectedHTTPStatus: http.StatusNotFound,
+		},
+		{
+			testName:           "testDeleteOtherUsersToDo",
+			requester:          "jdoe",
+			method:             http.MethodDelete,
+			url:                "/todos/1",
+			expectedHTTPStatus: http.StatusNotFound,
+		},
+		{
+			testName:           "testDeleteOwnToDo",
+			requester:          "ryoungkin",
+			method:             http.MethodDelete,
+			url:                "/todos/1",
+			expectedHTTPStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := todo.NewMemStore()
+			_, err := store.InsertToDo(context.Background(), todo.Item{
+				Note:    "walk the dog",
+				DueDate: time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC),
+				Owner:   "ryoungkin",
+			})
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected populating the todo store", err)
+			}
+
+			srvHandler, err := NewToDoHandler(store, nil, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
+			}
+
+			testSrv := httptest.NewServer(withRequester(srvHandler, tc.requester))
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(tc.method, testSrv.URL+tc.url, bytes.NewBuffer([]byte(tc.data)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			if len(tc.contentType) > 0 {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, resp.StatusCode)
+			}
+
+			td, err := store.GetToDoItem(context.Background(), "ryoungkin", 1)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected getting the todo", err)
+			}
+			if tc.requester != "ryoungkin" && (td == nil || td.Note != "walk the dog" || td.Completed) {
+				t.Errorf("expected the todo to be unchanged by another user, got %+v", td)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+)
+
+func TestPATCHToDo(t *testing.T) {
+	client := &http.Client{}
+
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	tcs := []struct {
+		testName           string
+		shouldPass         bool
+		url                string
+		contentType        string
+		patchData          string
+		expectedHTTPStatus int
+		expected           todo.Item
+	}{
+		{
+			testName:           "testPatchCompletedSuccess",
+			shouldPass:         true,
+			url:                "/todos/1",
+			contentType:        todo.MergePatchContentType,
+			patchData:          `{"completed":true}`,
+			expectedHTTPStatus: http.StatusOK,
+			expected: todo.Item{
+				ID:        1,
+				SelfRef:   "/todos/1",
+				ListID:    todo.DefaultListID,
+				Note:      "walk the dog",
+				DueDate:   date,
+				Repeat:    false,
+				Completed: true,
+				Version:   2,
+			},
+		},
+		{
+			testName:           "testPatchWrongContentType",
+			shouldPass:         false,
+			url:                "/todos/1",
+			contentType:        "application/json",
+			patchData:          `{"completed":true}`,
+			expectedHTTPStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			testName:           "testPatchNotFound",
+			shouldPass:         false,
+			url:                "/todos/100",
+			contentType:        todo.MergePatchContentType,
+			patchData:          `{"completed":true}`,
+			expectedHTTPStatus: http.StatusNotFound,
+		},
+		{
+			testName:           "testPatchInvalidResult",
+			shouldPass:         false,
+			url:                "/todos/1",
+			contentType:        todo.MergePatchContentType,
+			patchData:          `{"note":null}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+		},
+		{
+			testName:           "testPatchMalformedJSON",
+			shouldPass:         false,
+			url:                "/todos/1",
+			contentType:        todo.MergePatchContentType,
+			patchData:          `{"completed":`,
+			expectedHTTPStatus: http.StatusBadRequest,
+		},
+		{
+			testName:           "testPatchNonNumericResourceID",
+			shouldPass:         false,
+			url:                "/todos/somebadnumber",
+			contentType:        todo.MergePatchContentType,
+			patchData:          `{"completed":true}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := todo.NewMemStore()
+			_, err := store.InsertToDo(context.Background(), todo.Item{Note: "walk the dog", DueDate: date})
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected populating the todo store", err)
+			}
+
+			srvHandler, err := NewToDoHandler(store, nil, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
+			}
+
+			testSrv := httptest.NewServer(http.HandlerFunc(srvHandler.ServeHTTP))
+			defer testSrv.Close()
+
+			url := testSrv.URL + tc.url
+			req, err := http.NewRequest(http.MethodPatch, url, bytes.NewBuffer([]byte(tc.patchData)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+
+			req.Header.Set("Content-Type", tc.contentType)
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			status := resp.StatusCode
+			if status != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, status)
+			}
+
+			if tc.shouldPass {
+				actual, err := ioutil.ReadAll(resp.Body)
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected reading response body", err)
+				}
+
+				mExpected, err := json.Marshal(tc.expected)
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected Marshaling %+v", err, tc.expected)
+				}
+
+				if bytes.Compare(mExpected, actual) != 0 {
+					t.Errorf("expected %+v, got %+v", string(mExpected), string(actual))
+				}
+			}
+		})
+	}
+}
@@ -80,13 +80,34 @@ func TestPOSTToDoItem(t *testing.T) {
 			setupFunc:    todo.DBNoCallSetupHelper,
 			teardownFunc: todo.DBCallTeardownHelper,
 		},
+		{
+			// The todo's list must exist
+			testName:           "testInsertToDoItemFailListNotFound",
+			shouldPass:         false,
+			url:                "/todos",
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedResourceID: "",
+			postData:           `{"listid":100,"note": "walk the dog","duedate":"2020-04-02T13:13:13Z"}`,
+			todo: todo.Item{
+				ListID:  100,
+				Note:    "walk the dog",
+				DueDate: date,
+			},
+			setupFunc:    todo.DBGetListNotFoundSetupHelper,
+			teardownFunc: todo.DBCallTeardownHelper,
+		},
 	}
 
 	for _, tc := range tcs {
 		t.Run(tc.testName, func(t *testing.T) {
 			db, mock := tc.setupFunc(t, tc.todo)
 
-			srvHandler, err := NewToDoHandler(db, logger)
+			store, err := todo.NewPGStore(db, 0)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo store", err)
+			}
+
+			srvHandler, err := NewToDoHandler(store, nil, logger)
 			if err != nil {
 				t.Fatalf("error '%s' was not expected when getting a ToDo handler", err)
 			}
@@ -16,41 +16,59 @@ func TestPUTToDo(t *testing.T) {
 	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
 
 	tcs := []TestCase{
-		// {
-		// 	testName:           "testUpdateToDoItemSuccess",
-		// 	shouldPass:         true,
-		// 	url:                "/todos/1",
-		// 	expectedHTTPStatus: http.StatusOK,
-		// 	expectedResourceID: "/todos/1",
-		// 	postData:           `{"id":1,"note": "walk the dog","duedate":"2020-04-02T13:13:13Z","repeat": true,"completed": false}`,
-		// 	todo: todo.Item{
-		//		ID:        1,
-		// 		Note:      "walk the dog",
-		// 		DueDate:   now,
-		// 		Repeat:    true,
-		// 		Completed: false,
-		// 	},
-		// 	setupFunc:    todo.DBUpdateSetupHelper,
-		// 	teardownFunc: todo.DBCallTeardownHelper,
-		// },
-		// {
-		// 	testName:           "testUpdateDBError",
-		// 	shouldPass:         false,
-		// 	url:                "/todos/100",
-		// 	expectedHTTPStatus: http.StatusInternalServerError,
-		// 	updateResourceID:   "todos/100",
-		// 	expectedResourceID: "",
-		// 	postData:           "{\"id\":100,\"note\":\"walk the dog\",\"duedate\":\"2020-04-02T13:13:13Z\",\"repeat\":true,\"completed\":false}",
-		// 	todo: todo.Item{
-		// 		ID:        100,
-		// 		Note:      "walk the dog",
-		// 		DueDate:   date,
-		// 		Repeat:    true,
-		// 		Completed: false,
-		// 	},
-		// 	setupFunc:    todo.DBUpdateErrorSetupHelper,
-		// 	teardownFunc: todo.DBCallTeardownHelper,
-		// },
+		{
+			testName:           "testUpdateToDoItemSuccess",
+			shouldPass:         true,
+			url:                "/todos/1",
+			expectedHTTPStatus: http.StatusOK,
+			expectedResourceID: "/todos/1",
+			postData:           `{"id":1,"note": "walk the dog","duedate":"2020-04-02T13:13:13Z","repeat": true,"completed": false}`,
+			todo: todo.Item{
+				ID:        1,
+				Note:      "walk the dog",
+				DueDate:   date,
+				Repeat:    true,
+				Completed: false,
+			},
+			setupFunc:    todo.DBUpdateSetupHelper,
+			teardownFunc: todo.DBCallTeardownHelper,
+		},
+		{
+			testName:           "testUpdateToDoItemNotFound",
+			shouldPass:         false,
+			url:                "/todos/100",
+			expectedHTTPStatus: http.StatusNotFound,
+			updateResourceID:   "todos/100",
+			expectedResourceID: "",
+			postData:           `{"id":100,"note":"walk the dog","duedate":"2020-04-02T13:13:13Z","repeat":true,"completed":false}`,
+			todo: todo.Item{
+				ID:        100,
+				Note:      "walk the dog",
+				DueDate:   date,
+				Repeat:    true,
+				Completed: false,
+			},
+			setupFunc:    todo.DBUpdateNotFoundSetupHelper,
+			teardownFunc: todo.DBCallTeardownHelper,
+		},
+		{
+			testName:           "testUpdateDBError",
+			shouldPass:         false,
+			url:                "/todos/100",
+			expectedHTTPStatus: http.StatusInternalServerError,
+			updateResourceID:   "todos/100",
+			expectedResourceID: "",
+			postData:           "{\"id\":100,\"note\":\"walk the dog\",\"duedate\":\"2020-04-02T13:13:13Z\",\"repeat\":true,\"completed\":false}",
+			todo: todo.Item{
+				ID:        100,
+				Note:      "walk the dog",
+				DueDate:   date,
+				Repeat:    true,
+				Completed: false,
+			},
+			setupFunc:    todo.DBUpdateErrorSetupHelper,
+			teardownFunc: todo.DBCallTeardownHelper,
+		},
 		{
 			// ID in URL, '/todos/100', doesn't match ID in postData, '1' and todo '1'
 			testName:           "testUpdateValidationError",
@@ -112,7 +130,12 @@ func TestPUTToDo(t *testing.T) {
 		t.Run(tc.testName, func(t *testing.T) {
 			db, mock := tc.setupFunc(t, tc.todo)
 
-			srvHandler, err := NewToDoHandler(db, logger)
+			store, err := todo.NewPGStore(db, 0)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo store", err)
+			}
+
+			srvHandler, err := NewToDoHandler(store, nil, logger)
 			if err != nil {
 				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
 			}
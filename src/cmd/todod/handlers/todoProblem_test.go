@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+)
+
+func TestProblemResponses(t *testing.T) {
+	client := &http.Client{}
+
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	tcs := []struct {
+		testName           string
+		method             string
+		url                string
+		contentType        string
+		ifMatch            string
+		body               string
+		expectedHTTPStatus int
+		expectedErrCode    constants.ErrCode
+		expectDetail       bool
+	}{
+		{
+			testName:           "testGetNotFound",
+			method:             http.MethodGet,
+			url:                "/todos/100",
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.ToDoNotFoundErrorCode,
+		},
+		{
+			testName:           "testGetMalformedURL",
+			method:             http.MethodGet,
+			url:                "/todos/1/extra",
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.MalformedURLErrorCode,
+			expectDetail:       true,
+		},
+		{
+			testName:           "testGetInvalidQuery",
+			method:             http.MethodGet,
+			url:                "/todos?limit=abc",
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.MalformedURLErrorCode,
+			expectDetail:       true,
+		},
+		{
+			testName:           "testPostMalformedJSON",
+			method:             http.MethodPost,
+			url:                "/todos",
+			body:               `{"note":`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.JSONDecodingErrorCode,
+			expectDetail:       true,
+		},
+		{
+			testName:           "testPutIDMismatch",
+			method:             http.MethodPut,
+			url:                "/todos/2",
+			body:               `{"id":1,"note":"walk the dog"}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.ToDoRqstErrorCode,
+			expectDetail:       true,
+		},
+		{
+			testName:           "testPatchWrongContentType",
+			method:             http.MethodPatch,
+			url:                "/todos/1",
+			contentType:        "application/json",
+			body:               `{"completed":true}`,
+			expectedHTTPStatus: http.StatusUnsupportedMediaType,
+			expectedErrCode:    constants.RqstParsingErrorCode,
+			expectDetail:       true,
+		},
+		{
+			testName:           "testDeleteVersionConflict",
+			method:             http.MethodDelete,
+			url:                "/todos/1",
+			ifMatch:            `"5"`,
+			expectedHTTPStatus: http.StatusPreconditionFailed,
+			expectedErrCode:    constants.ToDoVersionConflictErrorCode,
+			expectDetail:       true,
+		},
+		{
+			testName:           "testUnsupportedMethod",
+			method:             http.MethodOptions,
+			url:                "/todos",
+			expectedHTTPStatus: http.StatusNotImplemented,
+			expectedErrCode:    constants.UnsupportedMethodErrorCode,
+			expectDetail:       true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := todo.NewMemStore()
+			_, err := store.InsertToDo(context.Background(), todo.Item{Note: "walk the dog", DueDate: date, Version: todo.InitialVersion})
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected populating the todo store", err)
+			}
+
+			srvHandler, err := NewToDoHandler(store, nil, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a todo handler", err)
+			}
+
+			testSrv := httptest.NewServer(http.HandlerFunc(srvHandler.ServeHTTP))
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(tc.method, testSrv.URL+tc.url, bytes.NewBuffer([]byte(tc.body)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			if len(tc.contentType) > 0 {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			if len(tc.ifMatch) > 0 {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, resp.StatusCode)
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != problemContentType {
+				t.Errorf("expected Content-Type %s, got %s", problemContentType, ct)
+			}
+
+			p := problem{}
+			err = json.NewDecoder(resp.Body).Decode(&p)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected decoding the problem details", err)
+			}
+			if p.ErrCode != tc.expectedErrCode {
+				t.Errorf("expected errcode %d, got %d", tc.expectedErrCode, p.ErrCode)
+			}
+			if p.Status != tc.expectedHTTPStatus {
+				t.Errorf("expected status %d, got %d", tc.expectedHTTPStatus, p.Status)
+			}
+			if p.Title != tc.expectedErrCode.Message() {
+				t.Errorf("expected title %q, got %q", tc.expectedErrCode.Message(), p.Title)
+			}
+			if p.Instance != req.URL.Path {
+				t.Errorf("expected instance %s, got %s", req.URL.Path, p.Instance)
+			}
+			if tc.expectDetail != (len(p.Detail) > 0) {
+				t.Errorf("expected detail present to be %t, got %q", tc.expectDetail, p.Detail)
+			}
+		})
+	}
+}
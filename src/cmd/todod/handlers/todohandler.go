@@ -1,28 +1,39 @@
 package handlers
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/juju/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
 	"github.com/youngkin/todoshaleapps/src/internal/todo"
+	"github.com/youngkin/todoshaleapps/src/internal/user"
+	"github.com/youngkin/todoshaleapps/src/internal/webhook"
+	"go.opentelemetry.io/otel"
 )
 
 type handler struct {
-	db     *sql.DB
-	logger *log.Entry
+	store     todo.Store
+	publisher EventPublisher
+	logger    *log.Entry
 }
 
 const nilToDoID = 0
 
 // ServeHTTP handles the request
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// 'h' is a copy, its logger is replaced by the request's so messages carry its ID
+	h.logger = requestLogger(r, h.logger)
 	bulk := r.URL.Query().Get("bulk")
 	switch r.Method {
 	case http.MethodGet:
@@ -30,26 +41,35 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.handleGet(w, r)
 	case http.MethodPost:
 		if len(bulk) > 0 {
-			h.logger.WithFields(log.Fields{
-				constants.Method:     r.Method,
-				constants.Path:       r.URL.Path,
-				constants.RemoteAddr: r.RemoteAddr,
-			}).Info("HTTP bulk request received")
+			logBulkRqstRcvd(r, h.logger)
 			h.handleBulkPost(w, r)
 			return
 		}
 
 		logRqstRcvd(r, h.logger)
-		td, pathNodes, err := parseRqst(r, h.logger)
+		td, pathNodes, errCode, err := parseRqst(r, h.logger)
 		if err != nil {
-			w.WriteHeader(http.StatusBadRequest)
+			writeProblem(w, r, http.StatusBadRequest, errCode, err.Error())
 			return
 		}
 		h.handlePost(w, r, td, pathNodes)
 	case http.MethodPut:
+		if len(bulk) > 0 {
+			logBulkRqstRcvd(r, h.logger)
+			h.handleBulkPut(w, r)
+			return
+		}
 		logRqstRcvd(r, h.logger)
 		h.handlePut(w, r)
+	case http.MethodPatch:
+		logRqstRcvd(r, h.logger)
+		h.handlePatch(w, r)
 	case http.MethodDelete:
+		if len(bulk) > 0 {
+			logBulkRqstRcvd(r, h.logger)
+			h.handleBulkDelete(w, r)
+			return
+		}
 		logRqstRcvd(r, h.logger)
 		h.handleDelete(w, r)
 	default:
@@ -59,8 +79,9 @@ func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			constants.Path:       r.URL.Path,
 			constants.HTTPStatus: httpStatus,
 			constants.RemoteAddr: r.RemoteAddr,
-		}).Warn("Expected GET, POST, PUT, or DELETE")
-		w.WriteHeader(httpStatus)
+		}).Warn("Expected GET, POST, PUT, PATCH, or DELETE")
+		writeProblem(w, r, httpStatus, constants.UnsupportedMethodErrorCode,
+			fmt.Sprintf("expected GET, POST, PUT, PATCH, or DELETE, got %s", r.Method))
 	}
 
 }
@@ -77,7 +98,7 @@ func (h handler) handleGet(w http.ResponseWriter, r *http.Request) {
 			constants.Path:        r.URL.Path,
 			constants.ErrorDetail: err,
 		}).Error(constants.MalformedURL)
-		w.WriteHeader(httpStatus)
+		writeProblem(w, r, httpStatus, constants.MalformedURLErrorCode, err.Error())
 		return
 	}
 
@@ -86,10 +107,12 @@ func (h handler) handleGet(w http.ResponseWriter, r *http.Request) {
 		errReason constants.ErrCode
 	)
 
+	owner := user.FromContext(r.Context())
 	if len(pathNodes) == 1 {
-		payload, errReason, err = h.handleGetToDoList(pathNodes[0])
+		// '/todos' is the default list
+		payload, errReason, err = h.handleGetToDoList(r.Context(), owner, pathNodes[0], todo.DefaultListID, r.URL.Query())
 	} else {
-		payload, errReason, err = h.handleGetToDoItem(pathNodes[0], pathNodes[1:])
+		payload, errReason, err = h.handleGetToDoItem(r.Context(), owner, pathNodes[0], pathNodes[1:])
 	}
 
 	if err != nil {
@@ -105,7 +128,7 @@ func (h handler) handleGet(w http.ResponseWriter, r *http.Request) {
 			// isn't applicable.
 			httpStatus = http.StatusBadRequest
 		}
-		w.WriteHeader(httpStatus)
+		writeProblem(w, r, httpStatus, errReason, err.Error())
 		return
 	}
 
@@ -118,29 +141,37 @@ func (h handler) handleGet(w http.ResponseWriter, r *http.Request) {
 			todoFound = false
 		}
 	case *todo.List:
-		if len(p.Items) == 0 {
-			todoFound = false
-		}
+		// An empty page of the list isn't an error, e.g., none of the todos match the filters
 	default:
 		httpStatus = http.StatusInternalServerError
 		h.logger.WithFields(log.Fields{
 			constants.ErrorCode:  constants.ToDoTypeConversionErrorCode,
 			constants.HTTPStatus: httpStatus,
 		}).Error(constants.ToDoTypeConversionError)
-		w.WriteHeader(httpStatus)
+		writeProblem(w, r, httpStatus, constants.ToDoTypeConversionErrorCode, "")
 		return
 	}
 
 	if !todoFound {
 		httpStatus = http.StatusNotFound
 		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:  constants.ToDoNotFoundErrorCode,
 			constants.HTTPStatus: httpStatus,
 			constants.Path:       r.URL.Path,
-		}).Error("ToDo not found")
-		w.WriteHeader(httpStatus)
+		}).Error(constants.ToDoNotFoundError)
+		writeProblem(w, r, httpStatus, constants.ToDoNotFoundErrorCode, "")
 		return
 	}
 
+	if td, ok := payload.(*todo.Item); ok {
+		etag := itemETag(td)
+		w.Header().Set("ETag", etag)
+		if !noneMatch(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
 	marshPayload, err := json.Marshal(payload)
 	if err != nil {
 		httpStatus = http.StatusInternalServerError
@@ -149,7 +180,7 @@ func (h handler) handleGet(w http.ResponseWriter, r *http.Request) {
 			constants.HTTPStatus:  httpStatus,
 			constants.ErrorDetail: err.Error(),
 		}).Error(constants.JSONMarshalingError)
-		w.WriteHeader(httpStatus)
+		writeProblem(w, r, httpStatus, constants.JSONMarshalingErrorCode, "")
 		return
 	}
 
@@ -157,29 +188,141 @@ func (h handler) handleGet(w http.ResponseWriter, r *http.Request) {
 	w.Write(marshPayload)
 }
 
-// handleGetToDoList will return the todo list, an error reason and error if there
-// was a problem retrieving the todo, or a nil todo and a nil error if the todo was
-// not found. The error reason will only be relevant when the error is non-nil.
-func (h handler) handleGetToDoList(path string) (item interface{}, errReason constants.ErrCode, err error) {
-	tds, err := todo.GetToDoList(h.db)
+// handleGetToDoList will return a page of 'owner's todos in the list identified by 'listID',
+// an error reason and error if there was a problem retrieving the todo, or a nil todo and
+// a nil error if the todo was not found. The error reason will only be relevant when the
+// error is non-nil. The page is selected by the 'limit' and 'after' query parameters.
+// When there are more items the returned list's 'Next' field will contain a link, relative
+// to 'path', to the next page. An 'after' that doesn't identify one of 'owner's todos is
+// reported as a malformed URL.
+func (h handler) handleGetToDoList(ctx context.Context, owner, path string, listID int64, query url.Values) (item interface{}, errReason constants.ErrCode, err error) {
+	opts, err := parseListOptions(query)
+	if err != nil {
+		return nil, constants.MalformedURLErrorCode, err
+	}
+	opts.Owner = owner
+	opts.ListID = listID
+
+	tds, more, err := h.store.GetToDoListPage(ctx, opts)
+	if errors.Cause(err) == todo.ErrUnknownCursor {
+		return nil, constants.MalformedURLErrorCode, errors.Errorf("'after' %d doesn't identify one of the todos", opts.After)
+	}
 	if err != nil {
 		return nil, constants.ToDoRqstErrorCode, errors.Annotate(err, "Error retrieving todos from DB")
 	}
 
 	h.logger.Debugf("handleGetToDoList() results: %+v", tds)
 
+	// An empty page contains an empty list of todos, not null
+	if tds.Items == nil {
+		tds.Items = []*todo.Item{}
+	}
+
 	for _, td := range tds.Items {
-		td.SelfRef = "/" + path + "/" + strconv.FormatInt(td.ID, 10)
+		td.SelfRef = itemSelfRef(td.ID)
+	}
+
+	if more {
+		// Preserve any other query parameters so the next page is selected the same way
+		next := url.Values{}
+		for k, v := range query {
+			next[k] = v
+		}
+		next.Set("limit", strconv.Itoa(opts.Limit))
+		next.Set("after", strconv.FormatInt(tds.Items[len(tds.Items)-1].ID, 10))
+		tds.Next = "/" + path + "?" + next.Encode()
 	}
 
 	return &tds, constants.NoErrorCode, nil
 }
 
-// handleGetToDoItem will return the todo referenced by the provided resource path,
-// an error reason and error if there was a problem retrieving the todo, or a nil todo and a nil
-// error if the todo was not found. The error reason will only be relevant when the error
-// is non-nil.
-func (h handler) handleGetToDoItem(path string, pathNodes []string) (item interface{}, errReason constants.ErrCode, err error) {
+// parseListOptions extracts the query parameters used to select a page of the todo list.
+// These are 'limit' and 'after' for pagination, 'sort' for ordering, and the 'completed',
+// 'repeat', 'due_before', and 'due_after' filters.
+func parseListOptions(query url.Values) (todo.ListOptions, error) {
+	opts := todo.ListOptions{Limit: todo.DefaultPageLimit}
+
+	if l := query.Get("limit"); len(l) > 0 {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit < 1 || limit > todo.MaxPageLimit {
+			return todo.ListOptions{}, errors.Errorf("expected 'limit' between 1 and %d, got %q", todo.MaxPageLimit, l)
+		}
+		opts.Limit = limit
+	}
+
+	if a := query.Get("after"); len(a) > 0 {
+		after, err := strconv.ParseInt(a, 10, 64)
+		if err != nil || after < 0 {
+			return todo.ListOptions{}, errors.Errorf("expected non-negative numeric 'after', got %q", a)
+		}
+		opts.After = after
+	}
+
+	// 'sort' names the field to sort by, a leading '-' indicates descending order
+	if v := query.Get("sort"); len(v) > 0 {
+		field := strings.TrimPrefix(v, "-")
+		opts.SortBy = todo.SortField(field)
+		opts.SortDesc = field != v
+		if !opts.SortBy.Valid() {
+			return todo.ListOptions{}, errors.Errorf("expected 'sort' of 'id', 'duedate', 'note', or 'completed', optionally prefixed by '-', got %q", v)
+		}
+	}
+
+	for _, f := range []struct {
+		name string
+		dest **bool
+	}{
+		{"completed", &opts.Completed},
+		{"repeat", &opts.Repeat},
+	} {
+		if v := query.Get(f.name); len(v) > 0 {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return todo.ListOptions{}, errors.Errorf("expected 'true' or 'false' for '%s', got %q", f.name, v)
+			}
+			*f.dest = &b
+		}
+	}
+
+	for _, f := range []struct {
+		name string
+		dest *time.Time
+	}{
+		{"due_before", &opts.DueBefore},
+		{"due_after", &opts.DueAfter},
+	} {
+		if v := query.Get(f.name); len(v) > 0 {
+			t, err := parseQueryTime(v)
+			if err != nil {
+				return todo.ListOptions{}, errors.Annotatef(err, "invalid '%s'", f.name)
+			}
+			*f.dest = t
+		}
+	}
+
+	return opts, nil
+}
+
+// parseQueryTime accepts either an RFC 3339 timestamp, as used for 'duedate' in the JSON
+// representation of a todo, or a date like '2020-04-02' which is interpreted as midnight UTC.
+func parseQueryTime(v string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, v)
+	if err == nil {
+		return t, nil
+	}
+	t, err = time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, errors.Errorf("expected RFC 3339 timestamp or YYYY-MM-DD date, got %q", v)
+	}
+	return t, nil
+}
+
+// handleGetToDoItem will return the todo, visible to 'requester', referenced by the provided
+// resource path, an error reason and error if there was a problem retrieving the todo, or a
+// nil todo and a nil error if the todo was not found. The error reason will only be relevant
+// when the error is non-nil. Todos are visible to their owner and to the users their list
+// is shared with.
+func (h handler) handleGetToDoItem(ctx context.Context, requester, path string, pathNodes []string) (item interface{}, errReason constants.ErrCode, err error) {
 	if len(pathNodes) > 1 {
 		err := errors.Errorf(("expected 1 pathNode, got %d: path %s"), len(pathNodes), pathNodes)
 		return nil, constants.MalformedURLErrorCode, err
@@ -191,7 +334,7 @@ func (h handler) handleGetToDoItem(path string, pathNodes []string) (item interf
 		return nil, constants.MalformedURLErrorCode, err
 	}
 
-	td, err := todo.GetToDoItem(h.db, id)
+	td, _, err := h.store.GetToDoItemRole(ctx, requester, id)
 	if err != nil {
 		return nil, constants.ToDoRqstErrorCode, err
 	}
@@ -220,7 +363,7 @@ func (h handler) handlePost(w http.ResponseWriter, r *http.Request, td todo.Item
 			constants.Path:        r.URL.Path,
 			constants.ErrorDetail: errMsg,
 		}).Error(constants.InvalidInsertError)
-		w.WriteHeader(httpStatus)
+		writeProblem(w, r, httpStatus, constants.InvalidInsertErrorCode, errMsg)
 		return
 	}
 
@@ -233,11 +376,26 @@ func (h handler) handlePost(w http.ResponseWriter, r *http.Request, td todo.Item
 			constants.Path:        r.URL.Path,
 			constants.ErrorDetail: errMsg,
 		}).Error(constants.MalformedURL)
-		w.WriteHeader(httpStatus)
+		writeProblem(w, r, httpStatus, constants.MalformedURLErrorCode, errMsg)
 		return
 	}
 
-	id, err := h.insertToDo(td)
+	if errCode, err := h.checkListExists(r.Context(), td); err != nil {
+		httpStatus := http.StatusInternalServerError
+		if errCode == constants.ListNotFoundErrorCode {
+			httpStatus = http.StatusBadRequest
+		}
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   errCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.Path:        r.URL.Path,
+			constants.ErrorDetail: err,
+		}).Error(errCode.Message())
+		writeProblem(w, r, httpStatus, errCode, err.Error())
+		return
+	}
+
+	id, err := h.insertToDo(r.Context(), td)
 	if err != nil {
 		httpStatus := http.StatusInternalServerError
 		h.logger.WithFields(log.Fields{
@@ -246,9 +404,15 @@ func (h handler) handlePost(w http.ResponseWriter, r *http.Request, td todo.Item
 			constants.Path:        r.URL.Path,
 			constants.ErrorDetail: err,
 		}).Error(constants.DBUpSertError)
-		w.WriteHeader(httpStatus)
+		writeProblem(w, r, httpStatus, constants.DBUpSertErrorCode, "")
 		return
 	}
+	td.ID = id
+	td.Version = todo.InitialVersion
+	if td.ListID == 0 {
+		td.ListID = todo.DefaultListID
+	}
+	h.publish(webhook.ToDoCreated, td)
 
 	// NOTE: Go is persnickity about the order of these next 2 statements.
 	// If 'w.Header' doesn't come first the 'Location' header isn't written.
@@ -257,33 +421,78 @@ func (h handler) handlePost(w http.ResponseWriter, r *http.Request, td todo.Item
 }
 
 func (h handler) handleBulkPost(w http.ResponseWriter, r *http.Request) {
-	tdl, pathNodes, err := parseBulkRqst(r, h.logger)
+	tdl, pathNodes, errCode, err := parseBulkRqst(r, h.logger)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, errCode, err.Error())
 		return
 	}
 
+	atomic := false
+	if a := r.URL.Query().Get("atomic"); len(a) > 0 {
+		atomic, err = strconv.ParseBool(a)
+		if err != nil {
+			httpStatus := http.StatusBadRequest
+			errMsg := fmt.Sprintf("invalid 'atomic' query parameter, must be true or false, got %s", a)
+			h.logger.WithFields(log.Fields{
+				constants.ErrorCode:   constants.MalformedURLErrorCode,
+				constants.HTTPStatus:  httpStatus,
+				constants.Path:        r.URL.Path,
+				constants.ErrorDetail: errMsg,
+			}).Error(constants.MalformedURL)
+			writeProblem(w, r, httpStatus, constants.MalformedURLErrorCode, errMsg)
+			return
+		}
+	}
+	if atomic {
+		h.handleAtomicBulkPost(w, r, tdl, pathNodes)
+		return
+	}
+
+	// The response channel is buffered so that inserts that are abandoned, because the
+	// request was cancelled or todod is shutting down, don't block
+	respChan := make(chan insertTodoResponse, len(tdl.Items))
+	httpOverallStatus := http.StatusCreated
+	var responses = []insertTodoResponse{}
 	numRqsts := 0
+	var abandoned error
 	for _, td := range tdl.Items {
-		td := td
-		rqst := insertTodoRequest{
-			r:         r,
-			td:        *td,
-			pathNodes: pathNodes,
-			respChan:  insertToDoRespChan,
+		if abandoned == nil {
+			rqst := insertTodoRequest{
+				r:         r,
+				td:        *td,
+				pathNodes: pathNodes,
+				respChan:  respChan,
+			}
+			select {
+			case InsertToDoRqstChan <- rqst:
+				numRqsts++
+				continue
+			case <-r.Context().Done():
+				abandoned = errors.New("the request was cancelled before the todo was inserted")
+			case <-ToDoPostDoneChan:
+				abandoned = errors.New("todod is shutting down, the todo wasn't inserted")
+			}
 		}
-		InsertToDoRqstChan <- rqst
-		numRqsts++
+		responses = append(responses, h.failedResponse(r, *td, http.StatusServiceUnavailable,
+			constants.ToDoInsertAbandonedErrorCode, abandoned))
+		httpOverallStatus = http.StatusConflict
 	}
 
 	h.logger.WithFields(log.Fields{
 		constants.Method: http.MethodPost,
 	}).Debugf("handleBulkPost, launched %d insert requests", numRqsts)
 
-	httpOverallStatus := http.StatusCreated
-	var responses = []insertTodoResponse{}
 	for i := 0; i < numRqsts; i++ {
-		resp := <-insertToDoRespChan
+		var resp insertTodoResponse
+		select {
+		case resp = <-respChan:
+		case <-r.Context().Done():
+			h.writeAbandonedInserts(w, r, numRqsts-i, responses, errors.New("the request was cancelled while the todos were being inserted"))
+			return
+		case <-ToDoPostDoneChan:
+			h.writeAbandonedInserts(w, r, numRqsts-i, responses, errors.New("todod is shutting down while the todos were being inserted"))
+			return
+		}
 		h.logger.WithFields(log.Fields{
 			constants.Method:        http.MethodPost,
 			constants.MessageDetail: fmt.Sprintf("Response: %+v", resp),
@@ -291,6 +500,7 @@ func (h handler) handleBulkPost(w http.ResponseWriter, r *http.Request) {
 
 		if resp.HTTPStatus == http.StatusCreated {
 			resp.Item.SelfRef = "/" + pathNodes[0] + "/" + strconv.FormatInt(resp.Item.ID, 10)
+			h.publish(webhook.ToDoCreated, resp.Item)
 		} else {
 			// Indicates that part of the request failed, body contains more detail regarding
 			// the actual error. See https://www.w3.org/Protocols/rfc2616/rfc2616-sec10.html
@@ -300,11 +510,110 @@ func (h handler) handleBulkPost(w http.ResponseWriter, r *http.Request) {
 		responses = append(responses, resp)
 	}
 
-	respOut := insertTodoResponses{
-		Responses: responses,
+	h.writeInsertResponses(w, r, httpOverallStatus, responses)
+}
+
+// writeAbandonedInserts responds to a bulk POST that stopped waiting for 'pending' of its
+// inserts because of 'reason'. Those inserts may, or may not, have been done so they're
+// reported as failed, in addition to 'responses', without their todos.
+func (h handler) writeAbandonedInserts(w http.ResponseWriter, r *http.Request, pending int, responses []insertTodoResponse, reason error) {
+	for i := 0; i < pending; i++ {
+		responses = append(responses, h.failedResponse(r, todo.Item{}, http.StatusServiceUnavailable,
+			constants.ToDoInsertAbandonedErrorCode, reason))
+	}
+	h.writeInsertResponses(w, r, http.StatusConflict, responses)
+}
+
+// handleAtomicBulkPost inserts all of the todos in 'tdl', or none of them. If any of the
+// todos are invalid nothing is inserted and the response contains the reason each invalid
+// todo was rejected. The valid todos are reported as failing because of the invalid ones.
+func (h handler) handleAtomicBulkPost(w http.ResponseWriter, r *http.Request, tdl todo.List, pathNodes []string) {
+	if len(pathNodes) != 1 {
+		h.writeBulkPathProblem(w, r, pathNodes)
+		return
+	}
+
+	responses := make([]insertTodoResponse, len(tdl.Items))
+	tds := make([]todo.Item, len(tdl.Items))
+	numInvalid := 0
+	for i, td := range tdl.Items {
+		tds[i] = *td
+		responses[i] = insertTodoResponse{Item: *td}
+
+		var (
+			errCode constants.ErrCode
+			errMsg  string
+		)
+		if td.ID != nilToDoID {
+			errCode = constants.InvalidInsertErrorCode
+			errMsg = fmt.Sprintf("expected unpopulated To Do item ID, got Item ID = %d", td.ID)
+		} else if err := todo.ValidateToDo(*td); err != nil {
+			errCode = constants.ToDoValidationErrorCode
+			errMsg = err.Error()
+		} else if code, err := h.checkListExists(r.Context(), *td); err != nil {
+			errCode = code
+			errMsg = err.Error()
+		} else {
+			continue
+		}
+
+		numInvalid++
+		responses[i].HTTPStatus = http.StatusBadRequest
+		responses[i].Err = errMsg
+		responses[i].Problem = newProblem(r, http.StatusBadRequest, errCode, errMsg)
+	}
+
+	if numInvalid > 0 {
+		httpStatus := http.StatusBadRequest
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.ToDoValidationErrorCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.Path:        r.URL.Path,
+			constants.ErrorDetail: fmt.Sprintf("%d of %d todos are invalid, none were inserted", numInvalid, len(tds)),
+		}).Error(constants.ToDoValidationError)
+
+		for i := range responses {
+			if responses[i].HTTPStatus == 0 {
+				// This todo is OK, it wasn't inserted because others in the request weren't
+				errMsg := "not inserted because other todos in the request are invalid"
+				responses[i].HTTPStatus = http.StatusFailedDependency
+				responses[i].Err = errMsg
+				responses[i].Problem = newProblem(r, http.StatusFailedDependency, constants.ToDoValidationErrorCode, errMsg)
+			}
+		}
+		h.writeInsertResponses(w, r, httpStatus, responses)
+		return
+	}
+
+	ids, err := h.store.InsertToDos(r.Context(), tds)
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.DBUpSertErrorCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.Path:        r.URL.Path,
+			constants.ErrorDetail: err,
+		}).Error(constants.DBUpSertError)
+		writeProblem(w, r, httpStatus, constants.DBUpSertErrorCode, "")
+		return
+	}
+
+	for i, id := range ids {
+		responses[i].Item.ID = id
+		responses[i].Item.SelfRef = "/" + pathNodes[0] + "/" + strconv.FormatInt(id, 10)
+		responses[i].Item.Version = todo.InitialVersion
+		if responses[i].Item.ListID == 0 {
+			responses[i].Item.ListID = todo.DefaultListID
+		}
+		responses[i].HTTPStatus = http.StatusCreated
+		h.publish(webhook.ToDoCreated, responses[i].Item)
 	}
+	h.writeInsertResponses(w, r, http.StatusCreated, responses)
+}
 
-	marshResp, err := json.Marshal(respOut)
+// writeInsertResponses writes the results of a bulk insert with the overall 'httpStatus'
+func (h handler) writeInsertResponses(w http.ResponseWriter, r *http.Request, httpStatus int, responses []insertTodoResponse) {
+	marshResp, err := json.Marshal(insertTodoResponses{Responses: responses})
 	if err != nil {
 		httpStatus := http.StatusInternalServerError
 		h.logger.WithFields(log.Fields{
@@ -312,16 +621,19 @@ func (h handler) handleBulkPost(w http.ResponseWriter, r *http.Request) {
 			constants.HTTPStatus:  httpStatus,
 			constants.ErrorDetail: err.Error(),
 		}).Error(constants.JSONMarshalingError)
-		w.WriteHeader(httpStatus)
+		writeProblem(w, r, httpStatus, constants.JSONMarshalingErrorCode, "")
 		return
 	}
 
-	w.WriteHeader(httpOverallStatus)
 	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
 	w.Write(marshResp)
 }
 
 func (h handler) handlePostItem(r *http.Request, td todo.Item, pathNodes []string, respChan chan insertTodoResponse) {
+	// Bulk inserts are run by PostRequestLauncher's copy of 'h', log through the request's
+	// logger so the messages of concurrent inserts can be attributed to their request
+	h.logger = requestLogger(r, h.logger)
 	h.logger.WithFields(log.Fields{
 		constants.Method:        http.MethodPost,
 		constants.MessageDetail: fmt.Sprintf("Item: %+v", td),
@@ -342,8 +654,10 @@ func (h handler) handlePostItem(r *http.Request, td todo.Item, pathNodes []strin
 			Item:       td,
 			HTTPStatus: httpStatus,
 			Err:        errMsg,
+			Problem:    newProblem(r, httpStatus, constants.InvalidInsertErrorCode, errMsg),
 		}
 		respChan <- resp
+		return
 	}
 
 	if len(pathNodes) != 1 {
@@ -359,11 +673,34 @@ func (h handler) handlePostItem(r *http.Request, td todo.Item, pathNodes []strin
 			Item:       td,
 			HTTPStatus: httpStatus,
 			Err:        errMsg,
+			Problem:    newProblem(r, httpStatus, constants.MalformedURLErrorCode, errMsg),
 		}
 		respChan <- resp
+		return
 	}
 
-	id, err := h.insertToDo(td)
+	if errCode, err := h.checkListExists(r.Context(), td); err != nil {
+		httpStatus := http.StatusInternalServerError
+		if errCode == constants.ListNotFoundErrorCode {
+			httpStatus = http.StatusBadRequest
+		}
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   errCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.Path:        r.URL.Path,
+			constants.ErrorDetail: err,
+		}).Error(errCode.Message())
+		resp := insertTodoResponse{
+			Item:       td,
+			HTTPStatus: httpStatus,
+			Err:        err.Error(),
+			Problem:    newProblem(r, httpStatus, errCode, err.Error()),
+		}
+		respChan <- resp
+		return
+	}
+
+	id, err := h.insertToDo(r.Context(), td)
 	if err != nil {
 		httpStatus := http.StatusInternalServerError
 		h.logger.WithFields(log.Fields{
@@ -376,11 +713,17 @@ func (h handler) handlePostItem(r *http.Request, td todo.Item, pathNodes []strin
 			Item:       td,
 			HTTPStatus: httpStatus,
 			Err:        fmt.Sprintf("call to insertToDo() failed, error: %s", err),
+			Problem:    newProblem(r, httpStatus, constants.DBUpSertErrorCode, ""),
 		}
 		respChan <- resp
+		return
 	}
 
 	td.ID = id
+	td.Version = todo.InitialVersion
+	if td.ListID == 0 {
+		td.ListID = todo.DefaultListID
+	}
 	resp := insertTodoResponse{
 		Item:       td,
 		HTTPStatus: http.StatusCreated,
@@ -393,8 +736,25 @@ func (h handler) handlePostItem(r *http.Request, td todo.Item, pathNodes []strin
 	}).Debugf("handlePostItem exit")
 }
 
-func (h handler) insertToDo(u todo.Item) (int64, error) {
-	id, err := todo.InsertToDo(h.db, u)
+// checkListExists returns ListNotFoundErrorCode and an error if 'td' is to be inserted into
+// a list that doesn't exist, or that isn't visible to td.Owner. Todos without a list ID are
+// inserted into the default list.
+func (h handler) checkListExists(ctx context.Context, td todo.Item) (constants.ErrCode, error) {
+	if td.ListID == 0 {
+		return constants.NoErrorCode, nil
+	}
+	l, err := h.store.GetList(ctx, td.Owner, td.ListID)
+	if err != nil {
+		return constants.DBQueryErrorCode, errors.Annotate(err, "error retrieving list from DB")
+	}
+	if l == nil {
+		return constants.ListNotFoundErrorCode, errors.Errorf("list %d doesn't exist", td.ListID)
+	}
+	return constants.NoErrorCode, nil
+}
+
+func (h handler) insertToDo(ctx context.Context, u todo.Item) (int64, error) {
+	id, err := h.store.InsertToDo(ctx, u)
 	if err != nil {
 		return -1, errors.Annotate(err, "error inserting todo")
 	}
@@ -403,39 +763,68 @@ func (h handler) insertToDo(u todo.Item) (int64, error) {
 
 func (h handler) handlePut(w http.ResponseWriter, r *http.Request) {
 	// parseRqst() logs parsing errors, no need to log again
-	td, pathNodes, err := parseRqst(r, h.logger)
+	td, pathNodes, errCode, err := parseRqst(r, h.logger)
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, errCode, err.Error())
 		return
 	}
 
 	if len(pathNodes) != 2 {
 		httpStatus := http.StatusBadRequest
+		errMsg := fmt.Sprintf("expecting resource path like /todos/{id}, got %+v", pathNodes)
 		h.logger.WithFields(log.Fields{
 			constants.ErrorCode:   constants.MalformedURLErrorCode,
 			constants.HTTPStatus:  httpStatus,
 			constants.Path:        r.URL.Path,
-			constants.ErrorDetail: fmt.Sprintf("expecting resource path like /todos/{id}, got %+v", pathNodes),
+			constants.ErrorDetail: errMsg,
 		}).Error(constants.MalformedURL)
-		w.WriteHeader(httpStatus)
+		writeProblem(w, r, httpStatus, constants.MalformedURLErrorCode, errMsg)
 		return
 	}
 
 	if pathNodes[1] != strconv.FormatInt(td.ID, 10) {
 		httpStatus := http.StatusBadRequest
+		errMsg := fmt.Sprintf("resource ID in url (%s) doesn't match resource ID in request body (%d)", pathNodes[1], td.ID)
 		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.ToDoRqstErrorCode,
 			constants.HTTPStatus:  httpStatus,
 			constants.Path:        r.URL.Path,
-			constants.ErrorDetail: fmt.Sprintf("resource ID in url (%s) doesn't match resource ID in request body (%d)", pathNodes[1], td.ID),
-		}).Error(constants.ToDoRqstErrorCode)
-		w.WriteHeader(httpStatus)
+			constants.ErrorDetail: errMsg,
+		}).Error(constants.ToDoRqstError)
+		writeProblem(w, r, httpStatus, constants.ToDoRqstErrorCode, errMsg)
 		return
 	}
 
-	errCode, err := todo.UpdateToDo(h.db, td)
+	// The version in the request body, if any, is ignored in favor of If-Match
+	version, ok := h.ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+	td.Version = version
+
+	owner, cur, ok := h.authorizeChange(w, r, int(td.ID))
+	if !ok {
+		return
+	}
+	if cur != nil && owner != td.Owner && td.ListID != 0 && td.ListID != cur.ListID {
+		h.writeForbidden(w, r, "only the list's owner can move its todos to another list")
+		return
+	}
+	td.Owner = owner
+
+	errCode, err = h.store.UpdateToDo(r.Context(), td)
+	if errCode == constants.ToDoVersionConflictErrorCode {
+		h.writeVersionConflict(w, r, err)
+		return
+	}
 	if err != nil {
 		httpStatus := http.StatusInternalServerError
-		if errCode == constants.DBInvalidRequestCode {
+		switch errCode {
+		case constants.DBInvalidRequestCode:
+			// The todo doesn't exist
+			httpStatus = http.StatusNotFound
+		case constants.ListNotFoundErrorCode:
+			// The todo can't be moved to a list that doesn't exist
 			httpStatus = http.StatusBadRequest
 		}
 		h.logger.WithFields(log.Fields{
@@ -443,14 +832,141 @@ func (h handler) handlePut(w http.ResponseWriter, r *http.Request) {
 			constants.HTTPStatus:  httpStatus,
 			constants.Path:        r.URL.Path,
 			constants.ErrorDetail: err,
-		}).Error(errCode)
-		w.WriteHeader(httpStatus)
+		}).Error(errCode.Message())
+		writeProblem(w, r, httpStatus, errCode, err.Error())
 		return
 	}
+	if h.publisher != nil {
+		h.publishUpdate(h.storedAfterUpdate(r.Context(), td), completes(cur, td.Completed))
+	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// handlePatch applies the JSON Merge Patch (RFC 7396) in the request body to the todo
+// identified by the URL. Only the fields in the patch are updated. The updated todo is
+// returned in the response body.
+func (h handler) handlePatch(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != todo.MergePatchContentType {
+		httpStatus := http.StatusUnsupportedMediaType
+		errMsg := fmt.Sprintf("expected Content-Type %s, got %q", todo.MergePatchContentType, r.Header.Get("Content-Type"))
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.RqstParsingErrorCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.Path:        r.URL.Path,
+			constants.ErrorDetail: errMsg,
+		}).Error(constants.RqstParsingError)
+		writeProblem(w, r, httpStatus, constants.RqstParsingErrorCode, errMsg)
+		return
+	}
+
+	pathNodes, err := getURLPathNodes(r.URL.Path)
+	if err != nil || len(pathNodes) != 2 {
+		httpStatus := http.StatusBadRequest
+		errMsg := fmt.Sprintf("expecting resource path like /todos/{id}, got %+v", pathNodes)
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.MalformedURLErrorCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.Path:        r.URL.Path,
+			constants.ErrorDetail: errMsg,
+		}).Error(constants.MalformedURL)
+		writeProblem(w, r, httpStatus, constants.MalformedURLErrorCode, errMsg)
+		return
+	}
+
+	id, err := strconv.Atoi(pathNodes[1])
+	if err != nil {
+		httpStatus := http.StatusBadRequest
+		errMsg := fmt.Sprintf("Invalid resource ID, must be int, got %v", pathNodes[1])
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.MalformedURLErrorCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.Path:        r.URL.Path,
+			constants.ErrorDetail: errMsg,
+		}).Error(constants.MalformedURL)
+		writeProblem(w, r, httpStatus, constants.MalformedURLErrorCode, errMsg)
+		return
+	}
+
+	patch, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		httpStatus := http.StatusBadRequest
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.RqstParsingErrorCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.Path:        r.URL.Path,
+			constants.ErrorDetail: err.Error(),
+		}).Error(constants.RqstParsingError)
+		writeProblem(w, r, httpStatus, constants.RqstParsingErrorCode, err.Error())
+		return
+	}
+
+	version, ok := h.ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	owner, cur, ok := h.authorizeChange(w, r, id)
+	if !ok {
+		return
+	}
+	if cur != nil && owner != user.FromContext(r.Context()) && patchMoves(patch, cur.ListID) {
+		h.writeForbidden(w, r, "only the list's owner can move its todos to another list")
+		return
+	}
+
+	td, errCode, err := h.store.PatchToDo(r.Context(), owner, id, patch, version)
+	if errCode == constants.ToDoVersionConflictErrorCode {
+		h.writeVersionConflict(w, r, err)
+		return
+	}
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		if errCode == constants.JSONDecodingErrorCode || errCode == constants.ToDoValidationErrorCode ||
+			errCode == constants.ListNotFoundErrorCode {
+			httpStatus = http.StatusBadRequest
+		}
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   errCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.Path:        r.URL.Path,
+			constants.ErrorDetail: err,
+		}).Error(errCode.Message())
+		writeProblem(w, r, httpStatus, errCode, err.Error())
+		return
+	}
+	if td == nil {
+		httpStatus := http.StatusNotFound
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:  constants.ToDoNotFoundErrorCode,
+			constants.HTTPStatus: httpStatus,
+			constants.Path:       r.URL.Path,
+		}).Error(constants.ToDoNotFoundError)
+		writeProblem(w, r, httpStatus, constants.ToDoNotFoundErrorCode, "")
+		return
+	}
+
+	td.SelfRef = "/" + pathNodes[0] + "/" + strconv.FormatInt(td.ID, 10)
+	h.publishUpdate(*td, completes(cur, patchCompletes(patch)))
+
+	marshTD, err := json.Marshal(td)
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.JSONMarshalingErrorCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.ErrorDetail: err.Error(),
+		}).Error(constants.JSONMarshalingError)
+		writeProblem(w, r, httpStatus, constants.JSONMarshalingErrorCode, "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", itemETag(td))
+	w.Write(marshTD)
+}
+
 func (h handler) handleDelete(w http.ResponseWriter, r *http.Request) {
 	pathNodes, err := getURLPathNodes(r.URL.Path)
 	if err != nil {
@@ -461,48 +977,143 @@ func (h handler) handleDelete(w http.ResponseWriter, r *http.Request) {
 			constants.ErrorDetail: err,
 		}).Error(constants.MalformedURL)
 
-		w.WriteHeader(http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, constants.MalformedURLErrorCode, err.Error())
 		return
 	}
 
 	if len(pathNodes) != 2 {
+		errMsg := fmt.Sprintf("expecting resource path like /todos/{id}, got %+v", pathNodes)
 		h.logger.WithFields(log.Fields{
 			constants.ErrorCode:   constants.MalformedURLErrorCode,
 			constants.HTTPStatus:  http.StatusBadRequest,
 			constants.Path:        r.URL.Path,
-			constants.ErrorDetail: fmt.Sprintf("expecting resource path like /todos/{id}, got %+v", pathNodes),
+			constants.ErrorDetail: errMsg,
 		}).Error(constants.MalformedURL)
-		w.WriteHeader(http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, constants.MalformedURLErrorCode, errMsg)
 		return
 	}
 
 	uid, err := strconv.Atoi(pathNodes[1])
 	if err != nil {
+		errMsg := fmt.Sprintf("Invalid resource ID, must be int, got %v", pathNodes[1])
 		h.logger.WithFields(log.Fields{
 			constants.ErrorCode:   constants.MalformedURLErrorCode,
 			constants.HTTPStatus:  http.StatusBadRequest,
 			constants.Path:        r.URL.Path,
-			constants.ErrorDetail: fmt.Sprintf("Invalid resource ID, must be int, got %v", pathNodes[1]),
+			constants.ErrorDetail: errMsg,
 		}).Error(constants.MalformedURL)
 
-		w.WriteHeader(http.StatusBadRequest)
+		writeProblem(w, r, http.StatusBadRequest, constants.MalformedURLErrorCode, errMsg)
+		return
+	}
+	version, ok := h.ifMatchVersion(w, r)
+	if !ok {
+		return
+	}
+
+	owner, _, ok := h.authorizeChange(w, r, uid)
+	if !ok {
+		return
+	}
+	deleted := h.beforeDelete(r.Context(), todo.Item{ID: int64(uid), Owner: owner})
+	errCode, err := h.store.DeleteToDo(r.Context(), owner, uid, version)
+	if errCode == constants.ToDoVersionConflictErrorCode {
+		h.writeVersionConflict(w, r, err)
 		return
 	}
-	errCode, err := todo.DeleteToDo(h.db, uid)
 	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		if errCode == constants.DBInvalidRequestCode {
+			// The todo doesn't exist
+			httpStatus = http.StatusNotFound
+		}
 		h.logger.WithFields(log.Fields{
 			constants.ErrorCode:   errCode,
-			constants.HTTPStatus:  http.StatusInternalServerError,
+			constants.HTTPStatus:  httpStatus,
 			constants.Path:        r.URL.Path,
 			constants.ErrorDetail: err,
-		}).Error(errCode)
-		w.WriteHeader(http.StatusInternalServerError)
+		}).Error(errCode.Message())
+		writeProblem(w, r, httpStatus, errCode, err.Error())
 		return
 	}
+	h.publish(webhook.ToDoDeleted, deleted)
 
 	w.WriteHeader(http.StatusOK)
 }
 
+// authorizeChange returns the owner of the todo identified by 'id', for a request that
+// changes it, and the todo itself if it's visible to the requester. The owner is the
+// requester unless the todo is in a list shared with them. Todos that don't exist, or
+// aren't visible to the requester, are left to the store to report as not found. If the
+// requester's role doesn't allow the change the error response has been written and false
+// is returned.
+func (h handler) authorizeChange(w http.ResponseWriter, r *http.Request, id int) (string, *todo.Item, bool) {
+	owner, td, errCode, err := h.changeOwner(r.Context(), id)
+	if errCode == constants.UserForbiddenErrorCode {
+		h.writeForbidden(w, r, err.Error())
+		return "", nil, false
+	}
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		h.logger.WithFields(log.Fields{
+			constants.ErrorCode:   errCode,
+			constants.HTTPStatus:  httpStatus,
+			constants.Path:        r.URL.Path,
+			constants.ErrorDetail: err,
+		}).Error(errCode.Message())
+		writeProblem(w, r, httpStatus, errCode, err.Error())
+		return "", nil, false
+	}
+	return owner, td, true
+}
+
+// changeOwner returns the owner of the todo identified by 'id', and the todo itself, as
+// described for authorizeChange. If the requester's role doesn't allow the todo to be
+// changed UserForbiddenErrorCode and an error are returned.
+func (h handler) changeOwner(ctx context.Context, id int) (string, *todo.Item, constants.ErrCode, error) {
+	requester := user.FromContext(ctx)
+	td, role, err := h.store.GetToDoItemRole(ctx, requester, id)
+	if err != nil {
+		return "", nil, constants.DBQueryErrorCode, err
+	}
+	if td == nil {
+		return requester, nil, constants.NoErrorCode, nil
+	}
+	if !role.CanEdit() {
+		return "", nil, constants.UserForbiddenErrorCode, errors.Errorf("the %s role can't change todo %d", role, id)
+	}
+	return td.Owner, td, constants.NoErrorCode, nil
+}
+
+// writeForbidden logs, and responds to, a request that the requester's role doesn't allow
+func (h handler) writeForbidden(w http.ResponseWriter, r *http.Request, detail string) {
+	httpStatus := http.StatusForbidden
+	h.logger.WithFields(log.Fields{
+		constants.ErrorCode:   constants.UserForbiddenErrorCode,
+		constants.HTTPStatus:  httpStatus,
+		constants.Path:        r.URL.Path,
+		constants.User:        user.FromContext(r.Context()),
+		constants.ErrorDetail: detail,
+	}).Warn(constants.UserForbiddenError)
+	writeProblem(w, r, httpStatus, constants.UserForbiddenErrorCode, detail)
+}
+
+// patchMoves returns true if the JSON Merge Patch 'patch' moves a todo out of the list
+// identified by 'listID'. A 'listid' of 'null' or 0 doesn't move it, see todo.MergePatch.
+func patchMoves(patch []byte, listID int64) bool {
+	var p struct {
+		ListID *int64 `json:"listid"`
+	}
+	err := json.Unmarshal(patch, &p)
+	return err == nil && p.ListID != nil && *p.ListID != 0 && *p.ListID != listID
+}
+
+// itemSelfRef returns the canonical resource path of the todo identified by 'id'. Todos in
+// every list are addressed as '/todos/{id}'.
+func itemSelfRef(id int64) string {
+	return "/todos/" + strconv.FormatInt(id, 10)
+}
+
 func logRqstRcvd(r *http.Request, logger *log.Entry) {
 	logger.WithFields(log.Fields{
 		constants.Method:     r.Method,
@@ -511,6 +1122,14 @@ func logRqstRcvd(r *http.Request, logger *log.Entry) {
 	}).Info("HTTP request received")
 }
 
+func logBulkRqstRcvd(r *http.Request, logger *log.Entry) {
+	logger.WithFields(log.Fields{
+		constants.Method:     r.Method,
+		constants.Path:       r.URL.Path,
+		constants.RemoteAddr: r.RemoteAddr,
+	}).Info("HTTP bulk request received")
+}
+
 func getURLPathNodes(path string) ([]string, error) {
 	pathNodes := strings.Split(path, "/")
 
@@ -529,7 +1148,7 @@ func getURLPathNodes(path string) ([]string, error) {
 	return pathNodes, nil
 }
 
-func parseRqst(r *http.Request, logger *log.Entry) (todo.Item, []string, error) {
+func parseRqst(r *http.Request, logger *log.Entry) (todo.Item, []string, constants.ErrCode, error) {
 	// Expecting a URL.Path like '/todos/' or '/todos?bulk=true'
 	pathNodes, err := getURLPathNodes(r.URL.Path)
 	if err != nil {
@@ -540,7 +1159,7 @@ func parseRqst(r *http.Request, logger *log.Entry) (todo.Item, []string, error)
 			constants.ErrorDetail: err,
 		}).Error(constants.MalformedURL)
 
-		return todo.Item{}, nil, errors.Annotate(err, "error occurred while extracting URL path nodes")
+		return todo.Item{}, nil, constants.MalformedURLErrorCode, errors.Annotate(err, "error occurred while extracting URL path nodes")
 	}
 
 	//
@@ -557,7 +1176,7 @@ func parseRqst(r *http.Request, logger *log.Entry) (todo.Item, []string, error)
 			constants.ErrorDetail: err.Error(),
 		}).Error(constants.JSONDecodingError)
 
-		return todo.Item{}, nil, errors.Annotate(err, "error occurred while unmarshaling request body")
+		return todo.Item{}, nil, constants.JSONDecodingErrorCode, errors.Annotate(err, "error occurred while unmarshaling request body")
 	}
 	if d.More() {
 		logger.WithFields(log.Fields{
@@ -566,10 +1185,13 @@ func parseRqst(r *http.Request, logger *log.Entry) (todo.Item, []string, error)
 		}).Warn(constants.JSONDecodingError)
 	}
 
-	return td, pathNodes, nil
+	// Todos always belong to the requester, whatever the request body says
+	td.Owner = user.FromContext(r.Context())
+
+	return td, pathNodes, constants.NoErrorCode, nil
 }
 
-func parseBulkRqst(r *http.Request, logger *log.Entry) (todo.List, []string, error) {
+func parseBulkRqst(r *http.Request, logger *log.Entry) (todo.List, []string, constants.ErrCode, error) {
 	// Expecting a URL.Path like '/todos/' or '/todos?bulk=true'
 	pathNodes, err := getURLPathNodes(r.URL.Path)
 	if err != nil {
@@ -580,7 +1202,7 @@ func parseBulkRqst(r *http.Request, logger *log.Entry) (todo.List, []string, err
 			constants.ErrorDetail: err,
 		}).Error(constants.MalformedURL)
 
-		return todo.List{}, nil, errors.Annotate(err, "error occurred while extracting URL path nodes")
+		return todo.List{}, nil, constants.MalformedURLErrorCode, errors.Annotate(err, "error occurred while extracting URL path nodes")
 	}
 
 	d := json.NewDecoder(r.Body)
@@ -594,7 +1216,7 @@ func parseBulkRqst(r *http.Request, logger *log.Entry) (todo.List, []string, err
 			constants.ErrorDetail: err.Error(),
 		}).Error(constants.JSONDecodingError)
 
-		return todo.List{}, nil, errors.Annotate(err, "error occurred while unmarshaling request body")
+		return todo.List{}, nil, constants.JSONDecodingErrorCode, errors.Annotate(err, "error occurred while unmarshaling request body")
 	}
 	if d.More() {
 		logger.WithFields(log.Fields{
@@ -603,25 +1225,34 @@ func parseBulkRqst(r *http.Request, logger *log.Entry) (todo.List, []string, err
 		}).Warn(constants.JSONDecodingError)
 	}
 
-	return tdl, pathNodes, nil
+	// Todos always belong to the requester, whatever the request body says
+	owner := user.FromContext(r.Context())
+	for _, td := range tdl.Items {
+		td.Owner = owner
+	}
+
+	return tdl, pathNodes, constants.NoErrorCode, nil
 }
 
-// NewToDoHandler returns a *http.Handler configured with a database connection
-func NewToDoHandler(db *sql.DB, logger *log.Entry) (http.Handler, error) {
-	if db == nil {
-		return nil, errors.New("non-nil sql.DB connection required")
+// NewToDoHandler returns a *http.Handler configured with a To Do item store. If 'publisher'
+// isn't nil it's notified of each change to the store's todos.
+func NewToDoHandler(store todo.Store, publisher EventPublisher, logger *log.Entry) (http.Handler, error) {
+	if store == nil {
+		return nil, errors.New("non-nil todo.Store required")
 	}
 	if logger == nil {
 		return nil, errors.New("non-nil log.Entry  required")
 	}
 
-	return handler{db: db, logger: logger}, nil
+	return handler{store: store, publisher: publisher, logger: logger}, nil
 }
 
 type insertTodoResponse struct {
 	Item       todo.Item `json:"item"`
 	HTTPStatus int       `json:"httpStatus"`
 	Err        string    `json:"error"`
+	// Problem describes the error, if any, as RFC 7807 problem details
+	Problem *problem `json:"problem,omitempty"`
 }
 
 type insertTodoResponses struct {
@@ -641,12 +1272,12 @@ var (
 	ToDoPostDoneChan = make(chan interface{})
 	// InsertToDoRqstChan is to send insert To Do Item requests for processing
 	InsertToDoRqstChan = make(chan insertTodoRequest, maxSimToDoInserts)
-	insertToDoRespChan = make(chan insertTodoResponse)
 )
 
 // PostRequestLauncher listens on its 'rqstChan' for insert To Do Item requests and
 // will launch each request into a goroutine for processing. It monitors its 'done'
-// channel to detect when it should exit.
+// channel to detect when it should exit. An insert is cancelled if its request is, or
+// when 'done' is closed. CheckBulkLauncher reports whether it's running.
 func PostRequestLauncher(h interface{}, done chan interface{}, rqstChan chan insertTodoRequest, logger *log.Entry) {
 	var hndlr handler
 
@@ -657,10 +1288,27 @@ func PostRequestLauncher(h interface{}, done chan interface{}, rqstChan chan ins
 		logger.Fatalf("PostRequestLauncher provided an 'h' parameter that is not of type 'handler'")
 	}
 	logger.Debug("PostRequestLauncher starting...")
+	atomic.StoreInt32(&launcherRunning, 1)
+	defer atomic.StoreInt32(&launcherRunning, 0)
 	for {
 		select {
 		case rqst := <-rqstChan:
-			go hndlr.handlePostItem(rqst.r, rqst.td, rqst.pathNodes, rqst.respChan)
+			bulkInsertsInFlight.Inc()
+			go func(rqst insertTodoRequest) {
+				defer bulkInsertsInFlight.Dec()
+				ctx, cancel := context.WithCancel(rqst.r.Context())
+				defer cancel()
+				go func() {
+					select {
+					case <-done:
+						cancel()
+					case <-ctx.Done():
+					}
+				}()
+				ctx, span := otel.Tracer(tracerName).Start(ctx, "bulk insert")
+				defer span.End()
+				hndlr.handlePostItem(rqst.r.WithContext(ctx), rqst.td, rqst.pathNodes, rqst.respChan)
+			}(rqst)
 		case <-done:
 			logger.Info("PostRequestLauncher exiting...")
 			return
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/logging"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans recorded by the handlers
+const tracerName = "github.com/youngkin/todoshaleapps/src/cmd/todod/handlers"
+
+// tracingHandler records a span for each request handled by 'next'
+type tracingHandler struct {
+	next   http.Handler
+	logger *log.Entry
+}
+
+// NewTracingHandler returns a handler that records a span for each request handled by
+// 'next'. The span continues the caller's trace if the request has a W3C 'traceparent'
+// header. The trace's ID is added to the messages logged while handling the request.
+func NewTracingHandler(next http.Handler, logger *log.Entry) (http.Handler, error) {
+	if next == nil {
+		return nil, errors.New("non-nil http.Handler required")
+	}
+	if logger == nil {
+		return nil, errors.New("non-nil log.Entry  required")
+	}
+	return tracingHandler{next: next, logger: logger}, nil
+}
+
+// ServeHTTP passes the request, carrying its span, to 'next'
+func (h tracingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	method, route := metricsMethod(r.Method), metricsRoute(r.URL.Path)
+	ctx, span := otel.Tracer(tracerName).Start(ctx, method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(method),
+			semconv.HTTPRoute(route),
+			semconv.URLPath(r.URL.Path),
+			attribute.String("http.request.header.x-request-id", r.Header.Get(RequestIDHeader)),
+		))
+	defer span.End()
+
+	if sc := span.SpanContext(); sc.HasTraceID() {
+		logger := requestLogger(r, h.logger).WithField(constants.TraceID, sc.TraceID().String())
+		ctx = logging.NewContext(ctx, logger)
+	}
+
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	h.next.ServeHTTP(sw, r.WithContext(ctx))
+
+	span.SetAttributes(semconv.HTTPResponseStatusCode(sw.status))
+	if sw.status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(sw.status))
+	}
+}
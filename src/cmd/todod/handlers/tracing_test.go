@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingHandler(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+	defer otel.SetTextMapPropagator(otel.GetTextMapPropagator())
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		todo.NewTracedStore(todo.NewMemStore()).GetToDoList(r.Context(), "ryoungkin")
+		w.WriteHeader(http.StatusNotFound)
+	})
+	h, err := NewTracingHandler(next, log.NewEntry(log.New()))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected creating the tracing handler", err)
+	}
+
+	traceID, callerSpanID := "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	req := httptest.NewRequest(http.MethodGet, "/todos/12", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+callerSpanID+"-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	storeSpan, serverSpan := spans[0], spans[1]
+
+	if serverSpan.Name() != "GET /todos/{id}" || serverSpan.SpanKind() != trace.SpanKindServer {
+		t.Errorf("expected a server span named 'GET /todos/{id}', got %s %s", serverSpan.SpanKind(), serverSpan.Name())
+	}
+	if serverSpan.SpanContext().TraceID().String() != traceID || serverSpan.Parent().SpanID().String() != callerSpanID {
+		t.Errorf("expected the server span to continue trace %s from span %s, got trace %s and parent %s",
+			traceID, callerSpanID, serverSpan.SpanContext().TraceID(), serverSpan.Parent().SpanID())
+	}
+	var status int64
+	for _, kv := range serverSpan.Attributes() {
+		if kv.Key == "http.response.status_code" {
+			status = kv.Value.AsInt64()
+		}
+	}
+	if status != http.StatusNotFound {
+		t.Errorf("expected http.response.status_code %d, got %d", http.StatusNotFound, status)
+	}
+
+	if storeSpan.Name() != "todo.GetToDoList" || storeSpan.Parent().SpanID() != serverSpan.SpanContext().SpanID() {
+		t.Errorf("expected a todo.GetToDoList span that's a child of the server span, got %s, a child of %s",
+			storeSpan.Name(), storeSpan.Parent().SpanID())
+	}
+}
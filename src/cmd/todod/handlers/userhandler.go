@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/user"
+)
+
+// userHandler handles user registration. It doesn't require authentication, a user can't
+// authenticate until they've registered.
+type userHandler struct {
+	users  user.Store
+	logger *log.Entry
+}
+
+// NewUserHandler returns a *http.Handler that registers users in 'users'
+func NewUserHandler(users user.Store, logger *log.Entry) (http.Handler, error) {
+	if users == nil {
+		return nil, errors.New("non-nil user.Store required")
+	}
+	if logger == nil {
+		return nil, errors.New("non-nil log.Entry  required")
+	}
+
+	return userHandler{users: users, logger: logger}, nil
+}
+
+// ServeHTTP handles 'POST /users'. The request body contains the new user's username and
+// password, the response body contains the user without their password.
+func (h userHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.logger = requestLogger(r, h.logger)
+	logRqstRcvd(r, h.logger)
+
+	pathNodes, err := getURLPathNodes(r.URL.Path)
+	if err != nil || len(pathNodes) != 1 {
+		h.writeError(w, r, http.StatusBadRequest, constants.MalformedURLErrorCode,
+			fmt.Sprintf("expected '/users', got %s", r.URL.Path))
+		return
+	}
+	if r.Method != http.MethodPost {
+		h.writeError(w, r, http.StatusMethodNotAllowed, constants.UnsupportedMethodErrorCode,
+			fmt.Sprintf("expected POST, got %s", r.Method))
+		return
+	}
+
+	c := user.Credentials{}
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	err = d.Decode(&c)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, constants.JSONDecodingErrorCode,
+			errors.Annotate(err, "error occurred while unmarshaling request body").Error())
+		return
+	}
+
+	u, err := user.NewUser(c)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, constants.UserValidationErrorCode, err.Error())
+		return
+	}
+
+	id, errCode, err := h.users.InsertUser(r.Context(), u)
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		switch errCode {
+		case constants.UserExistsErrorCode:
+			httpStatus = http.StatusConflict
+		case constants.UserValidationErrorCode:
+			httpStatus = http.StatusBadRequest
+		}
+		h.writeError(w, r, httpStatus, errCode, err.Error())
+		return
+	}
+
+	u.ID = id
+	body, err := json.Marshal(u)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, constants.JSONMarshalingErrorCode, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+}
+
+// writeError logs, and writes the problem details response for, a failed request
+func (h userHandler) writeError(w http.ResponseWriter, r *http.Request, httpStatus int, errCode constants.ErrCode, detail string) {
+	h.logger.WithFields(log.Fields{
+		constants.ErrorCode:   errCode,
+		constants.HTTPStatus:  httpStatus,
+		constants.Path:        r.URL.Path,
+		constants.ErrorDetail: detail,
+	}).Error(errCode.Message())
+	writeProblem(w, r, httpStatus, errCode, detail)
+}
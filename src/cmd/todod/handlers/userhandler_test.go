@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+func TestUserHandler(t *testing.T) {
+	tcs := []struct {
+		testName           string
+		method             string
+		url                string
+		data               string
+		expectedHTTPStatus int
+		expectedErrCode    constants.ErrCode
+		expectedBody       string
+	}{
+		{
+			testName:           "testPostUser",
+			method:             http.MethodPost,
+			url:                "/users",
+			data:               `{"username":"jdoe","password":"password123"}`,
+			expectedHTTPStatus: http.StatusCreated,
+			expectedBody:       `{"id":2,"username":"jdoe"}`,
+		},
+		{
+			testName:           "testPostExistingUser",
+			method:             http.MethodPost,
+			url:                "/users",
+			data:               `{"username":"ryoungkin","password":"password123"}`,
+			expectedHTTPStatus: http.StatusConflict,
+			expectedErrCode:    constants.UserExistsErrorCode,
+		},
+		{
+			testName:           "testPostShortPassword",
+			method:             http.MethodPost,
+			url:                "/users",
+			data:               `{"username":"jdoe","password":"short"}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.UserValidationErrorCode,
+		},
+		{
+			testName:           "testPostInvalidUsername",
+			method:             http.MethodPost,
+			url:                "/users",
+			data:               `{"username":"j doe","password":"password123"}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.UserValidationErrorCode,
+		},
+		{
+			testName:           "testPostUnknownField",
+			method:             http.MethodPost,
+			url:                "/users",
+			data:               `{"username":"jdoe","password":"password123","admin":true}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.JSONDecodingErrorCode,
+		},
+		{
+			testName:           "testUnsupportedMethod",
+			method:             http.MethodGet,
+			url:                "/users",
+			expectedHTTPStatus: http.StatusMethodNotAllowed,
+			expectedErrCode:    constants.UnsupportedMethodErrorCode,
+		},
+		{
+			testName:           "testBadPath",
+			method:             http.MethodPost,
+			url:                "/users/jdoe",
+			data:               `{"username":"jdoe","password":"password123"}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.MalformedURLErrorCode,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			srvHandler, err := NewUserHandler(newTestUsers(t, "ryoungkin"), logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a user handler", err)
+			}
+
+			mux := http.NewServeMux()
+			mux.Handle("/users", srvHandler)
+			mux.Handle("/users/", srvHandler)
+			testSrv := httptest.NewServer(mux)
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(tc.method, testSrv.URL+tc.url, bytes.NewBuffer([]byte(tc.data)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, resp.StatusCode)
+			}
+
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(resp.Body)
+			if tc.expectedHTTPStatus >= http.StatusBadRequest {
+				p := problem{}
+				err = json.Unmarshal(buf.Bytes(), &p)
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected decoding the problem details", err)
+				}
+				if p.ErrCode != tc.expectedErrCode {
+					t.Errorf("expected errcode %d, got %+v", tc.expectedErrCode, p)
+				}
+				return
+			}
+			if buf.String() != tc.expectedBody {
+				t.Errorf("expected body %s, got %s", tc.expectedBody, buf.String())
+			}
+		})
+	}
+}
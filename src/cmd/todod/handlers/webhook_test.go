@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/webhook"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	client := &http.Client{}
+
+	tcs := []struct {
+		testName           string
+		requester          string
+		method             string
+		url                string
+		data               string
+		expectedHTTPStatus int
+		expectedErrCode    constants.ErrCode
+		expectedBody       string
+		expectedLocation   string
+	}{
+		{
+			testName:           "testGetWebhooks",
+			method:             http.MethodGet,
+			url:                "/webhooks",
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody:       `{"webhooks":[{"id":1,"selfref":"/webhooks/1","url":"http://example.com/hook","events":["todo.created"]}]}`,
+		},
+		{
+			testName:           "testGetWebhook",
+			method:             http.MethodGet,
+			url:                "/webhooks/1",
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody:       `{"id":1,"selfref":"/webhooks/1","url":"http://example.com/hook","events":["todo.created"]}`,
+		},
+		{
+			testName:           "testGetWebhooksOtherUser",
+			requester:          "jdoe",
+			method:             http.MethodGet,
+			url:                "/webhooks",
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody:       `{"webhooks":[]}`,
+		},
+		{
+			testName:           "testGetOtherUsersWebhook",
+			requester:          "jdoe",
+			method:             http.MethodGet,
+			url:                "/webhooks/1",
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.WebhookNotFoundErrorCode,
+		},
+		{
+			testName:           "testDeleteOtherUsersWebhook",
+			requester:          "jdoe",
+			method:             http.MethodDelete,
+			url:                "/webhooks/1",
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.WebhookNotFoundErrorCode,
+		},
+		{
+			testName:           "testGetWebhookNotFound",
+			method:             http.MethodGet,
+			url:                "/webhooks/100",
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.WebhookNotFoundErrorCode,
+		},
+		{
+			testName:           "testGetWebhookBadID",
+			method:             http.MethodGet,
+			url:                "/webhooks/one",
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.MalformedURLErrorCode,
+		},
+		{
+			testName:           "testPostWebhook",
+			method:             http.MethodPost,
+			url:                "/webhooks",
+			data:               `{"url":"https://example.com/other","events":["todo.deleted"],"secret":"shh"}`,
+			expectedHTTPStatus: http.StatusCreated,
+			expectedBody:       `{"id":2,"selfref":"/webhooks/2","url":"https://example.com/other","events":["todo.deleted"],"secret":"shh"}`,
+			expectedLocation:   "/webhooks/2",
+		},
+		{
+			testName:           "testPostWebhookInvalid",
+			method:             http.MethodPost,
+			url:                "/webhooks",
+			data:               `{"url":"example.com/other","events":["todo.archived"]}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.WebhookValidationErrorCode,
+		},
+		{
+			testName:           "testPostWebhookMetadataService",
+			method:             http.MethodPost,
+			url:                "/webhooks",
+			data:               `{"url":"http://169.254.169.254/latest/meta-data","events":["todo.created"]}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.WebhookValidationErrorCode,
+		},
+		{
+			testName:           "testPostWebhookPrivateAddress",
+			method:             http.MethodPost,
+			url:                "/webhooks",
+			data:               `{"url":"http://10.0.0.5:5432/","events":["todo.created"]}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.WebhookValidationErrorCode,
+		},
+		{
+			testName:           "testPostWebhookAllowedInternalHost",
+			method:             http.MethodPost,
+			url:                "/webhooks",
+			data:               `{"url":"http://10.1.2.3/hook","events":["todo.created"],"secret":"shh"}`,
+			expectedHTTPStatus: http.StatusCreated,
+			expectedBody:       `{"id":2,"selfref":"/webhooks/2","url":"http://10.1.2.3/hook","events":["todo.created"],"secret":"shh"}`,
+			expectedLocation:   "/webhooks/2",
+		},
+		{
+			testName:           "testPutWebhookLoopback",
+			method:             http.MethodPut,
+			url:                "/webhooks/1",
+			data:               `{"url":"http://127.0.0.1:8080/admin","events":["todo.created"]}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.WebhookValidationErrorCode,
+		},
+		{
+			testName:           "testPostWebhookWithID",
+			method:             http.MethodPost,
+			url:                "/webhooks",
+			data:               `{"id":5,"url":"https://example.com/other","events":["todo.deleted"]}`,
+			expectedHTTPStatus: http.StatusBadRequest,
+			expectedErrCode:    constants.InvalidInsertErrorCode,
+		},
+		{
+			testName:           "testPutWebhook",
+			method:             http.MethodPut,
+			url:                "/webhooks/1",
+			data:               `{"url":"http://example.com/hook","events":["todo.created","todo.updated"]}`,
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody:       `{"id":1,"selfref":"/webhooks/1","url":"http://example.com/hook","events":["todo.created","todo.updated"]}`,
+		},
+		{
+			testName:           "testPutWebhookNotFound",
+			method:             http.MethodPut,
+			url:                "/webhooks/100",
+			data:               `{"url":"http://example.com/hook","events":["todo.created"]}`,
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.WebhookNotFoundErrorCode,
+		},
+		{
+			testName:           "testDeleteWebhook",
+			method:             http.MethodDelete,
+			url:                "/webhooks/1",
+			expectedHTTPStatus: http.StatusOK,
+		},
+		{
+			testName:           "testDeleteWebhookNotFound",
+			method:             http.MethodDelete,
+			url:                "/webhooks/100",
+			expectedHTTPStatus: http.StatusNotFound,
+			expectedErrCode:    constants.WebhookNotFoundErrorCode,
+		},
+		{
+			testName:           "testGetDeliveries",
+			method:             http.MethodGet,
+			url:                "/webhooks/1/deliveries",
+			expectedHTTPStatus: http.StatusOK,
+			expectedBody:       `{"deliveries":[{"id":1,"subscriptionid":1,"eventid":"abc","eventtype":"todo.created","attempt":1,"httpStatus":200,"deliveredat":"2020-04-02T13:13:00Z"}]}`,
+		},
+		{
+			testName:           "testUnsupportedMethod",
+			method:             http.MethodPatch,
+			url:                "/webhooks/1",
+			expectedHTTPStatus: http.StatusMethodNotAllowed,
+			expectedErrCode:    constants.UnsupportedMethodErrorCode,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			store := webhook.NewMemStore()
+			_, err := store.InsertSubscription(context.Background(), webhook.Subscription{
+				URL:    "http://example.com/hook",
+				Events: []webhook.EventType{webhook.ToDoCreated},
+				Secret: "secret",
+			})
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected populating the webhook store", err)
+			}
+			err = store.InsertDelivery(context.Background(), webhook.Delivery{
+				SubscriptionID: 1,
+				EventID:        "abc",
+				EventType:      webhook.ToDoCreated,
+				Attempt:        1,
+				HTTPStatus:     http.StatusOK,
+				DeliveredAt:    time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC),
+			})
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected populating the delivery log", err)
+			}
+
+			// example.com is allowed so the test doesn't depend on DNS
+			destinations, err := webhook.NewDestinations([]string{"example.com", "10.1.0.0/16"})
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating webhook destinations", err)
+			}
+			srvHandler, err := NewWebhookHandler(store, destinations, logger)
+			if err != nil {
+				t.Fatalf("error '%s' was not expected when getting a webhook handler", err)
+			}
+
+			testSrv := httptest.NewServer(withRequester(srvHandler, tc.requester))
+			defer testSrv.Close()
+
+			req, err := http.NewRequest(tc.method, testSrv.URL+tc.url, bytes.NewBuffer([]byte(tc.data)))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating HTTP request", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected calling (client.Do()) todod server", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedHTTPStatus {
+				t.Errorf("expected StatusCode = %d, got %d", tc.expectedHTTPStatus, resp.StatusCode)
+			}
+			if loc := resp.Header.Get("Location"); loc != tc.expectedLocation {
+				t.Errorf("expected Location %q, got %q", tc.expectedLocation, loc)
+			}
+
+			buf := new(bytes.Buffer)
+			buf.ReadFrom(resp.Body)
+			if tc.expectedHTTPStatus >= http.StatusBadRequest {
+				p := problem{}
+				err = json.Unmarshal(buf.Bytes(), &p)
+				if err != nil {
+					t.Fatalf("an error '%s' was not expected decoding the problem details", err)
+				}
+				if p.ErrCode != tc.expectedErrCode {
+					t.Errorf("expected errcode %d, got %+v", tc.expectedErrCode, p)
+				}
+				return
+			}
+			if len(tc.expectedBody) > 0 && buf.String() != tc.expectedBody {
+				t.Errorf("expected body %s, got %s", tc.expectedBody, buf.String())
+			}
+		})
+	}
+}
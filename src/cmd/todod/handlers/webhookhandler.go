@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/user"
+	"github.com/youngkin/todoshaleapps/src/internal/webhook"
+)
+
+type webhookHandler struct {
+	store        webhook.Store
+	destinations *webhook.Destinations
+	logger       *log.Entry
+}
+
+// webhookList is the response body of GET /webhooks
+type webhookList struct {
+	Webhooks []webhook.Subscription `json:"webhooks"`
+}
+
+// deliveryList is the response body of GET /webhooks/{id}/deliveries
+type deliveryList struct {
+	Deliveries []webhook.Delivery `json:"deliveries"`
+}
+
+// NewWebhookHandler returns a *http.Handler that manages the webhook subscriptions in
+// 'store'. Subscriptions are rejected if 'destinations' doesn't allow their URL's host.
+func NewWebhookHandler(store webhook.Store, destinations *webhook.Destinations, logger *log.Entry) (http.Handler, error) {
+	if store == nil {
+		return nil, errors.New("non-nil webhook.Store required")
+	}
+	if destinations == nil {
+		return nil, errors.New("non-nil webhook.Destinations required")
+	}
+	if logger == nil {
+		return nil, errors.New("non-nil log.Entry  required")
+	}
+
+	return webhookHandler{store: store, destinations: destinations, logger: logger}, nil
+}
+
+// ServeHTTP handles requests for '/webhooks', '/webhooks/{id}', and '/webhooks/{id}/deliveries'
+func (h webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.logger = requestLogger(r, h.logger)
+	logRqstRcvd(r, h.logger)
+
+	pathNodes, err := getURLPathNodes(r.URL.Path)
+	if err != nil || len(pathNodes) < 1 || len(pathNodes) > 3 ||
+		(len(pathNodes) == 3 && pathNodes[2] != "deliveries") {
+		h.writeError(w, r, http.StatusBadRequest, constants.MalformedURLErrorCode,
+			fmt.Sprintf("expected '/webhooks', '/webhooks/{id}', or '/webhooks/{id}/deliveries', got %s", r.URL.Path))
+		return
+	}
+
+	var id int64
+	if len(pathNodes) > 1 {
+		id, err = strconv.ParseInt(pathNodes[1], 10, 64)
+		if err != nil {
+			h.writeError(w, r, http.StatusBadRequest, constants.MalformedURLErrorCode,
+				fmt.Sprintf("Invalid resource ID, must be int, got %v", pathNodes[1]))
+			return
+		}
+	}
+
+	switch {
+	case len(pathNodes) == 1 && r.Method == http.MethodGet:
+		h.handleGetList(w, r)
+	case len(pathNodes) == 1 && r.Method == http.MethodPost:
+		h.handlePost(w, r)
+	case len(pathNodes) == 2 && r.Method == http.MethodGet:
+		h.handleGet(w, r, id)
+	case len(pathNodes) == 2 && r.Method == http.MethodPut:
+		h.handlePut(w, r, id)
+	case len(pathNodes) == 2 && r.Method == http.MethodDelete:
+		h.handleDelete(w, r, id)
+	case len(pathNodes) == 3 && r.Method == http.MethodGet:
+		h.handleGetDeliveries(w, r, id)
+	default:
+		h.writeError(w, r, http.StatusMethodNotAllowed, constants.UnsupportedMethodErrorCode,
+			fmt.Sprintf("%s isn't supported for %s", r.Method, r.URL.Path))
+	}
+}
+
+func (h webhookHandler) handleGetList(w http.ResponseWriter, r *http.Request) {
+	owned, err := h.store.GetSubscriptionsByOwner(r.Context(), user.FromContext(r.Context()))
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, constants.DBQueryErrorCode, err.Error())
+		return
+	}
+
+	subs := make([]webhook.Subscription, 0, len(owned))
+	for _, s := range owned {
+		subs = append(subs, withoutSecret(s))
+	}
+	h.writeJSON(w, r, http.StatusOK, webhookList{Webhooks: subs})
+}
+
+// handlePost creates a subscription. The response contains the subscription's secret,
+// it isn't returned by any other request.
+func (h webhookHandler) handlePost(w http.ResponseWriter, r *http.Request) {
+	s, ok := h.parseSubscription(w, r)
+	if !ok {
+		return
+	}
+	if s.ID != 0 {
+		h.writeError(w, r, http.StatusBadRequest, constants.InvalidInsertErrorCode,
+			fmt.Sprintf("expected unpopulated webhook ID, got ID = %d", s.ID))
+		return
+	}
+	if len(s.Secret) == 0 {
+		s.Secret = webhook.NewSecret()
+	}
+
+	err := webhook.ValidateSubscription(s)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, constants.WebhookValidationErrorCode, err.Error())
+		return
+	}
+	if !h.checkDestination(w, r, s) {
+		return
+	}
+
+	id, err := h.store.InsertSubscription(r.Context(), s)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, constants.DBUpSertErrorCode, err.Error())
+		return
+	}
+
+	s.ID = id
+	s.SelfRef = "/webhooks/" + strconv.FormatInt(id, 10)
+	w.Header().Set("Location", s.SelfRef)
+	h.writeJSON(w, r, http.StatusCreated, s)
+}
+
+func (h webhookHandler) handleGet(w http.ResponseWriter, r *http.Request, id int64) {
+	s, ok := h.getSubscription(w, r, id)
+	if !ok {
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, withoutSecret(*s))
+}
+
+// handlePut replaces a subscription. Its secret is only replaced if the request contains one.
+func (h webhookHandler) handlePut(w http.ResponseWriter, r *http.Request, id int64) {
+	s, ok := h.parseSubscription(w, r)
+	if !ok {
+		return
+	}
+	if s.ID != 0 && s.ID != id {
+		h.writeError(w, r, http.StatusBadRequest, constants.MalformedURLErrorCode,
+			fmt.Sprintf("resource ID in url (%d) doesn't match resource ID in request body (%d)", id, s.ID))
+		return
+	}
+	s.ID = id
+	if _, ok := h.getSubscription(w, r, id); !ok {
+		return
+	}
+	if err := webhook.ValidateSubscription(s); err == nil && !h.checkDestination(w, r, s) {
+		return
+	}
+
+	errCode, err := h.store.UpdateSubscription(r.Context(), s)
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		switch errCode {
+		case constants.WebhookValidationErrorCode:
+			httpStatus = http.StatusBadRequest
+		case constants.DBInvalidRequestCode:
+			httpStatus = http.StatusNotFound
+			errCode = constants.WebhookNotFoundErrorCode
+		}
+		h.writeError(w, r, httpStatus, errCode, err.Error())
+		return
+	}
+
+	s.SelfRef = "/webhooks/" + strconv.FormatInt(id, 10)
+	h.writeJSON(w, r, http.StatusOK, withoutSecret(s))
+}
+
+// checkDestination returns true if webhooks can be delivered to the host of 's's URL,
+// otherwise it responds with a 400
+func (h webhookHandler) checkDestination(w http.ResponseWriter, r *http.Request, s webhook.Subscription) bool {
+	err := h.destinations.Check(r.Context(), s.URL)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, constants.WebhookValidationErrorCode, err.Error())
+		return false
+	}
+	return true
+}
+
+func (h webhookHandler) handleDelete(w http.ResponseWriter, r *http.Request, id int64) {
+	if _, ok := h.getSubscription(w, r, id); !ok {
+		return
+	}
+
+	errCode, err := h.store.DeleteSubscription(r.Context(), id)
+	if err != nil {
+		httpStatus := http.StatusInternalServerError
+		if errCode == constants.DBInvalidRequestCode {
+			httpStatus = http.StatusNotFound
+			errCode = constants.WebhookNotFoundErrorCode
+		}
+		h.writeError(w, r, httpStatus, errCode, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h webhookHandler) handleGetDeliveries(w http.ResponseWriter, r *http.Request, id int64) {
+	if _, ok := h.getSubscription(w, r, id); !ok {
+		return
+	}
+
+	deliveries, err := h.store.GetDeliveries(r.Context(), id)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, constants.DBQueryErrorCode, err.Error())
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, deliveryList{Deliveries: deliveries})
+}
+
+// getSubscription returns the subscription identified by 'id' if it belongs to the requester.
+// Other users' subscriptions are reported as not found. If it can't be returned the error
+// response has been written and false is returned.
+func (h webhookHandler) getSubscription(w http.ResponseWriter, r *http.Request, id int64) (*webhook.Subscription, bool) {
+	s, err := h.store.GetSubscription(r.Context(), id)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, constants.DBQueryErrorCode, err.Error())
+		return nil, false
+	}
+	if s == nil || s.Owner != user.FromContext(r.Context()) {
+		h.writeError(w, r, http.StatusNotFound, constants.WebhookNotFoundErrorCode,
+			fmt.Sprintf("webhook %d doesn't exist", id))
+		return nil, false
+	}
+
+	s.SelfRef = "/webhooks/" + strconv.FormatInt(id, 10)
+	return s, true
+}
+
+// parseSubscription decodes the subscription in the request body, the subscription's owner
+// is always the requester. If it can't be decoded the error response has been written and
+// false is returned.
+func (h webhookHandler) parseSubscription(w http.ResponseWriter, r *http.Request) (webhook.Subscription, bool) {
+	s := webhook.Subscription{}
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	err := d.Decode(&s)
+	if err != nil {
+		h.writeError(w, r, http.StatusBadRequest, constants.JSONDecodingErrorCode,
+			errors.Annotate(err, "error occurred while unmarshaling request body").Error())
+		return webhook.Subscription{}, false
+	}
+	s.Owner = user.FromContext(r.Context())
+	return s, true
+}
+
+// writeJSON writes 'body', JSON encoded, with 'httpStatus'
+func (h webhookHandler) writeJSON(w http.ResponseWriter, r *http.Request, httpStatus int, body interface{}) {
+	marshBody, err := json.Marshal(body)
+	if err != nil {
+		h.writeError(w, r, http.StatusInternalServerError, constants.JSONMarshalingErrorCode, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(httpStatus)
+	w.Write(marshBody)
+}
+
+// writeError logs, and writes the problem details response for, a failed request
+func (h webhookHandler) writeError(w http.ResponseWriter, r *http.Request, httpStatus int, errCode constants.ErrCode, detail string) {
+	h.logger.WithFields(log.Fields{
+		constants.ErrorCode:   errCode,
+		constants.HTTPStatus:  httpStatus,
+		constants.Path:        r.URL.Path,
+		constants.ErrorDetail: detail,
+	}).Error(errCode.Message())
+	writeProblem(w, r, httpStatus, errCode, detail)
+}
+
+// withoutSecret returns 's' without its secret, secrets are only returned when a
+// subscription is created
+func withoutSecret(s webhook.Subscription) webhook.Subscription {
+	s.Secret = ""
+	if len(s.SelfRef) == 0 {
+		s.SelfRef = "/webhooks/" + strconv.FormatInt(s.ID, 10)
+	}
+	return s
+}
@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
@@ -16,69 +17,287 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/youngkin/todoshaleapps/src/cmd/todod/handlers"
+	"github.com/youngkin/todoshaleapps/src/internal/auth"
+	"github.com/youngkin/todoshaleapps/src/internal/config"
 	"github.com/youngkin/todoshaleapps/src/internal/logging"
+	"github.com/youngkin/todoshaleapps/src/internal/metrics"
+	"github.com/youngkin/todoshaleapps/src/internal/migrate"
 	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/reminder"
+	"github.com/youngkin/todoshaleapps/src/internal/stream"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+	"github.com/youngkin/todoshaleapps/src/internal/tracing"
+	"github.com/youngkin/todoshaleapps/src/internal/user"
+	"github.com/youngkin/todoshaleapps/src/internal/webhook"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	// requestTimeout is the time allowed to handle a request, other than /todos/events
+	requestTimeout = 5 * time.Second
+	// readinessTimeout is the time allowed for each /readyz check
+	readinessTimeout = 2 * time.Second
+	// webhookTimeout is the time allowed to deliver an event to a webhook
+	webhookTimeout = 10 * time.Second
 )
 
 func main() {
 	logger := logging.GetLogger().WithField(constants.Application, "ToDo")
 
-	dbportStr, ok := os.LookupEnv("POSTGRES_SERVICE_PORT")
-	if !ok {
-		logger.Warn("$POSTGRES_SERVICE_PORT not found, defaulting to 5432")
-		dbportStr = "5432"
-	}
-	dfltDbport, err := strconv.Atoi(dbportStr)
-	if err != nil {
-		logger.Warn("error converting POSTGRES_SERVICE_PORT to int, defaulting to 5432", dbportStr)
-		dfltDbport = 5432
-	}
-	dfltDbhost, ok := os.LookupEnv("POSTGRES_SERVICE_HOST")
-	if !ok {
-		logger.Warn("$POSTGRES_SERVICE_HOST not found, defaulting to localhost")
-		dfltDbhost = "localhost"
+	// 'todod migrate [flags] <command>' migrates the database schema instead of starting the
+	// service, it accepts the same flags
+	args := os.Args[1:]
+	migrateCmd := len(args) > 0 && args[0] == "migrate"
+	if migrateCmd {
+		args = args[1:]
 	}
 
-	logLevel := flag.Int("loglevel", 4,
+	// The flags override the corresponding settings from the configuration file and
+	// environment, see the config package. Only the flags that are provided are applied,
+	// their defaults are config.Default()'s and are shown for reference.
+	dflt := config.Default()
+	configFile := flag.String("config", os.Getenv("TODOD_CONFIG"),
+		"specifies a YAML configuration file, defaults to $TODOD_CONFIG. Flags and TODOD_* environment variables override its settings")
+	flag.Int("loglevel", dflt.LogLevel,
 		"specifies the logging level, 4(INFO) is the default. Levels run from 0 (PANIC) to 6 (TRACE)")
-	port := flag.Int("port", 8080, "specifies this service's listening port")
-	// Normally, info like this should NEVER come from the command line.
-	dbPort := flag.Int("dbport", dfltDbport, "specifies the database's connection port")
-	dbHost := flag.String("dbhost", dfltDbhost, "specifies the hostname or address of the database server")
-	dbUser := flag.String("dbuser", "todo", "DB user's login ID")
-	password := flag.String("passwd", "todo123", "DB user's password")
-	dbName := flag.String("dbname", "todo", "application's db name")
+	flag.Int("port", dflt.Port, "specifies this service's listening port")
+	flag.Int("dbport", dflt.DB.Port, "specifies the database's connection port, defaults to $POSTGRES_SERVICE_PORT if set")
+	flag.String("dbhost", dflt.DB.Host,
+		"specifies the hostname or address of the database server, defaults to $POSTGRES_SERVICE_HOST if set")
+	flag.String("dbuser", dflt.DB.User, "DB user's login ID")
+	flag.String("dbpasswordfile", "",
+		"specifies a file containing the DB user's password. The password can also be provided by the configuration file or $TODOD_DB_PASSWORD")
+	flag.String("dbname", dflt.DB.Name, "application's db name")
+	flag.String("dblegacyowner", "",
+		"specifies the user given the todos, lists, and webhooks created before todod had users, they have no owner")
+	flag.Duration("dbtimeout", dflt.DB.Timeout,
+		"specifies how long each database call may take, e.g., '5s'. 0 means calls are only limited by their request")
+	flag.String("store", dflt.Store,
+		"specifies where To Do items are kept, 'postgres' (the default) or 'memory'. 'memory' requires no database")
+	flag.Bool("automigrate", dflt.AutoMigrate,
+		"specifies whether pending schema migrations are applied at startup. If false todod won't start unless the schema is up to date")
+	flag.Duration("reminderinterval", dflt.Reminders.Interval,
+		"specifies how often to check for To Do items needing reminders, 0 disables reminders")
+	flag.Duration("reminderwindow", dflt.Reminders.Window,
+		"specifies how far ahead to remind about To Do items that are due soon, 0 only reminds about overdue items")
+	flag.String("reminderwebhook", "",
+		"specifies a URL that reminders are POSTed to, in addition to being logged")
+	flag.Int("eventlogsize", dflt.EventLogSize,
+		"specifies how many recent change events are kept so /todos/events clients can resume after reconnecting")
+	flag.Duration("shutdowndelay", dflt.ShutdownDelay,
+		"specifies how long /readyz reports not ready, on SIGTERM, before connections are drained")
+	flag.String("webhookallowedhosts", "",
+		"specifies a comma separated list of internal host names, IP addresses, and CIDRs webhooks can be delivered to, other internal addresses are refused")
+	flag.String("traceexporter", dflt.Tracing.Exporter,
+		"specifies where spans are exported, 'none' (the default), 'stdout', 'file', or 'otlp'")
+	flag.String("tracefile", "", "specifies the file spans are written to by the 'file' exporter")
+	flag.String("traceendpoint", dflt.Tracing.Endpoint,
+		"specifies the OTLP/HTTP traces endpoint of the collector spans are sent to by the 'otlp' exporter")
+	flag.Float64("tracesampleratio", dflt.Tracing.SampleRatio,
+		"specifies the fraction, from 0 to 1, of the traces started by todod that are recorded")
+	flag.String("jwtkeyfile", "",
+		"specifies a file containing the key that verifies JWT bearer tokens, a PEM RSA public key or certificate (RS256) or an HMAC secret (HS256)")
+	flag.String("jwksfile", "",
+		"specifies a JSON Web Key Set file containing keys that verify JWT bearer tokens, in addition to -jwtkeyfile")
+	flag.String("jwtissuer", "", "specifies the issuer ('iss') JWT bearer tokens must have, if any")
+	flag.String("jwtaudience", "", "specifies the audience ('aud') JWT bearer tokens must include, if any")
+	flag.String("apikeysfile", "",
+		"specifies a file of service account API keys accepted as bearer tokens, one '<account> <key>' per line")
 
-	flag.Parse()
+	flag.CommandLine.Parse(args)
+
+	flags := map[string]string{}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name != "config" {
+			flags[f.Name] = f.Value.String()
+		}
+	})
+	cfg, errCode, err := config.Load(*configFile, os.LookupEnv, flags)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:      errCode,
+			constants.ConfigFileName: *configFile,
+			constants.ErrorDetail:    err.Error(),
+		}).Fatal(errCode.Message())
+	}
 
 	// 'logger' comes set with a default log level. This will be used if there's a problem
 	// with the provided log level.
-	log.SetLevel(log.Level(*logLevel))
+	log.SetLevel(log.Level(cfg.LogLevel))
+
+	if migrateCmd {
+		if cfg.Store != "postgres" {
+			logger.WithFields(log.Fields{
+				constants.ErrorCode:   constants.UnableToGetConfigErrorCode,
+				constants.ErrorDetail: fmt.Sprintf("'migrate' requires the 'postgres' store, got %q", cfg.Store),
+			}).Fatal(constants.UnableToGetConfig)
+		}
+		cmd, n := parseMigrateCmd(flag.Args(), logger)
+		db := openDB(cfg.DB.Host, cfg.DB.Port, cfg.DB.User, cfg.DB.Password, cfg.DB.Name, logger)
+		defer db.Close()
+		runMigrate(db, cmd, n, cfg.DB.LegacyOwner, logger)
+		return
+	}
 
 	//
-	// Setup DB connection
+	// Setup tracing, spans are exported when todod exits
 	//
-	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", *dbHost, *dbPort, *dbUser, *password, *dbName)
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
 		logger.WithFields(log.Fields{
-			constants.ErrorCode:   constants.UnableToOpenDBConnErrorCode,
+			constants.ErrorCode:   constants.TracingErrorCode,
 			constants.ErrorDetail: err.Error(),
-		}).Fatal(constants.UnableToOpenDBConn)
-	}
-	err = db.Ping()
+		}).Warn(constants.TracingError)
+	}))
+	stopTracing, err := tracing.Start(cfg.Tracing)
 	if err != nil {
 		logger.WithFields(log.Fields{
-			constants.ErrorCode:   constants.UnableToOpenDBConnErrorCode,
+			constants.ErrorCode:   constants.TracingErrorCode,
 			constants.ErrorDetail: err.Error(),
-		}).Fatal(constants.UnableToOpenDBConn + ": database unreachable")
+		}).Fatal(constants.TracingError)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := stopTracing(ctx); err != nil {
+			logger.WithFields(log.Fields{
+				constants.ErrorCode:   constants.TracingErrorCode,
+				constants.ErrorDetail: err.Error(),
+			}).Warn(constants.TracingError)
+		}
+	}()
+
+	//
+	// Setup To Do item store
+	//
+	var (
+		store    todo.Store
+		sentLog  reminder.SentLog
+		webhooks webhook.Store
+		users    user.Store
+		// readinessChecks are the dependencies /readyz checks in addition to the bulk POST launcher
+		readinessChecks []handlers.ReadinessCheck
+	)
+	switch cfg.Store {
+	case "postgres":
+		db := openDB(cfg.DB.Host, cfg.DB.Port, cfg.DB.User, cfg.DB.Password, cfg.DB.Name, logger)
+		defer db.Close()
+		migrateSchema(db, cfg.AutoMigrate, cfg.DB.LegacyOwner, logger)
+		metrics.DefaultRegistry.RegisterDBStats(db)
+		migrator := newMigrator(db, logger)
+		readinessChecks = []handlers.ReadinessCheck{
+			{Name: "database", Check: db.PingContext},
+			{Name: "schema", Check: migrator.CheckContext},
+		}
+
+		store, err = todo.NewPGStore(db, cfg.DB.Timeout)
+		if err != nil {
+			logger.WithFields(log.Fields{
+				constants.ErrorCode:   constants.UnableToOpenDBConnErrorCode,
+				constants.ErrorDetail: err.Error(),
+			}).Fatal(constants.UnableToOpenDBConn)
+		}
+		sentLog, err = reminder.NewPGSentLog(db, cfg.DB.Timeout)
+		if err != nil {
+			logger.WithFields(log.Fields{
+				constants.ErrorCode:   constants.UnableToOpenDBConnErrorCode,
+				constants.ErrorDetail: err.Error(),
+			}).Fatal(constants.UnableToOpenDBConn)
+		}
+		webhooks, err = webhook.NewPGStore(db, cfg.DB.Timeout)
+		if err != nil {
+			logger.WithFields(log.Fields{
+				constants.ErrorCode:   constants.UnableToOpenDBConnErrorCode,
+				constants.ErrorDetail: err.Error(),
+			}).Fatal(constants.UnableToOpenDBConn)
+		}
+		users, err = user.NewPGStore(db, cfg.DB.Timeout)
+		if err != nil {
+			logger.WithFields(log.Fields{
+				constants.ErrorCode:   constants.UnableToOpenDBConnErrorCode,
+				constants.ErrorDetail: err.Error(),
+			}).Fatal(constants.UnableToOpenDBConn)
+		}
+	case "memory":
+		logger.Warn("using in-memory store, To Do items will be lost when todod exits")
+		store = todo.NewMemStore()
+		sentLog = reminder.NewMemSentLog()
+		webhooks = webhook.NewMemStore()
+		users = user.NewMemStore()
+	default:
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToGetConfigErrorCode,
+			constants.ErrorDetail: fmt.Sprintf("unknown store type %q, expected 'postgres' or 'memory'", cfg.Store),
+		}).Fatal(constants.UnableToGetConfig)
 	}
-	defer db.Close()
 
 	//
 	// Setup endpoints and start service
 	//
-	todoHandler, err := handlers.NewToDoHandler(db, logger)
+	destinations, err := webhook.NewDestinations(strings.Split(cfg.Webhooks.AllowedHosts, ","))
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToGetConfigErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToGetConfig)
+	}
+	dispatcher, err := webhook.NewDispatcher(webhooks, destinations.Client(webhookTimeout), logger)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToGetConfigErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToGetConfig)
+	}
+	eventLog := stream.NewLog(cfg.EventLogSize)
+	publisher := handlers.Publishers{dispatcher, eventLog}
+	// The handlers' store calls are recorded as children of their requests' spans
+	tracedStore := todo.NewTracedStore(store)
+	todoHandler, err := handlers.NewToDoHandler(tracedStore, publisher, logger)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToCreateHTTPHandlerErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToCreateHTTPHandler)
+	}
+	listHandler, err := handlers.NewListHandler(tracedStore, publisher, logger)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToCreateHTTPHandlerErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToCreateHTTPHandler)
+	}
+	webhookHandler, err := handlers.NewWebhookHandler(webhooks, destinations, logger)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToCreateHTTPHandlerErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToCreateHTTPHandler)
+	}
+	eventStreamHandler, err := handlers.NewEventStreamHandler(eventLog, handlers.ToDoPostDoneChan, logger)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToCreateHTTPHandlerErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToCreateHTTPHandler)
+	}
+	userHandler, err := handlers.NewUserHandler(users, logger)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToCreateHTTPHandlerErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToCreateHTTPHandler)
+	}
+
+	// Everything other than registration and health checks requires authentication
+	authMux := http.NewServeMux()
+	authMux.Handle("/todos", todoHandler) // Adding this route is necessary to support query parms like /todos?bulk=true
+	authMux.Handle("/todos/", todoHandler)
+	authMux.Handle("/lists", listHandler)
+	authMux.Handle("/lists/", listHandler)
+	authMux.Handle("/webhooks", webhookHandler)
+	authMux.Handle("/webhooks/", webhookHandler)
+	authMux.Handle("/todos/events", eventStreamHandler)
+	tokens := newTokenVerifier(cfg.Auth, logger)
+	authHandler, err := handlers.NewAuthHandler(users, tokens, authMux, logger)
 	if err != nil {
 		logger.WithFields(log.Fields{
 			constants.ErrorCode:   constants.UnableToCreateHTTPHandlerErrorCode,
@@ -87,31 +306,84 @@ func main() {
 	}
 
 	mux := http.NewServeMux()
-	mux.Handle("/todos", todoHandler) // Adding this route is necessary to support query parms like /todos?bulk=true
-	mux.Handle("/todos/", todoHandler)
+	mux.Handle("/", authHandler)
+	mux.Handle("/users", userHandler)
+	mux.Handle("/metrics", metrics.DefaultRegistry)
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		logger.WithFields(log.Fields{
 			constants.ServiceName: "health",
 		}).Info("handling request")
 		w.Write([]byte("I'm healthy!\n"))
 	})
+	livenessHandler, err := handlers.NewLivenessHandler(logger)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToCreateHTTPHandlerErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToCreateHTTPHandler)
+	}
+	mux.Handle("/livez", livenessHandler)
+	readinessChecks = append(readinessChecks, handlers.ReadinessCheck{Name: "bulkLauncher", Check: handlers.CheckBulkLauncher})
+	readinessHandler, err := handlers.NewReadinessHandler(readinessChecks, readinessTimeout, logger)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToCreateHTTPHandlerErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToCreateHTTPHandler)
+	}
+	mux.Handle("/readyz", readinessHandler)
+
+	// The event stream is long-lived so the server can't have a WriteTimeout. The other
+	// requests are limited to 'requestTimeout' instead. Streams end when
+	// ToDoPostDoneChan is closed so they don't hold up shutdown.
+	rootMux := http.NewServeMux()
+	rootMux.Handle("/todos/events", authHandler)
+	rootMux.Handle("/", http.TimeoutHandler(mux, requestTimeout, ""))
+	metricsHandler, err := handlers.NewMetricsHandler(rootMux, logger)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToCreateHTTPHandlerErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToCreateHTTPHandler)
+	}
+	tracingHandler, err := handlers.NewTracingHandler(metricsHandler, logger)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToCreateHTTPHandlerErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToCreateHTTPHandler)
+	}
+	requestIDHandler, err := handlers.NewRequestIDHandler(tracingHandler, logger)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToCreateHTTPHandlerErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToCreateHTTPHandler)
+	}
 
-	addr := ":" + strconv.Itoa(*port)
+	addr := ":" + strconv.Itoa(cfg.Port)
 	s := &http.Server{
 		Addr:              addr,
-		Handler:           mux,
+		Handler:           requestIDHandler,
 		ReadHeaderTimeout: 5 * time.Second,
-		WriteTimeout:      5 * time.Second,
 	}
 
 	go handlers.PostRequestLauncher(todoHandler, handlers.ToDoPostDoneChan, handlers.InsertToDoRqstChan, logger)
+	go dispatcher.Run(handlers.ToDoPostDoneChan)
+
+	if cfg.Reminders.Interval > 0 {
+		scheduler := newReminderScheduler(store, sentLog, cfg.Reminders.Interval, cfg.Reminders.Window, cfg.Reminders.Webhook, logger)
+		go scheduler.Run(handlers.ToDoPostDoneChan)
+	}
 
 	go func() {
 		logger.WithFields(log.Fields{
-			constants.Port:     addr,
-			constants.LogLevel: log.GetLevel().String(),
-			constants.DBHost:   *dbHost,
-			constants.DBPort:   *dbPort,
+			constants.Port:           addr,
+			constants.LogLevel:       log.GetLevel().String(),
+			constants.DBHost:         cfg.DB.Host,
+			constants.DBPort:         cfg.DB.Port,
+			constants.StoreType:      cfg.Store,
+			constants.ConfigFileName: *configFile,
 		}).Info("todod service starting")
 
 		if err := s.ListenAndServe(); err != http.ErrServerClosed {
@@ -120,17 +392,292 @@ func main() {
 		}
 	}()
 
-	handleTermSignal(s, logger, 10)
+	handleTermSignal(s, readinessHandler, cfg.ShutdownDelay, logger, 10)
+}
+
+// openDB returns a connection to the Postgres database described by the parameters, the
+// database has been verified to be reachable
+func openDB(host string, port int, user, password, name string, logger *log.Entry) *sql.DB {
+	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable", host, port, user, password, name)
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToOpenDBConnErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToOpenDBConn)
+	}
+	err = db.Ping()
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToOpenDBConnErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToOpenDBConn + ": database unreachable")
+	}
+	return db
+}
+
+// newMigrator returns a migrator for 'db's schema
+func newMigrator(db *sql.DB, logger *log.Entry) *migrate.Migrator {
+	m, err := migrate.NewMigrator(db)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.DBMigrationErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.DBMigrationError)
+	}
+	return m
+}
+
+// migrateSchema ensures 'db's schema is the version todod requires before the service
+// starts. If 'autoMigrate' is true pending migrations are applied, otherwise todod exits if
+// there are any. It always exits if the schema is newer than it understands. Rows without
+// an owner are then given to 'legacyOwner', see adoptOwnerless.
+func migrateSchema(db *sql.DB, autoMigrate bool, legacyOwner string, logger *log.Entry) {
+	m := newMigrator(db, logger)
+	ctx := context.Background()
+	version, err := m.Version(ctx)
+	if err == nil && (version > m.Latest() || !autoMigrate) {
+		err = m.CheckContext(ctx)
+	}
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:     constants.DBSchemaVersionErrorCode,
+			constants.SchemaVersion: version,
+			constants.ErrorDetail:   err.Error(),
+		}).Fatal(constants.DBSchemaVersionError)
+	}
+	if autoMigrate {
+		applied, err := m.Up(ctx)
+		logMigrations("applied schema migration", applied, logger)
+		if err != nil {
+			logger.WithFields(log.Fields{
+				constants.ErrorCode:   constants.DBMigrationErrorCode,
+				constants.ErrorDetail: err.Error(),
+			}).Fatal(constants.DBMigrationError)
+		}
+	}
+	adoptOwnerless(ctx, m, legacyOwner, logger)
+}
+
+// adoptOwnerless gives the todos, lists, and webhooks without an owner, those created
+// before todod had users, to 'legacyOwner'. If 'legacyOwner' is empty a warning is logged
+// if there are any, no user can see them.
+func adoptOwnerless(ctx context.Context, m *migrate.Migrator, legacyOwner string, logger *log.Entry) {
+	if len(legacyOwner) > 0 {
+		n, err := m.AdoptOwnerless(ctx, legacyOwner)
+		if err != nil {
+			logger.WithFields(log.Fields{
+				constants.ErrorCode:   constants.DBMigrationErrorCode,
+				constants.ErrorDetail: err.Error(),
+			}).Fatal(constants.DBMigrationError)
+		}
+		if n > 0 {
+			logger.WithFields(log.Fields{
+				constants.User: legacyOwner,
+			}).Infof("gave %d todos, lists, and webhooks without an owner to %s", n, legacyOwner)
+		}
+		return
+	}
+
+	n, err := m.Ownerless(ctx)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.DBQueryErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Warn(constants.DBQueryError)
+		return
+	}
+	if n > 0 {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode: constants.OwnerlessRowsErrorCode,
+			constants.ErrorDetail: fmt.Sprintf("%d todos, lists, and webhooks have no owner and can't be seen by any user, "+
+				"set db.legacyowner (-dblegacyowner) to give them to a user", n),
+		}).Warn(constants.OwnerlessRowsError)
+	}
+}
+
+// parseMigrateCmd returns the 'todod migrate' command in 'args' and, for 'down', the number
+// of migrations to revert. 'up' applies pending migrations, 'down [n|all]' reverts the 'n'
+// (1 if not provided) most recently applied migrations or all of them (0), and 'version'
+// reports the schema's version.
+func parseMigrateCmd(args []string, logger *log.Entry) (string, int) {
+	usage := func(detail string) {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToGetConfigErrorCode,
+			constants.ErrorDetail: detail + ", expected 'todod migrate [flags] up | down [n|all] | version'",
+		}).Fatal(constants.UnableToGetConfig)
+	}
+
+	switch {
+	case len(args) == 1 && (args[0] == "up" || args[0] == "down" || args[0] == "version"):
+		return args[0], 1
+	case len(args) == 2 && args[0] == "down" && args[1] == "all":
+		return args[0], 0
+	case len(args) == 2 && args[0] == "down":
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			usage(fmt.Sprintf("invalid number of migrations %q", args[1]))
+		}
+		return args[0], n
+	}
+	usage(fmt.Sprintf("invalid command %q", strings.Join(args, " ")))
+	return "", 0
+}
+
+// runMigrate runs the 'todod migrate' command 'cmd', see parseMigrateCmd, and reports the
+// resulting schema version. After 'up' rows without an owner are given to 'legacyOwner',
+// see adoptOwnerless.
+func runMigrate(db *sql.DB, cmd string, n int, legacyOwner string, logger *log.Entry) {
+	m := newMigrator(db, logger)
+	ctx := context.Background()
+	var (
+		migrated []migrate.Migration
+		err      error
+	)
+	switch cmd {
+	case "up":
+		migrated, err = m.Up(ctx)
+		logMigrations("applied schema migration", migrated, logger)
+		if err == nil {
+			adoptOwnerless(ctx, m, legacyOwner, logger)
+		}
+	case "down":
+		migrated, err = m.Down(ctx, n)
+		logMigrations("reverted schema migration", migrated, logger)
+	}
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.DBMigrationErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.DBMigrationError)
+	}
+
+	version, err := m.Version(ctx)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.DBMigrationErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.DBMigrationError)
+	}
+	logger.WithFields(log.Fields{
+		constants.SchemaVersion: version,
+	}).Infof("schema is at version %d, the latest version is %d", version, m.Latest())
+}
+
+// logMigrations logs 'msg' for each of 'migrations'
+func logMigrations(msg string, migrations []migrate.Migration, logger *log.Entry) {
+	for _, mig := range migrations {
+		logger.WithFields(log.Fields{
+			constants.SchemaVersion: mig.Version,
+			constants.MessageDetail: mig.Name,
+		}).Info(msg)
+	}
+}
+
+// newReminderScheduler returns a scheduler that logs reminders and, if 'webhook' is
+// provided, POSTs them to 'webhook'.
+func newReminderScheduler(store todo.Store, sentLog reminder.SentLog, interval time.Duration,
+	window time.Duration, webhook string, logger *log.Entry) *reminder.Scheduler {
+	var notifiers []reminder.Notifier
+	logNotifier, err := reminder.NewLogNotifier(logger)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToGetConfigErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToGetConfig)
+	}
+	notifiers = append(notifiers, logNotifier)
+
+	if len(webhook) > 0 {
+		webhookNotifier, err := reminder.NewWebhookNotifier(webhook, nil)
+		if err != nil {
+			logger.WithFields(log.Fields{
+				constants.ErrorCode:   constants.UnableToGetConfigErrorCode,
+				constants.ErrorDetail: err.Error(),
+			}).Fatal(constants.UnableToGetConfig)
+		}
+		notifiers = append(notifiers, webhookNotifier)
+	}
+
+	scheduler, err := reminder.NewScheduler(store, sentLog, notifiers, interval, window, logger)
+	if err != nil {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.UnableToGetConfigErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Fatal(constants.UnableToGetConfig)
+	}
+	return scheduler
+}
+
+// newTokenVerifier returns a verifier for the JWTs signed by the keys in 'cfg.JWTKeyFile' and
+// 'cfg.JWKSFile' and the API keys in 'cfg.APIKeysFile'. It returns nil if no files are provided,
+// i.e., bearer tokens aren't accepted.
+func newTokenVerifier(cfg config.Auth, logger *log.Entry) auth.TokenVerifier {
+	fatal := func(file string, err error) {
+		logger.WithFields(log.Fields{
+			constants.ErrorCode:      constants.UnableToLoadSecretsErrorCode,
+			constants.ConfigFileName: file,
+			constants.ErrorDetail:    err.Error(),
+		}).Fatal(constants.UnableToLoadSecrets)
+	}
+
+	var (
+		verifier auth.BearerVerifier
+		keys     []auth.Key
+	)
+	if len(cfg.JWTKeyFile) > 0 {
+		k, err := auth.LoadKeyFile(cfg.JWTKeyFile)
+		if err != nil {
+			fatal(cfg.JWTKeyFile, err)
+		}
+		keys = append(keys, k)
+	}
+	if len(cfg.JWKSFile) > 0 {
+		ks, err := auth.LoadJWKSFile(cfg.JWKSFile)
+		if err != nil {
+			fatal(cfg.JWKSFile, err)
+		}
+		keys = append(keys, ks...)
+	}
+	if len(keys) > 0 {
+		jwtVerifier, err := auth.NewJWTVerifier(keys, cfg.JWTIssuer, cfg.JWTAudience)
+		if err != nil {
+			fatal(cfg.JWTKeyFile+" "+cfg.JWKSFile, err)
+		}
+		verifier.JWT = jwtVerifier
+	}
+	if len(cfg.APIKeysFile) > 0 {
+		apiKeys, err := auth.LoadAPIKeysFile(cfg.APIKeysFile)
+		if err != nil {
+			fatal(cfg.APIKeysFile, err)
+		}
+		verifier.APIKeys = apiKeys
+	}
+
+	if verifier.JWT == nil && verifier.APIKeys == nil {
+		return nil
+	}
+	return verifier
 }
 
 // handleTermSignal provides a mechanism to catch SIGTERMs and gracefully
-// shutdown the service.
-func handleTermSignal(s *http.Server, logger *log.Entry, timeout int) {
+// shutdown the service. 'readiness' reports that todod isn't ready for 'delay' before
+// connections are drained, giving load balancers time to stop sending it requests.
+func handleTermSignal(s *http.Server, readiness *handlers.ReadinessHandler, delay time.Duration, logger *log.Entry, timeout int) {
 	sigs := make(chan os.Signal, 1)
 	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
 
 	<-sigs
 
+	readiness.ShutDown()
+	if delay > 0 {
+		logger.Infof("Server reporting not ready for %s before shutting down", delay)
+		time.Sleep(delay)
+	}
+
+	// Stop the background goroutines, and end event streams, before draining connections.
+	// Bulk POSTs in progress stop waiting for their inserts when this is closed, so they
+	// don't hold up shutdown either.
 	close(handlers.ToDoPostDoneChan)
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
 	defer cancel()
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// MinAPIKeyLength is the minimum length of a static API key
+const MinAPIKeyLength = 32
+
+// APIKeys is a TokenVerifier for static API keys. It maps the SHA-256 hash of each key to
+// the service account it identifies, the keys themselves aren't kept.
+type APIKeys map[[sha256.Size]byte]string
+
+// LoadAPIKeysFile returns the API keys in the file at 'path', see ParseAPIKeys
+func LoadAPIKeysFile(path string) (APIKeys, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Annotatef(err, "error reading API keys file %s", path)
+	}
+	return ParseAPIKeys(data)
+}
+
+// ParseAPIKeys returns the API keys in 'data'. Each line contains a service account name
+// and one of its keys separated by whitespace, e.g., "reporting 6f1c...". Blank lines and
+// lines starting with '#' are ignored. An account can have more than one key, so keys can
+// be rotated, but a key can't belong to more than one account.
+func ParseAPIKeys(data []byte) (APIKeys, error) {
+	keys := APIKeys{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, errors.Errorf("line %d: expected '<account> <key>'", lineNum)
+		}
+		account, key := fields[0], fields[1]
+		if len(key) < MinAPIKeyLength || strings.Contains(key, ".") {
+			return nil, errors.Errorf("line %d: key must be at least %d characters and not contain '.'",
+				lineNum, MinAPIKeyLength)
+		}
+		hash := sha256.Sum256([]byte(key))
+		if _, ok := keys[hash]; ok {
+			return nil, errors.Errorf("line %d: duplicate key", lineNum)
+		}
+		keys[hash] = account
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Annotate(err, "error reading API keys")
+	}
+	return keys, nil
+}
+
+// Verify returns the service account identified by 'token'. Keys are looked up by their
+// hash so the lookup doesn't reveal how much of a key was guessed correctly.
+func (k APIKeys) Verify(token string) (string, error) {
+	account, ok := k[sha256.Sum256([]byte(token))]
+	if !ok {
+		return "", errors.New("unknown API key")
+	}
+	return account, nil
+}
@@ -0,0 +1,62 @@
+// Package auth verifies the bearer tokens, JWTs and static API keys, that identify who is
+// making a request.
+package auth
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+)
+
+// The principals identified by bearer tokens are prefixed by the kind of token so they
+// can't be confused with users, or each other. Usernames never contain ':' so a user can't
+// register the name of a JWT subject or service account and act as them.
+const (
+	// JWTPrincipalPrefix prefixes the subject of a JWT, e.g., 'jwt:ryoungkin'
+	JWTPrincipalPrefix = "jwt:"
+	// APIKeyPrincipalPrefix prefixes the service account of an API key, e.g., 'svc:reporting'
+	APIKeyPrincipalPrefix = "svc:"
+)
+
+// TokenVerifier verifies bearer tokens
+type TokenVerifier interface {
+	// Verify returns the principal, the name of the user or service account, identified
+	// by 'token'. An error is returned if 'token' is invalid.
+	Verify(token string) (string, error)
+}
+
+// BearerVerifier is a TokenVerifier that verifies JWTs with 'JWT' and looks up any other
+// token in 'APIKeys'. Either may be nil if that kind of token isn't accepted.
+type BearerVerifier struct {
+	JWT     *JWTVerifier
+	APIKeys APIKeys
+}
+
+// Verify returns the principal identified by 'token', a JWT's subject prefixed by
+// JWTPrincipalPrefix or an API key's service account prefixed by APIKeyPrincipalPrefix
+func (v BearerVerifier) Verify(token string) (string, error) {
+	if isJWT(token) {
+		if v.JWT == nil {
+			return "", errors.New("JWTs aren't accepted")
+		}
+		subject, err := v.JWT.Verify(token)
+		if err != nil {
+			return "", err
+		}
+		return JWTPrincipalPrefix + subject, nil
+	}
+	if v.APIKeys == nil {
+		return "", errors.New("API keys aren't accepted")
+	}
+	account, err := v.APIKeys.Verify(token)
+	if err != nil {
+		return "", err
+	}
+	return APIKeyPrincipalPrefix + account, nil
+}
+
+// isJWT returns true if 'token' is shaped like a JWT, three base64url encoded segments
+// separated by '.'. API keys never contain a '.'.
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
@@ -0,0 +1,334 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	testSecret = []byte("0123456789abcdef0123456789abcdef")
+	testNow    = time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+)
+
+// signToken returns a JWT with 'hdr' and 'claims' signed by 'key', an HMAC secret ([]byte)
+// or an *rsa.PrivateKey
+func signToken(t *testing.T, hdr map[string]interface{}, claims map[string]interface{}, key interface{}) string {
+	encode := func(v interface{}) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected encoding %v", err, v)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	signed := encode(hdr) + "." + encode(claims)
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(signed))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signed))
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected signing a token", err)
+		}
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected generating an RSA key", err)
+	}
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected generating an RSA key", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected marshaling an RSA key", err)
+	}
+	pubPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER})
+
+	exp := float64(testNow.Add(time.Hour).Unix())
+	hs256 := map[string]interface{}{"alg": HS256, "typ": "JWT"}
+	rs256 := map[string]interface{}{"alg": RS256, "typ": "JWT", "kid": "rsa1"}
+
+	tcs := []struct {
+		testName        string
+		token           string
+		issuer          string
+		audience        string
+		expectedSubject string
+		shouldError     bool
+	}{
+		{
+			testName:        "testHS256",
+			token:           signToken(t, hs256, map[string]interface{}{"sub": "ryoungkin", "exp": exp}, testSecret),
+			expectedSubject: "ryoungkin",
+		},
+		{
+			testName: "testRS256",
+			token: signToken(t, rs256,
+				map[string]interface{}{"sub": "ryoungkin", "exp": exp, "iss": "https://idp.example.com", "aud": []string{"other", "todod"}},
+				rsaKey),
+			issuer:          "https://idp.example.com",
+			audience:        "todod",
+			expectedSubject: "ryoungkin",
+		},
+		{
+			testName:    "testWrongSecret",
+			token:       signToken(t, hs256, map[string]interface{}{"sub": "ryoungkin", "exp": exp}, []byte("fedcba9876543210fedcba9876543210")),
+			shouldError: true,
+		},
+		{
+			testName:    "testWrongRSAKey",
+			token:       signToken(t, rs256, map[string]interface{}{"sub": "ryoungkin", "exp": exp}, otherRSAKey),
+			shouldError: true,
+		},
+		{
+			testName:    "testUnknownKid",
+			token:       signToken(t, map[string]interface{}{"alg": RS256, "kid": "rsa2"}, map[string]interface{}{"sub": "ryoungkin", "exp": exp}, rsaKey),
+			shouldError: true,
+		},
+		{
+			// The RSA public key must not be usable as an HMAC secret
+			testName:    "testAlgorithmConfusion",
+			token:       signToken(t, hs256, map[string]interface{}{"sub": "ryoungkin", "exp": exp}, pubPEM),
+			shouldError: true,
+		},
+		{
+			testName:    "testAlgNone",
+			token:       strings.Join(strings.Split(signToken(t, map[string]interface{}{"alg": "none"}, map[string]interface{}{"sub": "ryoungkin", "exp": exp}, testSecret), ".")[:2], ".") + ".",
+			shouldError: true,
+		},
+		{
+			testName:    "testExpired",
+			token:       signToken(t, hs256, map[string]interface{}{"sub": "ryoungkin", "exp": testNow.Add(-2 * time.Minute).Unix()}, testSecret),
+			shouldError: true,
+		},
+		{
+			testName:        "testExpiredWithinClockSkew",
+			token:           signToken(t, hs256, map[string]interface{}{"sub": "ryoungkin", "exp": testNow.Add(-30 * time.Second).Unix()}, testSecret),
+			expectedSubject: "ryoungkin",
+		},
+		{
+			testName:    "testNoExpiration",
+			token:       signToken(t, hs256, map[string]interface{}{"sub": "ryoungkin"}, testSecret),
+			shouldError: true,
+		},
+		{
+			testName:    "testNotYetValid",
+			token:       signToken(t, hs256, map[string]interface{}{"sub": "ryoungkin", "exp": exp, "nbf": testNow.Add(10 * time.Minute).Unix()}, testSecret),
+			shouldError: true,
+		},
+		{
+			testName:    "testNoSubject",
+			token:       signToken(t, hs256, map[string]interface{}{"exp": exp}, testSecret),
+			shouldError: true,
+		},
+		{
+			testName:    "testUntrustedIssuer",
+			token:       signToken(t, rs256, map[string]interface{}{"sub": "ryoungkin", "exp": exp, "iss": "https://evil.example.com", "aud": "todod"}, rsaKey),
+			issuer:      "https://idp.example.com",
+			audience:    "todod",
+			shouldError: true,
+		},
+		{
+			testName:    "testWrongAudience",
+			token:       signToken(t, rs256, map[string]interface{}{"sub": "ryoungkin", "exp": exp, "iss": "https://idp.example.com", "aud": "other"}, rsaKey),
+			issuer:      "https://idp.example.com",
+			audience:    "todod",
+			shouldError: true,
+		},
+		{
+			testName:    "testMalformed",
+			token:       "not.a.jwt",
+			shouldError: true,
+		},
+	}
+
+	pemKey, err := ParseKey(pubPEM)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected parsing the PEM key", err)
+	}
+	pemKey.ID = "rsa1"
+	secretKey, err := ParseKey(append(testSecret, '\n'))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected parsing the HMAC secret", err)
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			v, err := NewJWTVerifier([]Key{secretKey, pemKey}, tc.issuer, tc.audience)
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected creating a JWTVerifier", err)
+			}
+			v.now = func() time.Time { return testNow }
+
+			subject, err := v.Verify(tc.token)
+			if (err != nil) != tc.shouldError {
+				t.Fatalf("expected error %t, got %v", tc.shouldError, err)
+			}
+			if subject != tc.expectedSubject {
+				t.Errorf("expected subject %q, got %q", tc.expectedSubject, subject)
+			}
+		})
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	_, err := ParseKey([]byte("too short"))
+	if err == nil {
+		t.Error("expected an error for a short HMAC secret")
+	}
+	_, err = NewJWTVerifier(nil, "", "")
+	if err == nil {
+		t.Error("expected an error creating a JWTVerifier without keys")
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected generating an RSA key", err)
+	}
+	n := base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())
+	k := base64.RawURLEncoding.EncodeToString(testSecret)
+
+	tcs := []struct {
+		testName     string
+		jwks         string
+		expectedKeys int
+		shouldError  bool
+	}{
+		{
+			testName:     "testRSAAndOct",
+			jwks:         fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"rsa1","use":"sig","alg":"RS256","n":%q,"e":%q},{"kty":"oct","kid":"hmac1","k":%q}]}`, n, e, k),
+			expectedKeys: 2,
+		},
+		{
+			testName:     "testSkipsUnsupportedKeys",
+			jwks:         fmt.Sprintf(`{"keys":[{"kty":"EC","kid":"ec1"},{"kty":"RSA","kid":"rsa1","use":"enc","n":%q,"e":%q},{"kty":"RSA","kid":"rsa2","n":%q,"e":%q}]}`, n, e, n, e),
+			expectedKeys: 1,
+		},
+		{
+			testName:    "testNoUsableKeys",
+			jwks:        `{"keys":[{"kty":"EC","kid":"ec1"}]}`,
+			shouldError: true,
+		},
+		{
+			testName:    "testShortSecret",
+			jwks:        `{"keys":[{"kty":"oct","kid":"hmac1","k":"c2hvcnQ"}]}`,
+			shouldError: true,
+		},
+		{
+			testName:    "testInvalidJSON",
+			jwks:        `{"keys":`,
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			keys, err := ParseJWKS([]byte(tc.jwks))
+			if (err != nil) != tc.shouldError {
+				t.Fatalf("expected error %t, got %v", tc.shouldError, err)
+			}
+			if len(keys) != tc.expectedKeys {
+				t.Errorf("expected %d keys, got %d", tc.expectedKeys, len(keys))
+			}
+		})
+	}
+
+	// A token signed by the RSA key verifies with the key from the JWKS
+	keys, err := ParseJWKS([]byte(fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"rsa1","n":%q,"e":%q}]}`, n, e)))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected parsing the JWKS", err)
+	}
+	v, err := NewJWTVerifier(keys, "", "")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected creating a JWTVerifier", err)
+	}
+	token := signToken(t, map[string]interface{}{"alg": RS256, "kid": "rsa1"},
+		map[string]interface{}{"sub": "ryoungkin", "exp": time.Now().Add(time.Hour).Unix()}, rsaKey)
+	subject, err := v.Verify(token)
+	if err != nil || subject != "ryoungkin" {
+		t.Errorf("expected subject ryoungkin, got %q, %v", subject, err)
+	}
+	principal, err := BearerVerifier{JWT: v}.Verify(token)
+	if err != nil || principal != JWTPrincipalPrefix+"ryoungkin" {
+		t.Errorf("expected principal %sryoungkin, got %q, %v", JWTPrincipalPrefix, principal, err)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	keys, err := ParseAPIKeys([]byte(`
+# service accounts
+reporting  reporting-key-0123456789abcdef0123
+reporting  reporting-key-rotated-0123456789abc
+backup     backup-key-0123456789abcdef0123456
+`))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected parsing API keys", err)
+	}
+
+	tcs := []struct {
+		testName        string
+		token           string
+		expectedAccount string
+		shouldError     bool
+	}{
+		{testName: "testKey", token: "reporting-key-0123456789abcdef0123", expectedAccount: "svc:reporting"},
+		{testName: "testRotatedKey", token: "reporting-key-rotated-0123456789abc", expectedAccount: "svc:reporting"},
+		{testName: "testOtherAccount", token: "backup-key-0123456789abcdef0123456", expectedAccount: "svc:backup"},
+		{testName: "testUnknownKey", token: "unknown-key-0123456789abcdef012345", shouldError: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			account, err := BearerVerifier{APIKeys: keys}.Verify(tc.token)
+			if (err != nil) != tc.shouldError {
+				t.Fatalf("expected error %t, got %v", tc.shouldError, err)
+			}
+			if account != tc.expectedAccount {
+				t.Errorf("expected account %q, got %q", tc.expectedAccount, account)
+			}
+		})
+	}
+
+	invalid := []string{
+		"reporting",
+		"reporting short-key",
+		"reporting reporting.key.0123456789abcdef0123",
+		"a reporting-key-0123456789abcdef0123\nb reporting-key-0123456789abcdef0123",
+	}
+	for _, data := range invalid {
+		_, err := ParseAPIKeys([]byte(data))
+		if err == nil {
+			t.Errorf("expected an error parsing %q", data)
+		}
+	}
+
+	_, err = BearerVerifier{APIKeys: keys}.Verify("a.b.c")
+	if err == nil {
+		t.Error("expected an error verifying a JWT without a JWTVerifier")
+	}
+}
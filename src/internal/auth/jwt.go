@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+const (
+	// HS256 is the JWT algorithm for HMAC-SHA256 signatures
+	HS256 = "HS256"
+	// RS256 is the JWT algorithm for RSASSA-PKCS1-v1_5 SHA-256 signatures
+	RS256 = "RS256"
+)
+
+// MinHMACSecretLength is the minimum length, in bytes, of an HS256 secret. RFC 7518 requires
+// the key to be at least as long as the hash.
+const MinHMACSecretLength = 32
+
+// clockSkew is how far the verifier's clock is allowed to differ from the token issuer's
+// when checking 'exp' and 'nbf'
+const clockSkew = time.Minute
+
+// Key verifies JWT signatures. Exactly one of Secret, which verifies HS256 signatures, or
+// RSA, which verifies RS256 signatures, is set.
+type Key struct {
+	// ID is the key's 'kid', if it has one. A token with a 'kid' is only verified by the
+	// key with the same ID, or by keys without an ID.
+	ID     string
+	Secret []byte
+	RSA    *rsa.PublicKey
+}
+
+// alg returns the algorithm 'k' verifies. It's determined by the key, not the token, so an
+// RSA public key can never be used as an HMAC secret.
+func (k Key) alg() string {
+	if k.RSA != nil {
+		return RS256
+	}
+	return HS256
+}
+
+// verify returns true if 'sig' is a valid signature of 'signed'
+func (k Key) verify(signed, sig []byte) bool {
+	if k.RSA != nil {
+		digest := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(k.RSA, crypto.SHA256, digest[:], sig) == nil
+	}
+	mac := hmac.New(sha256.New, k.Secret)
+	mac.Write(signed)
+	return hmac.Equal(sig, mac.Sum(nil))
+}
+
+// validate returns an error describing what's wrong with 'k', if anything
+func (k Key) validate() error {
+	switch {
+	case k.RSA != nil && len(k.Secret) > 0:
+		return errors.Errorf("key %q has both an RSA public key and an HMAC secret", k.ID)
+	case k.RSA != nil:
+		return nil
+	case len(k.Secret) < MinHMACSecretLength:
+		return errors.Errorf("key %q HMAC secret must be at least %d bytes", k.ID, MinHMACSecretLength)
+	}
+	return nil
+}
+
+// JWTVerifier verifies HS256 and RS256 signed JWTs. A token is valid if it's signed by one
+// of the verifier's keys, has a subject ('sub'), hasn't expired ('exp' is required), is
+// valid now ('nbf'), and, if the verifier has them, has the expected issuer ('iss') and
+// audience ('aud').
+type JWTVerifier struct {
+	keys     []Key
+	issuer   string
+	audience string
+	now      func() time.Time
+}
+
+// NewJWTVerifier returns a *JWTVerifier that accepts tokens signed by 'keys'. 'issuer' and
+// 'audience' are optional, if they're provided tokens must contain them.
+func NewJWTVerifier(keys []Key, issuer, audience string) (*JWTVerifier, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one key required")
+	}
+	for _, k := range keys {
+		err := k.validate()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &JWTVerifier{keys: keys, issuer: issuer, audience: audience, now: time.Now}, nil
+}
+
+// header is the JOSE header of a JWT
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// claims are the registered JWT claims the verifier checks
+type claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+}
+
+// audience is the 'aud' claim, it's either a string or an array of strings
+type audience []string
+
+// UnmarshalJSON decodes either form of 'aud'
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		*a = audience{s}
+		return nil
+	}
+	var ss []string
+	err := json.Unmarshal(data, &ss)
+	if err != nil {
+		return errors.New("'aud' must be a string or an array of strings")
+	}
+	*a = ss
+	return nil
+}
+
+// contains returns true if 'aud' is one of the audiences in 'a'
+func (a audience) contains(aud string) bool {
+	for _, s := range a {
+		if s == aud {
+			return true
+		}
+	}
+	return false
+}
+
+// Verify returns the subject of 'token' if it's valid
+func (v *JWTVerifier) Verify(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed JWT, expected 3 segments")
+	}
+
+	h := header{}
+	err := decodeSegment(parts[0], &h)
+	if err != nil {
+		return "", errors.Annotate(err, "malformed JWT header")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.Annotate(err, "malformed JWT signature")
+	}
+	err = v.verifySignature(h, []byte(parts[0]+"."+parts[1]), sig)
+	if err != nil {
+		return "", err
+	}
+
+	c := claims{}
+	err = decodeSegment(parts[1], &c)
+	if err != nil {
+		return "", errors.Annotate(err, "malformed JWT claims")
+	}
+	err = v.validateClaims(c)
+	if err != nil {
+		return "", err
+	}
+	return c.Subject, nil
+}
+
+// verifySignature returns an error unless 'sig' is a valid signature of 'signed' by one of
+// the verifier's keys for the algorithm in 'h'
+func (v *JWTVerifier) verifySignature(h header, signed, sig []byte) error {
+	if h.Alg != HS256 && h.Alg != RS256 {
+		return errors.Errorf("unsupported JWT algorithm %q, expected %s or %s", h.Alg, HS256, RS256)
+	}
+	for _, k := range v.keys {
+		if k.alg() != h.Alg {
+			continue
+		}
+		if len(h.Kid) > 0 && len(k.ID) > 0 && h.Kid != k.ID {
+			continue
+		}
+		if k.verify(signed, sig) {
+			return nil
+		}
+	}
+	return errors.New("invalid JWT signature")
+}
+
+// validateClaims returns an error describing why 'c' isn't acceptable, if it isn't
+func (v *JWTVerifier) validateClaims(c claims) error {
+	now := v.now()
+	if len(c.Subject) == 0 {
+		return errors.New("JWT has no subject ('sub')")
+	}
+	if c.ExpiresAt == nil {
+		return errors.New("JWT has no expiration time ('exp')")
+	}
+	if now.Add(-clockSkew).After(numericDate(*c.ExpiresAt)) {
+		return errors.New("JWT has expired")
+	}
+	if c.NotBefore != nil && now.Add(clockSkew).Before(numericDate(*c.NotBefore)) {
+		return errors.New("JWT isn't valid yet")
+	}
+	if len(v.issuer) > 0 && c.Issuer != v.issuer {
+		return errors.Errorf("JWT issuer %q isn't trusted", c.Issuer)
+	}
+	if len(v.audience) > 0 && !c.Audience.contains(v.audience) {
+		return errors.Errorf("JWT isn't intended for audience %q", v.audience)
+	}
+	return nil
+}
+
+// decodeSegment decodes the base64url encoded JSON in 'seg' into 'v'
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// numericDate converts a JWT NumericDate, seconds since the epoch, to a time.Time
+func numericDate(secs float64) time.Time {
+	return time.Unix(0, int64(secs*float64(time.Second)))
+}
@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+
+	"github.com/juju/errors"
+)
+
+// LoadKeyFile returns the key in the file at 'path', see ParseKey
+func LoadKeyFile(path string) (Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Key{}, errors.Annotatef(err, "error reading key file %s", path)
+	}
+	return ParseKey(data)
+}
+
+// ParseKey returns the key in 'data'. A PEM encoded RSA public key ("PUBLIC KEY" or "RSA
+// PUBLIC KEY"), or a certificate containing one, verifies RS256 JWTs. Anything else is
+// the secret that verifies HS256 JWTs, trailing whitespace excluded.
+func ParseKey(data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		k := Key{Secret: bytes.TrimRight(data, " \t\r\n")}
+		return k, k.validate()
+	}
+
+	var (
+		pub interface{}
+		err error
+	)
+	switch block.Type {
+	case "PUBLIC KEY":
+		pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		pub, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		var cert *x509.Certificate
+		cert, err = x509.ParseCertificate(block.Bytes)
+		if err == nil {
+			pub = cert.PublicKey
+		}
+	default:
+		return Key{}, errors.Errorf("unsupported PEM block %q, expected a public key or certificate", block.Type)
+	}
+	if err != nil {
+		return Key{}, errors.Annotate(err, "error parsing PEM key")
+	}
+	rsaKey, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return Key{}, errors.Errorf("unsupported public key type %T, expected an RSA key", pub)
+	}
+	return Key{RSA: rsaKey}, nil
+}
+
+// LoadJWKSFile returns the keys in the JSON Web Key Set file at 'path', see ParseJWKS
+func LoadJWKSFile(path string) ([]Key, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Annotatef(err, "error reading JWKS file %s", path)
+	}
+	return ParseJWKS(data)
+}
+
+// jwk is a JSON Web Key (RFC 7517), only the members used by RSA and symmetric keys are
+// decoded
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+// ParseJWKS returns the signature verification keys in the JSON Web Key Set 'data'. RSA
+// ("kty":"RSA") and symmetric ("kty":"oct") keys are supported. Keys of other types, for
+// encryption ("use":"enc"), or for algorithms other than RS256 and HS256 are skipped. It's
+// an error if no keys are left.
+func ParseJWKS(data []byte) ([]Key, error) {
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	err := json.Unmarshal(data, &set)
+	if err != nil {
+		return nil, errors.Annotate(err, "error decoding JWKS")
+	}
+
+	var keys []Key
+	for _, j := range set.Keys {
+		if j.Use == "enc" {
+			continue
+		}
+		var k Key
+		switch {
+		case j.Kty == "RSA" && (len(j.Alg) == 0 || j.Alg == RS256):
+			k, err = parseRSAJWK(j)
+		case j.Kty == "oct" && (len(j.Alg) == 0 || j.Alg == HS256):
+			k, err = parseOctJWK(j)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, errors.Annotatef(err, "invalid key %q", j.Kid)
+		}
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("JWKS contains no RS256 or HS256 signature keys")
+	}
+	return keys, nil
+}
+
+// parseRSAJWK returns the RSA public key described by 'j'
+func parseRSAJWK(j jwk) (Key, error) {
+	n, err := base64.RawURLEncoding.DecodeString(j.N)
+	if err != nil || len(n) == 0 {
+		return Key{}, errors.New("invalid modulus ('n')")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(j.E)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return Key{}, errors.New("invalid exponent ('e')")
+	}
+	exp := 0
+	for _, b := range e {
+		exp = exp<<8 | int(b)
+	}
+	return Key{ID: j.Kid, RSA: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exp}}, nil
+}
+
+// parseOctJWK returns the HMAC secret described by 'j'
+func parseOctJWK(j jwk) (Key, error) {
+	secret, err := base64.RawURLEncoding.DecodeString(j.K)
+	if err != nil {
+		return Key{}, errors.New("invalid key value ('k')")
+	}
+	k := Key{ID: j.Kid, Secret: secret}
+	return k, k.validate()
+}
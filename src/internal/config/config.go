@@ -0,0 +1,334 @@
+// Package config loads todod's configuration. Settings come from, in increasing order of
+// precedence, their defaults, a YAML configuration file, TODOD_* environment variables, and
+// command line flags. Secrets, like the DB password, can be read from files, e.g., those
+// mounted from Kubernetes secrets, so they don't have to appear in the configuration.
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/stream"
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix prefixes the names of the environment variables that override settings. A
+// setting's variable is named for its path in the configuration file, e.g., 'db.host' is
+// overridden by TODOD_DB_HOST.
+const EnvPrefix = "TODOD_"
+
+// Config is todod's configuration. The 'yaml' tags name the settings in the configuration
+// file, the 'flag' tags name the command line flags that override them, if any.
+type Config struct {
+	LogLevel      int           `yaml:"loglevel" flag:"loglevel"`
+	Port          int           `yaml:"port" flag:"port"`
+	Store         string        `yaml:"store" flag:"store"`
+	AutoMigrate   bool          `yaml:"automigrate" flag:"automigrate"`
+	EventLogSize  int           `yaml:"eventlogsize" flag:"eventlogsize"`
+	ShutdownDelay time.Duration `yaml:"shutdowndelay" flag:"shutdowndelay"`
+	DB            DB            `yaml:"db"`
+	Reminders     Reminders     `yaml:"reminders"`
+	Webhooks      Webhooks      `yaml:"webhooks"`
+	Auth          Auth          `yaml:"auth"`
+	Tracing       Tracing       `yaml:"tracing"`
+}
+
+// DB is the configuration of the Postgres database. The password can't be set by a flag,
+// command lines are visible to other processes. Timeout limits how long each call to the
+// todo, user, webhook, and reminder stores waits for the database, 0 means it waits as long
+// as the request does. Schema migrations aren't limited. LegacyOwner, if provided, is given
+// the todos, lists, and webhooks created before todod had users, they have no owner.
+type DB struct {
+	Host         string        `yaml:"host" flag:"dbhost"`
+	Port         int           `yaml:"port" flag:"dbport"`
+	User         string        `yaml:"user" flag:"dbuser"`
+	Name         string        `yaml:"name" flag:"dbname"`
+	Password     string        `yaml:"password"`
+	PasswordFile string        `yaml:"passwordfile" flag:"dbpasswordfile"`
+	Timeout      time.Duration `yaml:"timeout" flag:"dbtimeout"`
+	LegacyOwner  string        `yaml:"legacyowner" flag:"dblegacyowner"`
+}
+
+// Reminders is the configuration of reminders about overdue, and soon to be due, todos
+type Reminders struct {
+	Interval time.Duration `yaml:"interval" flag:"reminderinterval"`
+	Window   time.Duration `yaml:"window" flag:"reminderwindow"`
+	Webhook  string        `yaml:"webhook" flag:"reminderwebhook"`
+}
+
+// Webhooks is the configuration of webhook deliveries. Webhooks aren't delivered to
+// internal, e.g., loopback or private, addresses unless their host is in AllowedHosts, a
+// comma separated list of host names, IP addresses, and CIDRs.
+type Webhooks struct {
+	AllowedHosts string `yaml:"allowedhosts" flag:"webhookallowedhosts"`
+}
+
+// Auth is the configuration of bearer token authentication, it's disabled if no key files
+// are provided
+type Auth struct {
+	JWTKeyFile  string `yaml:"jwtkeyfile" flag:"jwtkeyfile"`
+	JWKSFile    string `yaml:"jwksfile" flag:"jwksfile"`
+	JWTIssuer   string `yaml:"jwtissuer" flag:"jwtissuer"`
+	JWTAudience string `yaml:"jwtaudience" flag:"jwtaudience"`
+	APIKeysFile string `yaml:"apikeysfile" flag:"apikeysfile"`
+}
+
+// Tracing is the configuration of OpenTelemetry tracing. Exporter is 'none', 'stdout',
+// 'file', which writes spans as JSON to File, or 'otlp', which sends them to the OTLP/HTTP
+// Endpoint of a collector. SampleRatio is the fraction of traces started by todod that are
+// recorded, traces started by a caller are recorded if the caller's are.
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" flag:"traceexporter"`
+	File        string  `yaml:"file" flag:"tracefile"`
+	Endpoint    string  `yaml:"endpoint" flag:"traceendpoint"`
+	SampleRatio float64 `yaml:"sampleratio" flag:"tracesampleratio"`
+}
+
+// Default returns the configuration used for settings that aren't provided
+func Default() Config {
+	return Config{
+		LogLevel:      4,
+		Port:          8080,
+		Store:         "postgres",
+		AutoMigrate:   true,
+		EventLogSize:  stream.DefaultLogSize,
+		ShutdownDelay: 5 * time.Second,
+		DB: DB{
+			Host:    "localhost",
+			Port:    5432,
+			User:    "todo",
+			Name:    "todo",
+			Timeout: 5 * time.Second,
+		},
+		Reminders: Reminders{
+			Interval: time.Minute,
+			Window:   time.Hour,
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			Endpoint:    "http://localhost:4318/v1/traces",
+			SampleRatio: 1,
+		},
+	}
+}
+
+// Load returns the configuration in the YAML file at 'path', if it's provided, overridden by
+// the environment variables returned by 'lookupEnv' and by 'flags', which maps flag names to
+// their values. Kubernetes' POSTGRES_SERVICE_HOST and POSTGRES_SERVICE_PORT variables are
+// also used for the DB's host and port, TODOD_DB_HOST and TODOD_DB_PORT take precedence.
+// The returned ErrCode identifies which step failed, if one did.
+func Load(path string, lookupEnv func(string) (string, bool), flags map[string]string) (Config, constants.ErrCode, error) {
+	c := Default()
+
+	if len(path) > 0 {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return Config{}, constants.UnableToOpenConfigErrorCode, errors.Annotatef(err, "error reading %s", path)
+		}
+		err = yaml.UnmarshalStrict(data, &c)
+		if err != nil {
+			return Config{}, constants.UnableToLoadConfigErrorCode, errors.Annotatef(err, "error parsing %s", path)
+		}
+	}
+
+	err := applyEnv(&c, lookupEnv)
+	if err != nil {
+		return Config{}, constants.UnableToLoadConfigErrorCode, errors.Trace(err)
+	}
+	err = applyFlags(&c, flags)
+	if err != nil {
+		return Config{}, constants.UnableToLoadConfigErrorCode, errors.Trace(err)
+	}
+
+	if len(c.DB.PasswordFile) > 0 {
+		if len(c.DB.Password) > 0 {
+			return Config{}, constants.UnableToLoadConfigErrorCode,
+				errors.New("only one of db.password and db.passwordfile can be provided")
+		}
+		data, err := ioutil.ReadFile(c.DB.PasswordFile)
+		if err != nil {
+			return Config{}, constants.UnableToLoadSecretsErrorCode,
+				errors.Annotatef(err, "error reading DB password file %s", c.DB.PasswordFile)
+		}
+		c.DB.Password = string(bytes.TrimRight(data, "\r\n"))
+	}
+
+	err = Validate(c)
+	if err != nil {
+		return Config{}, constants.UnableToLoadConfigErrorCode, errors.Trace(err)
+	}
+	return c, constants.NoErrorCode, nil
+}
+
+// Validate returns an error describing what's wrong with 'c', if anything
+func Validate(c Config) error {
+	var problems []string
+	if c.LogLevel < 0 || c.LogLevel > 6 {
+		problems = append(problems, "loglevel must be between 0 (PANIC) and 6 (TRACE)")
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		problems = append(problems, "port must be between 1 and 65535")
+	}
+	if c.EventLogSize < 1 {
+		problems = append(problems, "eventlogsize must be greater than 0")
+	}
+	if c.ShutdownDelay < 0 {
+		problems = append(problems, "shutdowndelay can't be negative")
+	}
+	switch c.Store {
+	case "memory":
+	case "postgres":
+		if len(c.DB.Host) == 0 || len(c.DB.User) == 0 || len(c.DB.Name) == 0 {
+			problems = append(problems, "db.host, db.user, and db.name must be provided for the 'postgres' store")
+		}
+		if c.DB.Port < 1 || c.DB.Port > 65535 {
+			problems = append(problems, "db.port must be between 1 and 65535")
+		}
+		if c.DB.Timeout < 0 {
+			problems = append(problems, "db.timeout can't be negative")
+		}
+	default:
+		problems = append(problems, "store must be 'postgres' or 'memory'")
+	}
+	if c.Reminders.Interval < 0 || c.Reminders.Window < 0 {
+		problems = append(problems, "reminders.interval and reminders.window can't be negative")
+	}
+	if len(c.Reminders.Webhook) > 0 {
+		u, err := url.Parse(c.Reminders.Webhook)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			problems = append(problems, "reminders.webhook must be an http or https URL")
+		}
+	}
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "file":
+		if len(c.Tracing.File) == 0 {
+			problems = append(problems, "tracing.file must be provided for the 'file' exporter")
+		}
+	case "otlp":
+		u, err := url.Parse(c.Tracing.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			problems = append(problems, "tracing.endpoint must be an http or https URL")
+		}
+	default:
+		problems = append(problems, "tracing.exporter must be 'none', 'stdout', 'file', or 'otlp'")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing.sampleratio must be between 0 and 1")
+	}
+
+	if len(problems) > 0 {
+		return errors.Errorf("invalid configuration: %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// applyEnv overrides the settings in 'c' that have environment variables
+func applyEnv(c *Config, lookupEnv func(string) (string, bool)) error {
+	if v, ok := lookupEnv("POSTGRES_SERVICE_HOST"); ok {
+		c.DB.Host = v
+	}
+	if v, ok := lookupEnv("POSTGRES_SERVICE_PORT"); ok {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.Errorf("POSTGRES_SERVICE_PORT must be an integer, got %q", v)
+		}
+		c.DB.Port = port
+	}
+
+	return walk(reflect.ValueOf(c).Elem(), EnvPrefix, func(field reflect.Value, sf reflect.StructField, env string) error {
+		v, ok := lookupEnv(env)
+		if !ok {
+			return nil
+		}
+		return errors.Annotatef(set(field, v), "%s", env)
+	})
+}
+
+// applyFlags overrides the settings in 'c' with the flags in 'flags'. It's an error if a
+// flag doesn't correspond to a setting.
+func applyFlags(c *Config, flags map[string]string) error {
+	applied := 0
+	err := walk(reflect.ValueOf(c).Elem(), EnvPrefix, func(field reflect.Value, sf reflect.StructField, env string) error {
+		name := sf.Tag.Get("flag")
+		v, ok := flags[name]
+		if len(name) == 0 || !ok {
+			return nil
+		}
+		applied++
+		return errors.Annotatef(set(field, v), "-%s", name)
+	})
+	if err != nil {
+		return errors.Trace(err)
+	}
+	if applied != len(flags) {
+		return errors.Errorf("unknown flags in %v", flags)
+	}
+	return nil
+}
+
+// walk calls 'fn' for each setting in the struct 'v' and its nested structs, along with the
+// name of its environment variable, 'prefix' followed by the setting's path
+func walk(v reflect.Value, prefix string, fn func(field reflect.Value, sf reflect.StructField, env string) error) error {
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		env := prefix + strings.ToUpper(sf.Tag.Get("yaml"))
+		if sf.Type.Kind() == reflect.Struct {
+			err := walk(v.Field(i), env+"_", fn)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err := fn(v.Field(i), sf, env)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// set sets 'field' to the value 's' represents
+func set(field reflect.Value, s string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return errors.Errorf("expected a duration, e.g., '1m', got %q", s)
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Int:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return errors.Errorf("expected an integer, got %q", s)
+		}
+		field.SetInt(int64(i))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return errors.Errorf("expected a number, got %q", s)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return errors.Errorf("expected 'true' or 'false', got %q", s)
+		}
+		field.SetBool(b)
+	default:
+		return errors.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
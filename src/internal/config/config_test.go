@@ -0,0 +1,191 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected creating a temp dir", err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		err := ioutil.WriteFile(path, []byte(data), 0600)
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected writing %s", err, path)
+		}
+		return path
+	}
+	configFile := write("todod.yaml", `
+port: 9090
+shutdowndelay: 0s
+db:
+  host: db.example.com
+  user: app
+  timeout: 2s
+  legacyowner: ryoungkin
+reminders:
+  interval: 5m
+  webhook: https://chat.example.com/hooks/reminders
+auth:
+  apikeysfile: /etc/todod/apikeys
+tracing:
+  exporter: otlp
+  endpoint: http://collector:4318/v1/traces
+  sampleratio: 0.25
+`)
+	passwordFile := write("dbpassword", "s3cret\n")
+	withPassword := write("password.yaml", "db:\n  password: s3cret\n")
+	unknownSetting := write("unknown.yaml", "db:\n  hostname: db.example.com\n")
+	invalid := write("invalid.yaml", "port: 0\nstore: disk\n")
+
+	fromFile := Default()
+	fromFile.Port = 9090
+	fromFile.ShutdownDelay = 0
+	fromFile.DB.Host = "db.example.com"
+	fromFile.DB.User = "app"
+	fromFile.DB.Timeout = 2 * time.Second
+	fromFile.DB.LegacyOwner = "ryoungkin"
+	fromFile.Reminders.Interval = 5 * time.Minute
+	fromFile.Reminders.Webhook = "https://chat.example.com/hooks/reminders"
+	fromFile.Auth.APIKeysFile = "/etc/todod/apikeys"
+	fromFile.Tracing.Exporter = "otlp"
+	fromFile.Tracing.Endpoint = "http://collector:4318/v1/traces"
+	fromFile.Tracing.SampleRatio = 0.25
+
+	overridden := fromFile
+	overridden.DB.Host = "env.example.com"
+	overridden.DB.Port = 6432
+	overridden.AutoMigrate = false
+	overridden.Port = 8081
+	overridden.Tracing.SampleRatio = 0.5
+
+	legacyEnv := Default()
+	legacyEnv.DB.Host = "10.0.0.5"
+	legacyEnv.DB.Port = 5433
+
+	withSecret := Default()
+	withSecret.DB.PasswordFile = passwordFile
+	withSecret.DB.Password = "s3cret"
+
+	tcs := []struct {
+		testName        string
+		path            string
+		env             map[string]string
+		flags           map[string]string
+		expectedConfig  Config
+		expectedErrCode constants.ErrCode
+	}{
+		{
+			testName:        "testDefaults",
+			expectedConfig:  Default(),
+			expectedErrCode: constants.NoErrorCode,
+		},
+		{
+			testName:        "testFile",
+			path:            configFile,
+			expectedConfig:  fromFile,
+			expectedErrCode: constants.NoErrorCode,
+		},
+		{
+			testName: "testOverrides",
+			path:     configFile,
+			env: map[string]string{
+				"POSTGRES_SERVICE_HOST":     "10.0.0.5",
+				"TODOD_DB_HOST":             "env.example.com",
+				"TODOD_DB_PORT":             "6432",
+				"TODOD_AUTOMIGRATE":         "false",
+				"TODOD_PORT":                "8082",
+				"TODOD_TRACING_SAMPLERATIO": "0.5",
+			},
+			flags:           map[string]string{"port": "8081"},
+			expectedConfig:  overridden,
+			expectedErrCode: constants.NoErrorCode,
+		},
+		{
+			testName:        "testKubernetesEnv",
+			env:             map[string]string{"POSTGRES_SERVICE_HOST": "10.0.0.5", "POSTGRES_SERVICE_PORT": "5433"},
+			expectedConfig:  legacyEnv,
+			expectedErrCode: constants.NoErrorCode,
+		},
+		{
+			testName:        "testPasswordFile",
+			env:             map[string]string{"TODOD_DB_PASSWORDFILE": passwordFile},
+			expectedConfig:  withSecret,
+			expectedErrCode: constants.NoErrorCode,
+		},
+		{
+			testName:        "testPasswordAndPasswordFile",
+			path:            withPassword,
+			flags:           map[string]string{"dbpasswordfile": passwordFile},
+			expectedErrCode: constants.UnableToLoadConfigErrorCode,
+		},
+		{
+			testName:        "testMissingPasswordFile",
+			flags:           map[string]string{"dbpasswordfile": filepath.Join(dir, "missing")},
+			expectedErrCode: constants.UnableToLoadSecretsErrorCode,
+		},
+		{
+			testName:        "testMissingFile",
+			path:            filepath.Join(dir, "missing.yaml"),
+			expectedErrCode: constants.UnableToOpenConfigErrorCode,
+		},
+		{
+			testName:        "testUnknownSetting",
+			path:            unknownSetting,
+			expectedErrCode: constants.UnableToLoadConfigErrorCode,
+		},
+		{
+			testName:        "testInvalidSettings",
+			path:            invalid,
+			expectedErrCode: constants.UnableToLoadConfigErrorCode,
+		},
+		{
+			testName:        "testInvalidEnv",
+			env:             map[string]string{"TODOD_REMINDERS_WINDOW": "an hour"},
+			expectedErrCode: constants.UnableToLoadConfigErrorCode,
+		},
+		{
+			testName:        "testTraceFileMissing",
+			flags:           map[string]string{"traceexporter": "file"},
+			expectedErrCode: constants.UnableToLoadConfigErrorCode,
+		},
+		{
+			testName:        "testUnknownFlag",
+			flags:           map[string]string{"passwd": "todo123"},
+			expectedErrCode: constants.UnableToLoadConfigErrorCode,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			lookupEnv := func(name string) (string, bool) {
+				v, ok := tc.env[name]
+				return v, ok
+			}
+			c, errCode, err := Load(tc.path, lookupEnv, tc.flags)
+			if errCode != tc.expectedErrCode {
+				t.Fatalf("expected error code %d, got %d, error %v", tc.expectedErrCode, errCode, err)
+			}
+			if errCode != constants.NoErrorCode {
+				if err == nil {
+					t.Errorf("expected an error with error code %d", errCode)
+				}
+				return
+			}
+			if !reflect.DeepEqual(tc.expectedConfig, c) {
+				t.Errorf("expected config %+v, got %+v", tc.expectedConfig, c)
+			}
+		})
+	}
+}
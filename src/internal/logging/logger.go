@@ -1,6 +1,7 @@
 package logging
 
 import (
+	"context"
 	"os"
 
 	log "github.com/sirupsen/logrus"
@@ -33,3 +34,21 @@ func init() {
 func GetLogger() *log.Entry {
 	return logger
 }
+
+// contextKey is the type of the key used to store a request's logger in a context.Context.
+// It's unexported so other packages can't collide with it.
+type contextKey struct{}
+
+// NewContext returns a copy of 'ctx' carrying 'logger', the logger for a request's log
+// messages, e.g., one with the request's ID
+func NewContext(ctx context.Context, logger *log.Entry) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext returns the logger carried by 'ctx', or 'fallback' if there isn't one
+func FromContext(ctx context.Context, fallback *log.Entry) *log.Entry {
+	if logger, ok := ctx.Value(contextKey{}).(*log.Entry); ok && logger != nil {
+		return logger
+	}
+	return fallback
+}
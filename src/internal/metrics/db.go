@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"database/sql"
+)
+
+// DBStatser is implemented by *sql.DB
+type DBStatser interface {
+	Stats() sql.DBStats
+}
+
+// RegisterDBStats registers gauges and counters for the connection pool statistics of 'db'.
+// They're read from 'db' each time the metrics are scraped.
+func (r *Registry) RegisterDBStats(db DBStatser) {
+	r.NewGaugeFunc("todod_db_max_open_connections", "Maximum number of open connections to the database.",
+		func() float64 { return float64(db.Stats().MaxOpenConnections) })
+	r.NewGaugeFunc("todod_db_open_connections", "Number of established connections to the database, in use and idle.",
+		func() float64 { return float64(db.Stats().OpenConnections) })
+	r.NewGaugeFunc("todod_db_in_use_connections", "Number of database connections currently in use.",
+		func() float64 { return float64(db.Stats().InUse) })
+	r.NewGaugeFunc("todod_db_idle_connections", "Number of idle database connections.",
+		func() float64 { return float64(db.Stats().Idle) })
+	r.NewCounterFunc("todod_db_wait_count_total", "Total number of times a database connection was waited for.",
+		func() float64 { return float64(db.Stats().WaitCount) })
+	r.NewCounterFunc("todod_db_wait_duration_seconds_total", "Total time spent waiting for database connections.",
+		func() float64 { return db.Stats().WaitDuration.Seconds() })
+	r.NewCounterFunc("todod_db_max_idle_closed_total", "Total number of database connections closed due to the idle connection limit.",
+		func() float64 { return float64(db.Stats().MaxIdleClosed) })
+	r.NewCounterFunc("todod_db_max_lifetime_closed_total", "Total number of database connections closed due to their maximum lifetime.",
+		func() float64 { return float64(db.Stats().MaxLifetimeClosed) })
+}
@@ -0,0 +1,307 @@
+// Package metrics keeps counters, gauges, and histograms and exposes them in the Prometheus
+// text exposition format (version 0.0.4) so they can be scraped by Prometheus. Metric names
+// are part of todod's interface, alerts are based on them, so they must not be changed.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the media type of the exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds, in seconds, of the buckets of a latency histogram
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// DefaultRegistry is the registry todod's metrics are registered with and served from
+var DefaultRegistry = NewRegistry()
+
+// collector is a metric that can write its samples in the exposition format
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry is a set of metrics. Its ServeHTTP method serves the metrics' current values.
+type Registry struct {
+	mu         sync.Mutex
+	names      []string
+	collectors map[string]collector
+}
+
+// NewRegistry returns an empty *Registry
+func NewRegistry() *Registry {
+	return &Registry{collectors: map[string]collector{}}
+}
+
+// register adds 'c' to the registry. Names must be unique, registering a name twice is a
+// programming error so it panics.
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.collectors[name]; ok {
+		panic(fmt.Sprintf("metric %s is already registered", name))
+	}
+	r.names = append(r.names, name)
+	r.collectors[name] = c
+}
+
+// ServeHTTP writes the current value of every metric, in name order
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	names := append([]string(nil), r.names...)
+	r.mu.Unlock()
+	sort.Strings(names)
+
+	w.Header().Set("Content-Type", ContentType)
+	bw := bufio.NewWriter(w)
+	for _, name := range names {
+		r.mu.Lock()
+		c := r.collectors[name]
+		r.mu.Unlock()
+		c.write(bw)
+	}
+	bw.Flush()
+}
+
+// writeHeader writes the HELP and TYPE lines that precede a metric's samples
+func writeHeader(w *bufio.Writer, name, help, typ string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// writeSample writes a sample of 'name' with the labels 'names' and 'values'
+func writeSample(w *bufio.Writer, name string, names, values []string, v float64) {
+	w.WriteString(name)
+	if len(names) > 0 {
+		w.WriteByte('{')
+		for i := range names {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(names[i])
+			w.WriteString(`="`)
+			w.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(values[i]))
+			w.WriteByte('"')
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(v))
+	w.WriteByte('\n')
+}
+
+// formatFloat formats 'v' as the exposition format expects
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// labelKey identifies a combination of label values
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// vec is the set of series, one per combination of label values, of a labeled metric
+type vec struct {
+	mu     sync.Mutex
+	labels []string
+	keys   []string
+	values map[string][]string
+}
+
+// series returns the key of the series identified by 'values', adding it if it's new.
+// Providing the wrong number of values is a programming error so it panics.
+func (v *vec) series(values []string, add func(key string)) string {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("expected values for labels %v, got %v", v.labels, values))
+	}
+	key := labelKey(values)
+	if _, ok := v.values[key]; !ok {
+		v.keys = append(v.keys, key)
+		sort.Strings(v.keys)
+		v.values[key] = append([]string(nil), values...)
+		add(key)
+	}
+	return key
+}
+
+// CounterVec is a counter, a value that only increases, with labels
+type CounterVec struct {
+	name, help string
+	vec
+	counts map[string]float64
+}
+
+// NewCounterVec registers and returns a counter named 'name' with the labels 'labels'
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{
+		name:   name,
+		help:   help,
+		vec:    vec{labels: labels, values: map[string][]string{}},
+		counts: map[string]float64{},
+	}
+	r.register(name, c)
+	return c
+}
+
+// Inc adds 1 to the series identified by 'values', one for each label
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds 'delta', which can't be negative, to the series identified by 'values'
+func (c *CounterVec) Add(delta float64, values ...string) {
+	if delta < 0 {
+		panic(fmt.Sprintf("counter %s can't be decreased", c.name))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := c.series(values, func(string) {})
+	c.counts[key] += delta
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeHeader(w, c.name, c.help, "counter")
+	for _, key := range c.keys {
+		writeSample(w, c.name, c.labels, c.values[key], c.counts[key])
+	}
+}
+
+// Gauge is a value that can increase and decrease
+type Gauge struct {
+	name, help string
+	mu         sync.Mutex
+	value      float64
+}
+
+// NewGauge registers and returns a gauge named 'name'
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	g := &Gauge{name: name, help: help}
+	r.register(name, g)
+	return g
+}
+
+// Add adds 'delta' to the gauge, it can be negative
+func (g *Gauge) Add(delta float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.value += delta
+}
+
+// Inc adds 1 to the gauge
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec subtracts 1 from the gauge
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	writeHeader(w, g.name, g.help, "gauge")
+	writeSample(w, g.name, nil, nil, g.value)
+}
+
+// funcMetric is a metric whose value is read from a function when it's scraped
+type funcMetric struct {
+	name, help, typ string
+	fn              func() float64
+}
+
+// NewGaugeFunc registers a gauge named 'name' whose value is returned by 'fn'
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name: name, help: help, typ: "gauge", fn: fn})
+}
+
+// NewCounterFunc registers a counter named 'name' whose value is returned by 'fn'. The
+// value must only increase.
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) {
+	r.register(name, &funcMetric{name: name, help: help, typ: "counter", fn: fn})
+}
+
+func (f *funcMetric) write(w *bufio.Writer) {
+	writeHeader(w, f.name, f.help, f.typ)
+	writeSample(w, f.name, nil, nil, f.fn())
+}
+
+// HistogramVec counts observations, e.g., request latencies, in buckets. It has labels.
+type HistogramVec struct {
+	name, help string
+	vec
+	buckets []float64
+	counts  map[string][]uint64
+	sums    map[string]float64
+	totals  map[string]uint64
+}
+
+// NewHistogramVec registers and returns a histogram named 'name' with the labels 'labels'.
+// 'buckets' are the buckets' upper bounds in increasing order, a +Inf bucket is added.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("histogram %s buckets must be in increasing order", name))
+	}
+	h := &HistogramVec{
+		name:    name,
+		help:    help,
+		vec:     vec{labels: labels, values: map[string][]string{}},
+		buckets: buckets,
+		counts:  map[string][]uint64{},
+		sums:    map[string]float64{},
+		totals:  map[string]uint64{},
+	}
+	r.register(name, h)
+	return h
+}
+
+// Observe adds the observation 'v' to the series identified by 'values'
+func (h *HistogramVec) Observe(v float64, values ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := h.series(values, func(key string) {
+		h.counts[key] = make([]uint64, len(h.buckets))
+	})
+	for i, upper := range h.buckets {
+		if v <= upper {
+			h.counts[key][i]++
+		}
+	}
+	h.sums[key] += v
+	h.totals[key]++
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.name, h.help, "histogram")
+	labels := append(append([]string(nil), h.labels...), "le")
+	for _, key := range h.keys {
+		values := h.values[key]
+		for i, upper := range h.buckets {
+			writeSample(w, h.name+"_bucket", labels, append(append([]string(nil), values...), formatFloat(upper)),
+				float64(h.counts[key][i]))
+		}
+		writeSample(w, h.name+"_bucket", labels, append(append([]string(nil), values...), "+Inf"),
+			float64(h.totals[key]))
+		writeSample(w, h.name+"_sum", h.labels, values, h.sums[key])
+		writeSample(w, h.name+"_count", h.labels, values, float64(h.totals[key]))
+	}
+}
@@ -0,0 +1,108 @@
+package metrics
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testDB struct{}
+
+func (testDB) Stats() sql.DBStats {
+	return sql.DBStats{MaxOpenConnections: 10, OpenConnections: 3, InUse: 2, Idle: 1, WaitCount: 4,
+		WaitDuration: 1500 * time.Millisecond}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("test_requests_total", "Total requests.", "method", "path")
+	requests.Inc("GET", "/todos")
+	requests.Inc("GET", "/todos")
+	requests.Add(3, "POST", `/todos/"1"`)
+	inFlight := r.NewGauge("test_in_flight", "In flight requests.")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+	r.NewGaugeFunc("test_queue_depth", "Queue depth.", func() float64 { return 7 })
+	latency := r.NewHistogramVec("test_duration_seconds", "Request latency.", []float64{0.1, 1}, "method")
+	latency.Observe(0.05, "GET")
+	latency.Observe(0.5, "GET")
+	latency.Observe(2, "GET")
+	r.RegisterDBStats(testDB{})
+
+	expected := `# HELP test_duration_seconds Request latency.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{method="GET",le="0.1"} 1
+test_duration_seconds_bucket{method="GET",le="1"} 2
+test_duration_seconds_bucket{method="GET",le="+Inf"} 3
+test_duration_seconds_sum{method="GET"} 2.55
+test_duration_seconds_count{method="GET"} 3
+# HELP test_in_flight In flight requests.
+# TYPE test_in_flight gauge
+test_in_flight 1
+# HELP test_queue_depth Queue depth.
+# TYPE test_queue_depth gauge
+test_queue_depth 7
+# HELP test_requests_total Total requests.
+# TYPE test_requests_total counter
+test_requests_total{method="GET",path="/todos"} 2
+test_requests_total{method="POST",path="/todos/\"1\""} 3
+# HELP todod_db_idle_connections Number of idle database connections.
+# TYPE todod_db_idle_connections gauge
+todod_db_idle_connections 1
+# HELP todod_db_in_use_connections Number of database connections currently in use.
+# TYPE todod_db_in_use_connections gauge
+todod_db_in_use_connections 2
+# HELP todod_db_max_idle_closed_total Total number of database connections closed due to the idle connection limit.
+# TYPE todod_db_max_idle_closed_total counter
+todod_db_max_idle_closed_total 0
+# HELP todod_db_max_lifetime_closed_total Total number of database connections closed due to their maximum lifetime.
+# TYPE todod_db_max_lifetime_closed_total counter
+todod_db_max_lifetime_closed_total 0
+# HELP todod_db_max_open_connections Maximum number of open connections to the database.
+# TYPE todod_db_max_open_connections gauge
+todod_db_max_open_connections 10
+# HELP todod_db_open_connections Number of established connections to the database, in use and idle.
+# TYPE todod_db_open_connections gauge
+todod_db_open_connections 3
+# HELP todod_db_wait_count_total Total number of times a database connection was waited for.
+# TYPE todod_db_wait_count_total counter
+todod_db_wait_count_total 4
+# HELP todod_db_wait_duration_seconds_total Total time spent waiting for database connections.
+# TYPE todod_db_wait_duration_seconds_total counter
+todod_db_wait_duration_seconds_total 1.5
+`
+
+	testSrv := httptest.NewServer(r)
+	defer testSrv.Close()
+	resp, err := http.Get(testSrv.URL)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected getting the metrics", err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected reading the metrics", err)
+	}
+
+	if ct := resp.Header.Get("Content-Type"); ct != ContentType {
+		t.Errorf("expected Content-Type %q, got %q", ContentType, ct)
+	}
+	if string(body) != expected {
+		t.Errorf("expected metrics:\n%s\ngot:\n%s", expected, body)
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("test_gauge", "A gauge.")
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected registering a duplicate metric name to panic")
+		}
+	}()
+	r.NewCounterVec("test_gauge", "A counter.")
+}
@@ -0,0 +1,288 @@
+// Package migrate manages the version of todod's Postgres schema. The schema is defined by
+// an ordered list of migrations compiled into todod, each of which upgrades (Up) the schema
+// from the previous version or downgrades (Down) it back to that version. The versions
+// applied to a database are recorded in its 'schema_migrations' table.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/juju/errors"
+	"github.com/lib/pq"
+)
+
+var (
+	createVersionTableStmt = "CREATE TABLE IF NOT EXISTS schema_migrations (version integer PRIMARY KEY, name text NOT NULL, applied timestamp NOT NULL DEFAULT now())"
+	lockStmt               = "SELECT pg_advisory_xact_lock($1)"
+	getVersionQuery        = "SELECT COALESCE(MAX(version), 0) FROM schema_migrations"
+	insertVersionStmt      = "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)"
+	deleteVersionStmt      = "DELETE FROM schema_migrations WHERE version = $1"
+
+	// Todos, lists, and webhooks created before todod had users have no owner. The default
+	// list, id 1, has no owner by design.
+	countOwnerlessQuery = "SELECT (SELECT COUNT(*) FROM todo WHERE owner = '') + " +
+		"(SELECT COUNT(*) FROM lists WHERE owner = '' AND id <> 1) + (SELECT COUNT(*) FROM webhook WHERE owner = '')"
+	adoptOwnerlessStmts = []string{
+		"UPDATE todo SET owner = $1 WHERE owner = ''",
+		"UPDATE lists SET owner = $1 WHERE owner = '' AND id <> 1",
+		"UPDATE webhook SET owner = $1 WHERE owner = ''",
+	}
+)
+
+// postgresUndefinedTableErrorCode is the Postgres error code for a query of a table that
+// doesn't exist
+const postgresUndefinedTableErrorCode = "42P01"
+
+// lockID identifies the advisory lock that serializes migrations, so todod instances
+// started at the same time don't apply the same migration twice. It's "todo" in ASCII.
+const lockID = 0x746f646f
+
+// Migration is one version of the schema. 'Up' upgrades the schema from 'Version'-1 to
+// 'Version', 'Down' reverses it. Both can contain several statements.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrator applies and reverts migrations
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator returns a *Migrator that will migrate the schema of the provided database
+// connection using the migrations compiled into todod
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	if db == nil {
+		return nil, errors.New("non-nil sql.DB connection required")
+	}
+	err := validate(migrations)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// validate returns an error if 'ms' aren't numbered 1, 2, 3, ... or any are incomplete
+func validate(ms []Migration) error {
+	for i, m := range ms {
+		if m.Version != i+1 {
+			return errors.Errorf("migration %q has version %d, expected %d", m.Name, m.Version, i+1)
+		}
+		if len(m.Name) == 0 || len(m.Up) == 0 || len(m.Down) == 0 {
+			return errors.Errorf("migration %d must have a name, an up, and a down", m.Version)
+		}
+	}
+	return nil
+}
+
+// Latest returns the newest schema version todod understands
+func (m *Migrator) Latest() int {
+	return len(m.migrations)
+}
+
+// Version returns the database's schema version, 0 if no migrations have been applied. It
+// creates the 'schema_migrations' table if it doesn't exist.
+func (m *Migrator) Version(ctx context.Context) (int, error) {
+	_, err := m.db.ExecContext(ctx, createVersionTableStmt)
+	if err != nil {
+		return 0, errors.Annotate(err, "error creating schema_migrations table")
+	}
+	var version int
+	err = m.db.QueryRowContext(ctx, getVersionQuery).Scan(&version)
+	if err != nil {
+		return 0, errors.Annotate(err, "error querying schema version")
+	}
+	return version, nil
+}
+
+// tooNew returns the error reported when the database's schema, 'version', was migrated by
+// a newer todod
+func (m *Migrator) tooNew(version int) error {
+	return errors.Errorf("schema version %d is newer than the latest version, %d, this todod supports",
+		version, m.Latest())
+}
+
+// CheckContext returns an error if the database's schema isn't the latest version, i.e., it
+// needs to be migrated or it was migrated by a newer todod. It only reads the schema version,
+// unlike Version it doesn't create the 'schema_migrations' table, so it's safe to call
+// repeatedly, e.g., from a readiness probe. A database without the table hasn't been migrated.
+func (m *Migrator) CheckContext(ctx context.Context) error {
+	var version int
+	err := m.db.QueryRowContext(ctx, getVersionQuery).Scan(&version)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == postgresUndefinedTableErrorCode {
+		return errors.New("the schema_migrations table doesn't exist, run 'todod migrate up'")
+	}
+	if err != nil {
+		return errors.Annotate(err, "error querying schema version")
+	}
+	return m.checkVersion(version)
+}
+
+// checkVersion returns an error if 'version' isn't the latest schema version
+func (m *Migrator) checkVersion(version int) error {
+	if version > m.Latest() {
+		return m.tooNew(version)
+	}
+	if version < m.Latest() {
+		return errors.Errorf("schema version %d is older than the required version, %d, run 'todod migrate up'",
+			version, m.Latest())
+	}
+	return nil
+}
+
+// Up applies the migrations that haven't been applied and returns them. It's an error if
+// the database's schema is newer than the latest version. Each migration is applied in its
+// own transaction, if one fails the migrations applied before it are kept.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	_, err := m.db.ExecContext(ctx, createVersionTableStmt)
+	if err != nil {
+		return nil, errors.Annotate(err, "error creating schema_migrations table")
+	}
+
+	var applied []Migration
+	for {
+		mig, err := m.step(ctx, func(version int) (*Migration, error) {
+			if version > m.Latest() {
+				return nil, m.tooNew(version)
+			}
+			if version == m.Latest() {
+				return nil, nil
+			}
+			return &m.migrations[version], nil
+		}, true)
+		if err != nil {
+			return applied, errors.Trace(err)
+		}
+		if mig == nil {
+			return applied, nil
+		}
+		applied = append(applied, *mig)
+	}
+}
+
+// Down reverts the 'n' most recently applied migrations, or all of them if 'n' is less than
+// 1, and returns them. It's an error if the database's schema is newer than the latest
+// version, todod doesn't know how to revert it.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	_, err := m.db.ExecContext(ctx, createVersionTableStmt)
+	if err != nil {
+		return nil, errors.Annotate(err, "error creating schema_migrations table")
+	}
+
+	var reverted []Migration
+	for n < 1 || len(reverted) < n {
+		mig, err := m.step(ctx, func(version int) (*Migration, error) {
+			if version > m.Latest() {
+				return nil, m.tooNew(version)
+			}
+			if version == 0 {
+				return nil, nil
+			}
+			return &m.migrations[version-1], nil
+		}, false)
+		if err != nil {
+			return reverted, errors.Trace(err)
+		}
+		if mig == nil {
+			break
+		}
+		reverted = append(reverted, *mig)
+	}
+	return reverted, nil
+}
+
+// step applies, if 'up' is true, or reverts the migration chosen by 'next' from the
+// database's current schema version. It returns the migration, or nil if 'next' doesn't
+// choose one. The version is read, and the migration run, while holding the migration
+// lock so concurrent migrations are serialized.
+func (m *Migrator) step(ctx context.Context, next func(version int) (*Migration, error), up bool) (*Migration, error) {
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.Annotate(err, "error beginning migration transaction")
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, lockStmt, lockID)
+	if err != nil {
+		return nil, errors.Annotate(err, "error acquiring migration lock")
+	}
+	var version int
+	err = tx.QueryRowContext(ctx, getVersionQuery).Scan(&version)
+	if err != nil {
+		return nil, errors.Annotate(err, "error querying schema version")
+	}
+	mig, err := next(version)
+	if err != nil || mig == nil {
+		return nil, err
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, mig.Up)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, insertVersionStmt, mig.Version, mig.Name)
+		}
+	} else {
+		_, err = tx.ExecContext(ctx, mig.Down)
+		if err == nil {
+			_, err = tx.ExecContext(ctx, deleteVersionStmt, mig.Version)
+		}
+	}
+	if err != nil {
+		return nil, errors.Annotatef(err, "error migrating schema version %d (%s)", mig.Version, mig.Name)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.Annotatef(err, "error committing schema version %d (%s)", mig.Version, mig.Name)
+	}
+	return mig, nil
+}
+
+// Ownerless returns the number of todos, lists, and webhooks without an owner, i.e., those
+// created before todod had users. No user can see them until they're adopted, see
+// AdoptOwnerless. The schema must be the latest version.
+func (m *Migrator) Ownerless(ctx context.Context) (int64, error) {
+	var n int64
+	err := m.db.QueryRowContext(ctx, countOwnerlessQuery).Scan(&n)
+	if err != nil {
+		return 0, errors.Annotate(err, "error counting rows without an owner")
+	}
+	return n, nil
+}
+
+// AdoptOwnerless makes 'owner' the owner of the todos, lists, and webhooks without one and
+// returns how many there were. They're adopted in a single transaction. The schema must be
+// the latest version.
+func (m *Migrator) AdoptOwnerless(ctx context.Context, owner string) (int64, error) {
+	if len(owner) == 0 {
+		return 0, errors.New("non-empty owner required")
+	}
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, errors.Annotate(err, "error beginning transaction")
+	}
+	defer tx.Rollback()
+
+	var adopted int64
+	for _, stmt := range adoptOwnerlessStmts {
+		result, err := tx.ExecContext(ctx, stmt, owner)
+		if err != nil {
+			return 0, errors.Annotatef(err, "error giving rows without an owner to %s", owner)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return 0, errors.Annotatef(err, "error giving rows without an owner to %s", owner)
+		}
+		adopted += n
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, errors.Annotatef(err, "error committing rows given to %s", owner)
+	}
+	return adopted, nil
+}
@@ -0,0 +1,293 @@
+package migrate
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "create a", Up: "CREATE TABLE a (id integer)", Down: "DROP TABLE a"},
+	{Version: 2, Name: "create b", Up: "CREATE TABLE b (id integer)", Down: "DROP TABLE b"},
+}
+
+// expectStep sets the expectations for a migration transaction that finds the schema at
+// 'version' and, if 'stmt' isn't empty, runs 'stmt' and then 'versionStmt'
+func expectStep(mock sqlmock.Sqlmock, version int, stmt, versionStmt string, versionArgs ...driver.Value) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(lockStmt)).WithArgs(lockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(getVersionQuery)).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(version))
+	if len(stmt) == 0 {
+		mock.ExpectRollback()
+		return
+	}
+	mock.ExpectExec(regexp.QuoteMeta(stmt)).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(versionStmt)).WithArgs(versionArgs...).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestMigrations(t *testing.T) {
+	err := validate(migrations)
+	if err != nil {
+		t.Errorf("expected valid migrations, got %s", err)
+	}
+
+	tcs := []struct {
+		testName   string
+		migrations []Migration
+		shouldPass bool
+	}{
+		{
+			testName:   "testValid",
+			migrations: testMigrations,
+			shouldPass: true,
+		},
+		{
+			testName:   "testGap",
+			migrations: []Migration{testMigrations[1]},
+			shouldPass: false,
+		},
+		{
+			testName:   "testNoDown",
+			migrations: []Migration{{Version: 1, Name: "create a", Up: "CREATE TABLE a (id integer)"}},
+			shouldPass: false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := validate(tc.migrations)
+			if (err == nil) != tc.shouldPass {
+				t.Errorf("expected shouldPass = %t, got error %v", tc.shouldPass, err)
+			}
+		})
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	tcs := []struct {
+		testName        string
+		setupMock       func(mock sqlmock.Sqlmock)
+		migrate         func(m *Migrator, ctx context.Context) ([]Migration, error)
+		expectedVersion []int
+		shouldPass      bool
+	}{
+		{
+			testName: "testUpFromEmpty",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectStep(mock, 0, "CREATE TABLE a", insertVersionStmt, 1, "create a")
+				expectStep(mock, 1, "CREATE TABLE b", insertVersionStmt, 2, "create b")
+				expectStep(mock, 2, "", "")
+			},
+			migrate:         (*Migrator).Up,
+			expectedVersion: []int{1, 2},
+			shouldPass:      true,
+		},
+		{
+			testName: "testUpToDate",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectStep(mock, 2, "", "")
+			},
+			migrate:    (*Migrator).Up,
+			shouldPass: true,
+		},
+		{
+			testName: "testUpTooNew",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectStep(mock, 3, "", "")
+			},
+			migrate:    (*Migrator).Up,
+			shouldPass: false,
+		},
+		{
+			testName: "testDownOne",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectStep(mock, 2, "DROP TABLE b", deleteVersionStmt, 2)
+			},
+			migrate:         func(m *Migrator, ctx context.Context) ([]Migration, error) { return m.Down(ctx, 1) },
+			expectedVersion: []int{2},
+			shouldPass:      true,
+		},
+		{
+			testName: "testDownAll",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectStep(mock, 2, "DROP TABLE b", deleteVersionStmt, 2)
+				expectStep(mock, 1, "DROP TABLE a", deleteVersionStmt, 1)
+				expectStep(mock, 0, "", "")
+			},
+			migrate:         func(m *Migrator, ctx context.Context) ([]Migration, error) { return m.Down(ctx, 0) },
+			expectedVersion: []int{2, 1},
+			shouldPass:      true,
+		},
+		{
+			testName: "testDownTooNew",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				expectStep(mock, 3, "", "")
+			},
+			migrate:    func(m *Migrator, ctx context.Context) ([]Migration, error) { return m.Down(ctx, 1) },
+			shouldPass: false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectExec(regexp.QuoteMeta(createVersionTableStmt)).WillReturnResult(sqlmock.NewResult(0, 0))
+			tc.setupMock(mock)
+
+			m, err := NewMigrator(db)
+			if err != nil {
+				t.Fatalf("unexpected error creating Migrator: %s", err)
+			}
+			m.migrations = testMigrations
+
+			migrated, err := tc.migrate(m, context.Background())
+			if (err == nil) != tc.shouldPass {
+				t.Errorf("expected shouldPass = %t, got error %v", tc.shouldPass, err)
+			}
+			if len(migrated) != len(tc.expectedVersion) {
+				t.Fatalf("expected versions %v to be migrated, got %+v", tc.expectedVersion, migrated)
+			}
+			for i, mig := range migrated {
+				if mig.Version != tc.expectedVersion[i] {
+					t.Errorf("expected versions %v to be migrated, got %+v", tc.expectedVersion, migrated)
+				}
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tcs := []struct {
+		testName   string
+		setupMock  func(mock sqlmock.Sqlmock)
+		shouldPass bool
+	}{
+		{
+			testName: "testLatest",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(getVersionQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+			},
+			shouldPass: true,
+		},
+		{
+			testName: "testOlder",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(getVersionQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+			},
+			shouldPass: false,
+		},
+		{
+			testName: "testNewer",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(getVersionQuery)).
+					WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+			},
+			shouldPass: false,
+		},
+		{
+			testName: "testNoVersionTable",
+			setupMock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(getVersionQuery)).
+					WillReturnError(&pq.Error{Code: postgresUndefinedTableErrorCode})
+			},
+			shouldPass: false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+			}
+			defer db.Close()
+
+			// Only the version is queried, the schema_migrations table isn't created
+			tc.setupMock(mock)
+
+			m, err := NewMigrator(db)
+			if err != nil {
+				t.Fatalf("unexpected error creating Migrator: %s", err)
+			}
+			m.migrations = testMigrations
+
+			err = m.CheckContext(context.Background())
+			if (err == nil) != tc.shouldPass {
+				t.Errorf("expected shouldPass = %t, got error %v", tc.shouldPass, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+// TestAdoptOwnerless upgrades a database created by 'createtables.sql', whose todos have
+// no owner, and gives its todos, lists, and webhooks to a user
+func TestAdoptOwnerless(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(createVersionTableStmt)).WillReturnResult(sqlmock.NewResult(0, 0))
+	for i, mig := range migrations {
+		expectStep(mock, i, mig.Up, insertVersionStmt, mig.Version, mig.Name)
+	}
+	expectStep(mock, len(migrations), "", "")
+	mock.ExpectQuery(regexp.QuoteMeta(countOwnerlessQuery)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(adoptOwnerlessStmts[0])).WithArgs("ryoungkin").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta(adoptOwnerlessStmts[1])).WithArgs("ryoungkin").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(adoptOwnerlessStmts[2])).WithArgs("ryoungkin").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(countOwnerlessQuery)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	m, err := NewMigrator(db)
+	if err != nil {
+		t.Fatalf("unexpected error creating Migrator: %s", err)
+	}
+	applied, err := m.Up(context.Background())
+	if err != nil || len(applied) != len(migrations) {
+		t.Fatalf("expected %d migrations to be applied, got %d, %v", len(migrations), len(applied), err)
+	}
+
+	n, err := m.Ownerless(context.Background())
+	if err != nil || n != 4 {
+		t.Errorf("expected 4 rows without an owner, got %d, %v", n, err)
+	}
+	n, err = m.AdoptOwnerless(context.Background(), "ryoungkin")
+	if err != nil || n != 4 {
+		t.Errorf("expected 4 rows to be adopted, got %d, %v", n, err)
+	}
+	n, err = m.Ownerless(context.Background())
+	if err != nil || n != 0 {
+		t.Errorf("expected no rows without an owner, got %d, %v", n, err)
+	}
+	if _, err := m.AdoptOwnerless(context.Background(), ""); err == nil {
+		t.Error("expected an error adopting rows for an empty owner")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
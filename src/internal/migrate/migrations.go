@@ -0,0 +1,133 @@
+package migrate
+
+// migrations is todod's schema, in version order. Applied migrations must not be changed,
+// schema changes are made by appending a migration.
+//
+// Version 1 also adopts databases created by the 'sql/createtables.sql' script that
+// preceded migrations, tables and columns that already exist are left as they are.
+var migrations = []Migration{
+	{
+		Version: 1,
+		Name:    "create users, lists, and todo tables",
+		Up: `
+CREATE TABLE IF NOT EXISTS users (
+    id SERIAL PRIMARY KEY,
+    username text NOT NULL UNIQUE,
+    password_hash text NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS lists (
+    id SERIAL PRIMARY KEY,
+    name text NOT NULL,
+    description text NOT NULL DEFAULT '',
+    owner text NOT NULL DEFAULT ''
+);
+
+-- The default list, id 1, holds the todos served by /todos
+INSERT INTO lists (name, description)
+    SELECT 'default', 'The default To Do list' WHERE NOT EXISTS (SELECT 1 FROM lists WHERE id = 1);
+
+CREATE TABLE IF NOT EXISTS todo (
+    id SERIAL PRIMARY KEY,
+    list_id integer NOT NULL DEFAULT 1 REFERENCES lists (id) ON DELETE CASCADE,
+    owner text NOT NULL DEFAULT '',
+    note text,
+    dueDate timestamp,
+    repeat boolean DEFAULT false,
+    recurrence text NOT NULL DEFAULT '',
+    completed boolean DEFAULT false,
+    version integer NOT NULL DEFAULT 1
+);
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS list_id integer NOT NULL DEFAULT 1 REFERENCES lists (id) ON DELETE CASCADE;
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS owner text NOT NULL DEFAULT '';
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS recurrence text NOT NULL DEFAULT '';
+ALTER TABLE todo ADD COLUMN IF NOT EXISTS version integer NOT NULL DEFAULT 1;
+`,
+		Down: `
+DROP TABLE IF EXISTS todo;
+DROP TABLE IF EXISTS lists;
+DROP TABLE IF EXISTS users;
+`,
+	},
+	{
+		Version: 2,
+		Name:    "create reminder table",
+		Up: `
+CREATE TABLE IF NOT EXISTS reminder (
+    todo_id integer NOT NULL REFERENCES todo (id) ON DELETE CASCADE,
+    kind text NOT NULL,
+    duedate timestamp NOT NULL,
+    sent timestamp NOT NULL DEFAULT now(),
+    PRIMARY KEY (todo_id, kind, duedate)
+);
+`,
+		Down: `
+DROP TABLE IF EXISTS reminder;
+`,
+	},
+	{
+		Version: 3,
+		Name:    "create webhook and webhook_delivery tables",
+		Up: `
+CREATE TABLE IF NOT EXISTS webhook (
+    id SERIAL PRIMARY KEY,
+    owner text NOT NULL DEFAULT '',
+    url text NOT NULL,
+    events text NOT NULL,
+    secret text NOT NULL
+);
+ALTER TABLE webhook ADD COLUMN IF NOT EXISTS owner text NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id SERIAL PRIMARY KEY,
+    webhook_id integer NOT NULL REFERENCES webhook (id) ON DELETE CASCADE,
+    event_id text NOT NULL,
+    event text NOT NULL,
+    attempt integer NOT NULL,
+    status integer NOT NULL,
+    error text NOT NULL DEFAULT '',
+    delivered timestamp NOT NULL
+);
+`,
+		Down: `
+DROP TABLE IF EXISTS webhook_delivery;
+DROP TABLE IF EXISTS webhook;
+`,
+	},
+	{
+		Version: 4,
+		Name:    "create list_shares table",
+		Up: `
+CREATE TABLE IF NOT EXISTS list_shares (
+    list_id integer NOT NULL REFERENCES lists (id) ON DELETE CASCADE,
+    username text NOT NULL,
+    role text NOT NULL CHECK (role IN ('editor', 'viewer')),
+    PRIMARY KEY (list_id, username)
+);
+`,
+		Down: `
+DROP TABLE IF EXISTS list_shares;
+`,
+	},
+	{
+		Version: 5,
+		Name:    "record reminders per notifier",
+		Up: `
+-- Reminders recorded before were sent to every notifier, the log and, if configured, the webhook
+ALTER TABLE reminder ADD COLUMN notifier text NOT NULL DEFAULT '';
+INSERT INTO reminder (todo_id, kind, duedate, sent, notifier)
+    SELECT todo_id, kind, duedate, sent, n.notifier FROM reminder, (VALUES ('log'), ('webhook')) AS n (notifier)
+    WHERE reminder.notifier = '';
+DELETE FROM reminder WHERE notifier = '';
+ALTER TABLE reminder DROP CONSTRAINT reminder_pkey;
+ALTER TABLE reminder ADD PRIMARY KEY (todo_id, kind, duedate, notifier);
+`,
+		Down: `
+ALTER TABLE reminder DROP CONSTRAINT reminder_pkey;
+DELETE FROM reminder a USING reminder b
+    WHERE a.todo_id = b.todo_id AND a.kind = b.kind AND a.duedate = b.duedate AND a.notifier > b.notifier;
+ALTER TABLE reminder DROP COLUMN notifier;
+ALTER TABLE reminder ADD PRIMARY KEY (todo_id, kind, duedate);
+`,
+	},
+}
@@ -29,6 +29,13 @@ const (
 	Path string = "URLPath"
 	Port string = "Port"
 
-	RemoteAddr  string = "RemoteAddr"
-	ServiceName string = "ServiceName"
+	RemoteAddr    string = "RemoteAddr"
+	RequestID     string = "RequestID"
+	SchemaVersion string = "SchemaVersion"
+	ServiceName   string = "ServiceName"
+	StoreType     string = "StoreType"
+
+	ToDoID  string = "ToDoID"
+	TraceID string = "TraceID"
+	User    string = "User"
 )
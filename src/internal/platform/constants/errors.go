@@ -1,5 +1,7 @@
 //
-// **NOTE** When adding constants, please order them in alphabetical sequence
+// **NOTE** When adding constants, please order them in alphabetical sequence. The exception
+// is ErrCode values. These are returned to clients so they must not change, new ErrCodes
+// are added to the end of their range.
 //
 
 package constants
@@ -17,9 +19,15 @@ const (
 	// DBInsertDuplicateToDoError indicates an attempt to insert a duplicate row
 	DBInsertDuplicateToDoError = "attempt to insert duplicate todo"
 	// DBInvalidRequest indicates an invalid DB request, like attempting to update a non-existent todo
-	DBInvalidRequest = "attempted update on a non-existent todo"
+	DBInvalidRequest = "attempted update or delete of a non-existent todo"
+	// DBMigrationError indicates that the DB schema couldn't be migrated
+	DBMigrationError = "Unable to migrate DB schema"
+	// DBQueryError indicates that a DB query failed
+	DBQueryError = "DB query failed"
 	// DBRowScanError indicates results from DB query could not be processed
 	DBRowScanError = "DB resultset processing failed"
+	// DBSchemaVersionError indicates that the DB schema isn't the version todod requires
+	DBSchemaVersionError = "Unsupported DB schema version"
 	// DBUpSertError indications that there was a problem executing a DB insert or update operation
 	DBUpSertError = "DB insert or update failed"
 
@@ -37,13 +45,22 @@ const (
 	// MalformedURL indicates there was a problem with the structure of the URL
 	MalformedURL = "Malformed URL"
 
+	// OwnerlessRowsError indicates that there are todos, lists, or webhooks without an owner
+	OwnerlessRowsError = "DB has todos, lists, or webhooks without an owner"
+
 	// NoError is needed for situations where ErrCode is returned, but no error occurred
 	NoError = "No error occurred"
 
+	// ReminderError indicates that a reminder about a due or overdue todo couldn't be sent
+	ReminderError = "Unable to send reminder"
+
 	// RqstParsingError indicates that an error occurred while the path and/or body of the was
 	// being evaluated.
 	RqstParsingError = "Request parsing error"
 
+	// TracingError indicates that tracing couldn't be started
+	TracingError = "Unable to start tracing"
+
 	// UnableToCreateHTTPHandler indications that there was a problem creating an http handler
 	UnableToCreateHTTPHandler = "Unable to create HTTP handler"
 	// UnableToGetConfig indicates there was a problem obtaining the application configuration
@@ -58,11 +75,15 @@ const (
 	UnableToOpenConfig = "Unable to open configuration file"
 	// UnableToOpenDBConn indicates there was a problem opening a database connection
 	UnableToOpenDBConn = "Unable to open DB connection"
+	// UnsupportedMethod indicates that the request's HTTP method isn't supported
+	UnsupportedMethod = "Unsupported HTTP method"
 
 	//
 	// Todo related error codes start at 1000 and go to 1999
 	//
 
+	// ToDoNotFoundError indicates that the requested todo doesn't exist
+	ToDoNotFoundError = "todo not found"
 	// ToDoRqstError indicates that GET(or PUT) /todos or GET(or PUT) /todos/{id} failed in some way
 	ToDoRqstError = "GET /todos or GET /todos/{id} failed"
 	// ToDoTypeConversionError indicates that the payload returned from GET /todos/{id} could
@@ -70,6 +91,49 @@ const (
 	ToDoTypeConversionError = "Unable to convert payload to ToDo(s) type"
 	// ToDoValidationError indicates a problem with the ToDo data
 	ToDoValidationError = "invalid todo data"
+	// ToDoVersionConflictError indicates the todo was changed, or deleted, since the version
+	// the request was based on
+	ToDoVersionConflictError = "todo version conflict"
+	// ToDoInsertAbandonedError indicates that a bulk insert wasn't done, or its result is
+	// unknown, because the request was cancelled or todod is shutting down
+	ToDoInsertAbandonedError = "todo insert abandoned"
+
+	//
+	// Webhook related error codes start at 2000 and go to 2999
+	//
+
+	// WebhookDeliveryError indicates that an event couldn't be delivered to a webhook
+	WebhookDeliveryError = "Unable to deliver webhook event"
+	// WebhookNotFoundError indicates that the requested webhook doesn't exist
+	WebhookNotFoundError = "webhook not found"
+	// WebhookValidationError indicates a problem with the webhook data
+	WebhookValidationError = "invalid webhook data"
+
+	//
+	// List related error codes start at 3000 and go to 3999
+	//
+
+	// ListNotFoundError indicates that the requested list doesn't exist
+	ListNotFoundError = "list not found"
+	// ListShareNotFoundError indicates that the list isn't shared with the requested user
+	ListShareNotFoundError = "list share not found"
+	// ListValidationError indicates a problem with the list data, or the requested change
+	// to the list
+	ListValidationError = "invalid list data"
+
+	//
+	// User related error codes start at 4000 and go to 4999
+	//
+
+	// UserAuthenticationError indicates that the request's credentials are missing or invalid
+	UserAuthenticationError = "authentication required"
+	// UserExistsError indicates an attempt to create a user with a username that's taken
+	UserExistsError = "user already exists"
+	// UserForbiddenError indicates that the requester's role doesn't allow the request, e.g.,
+	// a viewer attempting to change a shared list's todos
+	UserForbiddenError = "insufficient permissions"
+	// UserValidationError indicates a problem with the user data
+	UserValidationError = "invalid user data"
 )
 
 // ErrCode is the application type for reporting error codes
@@ -84,7 +148,7 @@ const (
 	DBDeleteErrorCode ErrCode = iota
 	// DBInsertDuplicateToDoErrorCode indicates an attempt to insert a duplicate row
 	DBInsertDuplicateToDoErrorCode
-	// DBInvalidRequestCode indication of an invalid request, e.g., an update was attempted on an existing todo
+	// DBInvalidRequestCode indication of an invalid request, e.g., an update was attempted on a non-existent todo
 	DBInvalidRequestCode
 	// DBQueryErrorCode is the error code associated with DBQueryError
 	DBQueryErrorCode
@@ -127,6 +191,18 @@ const (
 	UnableToOpenConfigErrorCode
 	// UnableToOpenDBConnErrorCode is the error code associated with UnableToOpenDBConn
 	UnableToOpenDBConnErrorCode
+	// UnsupportedMethodErrorCode is the error code associated with UnsupportedMethod
+	UnsupportedMethodErrorCode
+	// ReminderErrorCode is the error code associated with ReminderError
+	ReminderErrorCode
+	// DBMigrationErrorCode is the error code associated with DBMigrationError
+	DBMigrationErrorCode
+	// DBSchemaVersionErrorCode is the error code associated with DBSchemaVersionError
+	DBSchemaVersionErrorCode
+	// TracingErrorCode is the error code associated with TracingError
+	TracingErrorCode
+	// OwnerlessRowsErrorCode is the error code associated with OwnerlessRowsError
+	OwnerlessRowsErrorCode
 )
 
 const (
@@ -140,4 +216,109 @@ const (
 	ToDoTypeConversionErrorCode
 	// ToDoValidationErrorCode indicates a problem with the ToDo data
 	ToDoValidationErrorCode
+	// ToDoVersionConflictErrorCode is the error code associated with ToDoVersionConflictError
+	ToDoVersionConflictErrorCode
+	// ToDoNotFoundErrorCode is the error code associated with ToDoNotFoundError
+	ToDoNotFoundErrorCode
+	// ToDoInsertAbandonedErrorCode is the error code associated with ToDoInsertAbandonedError
+	ToDoInsertAbandonedErrorCode
 )
+
+const (
+	//
+	// Webhook related error codes start at 2000 and go to 2999
+	//
+
+	// WebhookNotFoundErrorCode is the error code associated with WebhookNotFoundError
+	WebhookNotFoundErrorCode ErrCode = iota + 2000
+	// WebhookValidationErrorCode is the error code associated with WebhookValidationError
+	WebhookValidationErrorCode
+	// WebhookDeliveryErrorCode is the error code associated with WebhookDeliveryError
+	WebhookDeliveryErrorCode
+)
+
+const (
+	//
+	// List related error codes start at 3000 and go to 3999
+	//
+
+	// ListNotFoundErrorCode is the error code associated with ListNotFoundError
+	ListNotFoundErrorCode ErrCode = iota + 3000
+	// ListValidationErrorCode is the error code associated with ListValidationError
+	ListValidationErrorCode
+	// ListShareNotFoundErrorCode is the error code associated with ListShareNotFoundError
+	ListShareNotFoundErrorCode
+)
+
+const (
+	//
+	// User related error codes start at 4000 and go to 4999
+	//
+
+	// UserAuthenticationErrorCode is the error code associated with UserAuthenticationError
+	UserAuthenticationErrorCode ErrCode = iota + 4000
+	// UserExistsErrorCode is the error code associated with UserExistsError
+	UserExistsErrorCode
+	// UserValidationErrorCode is the error code associated with UserValidationError
+	UserValidationErrorCode
+	// UserForbiddenErrorCode is the error code associated with UserForbiddenError
+	UserForbiddenErrorCode
+)
+
+// errCodeMessages maps each ErrCode to the message that describes it
+var errCodeMessages = map[ErrCode]string{
+	DBDeleteErrorCode:                  DBDeleteError,
+	DBInsertDuplicateToDoErrorCode:     DBInsertDuplicateToDoError,
+	DBInvalidRequestCode:               DBInvalidRequest,
+	DBMigrationErrorCode:               DBMigrationError,
+	DBQueryErrorCode:                   DBQueryError,
+	DBRowScanErrorCode:                 DBRowScanError,
+	DBSchemaVersionErrorCode:           DBSchemaVersionError,
+	DBUpSertErrorCode:                  DBUpSertError,
+	HTTPWriteErrorCode:                 HTTPWriteError,
+	InvalidInsertErrorCode:             InvalidInsertError,
+	JSONDecodingErrorCode:              JSONDecodingError,
+	JSONMarshalingErrorCode:            JSONMarshalingError,
+	MalformedURLErrorCode:              MalformedURL,
+	NoErrorCode:                        NoError,
+	ReminderErrorCode:                  ReminderError,
+	RqstParsingErrorCode:               RqstParsingError,
+	TracingErrorCode:                   TracingError,
+	UnableToCreateHTTPHandlerErrorCode: UnableToCreateHTTPHandler,
+	UnableToGetConfigErrorCode:         UnableToGetConfig,
+	UnableToGetDBConnStrErrorCode:      UnableToGetDBConnStr,
+	UnableToLoadConfigErrorCode:        UnableToLoadConfig,
+	UnableToLoadSecretsErrorCode:       UnableToLoadSecrets,
+	UnableToOpenConfigErrorCode:        UnableToOpenConfig,
+	UnableToOpenDBConnErrorCode:        UnableToOpenDBConn,
+	UnsupportedMethodErrorCode:         UnsupportedMethod,
+
+	ToDoInsertAbandonedErrorCode: ToDoInsertAbandonedError,
+	ToDoNotFoundErrorCode:        ToDoNotFoundError,
+	ToDoRqstErrorCode:            ToDoRqstError,
+	ToDoTypeConversionErrorCode:  ToDoTypeConversionError,
+	ToDoValidationErrorCode:      ToDoValidationError,
+	ToDoVersionConflictErrorCode: ToDoVersionConflictError,
+
+	WebhookDeliveryErrorCode:   WebhookDeliveryError,
+	WebhookNotFoundErrorCode:   WebhookNotFoundError,
+	WebhookValidationErrorCode: WebhookValidationError,
+
+	ListNotFoundErrorCode:      ListNotFoundError,
+	ListValidationErrorCode:    ListValidationError,
+	ListShareNotFoundErrorCode: ListShareNotFoundError,
+
+	UserAuthenticationErrorCode: UserAuthenticationError,
+	UserExistsErrorCode:         UserExistsError,
+	UserValidationErrorCode:     UserValidationError,
+	UserForbiddenErrorCode:      UserForbiddenError,
+}
+
+// Message returns the message describing 'e', e.g., MalformedURL for MalformedURLErrorCode
+func (e ErrCode) Message() string {
+	msg, ok := errCodeMessages[e]
+	if !ok {
+		return "Unknown error"
+	}
+	return msg
+}
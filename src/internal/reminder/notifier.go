@@ -0,0 +1,84 @@
+package reminder
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+// LogNotifier writes reminders to a log
+type LogNotifier struct {
+	logger *log.Entry
+}
+
+// NewLogNotifier returns a *LogNotifier that writes reminders to 'logger'
+func NewLogNotifier(logger *log.Entry) (*LogNotifier, error) {
+	if logger == nil {
+		return nil, errors.New("non-nil log.Entry  required")
+	}
+	return &LogNotifier{logger: logger}, nil
+}
+
+// Name returns "log"
+func (n *LogNotifier) Name() string {
+	return "log"
+}
+
+// Notify logs 'r'
+func (n *LogNotifier) Notify(r Reminder) error {
+	n.logger.WithFields(log.Fields{
+		constants.ToDoID:        r.ToDo.ID,
+		constants.MessageDetail: r.ToDo.Note,
+	}).Infof("Reminder: todo is %s, due %s", r.Kind, r.ToDo.DueDate.Format(time.RFC3339))
+	return nil
+}
+
+// WebhookNotifier POSTs reminders, as JSON, to a URL
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier returns a *WebhookNotifier that POSTs reminders to 'url'
+func NewWebhookNotifier(url string, client *http.Client) (*WebhookNotifier, error) {
+	if len(url) == 0 {
+		return nil, errors.New("non-empty webhook URL required")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &WebhookNotifier{url: url, client: client}, nil
+}
+
+// Name returns "webhook"
+func (n *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+// Notify POSTs 'r' to the webhook. Any 2xx response is considered success.
+func (n *WebhookNotifier) Notify(r Reminder) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return errors.Annotate(err, "error marshaling reminder")
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return errors.Annotatef(err, "error POSTing reminder to %s", n.url)
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("webhook %s responded with status %d", n.url, resp.StatusCode)
+	}
+	return nil
+}
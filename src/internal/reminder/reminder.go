@@ -0,0 +1,211 @@
+// Package reminder sends reminders about todos that are overdue or will soon be due.
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+)
+
+// Kind identifies why a reminder was sent
+type Kind string
+
+const (
+	// Overdue reminders are sent for todos whose due date has passed
+	Overdue Kind = "overdue"
+	// DueSoon reminders are sent for todos that will be due within the Scheduler's window
+	DueSoon Kind = "due_soon"
+)
+
+// Reminder is a notification that a todo is overdue or will soon be due
+type Reminder struct {
+	Kind Kind      `json:"kind"`
+	ToDo todo.Item `json:"todo"`
+	// SentAt is when the Scheduler found the todo needed a reminder
+	SentAt time.Time `json:"sentat"`
+}
+
+// key identifies a reminder sent by 'n' so it's only sent once. A todo that's rolled
+// forward to its next occurrence has a new due date and so it's reminded about again.
+func (r Reminder) key(n Notifier) Key {
+	return Key{ToDoID: r.ToDo.ID, Kind: r.Kind, DueDate: r.ToDo.DueDate, Notifier: n.Name()}
+}
+
+// Notifier sends reminders somewhere, e.g., a log or a webhook
+type Notifier interface {
+	// Name identifies the notifier in the SentLog, it must not change and must be unique
+	// among a Scheduler's notifiers
+	Name() string
+	Notify(r Reminder) error
+}
+
+// Scheduler periodically looks for todos that are overdue, or will be due within its
+// window, and sends a reminder about each of them to its notifiers. The SentLog ensures
+// each notifier only sends each reminder once.
+type Scheduler struct {
+	store     todo.Store
+	sent      SentLog
+	notifiers []Notifier
+	interval  time.Duration
+	window    time.Duration
+	logger    *log.Entry
+	now       func() time.Time
+}
+
+// NewScheduler returns a *Scheduler that checks 'store' every 'interval' for todos that
+// are overdue or due within 'window'. A 'window' of 0 means only overdue todos are
+// reminded about.
+func NewScheduler(store todo.Store, sent SentLog, notifiers []Notifier, interval time.Duration,
+	window time.Duration, logger *log.Entry) (*Scheduler, error) {
+	if store == nil {
+		return nil, errors.New("non-nil todo.Store required")
+	}
+	if sent == nil {
+		return nil, errors.New("non-nil SentLog required")
+	}
+	if len(notifiers) == 0 {
+		return nil, errors.New("at least one Notifier required")
+	}
+	names := map[string]bool{}
+	for _, n := range notifiers {
+		if names[n.Name()] {
+			return nil, errors.Errorf("Notifier names must be unique, got %q more than once", n.Name())
+		}
+		names[n.Name()] = true
+	}
+	if interval <= 0 {
+		return nil, errors.Errorf("positive interval required, got %s", interval)
+	}
+	if window < 0 {
+		return nil, errors.Errorf("non-negative window required, got %s", window)
+	}
+	if logger == nil {
+		return nil, errors.New("non-nil log.Entry  required")
+	}
+
+	return &Scheduler{
+		store:     store,
+		sent:      sent,
+		notifiers: notifiers,
+		interval:  interval,
+		window:    window,
+		logger:    logger,
+		now:       time.Now,
+	}, nil
+}
+
+// Run checks for todos needing reminders every interval until 'done' is closed. Closing
+// 'done' also cancels a check that's in progress.
+func (s *Scheduler) Run(done chan interface{}) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	s.logger.Infof("Reminder scheduler started, interval %s, window %s", s.interval, s.window)
+	for {
+		s.Check(ctx)
+		select {
+		case <-done:
+			s.logger.Info("Reminder scheduler exiting")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check sends any reminders that are needed now. It stops if 'ctx' is done.
+func (s *Scheduler) Check(ctx context.Context) {
+	now := s.now()
+	completed := false
+	opts := todo.ListOptions{
+		Limit:     todo.MaxPageLimit,
+		Completed: &completed,
+		DueBefore: now.Add(s.window),
+		AllOwners: true,
+	}
+
+	for {
+		tdl, more, err := s.store.GetToDoListPage(ctx, opts)
+		if err != nil {
+			s.logger.WithFields(log.Fields{
+				constants.ErrorCode:   constants.DBQueryErrorCode,
+				constants.ErrorDetail: err.Error(),
+			}).Error(constants.DBQueryError)
+			return
+		}
+
+		for _, td := range tdl.Items {
+			if td.DueDate.IsZero() {
+				continue
+			}
+			kind := DueSoon
+			if !td.DueDate.After(now) {
+				kind = Overdue
+			}
+			s.remind(ctx, Reminder{Kind: kind, ToDo: *td, SentAt: now})
+		}
+
+		if !more || len(tdl.Items) == 0 {
+			return
+		}
+		opts.After = tdl.Items[len(tdl.Items)-1].ID
+	}
+}
+
+// remind sends 'r' to each of the notifiers that hasn't already sent it. Each notifier's
+// reminder is claimed separately, if a notifier fails its reminder is left unsent so it
+// will be tried again, without sending it again to the notifiers that succeeded.
+func (s *Scheduler) remind(ctx context.Context, r Reminder) {
+	for _, n := range s.notifiers {
+		s.notify(ctx, n, r)
+	}
+}
+
+// notify sends 'r' to 'n' unless 'n' has already sent it
+func (s *Scheduler) notify(ctx context.Context, n Notifier, r Reminder) {
+	k := r.key(n)
+	claimed, err := s.sent.Claim(ctx, k)
+	if err != nil {
+		s.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.ReminderErrorCode,
+			constants.ToDoID:      r.ToDo.ID,
+			constants.ErrorDetail: err.Error(),
+		}).Error(constants.ReminderError)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	err = n.Notify(r)
+	if err == nil {
+		return
+	}
+	s.logger.WithFields(log.Fields{
+		constants.ErrorCode:   constants.ReminderErrorCode,
+		constants.ToDoID:      r.ToDo.ID,
+		constants.ErrorDetail: fmt.Sprintf("%s: %s", n.Name(), err),
+	}).Error(constants.ReminderError)
+
+	err = s.sent.Release(ctx, k)
+	if err != nil {
+		s.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.ReminderErrorCode,
+			constants.ToDoID:      r.ToDo.ID,
+			constants.ErrorDetail: errors.Annotate(err, "unable to release unsent reminder").Error(),
+		}).Error(constants.ReminderError)
+	}
+}
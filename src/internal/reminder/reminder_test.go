@@ -0,0 +1,220 @@
+package reminder
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/juju/errors"
+	"github.com/youngkin/todoshaleapps/src/internal/logging"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+)
+
+var logger = logging.GetLogger()
+
+// testNotifier records the reminders it's sent, or fails if 'err' is set. Its name is
+// 'name', or "test" if that isn't set.
+type testNotifier struct {
+	name      string
+	reminders []Reminder
+	err       error
+}
+
+func (n *testNotifier) Name() string {
+	if len(n.name) == 0 {
+		return "test"
+	}
+	return n.name
+}
+
+func (n *testNotifier) Notify(r Reminder) error {
+	if n.err != nil {
+		return n.err
+	}
+	n.reminders = append(n.reminders, r)
+	return nil
+}
+
+func TestSchedulerCheck(t *testing.T) {
+	now := time.Date(2020, 4, 2, 12, 0, 0, 0, time.UTC)
+
+	store := todo.NewMemStore()
+	_, err := store.InsertToDos(context.Background(), []todo.Item{
+		{Note: "overdue", DueDate: now.Add(-time.Hour)},
+		{Note: "due soon", DueDate: now.Add(30 * time.Minute)},
+		{Note: "due later", DueDate: now.Add(2 * time.Hour)},
+		{Note: "completed", DueDate: now.Add(-time.Hour), Completed: true},
+		{Note: "no due date"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error populating the todo store: %s", err)
+	}
+
+	sent := NewMemSentLog()
+	notifier := &testNotifier{}
+	newScheduler := func(n Notifier) *Scheduler {
+		s, err := NewScheduler(store, sent, []Notifier{n}, time.Minute, time.Hour, logger)
+		if err != nil {
+			t.Fatalf("unexpected error creating scheduler: %s", err)
+		}
+		s.now = func() time.Time { return now }
+		return s
+	}
+
+	s := newScheduler(notifier)
+	s.Check(context.Background())
+	if len(notifier.reminders) != 2 {
+		t.Fatalf("expected 2 reminders, got %+v", notifier.reminders)
+	}
+	if r := notifier.reminders[0]; r.ToDo.ID != 1 || r.Kind != Overdue {
+		t.Errorf("expected overdue reminder for todo 1, got %+v", r)
+	}
+	if r := notifier.reminders[1]; r.ToDo.ID != 2 || r.Kind != DueSoon {
+		t.Errorf("expected due soon reminder for todo 2, got %+v", r)
+	}
+
+	// Neither checking again nor a new scheduler, e.g., after a restart, sends them again
+	s.Check(context.Background())
+	newScheduler(notifier).Check(context.Background())
+	if len(notifier.reminders) != 2 {
+		t.Errorf("expected reminders to only be sent once, got %+v", notifier.reminders)
+	}
+
+	// Once todo 2 is overdue it gets an overdue reminder
+	now = now.Add(time.Hour)
+	s.Check(context.Background())
+	if len(notifier.reminders) != 3 {
+		t.Fatalf("expected 3 reminders, got %+v", notifier.reminders)
+	}
+	if r := notifier.reminders[2]; r.ToDo.ID != 2 || r.Kind != Overdue {
+		t.Errorf("expected overdue reminder for todo 2, got %+v", r)
+	}
+
+	// Reminders that couldn't be sent are tried again
+	now = now.Add(30 * time.Minute)
+	newScheduler(&testNotifier{err: errors.New("notifier unavailable")}).Check(context.Background())
+	s.Check(context.Background())
+	if len(notifier.reminders) != 4 {
+		t.Fatalf("expected 4 reminders, got %+v", notifier.reminders)
+	}
+	if r := notifier.reminders[3]; r.ToDo.ID != 3 || r.Kind != DueSoon {
+		t.Errorf("expected due soon reminder for todo 3, got %+v", r)
+	}
+}
+
+// TestSchedulerFailingNotifier verifies that a reminder a notifier fails to send is tried
+// again, even though another notifier sent it, and that it's only sent once by each
+func TestSchedulerFailingNotifier(t *testing.T) {
+	now := time.Date(2020, 4, 2, 12, 0, 0, 0, time.UTC)
+	store := todo.NewMemStore()
+	_, err := store.InsertToDo(context.Background(), todo.Item{Note: "overdue", DueDate: now.Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("unexpected error populating the todo store: %s", err)
+	}
+
+	logNotifier := &testNotifier{name: "log"}
+	webhookNotifier := &testNotifier{name: "webhook", err: errors.New("webhook unavailable")}
+	s, err := NewScheduler(store, NewMemSentLog(), []Notifier{logNotifier, webhookNotifier}, time.Minute, time.Hour, logger)
+	if err != nil {
+		t.Fatalf("unexpected error creating scheduler: %s", err)
+	}
+	s.now = func() time.Time { return now }
+
+	s.Check(context.Background())
+	if len(logNotifier.reminders) != 1 || len(webhookNotifier.reminders) != 0 {
+		t.Fatalf("expected the reminder to be logged but not sent to the webhook, got %+v and %+v",
+			logNotifier.reminders, webhookNotifier.reminders)
+	}
+
+	webhookNotifier.err = nil
+	s.Check(context.Background())
+	s.Check(context.Background())
+	if len(logNotifier.reminders) != 1 || len(webhookNotifier.reminders) != 1 {
+		t.Errorf("expected the reminder to be sent once by each notifier, got %+v and %+v",
+			logNotifier.reminders, webhookNotifier.reminders)
+	}
+
+	_, err = NewScheduler(store, NewMemSentLog(), []Notifier{logNotifier, &testNotifier{name: "log"}}, time.Minute, time.Hour, logger)
+	if err == nil {
+		t.Error("expected an error creating a scheduler with notifiers with the same name")
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	var received Reminder
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := json.NewDecoder(r.Body).Decode(&received)
+		if err != nil {
+			t.Errorf("unexpected error decoding reminder: %s", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	n, err := NewWebhookNotifier(srv.URL, nil)
+	if err != nil {
+		t.Fatalf("unexpected error creating webhook notifier: %s", err)
+	}
+
+	r := Reminder{Kind: Overdue, ToDo: todo.Item{ID: 1, Note: "walk the dog"}}
+	err = n.Notify(r)
+	if err != nil {
+		t.Errorf("unexpected error notifying webhook: %s", err)
+	}
+	if received.Kind != r.Kind || received.ToDo.ID != r.ToDo.ID {
+		t.Errorf("expected webhook to receive %+v, got %+v", r, received)
+	}
+
+	status = http.StatusInternalServerError
+	err = n.Notify(r)
+	if err == nil {
+		t.Error("expected error when webhook fails")
+	}
+}
+
+func TestPGSentLog(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	k := Key{ToDoID: 1, Kind: Overdue, DueDate: time.Date(2020, 4, 2, 12, 0, 0, 0, time.UTC), Notifier: "webhook"}
+	mock.ExpectExec(regexp.QuoteMeta(claimReminderStmt)).
+		WithArgs(k.ToDoID, k.Kind, k.DueDate, k.Notifier).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(claimReminderStmt)).
+		WithArgs(k.ToDoID, k.Kind, k.DueDate, k.Notifier).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(releaseReminderStmt)).
+		WithArgs(k.ToDoID, k.Kind, k.DueDate, k.Notifier).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	l, err := NewPGSentLog(db, 0)
+	if err != nil {
+		t.Fatalf("unexpected error creating PGSentLog: %s", err)
+	}
+
+	claimed, err := l.Claim(context.Background(), k)
+	if err != nil || !claimed {
+		t.Errorf("expected first claim to succeed, got %t, %v", claimed, err)
+	}
+	claimed, err = l.Claim(context.Background(), k)
+	if err != nil || claimed {
+		t.Errorf("expected second claim to fail, got %t, %v", claimed, err)
+	}
+	err = l.Release(context.Background(), k)
+	if err != nil {
+		t.Errorf("unexpected error releasing reminder: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,124 @@
+package reminder
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// Key identifies a reminder sent by a notifier, see Notifier.Name
+type Key struct {
+	ToDoID   int64
+	Kind     Kind
+	DueDate  time.Time
+	Notifier string
+}
+
+// SentLog records which reminders have been sent
+type SentLog interface {
+	// Claim records that the reminder identified by 'k' is being sent. It returns false
+	// if the reminder has already been claimed, in which case it mustn't be sent again.
+	Claim(ctx context.Context, k Key) (bool, error)
+	// Release undoes a Claim for a reminder that couldn't be sent
+	Release(ctx context.Context, k Key) error
+}
+
+var (
+	claimReminderStmt   = "INSERT INTO reminder (todo_id, kind, duedate, notifier) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING"
+	releaseReminderStmt = "DELETE FROM reminder WHERE todo_id = $1 AND kind = $2 AND duedate = $3 AND notifier = $4"
+)
+
+// PGSentLog is a SentLog kept in the Postgres 'reminder' table so that reminders aren't
+// sent again when todod restarts
+type PGSentLog struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+// NewPGSentLog returns a *PGSentLog that will use the provided database connection. Each
+// operation is cancelled if it takes longer than 'timeout', 0 means operations are only
+// limited by their contexts.
+func NewPGSentLog(db *sql.DB, timeout time.Duration) (*PGSentLog, error) {
+	if db == nil {
+		return nil, errors.New("non-nil sql.DB connection required")
+	}
+	if timeout < 0 {
+		return nil, errors.New("non-negative timeout required")
+	}
+	return &PGSentLog{db: db, timeout: timeout}, nil
+}
+
+// withTimeout returns a copy of 'ctx' that's cancelled after the log's timeout, if it has
+// one. 'cancel' must be called when the operation completes.
+func (l *PGSentLog) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if l.timeout > 0 {
+		return context.WithTimeout(ctx, l.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// Claim inserts a row for the reminder, the insert does nothing if the row already exists
+func (l *PGSentLog) Claim(ctx context.Context, k Key) (bool, error) {
+	ctx, cancel := l.withTimeout(ctx)
+	defer cancel()
+
+	result, err := l.db.ExecContext(ctx, claimReminderStmt, k.ToDoID, k.Kind, k.DueDate, k.Notifier)
+	if err != nil {
+		return false, errors.Annotatef(err, "error claiming reminder %+v", k)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Annotatef(err, "error claiming reminder %+v", k)
+	}
+	return n == 1, nil
+}
+
+// Release deletes the reminder's row
+func (l *PGSentLog) Release(ctx context.Context, k Key) error {
+	ctx, cancel := l.withTimeout(ctx)
+	defer cancel()
+
+	_, err := l.db.ExecContext(ctx, releaseReminderStmt, k.ToDoID, k.Kind, k.DueDate, k.Notifier)
+	if err != nil {
+		return errors.Annotatef(err, "error releasing reminder %+v", k)
+	}
+	return nil
+}
+
+// MemSentLog is a SentLog kept in memory. It's intended for use with a todo.MemStore,
+// whose todos are also lost when the process exits.
+type MemSentLog struct {
+	mu   sync.Mutex
+	sent map[Key]bool
+}
+
+// NewMemSentLog returns an empty *MemSentLog
+func NewMemSentLog() *MemSentLog {
+	return &MemSentLog{sent: map[Key]bool{}}
+}
+
+// Claim records the reminder if it hasn't already been recorded
+func (l *MemSentLog) Claim(ctx context.Context, k Key) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	k.DueDate = k.DueDate.UTC() // so equal times are equal keys
+	if l.sent[k] {
+		return false, nil
+	}
+	l.sent[k] = true
+	return true, nil
+}
+
+// Release forgets the reminder
+func (l *MemSentLog) Release(ctx context.Context, k Key) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	k.DueDate = k.DueDate.UTC()
+	delete(l.sent, k)
+	return nil
+}
@@ -0,0 +1,149 @@
+// Package stream keeps a bounded log of recent todo change events so they can be streamed
+// to clients, e.g., as Server-Sent Events, and replayed to clients that reconnect.
+package stream
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/youngkin/todoshaleapps/src/internal/webhook"
+)
+
+const (
+	// DefaultLogSize is the default number of events kept for replay
+	DefaultLogSize = 1000
+	// subscriberBuffer is the number of events that can be waiting to be sent to a
+	// subscriber before it's considered too slow and is dropped
+	subscriberBuffer = 64
+)
+
+// ID identifies an event in a log. Epoch identifies the log, it's the time, in nanoseconds
+// since the Unix epoch, the log was created, so IDs from before a restart aren't mistaken for
+// events in the current log. Seq increases by one for each event, starting at 1.
+type ID struct {
+	Epoch int64
+	Seq   int64
+}
+
+// String returns the ID in the form '<epoch>-<seq>'
+func (id ID) String() string {
+	return fmt.Sprintf("%d-%d", id.Epoch, id.Seq)
+}
+
+// ParseID parses an ID in the form returned by ID.String. An ID without an epoch, e.g.,
+// '42', is from a log that's no longer running, it has an Epoch of 0.
+func ParseID(s string) (ID, error) {
+	epoch, seq := "0", s
+	if i := strings.Index(s, "-"); i >= 0 {
+		epoch, seq = s[:i], s[i+1:]
+	}
+	id := ID{}
+	var err error
+	id.Epoch, err = strconv.ParseInt(epoch, 10, 64)
+	if err == nil {
+		id.Seq, err = strconv.ParseInt(seq, 10, 64)
+	}
+	if err != nil || id.Epoch < 0 || id.Seq < 0 {
+		return ID{}, errors.Errorf("invalid event ID %q, expected '<epoch>-<seq>'", s)
+	}
+	return id, nil
+}
+
+// Entry is an event in the log
+type Entry struct {
+	ID    ID
+	Event webhook.Event
+}
+
+// Log is a bounded, in memory, log of events. Events are published to it and subscribers
+// are sent each event as it's published.
+type Log struct {
+	mu      sync.Mutex
+	entries []Entry
+	size    int
+	epoch   int64
+	lastSeq int64
+	subs    map[chan Entry]struct{}
+}
+
+// NewLog returns a *Log that keeps the most recent 'size' events
+func NewLog(size int) *Log {
+	if size <= 0 {
+		size = DefaultLogSize
+	}
+	return &Log{size: size, epoch: time.Now().UnixNano(), subs: map[chan Entry]struct{}{}}
+}
+
+// Epoch returns the epoch of the log's event IDs, see ID
+func (l *Log) Epoch() int64 {
+	return l.epoch
+}
+
+// Publish adds 'e' to the log and sends it to the subscribers. Subscribers that aren't
+// keeping up are dropped, their channel is closed, rather than delaying the publisher.
+func (l *Log) Publish(e webhook.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lastSeq++
+	entry := Entry{ID: ID{Epoch: l.epoch, Seq: l.lastSeq}, Event: e}
+	l.entries = append(l.entries, entry)
+	if len(l.entries) > l.size {
+		l.entries = l.entries[len(l.entries)-l.size:]
+	}
+
+	for ch := range l.subs {
+		select {
+		case ch <- entry:
+		default:
+			delete(l.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns the events published after the one identified by 'lastID', and a
+// channel that's sent each event published from now on. The zero ID means no earlier
+// events are wanted. 'complete' is false if some of the events after 'lastID' are no
+// longer in the log, or 'lastID' is unknown, e.g., it's from another epoch because todod
+// restarted. In that case all of the events in the log are returned.
+//
+// The channel is closed if the subscriber falls too far behind. 'cancel' must be called
+// when the subscriber is done.
+func (l *Log) Subscribe(lastID ID) (backlog []Entry, ch <-chan Entry, complete bool, cancel func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	complete = true
+	switch {
+	case lastID == ID{}:
+	case lastID.Epoch != l.epoch || lastID.Seq > l.lastSeq:
+		complete = false
+		backlog = append(backlog, l.entries...)
+	default:
+		first := l.lastSeq - int64(len(l.entries)) + 1
+		if lastID.Seq+1 < first {
+			complete = false
+			backlog = append(backlog, l.entries...)
+		} else {
+			backlog = append(backlog, l.entries[lastID.Seq+1-first:]...)
+		}
+	}
+
+	c := make(chan Entry, subscriberBuffer)
+	l.subs[c] = struct{}{}
+	cancel = func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, ok := l.subs[c]; ok {
+			delete(l.subs, c)
+			close(c)
+		}
+	}
+
+	return backlog, c, complete, cancel
+}
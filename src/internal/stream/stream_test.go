@@ -0,0 +1,142 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+	"github.com/youngkin/todoshaleapps/src/internal/webhook"
+)
+
+func TestSubscribe(t *testing.T) {
+	tcs := []struct {
+		testName         string
+		lastID           ID
+		otherEpoch       bool
+		expectedBacklog  []int64
+		expectedComplete bool
+	}{
+		{
+			testName:         "testNoLastID",
+			lastID:           ID{},
+			expectedComplete: true,
+		},
+		{
+			testName:         "testResume",
+			lastID:           ID{Seq: 3},
+			expectedBacklog:  []int64{4, 5},
+			expectedComplete: true,
+		},
+		{
+			testName:         "testUpToDate",
+			lastID:           ID{Seq: 5},
+			expectedComplete: true,
+		},
+		{
+			testName:         "testResumeOldestRetained",
+			lastID:           ID{Seq: 2},
+			expectedBacklog:  []int64{3, 4, 5},
+			expectedComplete: true,
+		},
+		{
+			testName:         "testResumeTooOld",
+			lastID:           ID{Seq: 1},
+			expectedBacklog:  []int64{3, 4, 5},
+			expectedComplete: false,
+		},
+		{
+			testName:         "testResumeUnknown",
+			lastID:           ID{Seq: 100},
+			expectedBacklog:  []int64{3, 4, 5},
+			expectedComplete: false,
+		},
+		{
+			// The ID is from before a restart, it's in the log's range but not its epoch
+			testName:         "testResumeOtherEpoch",
+			lastID:           ID{Seq: 3},
+			otherEpoch:       true,
+			expectedBacklog:  []int64{3, 4, 5},
+			expectedComplete: false,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			l := NewLog(3)
+			for i := int64(1); i <= 5; i++ {
+				l.Publish(webhook.NewEvent(webhook.ToDoCreated, todo.Item{ID: i}))
+			}
+
+			if tc.lastID != (ID{}) {
+				tc.lastID.Epoch = l.Epoch()
+				if tc.otherEpoch {
+					tc.lastID.Epoch--
+				}
+			}
+			backlog, ch, complete, cancel := l.Subscribe(tc.lastID)
+			defer cancel()
+
+			if complete != tc.expectedComplete {
+				t.Errorf("expected complete %t, got %t", tc.expectedComplete, complete)
+			}
+			if len(backlog) != len(tc.expectedBacklog) {
+				t.Fatalf("expected backlog %v, got %+v", tc.expectedBacklog, backlog)
+			}
+			for i, e := range backlog {
+				if e.ID.Seq != tc.expectedBacklog[i] || e.ID.Epoch != l.Epoch() || e.Event.ToDo.ID != tc.expectedBacklog[i] {
+					t.Errorf("expected backlog %v, got %+v", tc.expectedBacklog, backlog)
+				}
+			}
+
+			l.Publish(webhook.NewEvent(webhook.ToDoDeleted, todo.Item{ID: 6}))
+			e := <-ch
+			if e.ID.Seq != 6 || e.Event.Type != webhook.ToDoDeleted {
+				t.Errorf("expected event 6 to be sent to the subscriber, got %+v", e)
+			}
+		})
+	}
+}
+
+func TestSlowSubscriberDropped(t *testing.T) {
+	l := NewLog(10)
+	_, ch, _, cancel := l.Subscribe(ID{})
+	defer cancel()
+
+	for i := int64(1); i <= subscriberBuffer+1; i++ {
+		l.Publish(webhook.NewEvent(webhook.ToDoCreated, todo.Item{ID: i}))
+	}
+
+	n := 0
+	for range ch {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Errorf("expected %d events before the subscriber was dropped, got %d", subscriberBuffer, n)
+	}
+}
+
+func TestParseID(t *testing.T) {
+	tcs := []struct {
+		testName   string
+		id         string
+		expectedID ID
+		shouldPass bool
+	}{
+		{testName: "testEpochAndSeq", id: "1586833980000000000-42", expectedID: ID{Epoch: 1586833980000000000, Seq: 42}, shouldPass: true},
+		{testName: "testNoEpoch", id: "42", expectedID: ID{Seq: 42}, shouldPass: true},
+		{testName: "testNotANumber", id: "one", shouldPass: false},
+		{testName: "testNegativeSeq", id: "1586833980000000000--1", shouldPass: false},
+		{testName: "testNoSeq", id: "1586833980000000000-", shouldPass: false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			id, err := ParseID(tc.id)
+			if (err == nil) != tc.shouldPass {
+				t.Fatalf("expected shouldPass = %t, got error %v", tc.shouldPass, err)
+			}
+			if id != tc.expectedID {
+				t.Errorf("expected %+v, got %+v", tc.expectedID, id)
+			}
+		})
+	}
+}
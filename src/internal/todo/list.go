@@ -0,0 +1,41 @@
+package todo
+
+import (
+	"github.com/juju/errors"
+)
+
+// DefaultListID identifies the list that todos belong to when they're created without a
+// list ID. It's the list served by '/todos' and it can't be deleted. It has no owner, every
+// user keeps their own todos in it.
+const DefaultListID int64 = 1
+
+// ListInfo describes a named To Do list, e.g., "sprint" or "personal". Todos belong to
+// exactly one list, identified by Item.ListID.
+type ListInfo struct {
+	ID          int64  `json:"id"`
+	SelfRef     string `json:"selfref"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Owner       string `json:"owner"`
+}
+
+// ValidateList returns an error describing what's wrong with 'l's data, if anything
+func ValidateList(l ListInfo) error {
+	if len(l.Name) == 0 {
+		return errors.New("list name must be populated")
+	}
+	return nil
+}
+
+// visibleTo indicates if 'owner' can see 'l', i.e., 'l' is theirs or it has no owner
+func (l ListInfo) visibleTo(owner string) bool {
+	return l.Owner == owner || l.Owner == ""
+}
+
+// listID returns the ID of the list 'td' belongs to, DefaultListID if it isn't set
+func listID(td Item) int64 {
+	if td.ListID == 0 {
+		return DefaultListID
+	}
+	return td.ListID
+}
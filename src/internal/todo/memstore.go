@@ -0,0 +1,451 @@
+package todo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+// MemStore is a Store that keeps To Do items in memory. It's intended for local
+// development and testing where a database isn't available. Its contents are
+// lost when the process exits. Its operations don't wait so they ignore their contexts.
+type MemStore struct {
+	mu         sync.RWMutex
+	items      map[int64]Item
+	lastID     int64
+	lists      map[int64]ListInfo
+	lastListID int64
+	// shares maps list IDs to the roles of the users the list is shared with
+	shares map[int64]map[string]Role
+}
+
+// NewMemStore returns a *MemStore containing only the default list
+func NewMemStore() *MemStore {
+	return &MemStore{
+		items:      make(map[int64]Item),
+		lists:      map[int64]ListInfo{DefaultListID: {ID: DefaultListID, Name: "default"}},
+		lastListID: DefaultListID,
+		shares:     make(map[int64]map[string]Role),
+	}
+}
+
+// GetToDoList will return all of the ToDo items belonging to 'owner' ordered by ID
+func (s *MemStore) GetToDoList(ctx context.Context, owner string) (List, error) {
+	return s.getToDoList(ListOptions{Owner: owner})
+}
+
+// getToDoList will return all of the ToDo items belonging to the owner, or owners,
+// selected by 'opts' ordered by ID
+func (s *MemStore) getToDoList(opts ListOptions) (List, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	ids := make([]int64, 0, len(s.items))
+	for id, td := range s.items {
+		if opts.AllOwners || td.Owner == opts.Owner {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	tdl := List{}
+	for _, id := range ids {
+		td := s.items[id]
+		tdl.Items = append(tdl.Items, &td)
+	}
+
+	return tdl, nil
+}
+
+// GetToDoListPage will return, in the order specified by 'opts', up to opts.Limit ToDo
+// items following opts.After that satisfy the filters in 'opts'. 'more' will be true if
+// there are additional items after the returned page. ErrUnknownCursor is returned if
+// opts.After doesn't identify one of opts.Owner's todos.
+func (s *MemStore) GetToDoListPage(ctx context.Context, opts ListOptions) (List, bool, error) {
+	all, err := s.getToDoList(opts)
+	if err != nil {
+		return List{}, false, err
+	}
+
+	sort.SliceStable(all.Items, func(i, j int) bool {
+		return opts.less(*all.Items[i], *all.Items[j])
+	})
+
+	var after *Item
+	if opts.After > 0 {
+		s.mu.RLock()
+		if td, ok := s.items[opts.After]; ok && (opts.AllOwners || td.Owner == opts.Owner) {
+			after = &td
+		}
+		s.mu.RUnlock()
+		if after == nil {
+			return List{}, false, ErrUnknownCursor
+		}
+	}
+
+	limit := opts.limit()
+	tdl := List{}
+	more := false
+	for _, td := range all.Items {
+		if (after != nil && !opts.less(*after, *td)) || !opts.matches(*td) {
+			continue
+		}
+		if len(tdl.Items) == limit {
+			more = true
+			break
+		}
+		tdl.Items = append(tdl.Items, td)
+	}
+
+	return tdl, more, nil
+}
+
+// GetToDoItem will return the todo identified by 'id', and belonging to 'owner', or a nil
+// todo if there wasn't a matching todo.
+func (s *MemStore) GetToDoItem(ctx context.Context, owner string, id int) (*Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	td, ok := s.items[int64(id)]
+	if !ok || td.Owner != owner {
+		return nil, nil
+	}
+
+	return &td, nil
+}
+
+// InsertToDo takes the provided todo data, stores it, and returns the newly created todo ID.
+func (s *MemStore) InsertToDo(ctx context.Context, td Item) (int64, error) {
+	err := ValidateToDo(td)
+	if err != nil {
+		return 0, errors.Annotate(err, "ToDo validation failure")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	td.ListID = listID(td)
+	if l, ok := s.lists[td.ListID]; !ok || !l.visibleTo(td.Owner) {
+		return 0, errors.Errorf("list %d doesn't exist", td.ListID)
+	}
+	s.lastID++
+	td.ID = s.lastID
+	td.SelfRef = ""
+	td.Version = InitialVersion
+	s.items[td.ID] = td
+
+	return td.ID, nil
+}
+
+// InsertToDos validates all of the todos before inserting any of them
+func (s *MemStore) InsertToDos(ctx context.Context, tds []Item) ([]int64, error) {
+	for i, td := range tds {
+		err := ValidateToDo(td)
+		if err != nil {
+			return nil, errors.Annotate(err, fmt.Sprintf("ToDo validation failure, todo %d", i))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, td := range tds {
+		if l, ok := s.lists[listID(td)]; !ok || !l.visibleTo(td.Owner) {
+			return nil, errors.Errorf("list %d doesn't exist, todo %d", listID(td), i)
+		}
+	}
+
+	ids := make([]int64, 0, len(tds))
+	for _, td := range tds {
+		td.ListID = listID(td)
+		s.lastID++
+		td.ID = s.lastID
+		td.SelfRef = ""
+		td.Version = InitialVersion
+		s.items[td.ID] = td
+		ids = append(ids, td.ID)
+	}
+
+	return ids, nil
+}
+
+// UpdateToDo takes the provided todo data and replaces the stored todo identified by td.ID.
+// If td.Version is non-zero the todo is only replaced if its version matches, otherwise
+// ToDoVersionConflictErrorCode is returned. DBInvalidRequestCode is returned if the todo
+// doesn't exist.
+func (s *MemStore) UpdateToDo(ctx context.Context, td Item) (constants.ErrCode, error) {
+	err := ValidateToDo(td)
+	if err != nil {
+		return constants.DBUpSertErrorCode, errors.Annotate(err, "ToDo validation failure")
+	}
+	td = completeOccurrence(td)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cur, ok := s.items[td.ID]
+	ok = ok && cur.Owner == td.Owner
+	if errCode, err := checkVersion(td.ID, cur, ok, td.Version); err != nil {
+		return errCode, err
+	}
+	if !ok {
+		return constants.DBInvalidRequestCode, errors.Errorf("todo %d doesn't exist", td.ID)
+	}
+	if td.ListID == 0 {
+		td.ListID = cur.ListID
+	}
+	if l, ok := s.lists[td.ListID]; !ok || !l.visibleTo(td.Owner) {
+		return constants.ListNotFoundErrorCode, errors.Errorf("list %d doesn't exist", td.ListID)
+	}
+	td.SelfRef = ""
+	td.Version = cur.Version + 1
+	s.items[td.ID] = td
+
+	return constants.NoErrorCode, nil
+}
+
+// PatchToDo atomically applies the JSON Merge Patch, 'patch', to the todo identified
+// by 'id' and returns the updated todo, or a nil todo if there wasn't a matching todo.
+// If 'version' is non-zero the todo is only patched if its version matches, otherwise
+// ToDoVersionConflictErrorCode is returned.
+func (s *MemStore) PatchToDo(ctx context.Context, owner string, id int, patch []byte, version int64) (*Item, constants.ErrCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	td, ok := s.items[int64(id)]
+	ok = ok && td.Owner == owner
+	if errCode, err := checkVersion(int64(id), td, ok, version); err != nil {
+		return nil, errCode, err
+	}
+	if !ok {
+		return nil, constants.NoErrorCode, nil
+	}
+
+	patched, errCode, err := MergePatch(td, patch)
+	if err != nil {
+		return nil, errCode, err
+	}
+	patched = completeOccurrence(patched)
+	if l, ok := s.lists[patched.ListID]; !ok || !l.visibleTo(owner) {
+		return nil, constants.ListNotFoundErrorCode, errors.Errorf("list %d doesn't exist", patched.ListID)
+	}
+	patched.Version++
+	s.items[patched.ID] = patched
+
+	return &patched, constants.NoErrorCode, nil
+}
+
+// DeleteToDo deletes the todo identified by 'id', and belonging to 'owner'. If 'version' is
+// non-zero the todo is only deleted if its version matches, otherwise
+// ToDoVersionConflictErrorCode is returned. DBInvalidRequestCode is returned if the todo
+// doesn't exist.
+func (s *MemStore) DeleteToDo(ctx context.Context, owner string, id int, version int64) (constants.ErrCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	td, ok := s.items[int64(id)]
+	ok = ok && td.Owner == owner
+	if errCode, err := checkVersion(int64(id), td, ok, version); err != nil {
+		return errCode, err
+	}
+	if !ok {
+		return constants.DBInvalidRequestCode, errors.Errorf("todo %d doesn't exist", id)
+	}
+	delete(s.items, int64(id))
+
+	return constants.NoErrorCode, nil
+}
+
+// checkVersion reports a version conflict if 'version' is non-zero and 'td', identified by
+// 'id', doesn't exist or is at a different version. 'exists' indicates if 'td' exists.
+func checkVersion(id int64, td Item, exists bool, version int64) (constants.ErrCode, error) {
+	if version != 0 && (!exists || td.Version != version) {
+		return constants.ToDoVersionConflictErrorCode, errors.Errorf("todo %d doesn't exist at version %d", id, version)
+	}
+	return constants.NoErrorCode, nil
+}
+
+// GetToDoItemRole returns the todo identified by 'id' and 'username's role on it, or a nil
+// todo if it doesn't exist or isn't visible to 'username'
+func (s *MemStore) GetToDoItemRole(ctx context.Context, username string, id int) (*Item, Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	td, ok := s.items[int64(id)]
+	if !ok {
+		return nil, "", nil
+	}
+	if td.Owner == username {
+		return &td, RoleOwner, nil
+	}
+	if role, ok := s.shares[td.ListID][username]; ok {
+		return &td, role, nil
+	}
+
+	return nil, "", nil
+}
+
+// GetLists returns all of the lists visible to 'owner', including those shared with it, in
+// ID order
+func (s *MemStore) GetLists(ctx context.Context, owner string) ([]ListInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	lists := make([]ListInfo, 0, len(s.lists))
+	for _, l := range s.lists {
+		if _, shared := s.shares[l.ID][owner]; shared || l.visibleTo(owner) {
+			lists = append(lists, l)
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool { return lists[i].ID < lists[j].ID })
+
+	return lists, nil
+}
+
+// GetList returns the list identified by 'id' or nil if there isn't one visible to 'owner'
+func (s *MemStore) GetList(ctx context.Context, owner string, id int64) (*ListInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.lists[id]
+	if !ok || !l.visibleTo(owner) {
+		return nil, nil
+	}
+
+	return &l, nil
+}
+
+// GetListRole returns the list identified by 'id' and 'username's role on it, or a nil list
+// if it doesn't exist or isn't visible to 'username'
+func (s *MemStore) GetListRole(ctx context.Context, username string, id int64) (*ListInfo, Role, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	l, ok := s.lists[id]
+	if !ok {
+		return nil, "", nil
+	}
+	if l.visibleTo(username) {
+		return &l, RoleOwner, nil
+	}
+	if role, ok := s.shares[id][username]; ok {
+		return &l, role, nil
+	}
+
+	return nil, "", nil
+}
+
+// InsertList stores 'l' and returns its newly created ID
+func (s *MemStore) InsertList(ctx context.Context, l ListInfo) (int64, error) {
+	err := ValidateList(l)
+	if err != nil {
+		return 0, errors.Annotate(err, "list validation failure")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastListID++
+	l.ID = s.lastListID
+	l.SelfRef = ""
+	s.lists[l.ID] = l
+
+	return l.ID, nil
+}
+
+// UpdateList replaces the name and description of the list identified by l.ID, and owned
+// by l.Owner. ListNotFoundErrorCode is returned if the list doesn't exist.
+func (s *MemStore) UpdateList(ctx context.Context, l ListInfo) (constants.ErrCode, error) {
+	err := ValidateList(l)
+	if err != nil {
+		return constants.ListValidationErrorCode, errors.Annotate(err, "list validation failure")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cur, ok := s.lists[l.ID]; !ok || cur.Owner != l.Owner {
+		return constants.ListNotFoundErrorCode, errors.Errorf("list %d doesn't exist", l.ID)
+	}
+	l.SelfRef = ""
+	s.lists[l.ID] = l
+
+	return constants.NoErrorCode, nil
+}
+
+// DeleteList deletes the list identified by 'id', and owned by 'owner', and all of its todos
+func (s *MemStore) DeleteList(ctx context.Context, owner string, id int64) (constants.ErrCode, error) {
+	if id == DefaultListID {
+		return constants.ListValidationErrorCode, errors.New("the default list can't be deleted")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if l, ok := s.lists[id]; !ok || l.Owner != owner {
+		return constants.ListNotFoundErrorCode, errors.Errorf("list %d doesn't exist", id)
+	}
+	for tdID, td := range s.items {
+		if td.ListID == id {
+			delete(s.items, tdID)
+		}
+	}
+	delete(s.lists, id)
+	delete(s.shares, id)
+
+	return constants.NoErrorCode, nil
+}
+
+// GetShares returns the shares of the list identified by 'listID' ordered by username
+func (s *MemStore) GetShares(ctx context.Context, listID int64) ([]Share, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	shares := make([]Share, 0, len(s.shares[listID]))
+	for username, role := range s.shares[listID] {
+		shares = append(shares, Share{ListID: listID, Username: username, Role: role})
+	}
+	sort.Slice(shares, func(i, j int) bool { return shares[i].Username < shares[j].Username })
+
+	return shares, nil
+}
+
+// PutShare grants s.Username s.Role on the list identified by s.ListID, replacing any role
+// they already have. 'created' is true if they didn't have one.
+func (s *MemStore) PutShare(ctx context.Context, sh Share) (bool, constants.ErrCode, error) {
+	err := ValidateShare(sh)
+	if err != nil {
+		return false, constants.ListValidationErrorCode, errors.Annotate(err, "share validation failure")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.lists[sh.ListID]; !ok {
+		return false, constants.ListNotFoundErrorCode, errors.Errorf("list %d doesn't exist", sh.ListID)
+	}
+	if s.shares[sh.ListID] == nil {
+		s.shares[sh.ListID] = make(map[string]Role)
+	}
+	_, exists := s.shares[sh.ListID][sh.Username]
+	s.shares[sh.ListID][sh.Username] = sh.Role
+
+	return !exists, constants.NoErrorCode, nil
+}
+
+// DeleteShare revokes 'username's access to the list identified by 'listID'
+func (s *MemStore) DeleteShare(ctx context.Context, listID int64, username string) (constants.ErrCode, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.shares[listID][username]; !ok {
+		return constants.ListShareNotFoundErrorCode, errors.Errorf("list %d isn't shared with %s", listID, username)
+	}
+	delete(s.shares[listID], username)
+
+	return constants.NoErrorCode, nil
+}
@@ -0,0 +1,490 @@
+package todo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+func TestMemStore(t *testing.T) {
+	var s Store = NewMemStore()
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	id, err := s.InsertToDo(context.Background(), Item{Note: "walk the dog", DueDate: date, Repeat: true})
+	if err != nil {
+		t.Fatalf("unexpected error inserting todo: %s", err)
+	}
+	if id != 1 {
+		t.Errorf("expected ID 1, got %d", id)
+	}
+
+	_, err = s.InsertToDo(context.Background(), Item{Note: ""})
+	if err == nil {
+		t.Error("expected validation error inserting todo with empty note")
+	}
+
+	_, err = s.InsertToDo(context.Background(), Item{Note: "get groceries", DueDate: date})
+	if err != nil {
+		t.Fatalf("unexpected error inserting todo: %s", err)
+	}
+
+	tdl, err := s.GetToDoList(context.Background(), "")
+	if err != nil {
+		t.Fatalf("unexpected error getting todo list: %s", err)
+	}
+	if len(tdl.Items) != 2 || tdl.Items[0].ID != 1 || tdl.Items[1].ID != 2 {
+		t.Errorf("expected todos 1 and 2 in order, got %+v", tdl.Items)
+	}
+
+	_, err = s.UpdateToDo(context.Background(), Item{ID: 1, Note: "walk the cat", DueDate: date, Completed: true})
+	if err != nil {
+		t.Fatalf("unexpected error updating todo: %s", err)
+	}
+	td, err := s.GetToDoItem(context.Background(), "", 1)
+	if err != nil {
+		t.Fatalf("unexpected error getting todo: %s", err)
+	}
+	if td == nil || td.Note != "walk the cat" || !td.Completed {
+		t.Errorf("expected updated todo, got %+v", td)
+	}
+
+	_, err = s.DeleteToDo(context.Background(), "", 1, 0)
+	if err != nil {
+		t.Fatalf("unexpected error deleting todo: %s", err)
+	}
+	td, err = s.GetToDoItem(context.Background(), "", 1)
+	if err != nil {
+		t.Fatalf("unexpected error getting todo: %s", err)
+	}
+	if td != nil {
+		t.Errorf("expected deleted todo to be nil, got %+v", td)
+	}
+}
+
+func TestMemStorePaging(t *testing.T) {
+	s := NewMemStore()
+	for _, note := range []string{"one", "two", "three"} {
+		if _, err := s.InsertToDo(context.Background(), Item{Note: note}); err != nil {
+			t.Fatalf("unexpected error inserting todo: %s", err)
+		}
+	}
+
+	tdl, more, err := s.GetToDoListPage(context.Background(), ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error getting first page: %s", err)
+	}
+	if !more || len(tdl.Items) != 2 || tdl.Items[1].ID != 2 {
+		t.Errorf("expected 2 items and more, got %+v, more = %t", tdl.Items, more)
+	}
+
+	tdl, more, err = s.GetToDoListPage(context.Background(), ListOptions{After: 2, Limit: 2})
+	if err != nil {
+		t.Fatalf("unexpected error getting second page: %s", err)
+	}
+	if more || len(tdl.Items) != 1 || tdl.Items[0].ID != 3 {
+		t.Errorf("expected only item 3 and no more, got %+v, more = %t", tdl.Items, more)
+	}
+
+	// Neither a deleted todo nor another user's todo can be paged after
+	if _, err := s.DeleteToDo(context.Background(), "", 2, 0); err != nil {
+		t.Fatalf("unexpected error deleting todo: %s", err)
+	}
+	if _, _, err = s.GetToDoListPage(context.Background(), ListOptions{After: 2, Limit: 2}); err != ErrUnknownCursor {
+		t.Errorf("expected ErrUnknownCursor paging after a deleted todo, got %v", err)
+	}
+	if _, _, err = s.GetToDoListPage(context.Background(), ListOptions{After: 1, Limit: 2, Owner: "jdoe"}); err != ErrUnknownCursor {
+		t.Errorf("expected ErrUnknownCursor paging after another user's todo, got %v", err)
+	}
+}
+
+func TestMemStoreFiltering(t *testing.T) {
+	s := NewMemStore()
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+	for _, td := range []Item{
+		{Note: "done", DueDate: date, Completed: true},
+		{Note: "open, due early", DueDate: date},
+		{Note: "open, due late", DueDate: date.AddDate(0, 0, 7), Repeat: true},
+	} {
+		if _, err := s.InsertToDo(context.Background(), td); err != nil {
+			t.Fatalf("unexpected error inserting todo: %s", err)
+		}
+	}
+
+	no := false
+	tdl, _, err := s.GetToDoListPage(context.Background(), ListOptions{Completed: &no, DueBefore: date.AddDate(0, 0, 1)})
+	if err != nil {
+		t.Fatalf("unexpected error getting filtered list: %s", err)
+	}
+	if len(tdl.Items) != 1 || tdl.Items[0].ID != 2 {
+		t.Errorf("expected only item 2, got %+v", tdl.Items)
+	}
+}
+
+func TestMemStoreSorting(t *testing.T) {
+	s := NewMemStore()
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+	for _, td := range []Item{
+		{Note: "a", DueDate: date},
+		{Note: "b", DueDate: date.AddDate(0, 0, 2)},
+		{Note: "c", DueDate: date.AddDate(0, 0, 1)},
+		{Note: "d", DueDate: date.AddDate(0, 0, 1)},
+	} {
+		if _, err := s.InsertToDo(context.Background(), td); err != nil {
+			t.Fatalf("unexpected error inserting todo: %s", err)
+		}
+	}
+
+	opts := ListOptions{SortBy: SortByDueDate, SortDesc: true, Limit: 2}
+	var ids []int64
+	for {
+		tdl, more, err := s.GetToDoListPage(context.Background(), opts)
+		if err != nil {
+			t.Fatalf("unexpected error getting sorted page: %s", err)
+		}
+		for _, td := range tdl.Items {
+			ids = append(ids, td.ID)
+		}
+		if !more {
+			break
+		}
+		opts.After = tdl.Items[len(tdl.Items)-1].ID
+	}
+
+	expected := []int64{2, 4, 3, 1}
+	if !reflect.DeepEqual(expected, ids) {
+		t.Errorf("expected IDs %v, got %v", expected, ids)
+	}
+
+	// Notes are sorted in byte order, as the PGStore's "C" collation does, so upper case
+	// letters come before lower case ones
+	for _, note := range []string{"Zebra", "apple"} {
+		if _, err := s.InsertToDo(context.Background(), Item{Note: note, DueDate: date}); err != nil {
+			t.Fatalf("unexpected error inserting todo: %s", err)
+		}
+	}
+	tdl, _, err := s.GetToDoListPage(context.Background(), ListOptions{SortBy: SortByNote})
+	if err != nil {
+		t.Fatalf("unexpected error getting sorted page: %s", err)
+	}
+	var notes []string
+	for _, td := range tdl.Items {
+		notes = append(notes, td.Note)
+	}
+	expectedNotes := []string{"Zebra", "a", "apple", "b", "c", "d"}
+	if !reflect.DeepEqual(expectedNotes, notes) {
+		t.Errorf("expected notes %v, got %v", expectedNotes, notes)
+	}
+}
+
+func TestMemStoreVersions(t *testing.T) {
+	s := NewMemStore()
+	id, err := s.InsertToDo(context.Background(), Item{Note: "walk the dog"})
+	if err != nil {
+		t.Fatalf("unexpected error inserting todo: %s", err)
+	}
+
+	errCode, err := s.UpdateToDo(context.Background(), Item{ID: id, Note: "walk the cat", Version: InitialVersion})
+	if err != nil {
+		t.Fatalf("unexpected error updating todo at current version, error code %d: %s", errCode, err)
+	}
+
+	errCode, _ = s.UpdateToDo(context.Background(), Item{ID: id, Note: "walk the bird", Version: InitialVersion})
+	if errCode != constants.ToDoVersionConflictErrorCode {
+		t.Errorf("expected version conflict updating stale todo, got error code %d", errCode)
+	}
+
+	td, errCode, err := s.PatchToDo(context.Background(), "", int(id), []byte(`{"completed":true}`), InitialVersion+1)
+	if err != nil {
+		t.Fatalf("unexpected error patching todo at current version, error code %d: %s", errCode, err)
+	}
+	if td.Version != InitialVersion+2 || td.Note != "walk the cat" {
+		t.Errorf("expected 'walk the cat' at version %d, got %+v", InitialVersion+2, td)
+	}
+
+	errCode, _ = s.DeleteToDo(context.Background(), "", int(id), InitialVersion)
+	if errCode != constants.ToDoVersionConflictErrorCode {
+		t.Errorf("expected version conflict deleting stale todo, got error code %d", errCode)
+	}
+	errCode, _ = s.DeleteToDo(context.Background(), "", int(id)+1, InitialVersion)
+	if errCode != constants.ToDoVersionConflictErrorCode {
+		t.Errorf("expected version conflict deleting non-existent todo, got error code %d", errCode)
+	}
+}
+
+func TestMemStoreInsertToDos(t *testing.T) {
+	s := NewMemStore()
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	_, err := s.InsertToDos(context.Background(), []Item{{Note: "walk the dog", DueDate: date}, {Note: ""}})
+	if err == nil {
+		t.Error("expected validation error inserting todos with an empty note")
+	}
+	tdl, err := s.GetToDoList(context.Background(), "")
+	if err != nil {
+		t.Fatalf("unexpected error getting todo list: %s", err)
+	}
+	if len(tdl.Items) != 0 {
+		t.Errorf("expected no todos after failed insert, got %+v", tdl.Items)
+	}
+
+	ids, err := s.InsertToDos(context.Background(), []Item{{Note: "walk the dog", DueDate: date}, {Note: "get groceries", DueDate: date}})
+	if err != nil {
+		t.Fatalf("unexpected error inserting todos: %s", err)
+	}
+	if !reflect.DeepEqual(ids, []int64{1, 2}) {
+		t.Errorf("expected IDs [1 2], got %v", ids)
+	}
+	td, err := s.GetToDoItem(context.Background(), "", 2)
+	if err != nil {
+		t.Fatalf("unexpected error getting todo: %s", err)
+	}
+	if td == nil || td.Note != "get groceries" || td.Version != InitialVersion {
+		t.Errorf("expected inserted todo 2, got %+v", td)
+	}
+}
+
+func TestMemStoreLists(t *testing.T) {
+	s := NewMemStore()
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	lists, err := s.GetLists(context.Background(), "ryoungkin")
+	if err != nil {
+		t.Fatalf("unexpected error getting lists: %s", err)
+	}
+	if len(lists) != 1 || lists[0].ID != DefaultListID {
+		t.Errorf("expected only the default list, got %+v", lists)
+	}
+
+	_, err = s.InsertList(context.Background(), ListInfo{})
+	if err == nil {
+		t.Error("expected validation error inserting list with empty name")
+	}
+	listID, err := s.InsertList(context.Background(), ListInfo{Name: "sprint", Owner: "ryoungkin"})
+	if err != nil {
+		t.Fatalf("unexpected error inserting list: %s", err)
+	}
+
+	_, err = s.InsertToDo(context.Background(), Item{Note: "walk the dog", DueDate: date})
+	if err != nil {
+		t.Fatalf("unexpected error inserting todo: %s", err)
+	}
+	_, err = s.InsertToDo(context.Background(), Item{Note: "fix bug", DueDate: date, ListID: listID, Owner: "ryoungkin"})
+	if err != nil {
+		t.Fatalf("unexpected error inserting todo: %s", err)
+	}
+	_, err = s.InsertToDo(context.Background(), Item{Note: "lost", DueDate: date, ListID: 100})
+	if err == nil {
+		t.Error("expected error inserting todo into non-existent list")
+	}
+
+	tdl, _, err := s.GetToDoListPage(context.Background(), ListOptions{ListID: listID, Owner: "ryoungkin"})
+	if err != nil {
+		t.Fatalf("unexpected error getting todo list page: %s", err)
+	}
+	if len(tdl.Items) != 1 || tdl.Items[0].Note != "fix bug" {
+		t.Errorf("expected only the sprint list's todo, got %+v", tdl.Items)
+	}
+
+	// Moving a todo to a non-existent list fails, a zero ListID leaves it where it is
+	errCode, _ := s.UpdateToDo(context.Background(), Item{ID: 1, Note: "walk the dog", DueDate: date, ListID: 100})
+	if errCode != constants.ListNotFoundErrorCode {
+		t.Errorf("expected list not found updating todo, got error code %d", errCode)
+	}
+	_, err = s.UpdateToDo(context.Background(), Item{ID: 2, Note: "fix bugs", DueDate: date, Owner: "ryoungkin"})
+	if err != nil {
+		t.Fatalf("unexpected error updating todo: %s", err)
+	}
+	td, _ := s.GetToDoItem(context.Background(), "ryoungkin", 2)
+	if td == nil || td.ListID != listID {
+		t.Errorf("expected todo to remain in list %d, got %+v", listID, td)
+	}
+
+	errCode, _ = s.UpdateList(context.Background(), ListInfo{ID: 100, Name: "missing", Owner: "ryoungkin"})
+	if errCode != constants.ListNotFoundErrorCode {
+		t.Errorf("expected list not found updating list, got error code %d", errCode)
+	}
+	errCode, _ = s.DeleteList(context.Background(), "ryoungkin", DefaultListID)
+	if errCode != constants.ListValidationErrorCode {
+		t.Errorf("expected validation error deleting the default list, got error code %d", errCode)
+	}
+
+	_, err = s.DeleteList(context.Background(), "ryoungkin", listID)
+	if err != nil {
+		t.Fatalf("unexpected error deleting list: %s", err)
+	}
+	l, _ := s.GetList(context.Background(), "ryoungkin", listID)
+	td, _ = s.GetToDoItem(context.Background(), "ryoungkin", 2)
+	if l != nil || td != nil {
+		t.Errorf("expected list and its todos to be deleted, got %+v and %+v", l, td)
+	}
+	td, _ = s.GetToDoItem(context.Background(), "", 1)
+	if td == nil {
+		t.Error("expected the default list's todo to remain")
+	}
+}
+
+func TestMemStoreOwners(t *testing.T) {
+	s := NewMemStore()
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	id, err := s.InsertToDo(context.Background(), Item{Note: "walk the dog", DueDate: date, Owner: "ryoungkin"})
+	if err != nil {
+		t.Fatalf("unexpected error inserting todo: %s", err)
+	}
+	_, err = s.InsertToDo(context.Background(), Item{Note: "get groceries", DueDate: date, Owner: "jdoe"})
+	if err != nil {
+		t.Fatalf("unexpected error inserting todo: %s", err)
+	}
+	listID, err := s.InsertList(context.Background(), ListInfo{Name: "sprint", Owner: "ryoungkin"})
+	if err != nil {
+		t.Fatalf("unexpected error inserting list: %s", err)
+	}
+
+	tdl, err := s.GetToDoList(context.Background(), "ryoungkin")
+	if err != nil {
+		t.Fatalf("unexpected error getting todo list: %s", err)
+	}
+	if len(tdl.Items) != 1 || tdl.Items[0].ID != id {
+		t.Errorf("expected only todo %d, got %+v", id, tdl.Items)
+	}
+	tdl, _, err = s.GetToDoListPage(context.Background(), ListOptions{AllOwners: true})
+	if err != nil {
+		t.Fatalf("unexpected error getting todo list page: %s", err)
+	}
+	if len(tdl.Items) != 2 {
+		t.Errorf("expected every owner's todos, got %+v", tdl.Items)
+	}
+
+	// Other users' todos and lists are treated as if they don't exist
+	td, _ := s.GetToDoItem(context.Background(), "jdoe", int(id))
+	if td != nil {
+		t.Errorf("expected another user's todo to be hidden, got %+v", td)
+	}
+	errCode, _ := s.UpdateToDo(context.Background(), Item{ID: id, Note: "walk the cat", DueDate: date, Owner: "jdoe"})
+	if errCode != constants.DBInvalidRequestCode {
+		t.Errorf("expected not found updating another user's todo, got error code %d", errCode)
+	}
+	td, _, _ = s.PatchToDo(context.Background(), "jdoe", int(id), []byte(`{"completed":true}`), 0)
+	if td != nil {
+		t.Errorf("expected another user's todo to be hidden, got %+v", td)
+	}
+	errCode, _ = s.DeleteToDo(context.Background(), "jdoe", int(id), 0)
+	if errCode != constants.DBInvalidRequestCode {
+		t.Errorf("expected not found deleting another user's todo, got error code %d", errCode)
+	}
+	_, err = s.InsertToDo(context.Background(), Item{Note: "fix bug", DueDate: date, ListID: listID, Owner: "jdoe"})
+	if err == nil {
+		t.Error("expected error inserting todo into another user's list")
+	}
+	lists, _ := s.GetLists(context.Background(), "jdoe")
+	if len(lists) != 1 || lists[0].ID != DefaultListID {
+		t.Errorf("expected only the default list, got %+v", lists)
+	}
+	errCode, _ = s.DeleteList(context.Background(), "jdoe", listID)
+	if errCode != constants.ListNotFoundErrorCode {
+		t.Errorf("expected list not found deleting another user's list, got error code %d", errCode)
+	}
+}
+
+func TestMemStoreShares(t *testing.T) {
+	s := NewMemStore()
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	listID, err := s.InsertList(context.Background(), ListInfo{Name: "sprint", Owner: "ryoungkin"})
+	if err != nil {
+		t.Fatalf("unexpected error inserting list: %s", err)
+	}
+	id, err := s.InsertToDo(context.Background(), Item{Note: "fix bug", DueDate: date, ListID: listID, Owner: "ryoungkin"})
+	if err != nil {
+		t.Fatalf("unexpected error inserting todo: %s", err)
+	}
+	defaultID, err := s.InsertToDo(context.Background(), Item{Note: "walk the dog", DueDate: date, Owner: "ryoungkin"})
+	if err != nil {
+		t.Fatalf("unexpected error inserting todo: %s", err)
+	}
+
+	created, _, err := s.PutShare(context.Background(), Share{ListID: listID, Username: "jdoe", Role: RoleViewer})
+	if err != nil || !created {
+		t.Fatalf("expected a new share, got %t, %v", created, err)
+	}
+	created, _, err = s.PutShare(context.Background(), Share{ListID: listID, Username: "jdoe", Role: RoleEditor})
+	if err != nil || created {
+		t.Fatalf("expected the share to be replaced, got %t, %v", created, err)
+	}
+
+	tcs := []struct {
+		testName     string
+		username     string
+		listID       int64
+		todoID       int64
+		expectedRole Role
+	}{
+		{testName: "testOwner", username: "ryoungkin", listID: listID, todoID: id, expectedRole: RoleOwner},
+		{testName: "testEditor", username: "jdoe", listID: listID, todoID: id, expectedRole: RoleEditor},
+		{testName: "testNotShared", username: "asmith", listID: listID, todoID: id},
+		{testName: "testDefaultListTodoNotShared", username: "jdoe", listID: DefaultListID, todoID: defaultID, expectedRole: RoleOwner},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			l, role, err := s.GetListRole(context.Background(), tc.username, tc.listID)
+			if err != nil {
+				t.Fatalf("unexpected error getting list role: %s", err)
+			}
+			if role != tc.expectedRole || (l == nil) != (len(tc.expectedRole) == 0) {
+				t.Errorf("expected list role %q, got %q for %+v", tc.expectedRole, role, l)
+			}
+
+			// Everybody owns the default list, but not other users' todos in it
+			expectedRole := tc.expectedRole
+			if tc.listID == DefaultListID {
+				expectedRole = ""
+			}
+			td, role, err := s.GetToDoItemRole(context.Background(), tc.username, int(tc.todoID))
+			if err != nil {
+				t.Fatalf("unexpected error getting todo role: %s", err)
+			}
+			if role != expectedRole || (td == nil) != (len(expectedRole) == 0) {
+				t.Errorf("expected todo role %q, got %q for %+v", expectedRole, role, td)
+			}
+		})
+	}
+
+	lists, _ := s.GetLists(context.Background(), "jdoe")
+	if len(lists) != 2 || lists[1].ID != listID {
+		t.Errorf("expected the default list and the shared list, got %+v", lists)
+	}
+	shares, _ := s.GetShares(context.Background(), listID)
+	expected := []Share{{ListID: listID, Username: "jdoe", Role: RoleEditor}}
+	if !reflect.DeepEqual(expected, shares) {
+		t.Errorf("expected shares %+v, got %+v", expected, shares)
+	}
+
+	_, errCode, _ := s.PutShare(context.Background(), Share{ListID: DefaultListID, Username: "jdoe", Role: RoleViewer})
+	if errCode != constants.ListValidationErrorCode {
+		t.Errorf("expected validation error sharing the default list, got error code %d", errCode)
+	}
+	_, errCode, _ = s.PutShare(context.Background(), Share{ListID: listID, Username: "jdoe", Role: RoleOwner})
+	if errCode != constants.ListValidationErrorCode {
+		t.Errorf("expected validation error granting the owner role, got error code %d", errCode)
+	}
+	_, errCode, _ = s.PutShare(context.Background(), Share{ListID: 99, Username: "jdoe", Role: RoleViewer})
+	if errCode != constants.ListNotFoundErrorCode {
+		t.Errorf("expected list not found sharing a missing list, got error code %d", errCode)
+	}
+
+	_, err = s.DeleteShare(context.Background(), listID, "jdoe")
+	if err != nil {
+		t.Errorf("unexpected error deleting share: %s", err)
+	}
+	errCode, _ = s.DeleteShare(context.Background(), listID, "jdoe")
+	if errCode != constants.ListShareNotFoundErrorCode {
+		t.Errorf("expected share not found deleting a revoked share, got error code %d", errCode)
+	}
+	if l, _, _ := s.GetListRole(context.Background(), "jdoe", listID); l != nil {
+		t.Errorf("expected a revoked list to be hidden, got %+v", l)
+	}
+}
@@ -0,0 +1,96 @@
+package todo
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/juju/errors"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+// MergePatchContentType is the media type of an RFC 7396 JSON Merge Patch document
+const MergePatchContentType = "application/merge-patch+json"
+
+// MergePatch applies the RFC 7396 JSON Merge Patch document 'patch' to 'td' and returns
+// the patched todo. Only the fields present in 'patch' are changed, a field set to
+// 'null' is reset to its zero value. A todo is always in a list, a 'listid' of 'null' or
+// 0 leaves it in its list. The todo's ID can't be changed and its Version isn't affected. The patched todo is validated before it's returned. The error code
+// will be JSONDecodingErrorCode if 'patch' isn't a valid patch document, or
+// ToDoValidationErrorCode if the patched todo is invalid.
+func MergePatch(td Item, patch []byte) (Item, constants.ErrCode, error) {
+	var p interface{}
+	err := json.Unmarshal(patch, &p)
+	if err != nil {
+		return Item{}, constants.JSONDecodingErrorCode, errors.Annotate(err, "error unmarshaling merge patch")
+	}
+	patchObj, ok := p.(map[string]interface{})
+	if !ok {
+		return Item{}, constants.JSONDecodingErrorCode, errors.New("merge patch must be a JSON object")
+	}
+
+	if id, ok := patchObj["id"]; ok {
+		if n, isNum := id.(float64); !isNum || int64(n) != td.ID {
+			return Item{}, constants.ToDoValidationErrorCode, errors.Errorf("todo ID can't be changed, expected %d, got %v", td.ID, id)
+		}
+	}
+
+	orig, err := json.Marshal(td)
+	if err != nil {
+		return Item{}, constants.JSONMarshalingErrorCode, errors.Annotate(err, "error marshaling todo")
+	}
+	var target map[string]interface{}
+	err = json.Unmarshal(orig, &target)
+	if err != nil {
+		return Item{}, constants.JSONMarshalingErrorCode, errors.Annotate(err, "error unmarshaling todo")
+	}
+
+	merged, err := json.Marshal(mergeJSON(target, patchObj))
+	if err != nil {
+		return Item{}, constants.JSONMarshalingErrorCode, errors.Annotate(err, "error marshaling patched todo")
+	}
+
+	d := json.NewDecoder(bytes.NewReader(merged))
+	d.DisallowUnknownFields() // error if the patch contains fields a todo doesn't have
+	patched := Item{}
+	err = d.Decode(&patched)
+	if err != nil {
+		return Item{}, constants.JSONDecodingErrorCode, errors.Annotate(err, "error applying merge patch")
+	}
+	patched.ID = td.ID
+	if patched.ListID == 0 {
+		patched.ListID = td.ListID
+	}
+	patched.SelfRef = td.SelfRef
+	patched.Version = td.Version
+	patched.Owner = td.Owner
+
+	err = ValidateToDo(patched)
+	if err != nil {
+		return Item{}, constants.ToDoValidationErrorCode, errors.Annotate(err, fmt.Sprintf("patched todo is invalid: %+v", patched))
+	}
+
+	return patched, constants.NoErrorCode, nil
+}
+
+// mergeJSON implements the MergePatch algorithm from RFC 7396 section 2
+func mergeJSON(target interface{}, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+		targetObj[k] = mergeJSON(targetObj[k], v)
+	}
+
+	return targetObj
+}
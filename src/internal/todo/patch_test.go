@@ -0,0 +1,169 @@
+package todo
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+func TestMergePatch(t *testing.T) {
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+	orig := Item{ID: 1, ListID: 2, Note: "walk the dog", DueDate: date, Repeat: true}
+
+	testcases := []struct {
+		testname        string
+		patch           string
+		expected        Item
+		expectedErrCode constants.ErrCode
+	}{
+		{
+			testname:        "CompleteItem",
+			patch:           `{"completed": true}`,
+			expected:        Item{ID: 1, ListID: 2, Note: "walk the dog", DueDate: date, Repeat: true, Completed: true},
+			expectedErrCode: constants.NoErrorCode,
+		},
+		{
+			testname:        "NullResetsField",
+			patch:           `{"repeat": null, "note": "walk the cat"}`,
+			expected:        Item{ID: 1, ListID: 2, Note: "walk the cat", DueDate: date},
+			expectedErrCode: constants.NoErrorCode,
+		},
+		{
+			testname:        "SameIDAllowed",
+			patch:           `{"id": 1, "completed": true}`,
+			expected:        Item{ID: 1, ListID: 2, Note: "walk the dog", DueDate: date, Repeat: true, Completed: true},
+			expectedErrCode: constants.NoErrorCode,
+		},
+		{
+			testname:        "NullListIDUnchanged",
+			patch:           `{"listid": null, "completed": true}`,
+			expected:        Item{ID: 1, ListID: 2, Note: "walk the dog", DueDate: date, Repeat: true, Completed: true},
+			expectedErrCode: constants.NoErrorCode,
+		},
+		{
+			testname:        "ZeroListIDUnchanged",
+			patch:           `{"listid": 0}`,
+			expected:        Item{ID: 1, ListID: 2, Note: "walk the dog", DueDate: date, Repeat: true},
+			expectedErrCode: constants.NoErrorCode,
+		},
+		{
+			testname:        "ChangedID",
+			patch:           `{"id": 2}`,
+			expectedErrCode: constants.ToDoValidationErrorCode,
+		},
+		{
+			testname:        "InvalidResult",
+			patch:           `{"note": null}`,
+			expectedErrCode: constants.ToDoValidationErrorCode,
+		},
+		{
+			testname:        "UnknownField",
+			patch:           `{"priority": 1}`,
+			expectedErrCode: constants.JSONDecodingErrorCode,
+		},
+		{
+			testname:        "WrongFieldType",
+			patch:           `{"completed": "yes"}`,
+			expectedErrCode: constants.JSONDecodingErrorCode,
+		},
+		{
+			testname:        "NotAnObject",
+			patch:           `[{"completed": true}]`,
+			expectedErrCode: constants.JSONDecodingErrorCode,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testname, func(t *testing.T) {
+			patched, errCode, err := MergePatch(orig, []byte(tc.patch))
+			if errCode != tc.expectedErrCode {
+				t.Errorf("expected error code %d, got %d, error: %v", tc.expectedErrCode, errCode, err)
+			}
+			if tc.expectedErrCode != constants.NoErrorCode {
+				if err == nil {
+					t.Error("expected error")
+				}
+				return
+			}
+			if patched != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, patched)
+			}
+		})
+	}
+}
+
+// TestStorePatchListID checks that both stores leave a todo in its list when a patch's
+// 'listid' is null or 0
+func TestStorePatchListID(t *testing.T) {
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	newMemStore := func(t *testing.T) (Store, sqlmock.Sqlmock) {
+		s := NewMemStore()
+		listID, err := s.InsertList(context.Background(), ListInfo{Name: "sprint", Owner: "ryoungkin"})
+		if err != nil {
+			t.Fatalf("unexpected error inserting list: %s", err)
+		}
+		_, err = s.InsertToDo(context.Background(), Item{Note: "fix bug", DueDate: date, ListID: listID, Owner: "ryoungkin"})
+		if err != nil {
+			t.Fatalf("unexpected error inserting todo: %s", err)
+		}
+		return s, nil
+	}
+	newPGStore := func(t *testing.T) (Store, sqlmock.Sqlmock) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(getToDoForUpdate)).
+			WithArgs(1, "ryoungkin").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "list_id", "owner", "note", "duedate", "repeat", "recurrence", "completed", "version"}).
+				AddRow(1, 2, "ryoungkin", "fix bug", date, false, "", false, 1))
+		// The todo isn't moved so the list isn't checked
+		mock.ExpectExec(regexp.QuoteMeta(updateToDoStmt)).
+			WithArgs(int64(2), "fix bug", &AnyTime{}, false, "", true, int64(1), "ryoungkin").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		s, err := NewPGStore(db, 0)
+		if err != nil {
+			t.Fatalf("unexpected error creating PGStore: %s", err)
+		}
+		return s, mock
+	}
+
+	tcs := []struct {
+		testName string
+		newStore func(t *testing.T) (Store, sqlmock.Sqlmock)
+		patch    string
+	}{
+		{testName: "testMemStoreNullListID", newStore: newMemStore, patch: `{"listid": null, "completed": true}`},
+		{testName: "testMemStoreZeroListID", newStore: newMemStore, patch: `{"listid": 0, "completed": true}`},
+		{testName: "testPGStoreNullListID", newStore: newPGStore, patch: `{"listid": null, "completed": true}`},
+		{testName: "testPGStoreZeroListID", newStore: newPGStore, patch: `{"listid": 0, "completed": true}`},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			s, mock := tc.newStore(t)
+
+			td, errCode, err := s.PatchToDo(context.Background(), "ryoungkin", 1, []byte(tc.patch), 0)
+			if err != nil {
+				t.Fatalf("unexpected error patching todo, error code %d: %s", errCode, err)
+			}
+			if td == nil || td.ListID != 2 || !td.Completed {
+				t.Errorf("expected the todo to be completed and remain in list 2, got %+v", td)
+			}
+
+			if mock != nil {
+				DBCallTeardownHelper(t, mock)
+			}
+		})
+	}
+}
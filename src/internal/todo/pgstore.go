@@ -0,0 +1,796 @@
+package todo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/lib/pq"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+var (
+	getAllToDosQuery = "SELECT id, list_id, owner, note, duedate, repeat, recurrence, completed, version FROM todo"
+	getToDoListQuery = "SELECT id, list_id, owner, note, duedate, repeat, recurrence, completed, version FROM todo WHERE owner = $1 ORDER BY id ASC"
+	getToDoQuery     = "SELECT id, list_id, owner, note, duedate, repeat, recurrence, completed, version FROM todo WHERE id = $1 AND owner = $2"
+	// A page's cursor must be one of the owner's todos, unless every owner's todos are paged
+	getCursorQuery      = "SELECT id FROM todo WHERE id = $1"
+	getOwnedCursorQuery = "SELECT id FROM todo WHERE id = $1 AND owner = $2"
+	// A todo is visible to its owner and to the users its list is shared with
+	getToDoRoleQuery = "SELECT t.id, t.list_id, t.owner, t.note, t.duedate, t.repeat, t.recurrence, t.completed, t.version, CASE WHEN t.owner = $2 THEN 'owner' ELSE s.role END FROM todo t LEFT JOIN list_shares s ON s.list_id = t.list_id AND s.username = $2 WHERE t.id = $1 AND (t.owner = $2 OR s.role IS NOT NULL)"
+	getToDoForUpdate = "SELECT id, list_id, owner, note, duedate, repeat, recurrence, completed, version FROM todo WHERE id = $1 AND owner = $2 FOR UPDATE"
+	insertToDoStmt   = "INSERT INTO todo (list_id, owner, note, duedate, repeat, recurrence, completed) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id"
+	// A list_id of 0 leaves the todo in its current list
+	updateToDoStmt   = "UPDATE todo SET list_id = COALESCE(NULLIF($1, 0), list_id), note = $2, duedate = $3, repeat = $4, recurrence = $5, completed = $6, version = version + 1 WHERE id = $7 AND owner = $8"
+	updateToDoIfStmt = "UPDATE todo SET list_id = COALESCE(NULLIF($1, 0), list_id), note = $2, duedate = $3, repeat = $4, recurrence = $5, completed = $6, version = version + 1 WHERE id = $7 AND owner = $8 AND version = $9"
+	deleteToDoStmt   = "DELETE FROM todo WHERE id = $1 AND owner = $2"
+	deleteToDoIfStmt = "DELETE FROM todo WHERE id = $1 AND owner = $2 AND version = $3"
+
+	// Lists without an owner, i.e., the default list, are visible to everyone
+	getListsQuery    = "SELECT id, name, description, owner FROM lists WHERE owner = $1 OR owner = '' OR id IN (SELECT list_id FROM list_shares WHERE username = $1) ORDER BY id ASC"
+	getListQuery     = "SELECT id, name, description, owner FROM lists WHERE id = $1 AND (owner = $2 OR owner = '')"
+	getListRoleQuery = "SELECT l.id, l.name, l.description, l.owner, CASE WHEN l.owner = $2 OR l.owner = '' THEN 'owner' ELSE s.role END FROM lists l LEFT JOIN list_shares s ON s.list_id = l.id AND s.username = $2 WHERE l.id = $1 AND (l.owner = $2 OR l.owner = '' OR s.role IS NOT NULL)"
+	insertListStmt   = "INSERT INTO lists (name, description, owner) VALUES ($1, $2, $3) RETURNING id"
+	updateListStmt   = "UPDATE lists SET name = $1, description = $2 WHERE id = $3 AND owner = $4"
+	// The list's todos and shares are deleted by the database, 'ON DELETE CASCADE'
+	deleteListStmt = "DELETE FROM lists WHERE id = $1 AND owner = $2"
+
+	getSharesQuery = "SELECT list_id, username, role FROM list_shares WHERE list_id = $1 ORDER BY username ASC"
+	// 'xmax = 0' is only true for a newly inserted row, it distinguishes new shares from
+	// updated ones
+	putShareStmt    = "INSERT INTO list_shares (list_id, username, role) VALUES ($1, $2, $3) ON CONFLICT (list_id, username) DO UPDATE SET role = EXCLUDED.role RETURNING (xmax = 0)"
+	deleteShareStmt = "DELETE FROM list_shares WHERE list_id = $1 AND username = $2"
+)
+
+// postgresForeignKeyErrorCode is the Postgres error code for a foreign key violation, e.g.,
+// a todo referencing a list that doesn't exist. See https://www.postgresql.org/docs/current/errcodes-appendix.html
+const postgresForeignKeyErrorCode = "23503"
+
+// sortColumns maps each SortField to the todo table column it sorts by. Notes are compared
+// byte by byte, the "C" collation, rather than by the database's collation so they're in
+// the same order as a MemStore's, see ListOptions.less.
+var sortColumns = map[SortField]string{
+	SortByCompleted: "completed",
+	SortByDueDate:   "duedate",
+	SortByID:        "id",
+	SortByNote:      `note COLLATE "C"`,
+}
+
+// PGStore is a Store backed by a Postgres database
+type PGStore struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+// NewPGStore returns a *PGStore that will use the provided database connection. Each
+// operation is cancelled if it takes longer than 'timeout', 0 means operations are only
+// limited by their contexts.
+func NewPGStore(db *sql.DB, timeout time.Duration) (*PGStore, error) {
+	if db == nil {
+		return nil, errors.New("non-nil sql.DB connection required")
+	}
+	if timeout < 0 {
+		return nil, errors.New("non-negative timeout required")
+	}
+
+	return &PGStore{db: db, timeout: timeout}, nil
+}
+
+// withTimeout returns a copy of 'ctx' that's cancelled after the store's timeout, if it has
+// one. 'cancel' must be called when the operation completes.
+func (s *PGStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.timeout > 0 {
+		return context.WithTimeout(ctx, s.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// GetToDoList will return all of the ToDo items belonging to 'owner'
+func (s *PGStore) GetToDoList(ctx context.Context, owner string) (List, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	results, err := s.db.QueryContext(ctx, getToDoListQuery, owner)
+	if err != nil {
+		return List{}, errors.Annotate(err, "error querying DB")
+	}
+	defer results.Close()
+
+	tdl := List{}
+	for results.Next() {
+		var td Item
+
+		err = results.Scan(&td.ID,
+			&td.ListID,
+			&td.Owner,
+			&td.Note,
+			&td.DueDate,
+			&td.Repeat,
+			&td.Recurrence,
+			&td.Completed,
+			&td.Version)
+		if err != nil {
+			return List{}, errors.Annotate(err, "error scanning result set")
+		}
+
+		tdl.Items = append(tdl.Items, &td)
+	}
+	if err = results.Err(); err != nil {
+		return List{}, errors.Annotate(err, "error iterating result set")
+	}
+
+	return tdl, nil
+}
+
+// GetToDoListPage will return, in the order specified by 'opts', up to opts.Limit ToDo
+// items following opts.After that satisfy the filters in 'opts'. 'more' will be true if
+// there are additional items after the returned page. ErrUnknownCursor is returned if
+// opts.After doesn't identify one of opts.Owner's todos.
+func (s *PGStore) GetToDoListPage(ctx context.Context, opts ListOptions) (List, bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if opts.After > 0 {
+		err := s.checkCursor(ctx, opts)
+		if err != nil {
+			return List{}, false, err
+		}
+	}
+
+	limit := opts.limit()
+	query, args := buildPageQuery(opts)
+	results, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return List{}, false, errors.Annotate(err, "error querying DB")
+	}
+	defer results.Close()
+
+	tdl := List{}
+	for results.Next() {
+		var td Item
+
+		err = results.Scan(&td.ID,
+			&td.ListID,
+			&td.Owner,
+			&td.Note,
+			&td.DueDate,
+			&td.Repeat,
+			&td.Recurrence,
+			&td.Completed,
+			&td.Version)
+		if err != nil {
+			return List{}, false, errors.Annotate(err, "error scanning result set")
+		}
+
+		tdl.Items = append(tdl.Items, &td)
+	}
+	if err = results.Err(); err != nil {
+		return List{}, false, errors.Annotate(err, "error iterating result set")
+	}
+
+	more := false
+	if len(tdl.Items) > limit {
+		tdl.Items = tdl.Items[:limit]
+		more = true
+	}
+
+	return tdl, more, nil
+}
+
+// checkCursor returns ErrUnknownCursor if opts.After doesn't identify one of opts.Owner's
+// todos, or any todo if opts.AllOwners is set
+func (s *PGStore) checkCursor(ctx context.Context, opts ListOptions) error {
+	query, args := getOwnedCursorQuery, []interface{}{opts.After, opts.Owner}
+	if opts.AllOwners {
+		query, args = getCursorQuery, []interface{}{opts.After}
+	}
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return ErrUnknownCursor
+	}
+	if err != nil {
+		return errors.Annotate(err, "error querying DB for cursor todo")
+	}
+	return nil
+}
+
+// buildPageQuery returns the parameterized query, and its arguments, needed to retrieve
+// the page of todos described by 'opts'. The query is getAllToDosQuery with a condition
+// for each of the options that are set.
+//
+// Pages are selected using keyset pagination. The cursor, opts.After, identifies the last
+// item of the previous page. The next page starts with the items whose (sort column, id)
+// follow that item's (sort column, id).
+func buildPageQuery(opts ListOptions) (string, []interface{}) {
+	where := []string{}
+	args := []interface{}{}
+	addCondition := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, len(args)))
+	}
+
+	// The sort column is never user provided text, it comes from sortColumns, so it's safe
+	// to include it directly in the query.
+	col := sortColumns[opts.sortBy()]
+	cmp, dir := ">", "ASC"
+	if opts.SortDesc {
+		cmp, dir = "<", "DESC"
+	}
+
+	if !opts.AllOwners {
+		addCondition("owner = $%d", opts.Owner)
+	}
+	if opts.ListID > 0 {
+		addCondition("list_id = $%d", opts.ListID)
+	}
+	if opts.After > 0 {
+		switch {
+		case col == "id":
+			addCondition("id "+cmp+" $%d", opts.After)
+		case opts.AllOwners:
+			addCondition(fmt.Sprintf("(%s, id) %s (SELECT %s, id FROM todo WHERE id = $%%d)", col, cmp, col), opts.After)
+		default:
+			// Other users' todos can't be used to select the page
+			args = append(args, opts.After, opts.Owner)
+			where = append(where, fmt.Sprintf("(%s, id) %s (SELECT %s, id FROM todo WHERE id = $%d AND owner = $%d)",
+				col, cmp, col, len(args)-1, len(args)))
+		}
+	}
+	if opts.Completed != nil {
+		addCondition("completed = $%d", *opts.Completed)
+	}
+	if opts.Repeat != nil {
+		addCondition("repeat = $%d", *opts.Repeat)
+	}
+	if !opts.DueBefore.IsZero() {
+		addCondition("duedate < $%d", opts.DueBefore)
+	}
+	if !opts.DueAfter.IsZero() {
+		addCondition("duedate > $%d", opts.DueAfter)
+	}
+
+	query := getAllToDosQuery
+	if len(where) > 0 {
+		query = query + " WHERE " + strings.Join(where, " AND ")
+	}
+
+	orderBy := "id " + dir
+	if col != "id" {
+		orderBy = col + " " + dir + ", " + orderBy
+	}
+
+	// Ask for one more row than needed to find out if there's another page
+	args = append(args, opts.limit()+1)
+	query = fmt.Sprintf("%s ORDER BY %s LIMIT $%d", query, orderBy, len(args))
+
+	return query, args
+}
+
+// GetToDoItem will return the todo identified by 'id', and belonging to 'owner', or a nil
+// todo if there wasn't a matching todo.
+func (s *PGStore) GetToDoItem(ctx context.Context, owner string, id int) (*Item, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, getToDoQuery, id, owner)
+	var td Item
+	err := row.Scan(&td.ID,
+		&td.ListID,
+		&td.Owner,
+		&td.Note,
+		&td.DueDate,
+		&td.Repeat,
+		&td.Recurrence,
+		&td.Completed,
+		&td.Version)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, errors.Annotate(err, "error scanning todo row")
+	}
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	return &td, nil
+}
+
+// GetToDoItemRole returns the todo identified by 'id' and 'username's role on it, or a nil
+// todo if it doesn't exist or isn't visible to 'username'
+func (s *PGStore) GetToDoItemRole(ctx context.Context, username string, id int) (*Item, Role, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, getToDoRoleQuery, id, username)
+	var (
+		td   Item
+		role Role
+	)
+	err := row.Scan(&td.ID,
+		&td.ListID,
+		&td.Owner,
+		&td.Note,
+		&td.DueDate,
+		&td.Repeat,
+		&td.Recurrence,
+		&td.Completed,
+		&td.Version,
+		&role)
+	if err == sql.ErrNoRows {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", errors.Annotate(err, "error scanning todo row")
+	}
+
+	return &td, role, nil
+}
+
+// InsertToDo takes the provided todo data, inserts it into the db, and returns the newly created todo ID.
+// The todo's list must be visible to its owner.
+func (s *PGStore) InsertToDo(ctx context.Context, td Item) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	err := ValidateToDo(td)
+	if err != nil {
+		return 0, errors.Annotate(err, "ToDo validation failure")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, errors.Annotate(err, "error starting transaction")
+	}
+	// Rollback is a no-op if the transaction has been committed
+	defer tx.Rollback()
+
+	err = checkList(ctx, tx, listID(td), td.Owner)
+	if err != nil {
+		return 0, errors.Trace(err)
+	}
+	var id int64
+	err = tx.QueryRowContext(ctx, insertToDoStmt, listID(td), td.Owner, td.Note, td.DueDate, td.Repeat, td.Recurrence, td.Completed).Scan(&id)
+	if err != nil {
+		return 0, errors.Annotate(err, fmt.Sprintf("error inserting todo %+v into DB", td))
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, errors.Annotate(err, "error committing transaction")
+	}
+
+	return id, nil
+}
+
+// InsertToDos inserts the todos in a single transaction, if any of them can't be
+// inserted the transaction is rolled back. Each todo's list must be visible to its owner.
+func (s *PGStore) InsertToDos(ctx context.Context, tds []Item) ([]int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	for i, td := range tds {
+		err := ValidateToDo(td)
+		if err != nil {
+			return nil, errors.Annotate(err, fmt.Sprintf("ToDo validation failure, todo %d", i))
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, errors.Annotate(err, "error starting transaction")
+	}
+	// Rollback is a no-op if the transaction has been committed
+	defer tx.Rollback()
+
+	// Each list is only checked once for each owner, bulk inserts are usually to one list
+	type listOwner struct {
+		listID int64
+		owner  string
+	}
+	checked := map[listOwner]bool{}
+	ids := make([]int64, 0, len(tds))
+	for _, td := range tds {
+		lo := listOwner{listID: listID(td), owner: td.Owner}
+		if !checked[lo] {
+			err = checkList(ctx, tx, lo.listID, lo.owner)
+			if err != nil {
+				return nil, errors.Trace(err)
+			}
+			checked[lo] = true
+		}
+		var id int64
+		err = tx.QueryRowContext(ctx, insertToDoStmt, lo.listID, td.Owner, td.Note, td.DueDate, td.Repeat, td.Recurrence, td.Completed).Scan(&id)
+		if err != nil {
+			return nil, errors.Annotate(err, fmt.Sprintf("error inserting todo %+v into DB", td))
+		}
+		ids = append(ids, id)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, errors.Annotate(err, "error committing transaction")
+	}
+
+	return ids, nil
+}
+
+// checkList returns an error if the list identified by 'listID' doesn't exist or isn't
+// visible to 'owner'. The list is read in 'tx', the transaction that adds todos to it.
+func checkList(ctx context.Context, tx *sql.Tx, listID int64, owner string) error {
+	var l ListInfo
+	err := tx.QueryRowContext(ctx, getListQuery, listID, owner).Scan(&l.ID, &l.Name, &l.Description, &l.Owner)
+	if err == sql.ErrNoRows {
+		return errors.Errorf("list %d doesn't exist", listID)
+	}
+	if err != nil {
+		return errors.Annotate(err, "error scanning list row")
+	}
+	return nil
+}
+
+// UpdateToDo takes the provided todo data and updates the matching todo in the db.
+// If td.Version is non-zero the update will only be done if it matches the version
+// in the db, otherwise ToDoVersionConflictErrorCode is returned. DBInvalidRequestCode
+// is returned if the todo doesn't exist.
+func (s *PGStore) UpdateToDo(ctx context.Context, td Item) (constants.ErrCode, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	err := ValidateToDo(td)
+	if err != nil {
+		return constants.DBUpSertErrorCode, errors.Annotate(err, "ToDo validation failure")
+	}
+	td = completeOccurrence(td)
+
+	if td.ListID != 0 {
+		l, err := s.GetList(ctx, td.Owner, td.ListID)
+		if err != nil {
+			return constants.DBUpSertErrorCode, errors.Annotate(err, "error retrieving todo's list")
+		}
+		if l == nil {
+			return constants.ListNotFoundErrorCode, errors.Errorf("list %d doesn't exist", td.ListID)
+		}
+	}
+
+	var result sql.Result
+	if td.Version == 0 {
+		result, err = s.db.ExecContext(ctx, updateToDoStmt, td.ListID, td.Note, td.DueDate, td.Repeat, td.Recurrence, td.Completed, td.ID, td.Owner)
+	} else {
+		result, err = s.db.ExecContext(ctx, updateToDoIfStmt, td.ListID, td.Note, td.DueDate, td.Repeat, td.Recurrence, td.Completed, td.ID, td.Owner, td.Version)
+	}
+	if isForeignKeyError(err) {
+		return constants.ListNotFoundErrorCode, errors.Errorf("list %d doesn't exist", td.ListID)
+	}
+	if err != nil {
+		return constants.DBUpSertErrorCode, errors.Annotate(err, fmt.Sprintf("error updating todo in the database: %+v", td))
+	}
+
+	return checkResult(result, td.ID, td.Version, constants.DBUpSertErrorCode)
+}
+
+// PatchToDo atomically applies the JSON Merge Patch, 'patch', to the todo identified
+// by 'id' and returns the updated todo, or a nil todo if there wasn't a matching todo.
+// If 'version' is non-zero the patch will only be applied if it matches the todo's
+// version, otherwise ToDoVersionConflictErrorCode is returned. The todo's row is locked
+// until the update completes so that concurrent updates aren't lost.
+func (s *PGStore) PatchToDo(ctx context.Context, owner string, id int, patch []byte, version int64) (*Item, constants.ErrCode, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, constants.DBUpSertErrorCode, errors.Annotate(err, "error starting transaction")
+	}
+	// Rollback is a no-op if the transaction has been committed
+	defer tx.Rollback()
+
+	var td Item
+	err = tx.QueryRowContext(ctx, getToDoForUpdate, id, owner).Scan(&td.ID,
+		&td.ListID,
+		&td.Owner,
+		&td.Note,
+		&td.DueDate,
+		&td.Repeat,
+		&td.Recurrence,
+		&td.Completed,
+		&td.Version)
+	if err == sql.ErrNoRows && version == 0 {
+		return nil, constants.NoErrorCode, nil
+	}
+	if err != nil && err != sql.ErrNoRows {
+		return nil, constants.DBRowScanErrorCode, errors.Annotate(err, "error scanning todo row")
+	}
+	if version != 0 && version != td.Version {
+		return nil, constants.ToDoVersionConflictErrorCode, errors.Errorf("todo %d doesn't exist at version %d", id, version)
+	}
+
+	patched, errCode, err := MergePatch(td, patch)
+	if err != nil {
+		return nil, errCode, err
+	}
+	patched = completeOccurrence(patched)
+
+	if patched.ListID != td.ListID {
+		var l ListInfo
+		err = tx.QueryRowContext(ctx, getListQuery, patched.ListID, owner).Scan(&l.ID, &l.Name, &l.Description, &l.Owner)
+		if err == sql.ErrNoRows {
+			return nil, constants.ListNotFoundErrorCode, errors.Errorf("list %d doesn't exist", patched.ListID)
+		}
+		if err != nil {
+			return nil, constants.DBRowScanErrorCode, errors.Annotate(err, "error scanning list row")
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, updateToDoStmt, patched.ListID, patched.Note, patched.DueDate, patched.Repeat, patched.Recurrence, patched.Completed, patched.ID, owner)
+	if isForeignKeyError(err) {
+		return nil, constants.ListNotFoundErrorCode, errors.Errorf("list %d doesn't exist", patched.ListID)
+	}
+	if err != nil {
+		return nil, constants.DBUpSertErrorCode, errors.Annotate(err, fmt.Sprintf("error updating todo in the database: %+v", patched))
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, constants.DBUpSertErrorCode, errors.Annotate(err, "error committing transaction")
+	}
+	patched.Version++
+
+	return &patched, constants.NoErrorCode, nil
+}
+
+// DeleteToDo deletes the todo identified by td.id from the database. If 'version' is
+// non-zero the delete will only be done if it matches the version in the db, otherwise
+// ToDoVersionConflictErrorCode is returned. DBInvalidRequestCode is returned if the todo
+// doesn't exist.
+func (s *PGStore) DeleteToDo(ctx context.Context, owner string, id int, version int64) (constants.ErrCode, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var (
+		result sql.Result
+		err    error
+	)
+	if version == 0 {
+		result, err = s.db.ExecContext(ctx, deleteToDoStmt, id, owner)
+	} else {
+		result, err = s.db.ExecContext(ctx, deleteToDoIfStmt, id, owner, version)
+	}
+	if err != nil {
+		return constants.DBDeleteErrorCode, errors.Annotate(err, fmt.Sprintf("ToDo delete error for ID %d", id))
+	}
+
+	return checkResult(result, int64(id), version, constants.DBDeleteErrorCode)
+}
+
+// checkResult determines the outcome of an update or delete of the todo identified by
+// 'id' from the number of rows it affected. If no row was affected the todo didn't exist,
+// or, for a versioned ('version' is non-zero) request, it didn't exist at that version. The
+// latter is reported as a version conflict since a todo that doesn't exist can't have a
+// matching version. 'errCode' is returned if the result can't be evaluated.
+func checkResult(result sql.Result, id int64, version int64, errCode constants.ErrCode) (constants.ErrCode, error) {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errCode, errors.Annotate(err, "error getting rows affected")
+	}
+	if n == 0 && version != 0 {
+		return constants.ToDoVersionConflictErrorCode, errors.Errorf("todo %d doesn't exist at version %d", id, version)
+	}
+	if n == 0 {
+		return constants.DBInvalidRequestCode, errors.Errorf("todo %d doesn't exist", id)
+	}
+
+	return constants.NoErrorCode, nil
+}
+
+// isForeignKeyError returns true if 'err' is a Postgres foreign key violation
+func isForeignKeyError(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == postgresForeignKeyErrorCode
+}
+
+// GetLists returns all of the lists visible to 'owner' in ID order
+func (s *PGStore) GetLists(ctx context.Context, owner string) ([]ListInfo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	results, err := s.db.QueryContext(ctx, getListsQuery, owner)
+	if err != nil {
+		return nil, errors.Annotate(err, "error querying DB")
+	}
+	defer results.Close()
+
+	lists := []ListInfo{}
+	for results.Next() {
+		var l ListInfo
+		err = results.Scan(&l.ID, &l.Name, &l.Description, &l.Owner)
+		if err != nil {
+			return nil, errors.Annotate(err, "error scanning result set")
+		}
+		lists = append(lists, l)
+	}
+	if err = results.Err(); err != nil {
+		return nil, errors.Annotate(err, "error iterating result set")
+	}
+
+	return lists, nil
+}
+
+// GetList returns the list identified by 'id' or nil if there isn't one visible to 'owner'
+func (s *PGStore) GetList(ctx context.Context, owner string, id int64) (*ListInfo, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var l ListInfo
+	err := s.db.QueryRowContext(ctx, getListQuery, id, owner).Scan(&l.ID, &l.Name, &l.Description, &l.Owner)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Annotate(err, "error scanning list row")
+	}
+
+	return &l, nil
+}
+
+// GetListRole returns the list identified by 'id' and 'username's role on it, or a nil list
+// if it doesn't exist or isn't visible to 'username'
+func (s *PGStore) GetListRole(ctx context.Context, username string, id int64) (*ListInfo, Role, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var (
+		l    ListInfo
+		role Role
+	)
+	err := s.db.QueryRowContext(ctx, getListRoleQuery, id, username).Scan(&l.ID, &l.Name, &l.Description, &l.Owner, &role)
+	if err == sql.ErrNoRows {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", errors.Annotate(err, "error scanning list row")
+	}
+
+	return &l, role, nil
+}
+
+// InsertList stores 'l' and returns its newly created ID
+func (s *PGStore) InsertList(ctx context.Context, l ListInfo) (int64, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	err := ValidateList(l)
+	if err != nil {
+		return 0, errors.Annotate(err, "list validation failure")
+	}
+
+	var id int64
+	err = s.db.QueryRowContext(ctx, insertListStmt, l.Name, l.Description, l.Owner).Scan(&id)
+	if err != nil {
+		return 0, errors.Annotate(err, fmt.Sprintf("error inserting list %+v into DB", l))
+	}
+
+	return id, nil
+}
+
+// UpdateList replaces the name and description of the list identified by l.ID, and owned
+// by l.Owner. ListNotFoundErrorCode is returned if the list doesn't exist.
+func (s *PGStore) UpdateList(ctx context.Context, l ListInfo) (constants.ErrCode, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	err := ValidateList(l)
+	if err != nil {
+		return constants.ListValidationErrorCode, errors.Annotate(err, "list validation failure")
+	}
+
+	result, err := s.db.ExecContext(ctx, updateListStmt, l.Name, l.Description, l.ID, l.Owner)
+	if err != nil {
+		return constants.DBUpSertErrorCode, errors.Annotate(err, fmt.Sprintf("error updating list in the database: %+v", l))
+	}
+
+	return checkListResult(result, l.ID, constants.DBUpSertErrorCode)
+}
+
+// DeleteList deletes the list identified by 'id', and owned by 'owner', and all of its todos
+func (s *PGStore) DeleteList(ctx context.Context, owner string, id int64) (constants.ErrCode, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if id == DefaultListID {
+		return constants.ListValidationErrorCode, errors.New("the default list can't be deleted")
+	}
+
+	result, err := s.db.ExecContext(ctx, deleteListStmt, id, owner)
+	if err != nil {
+		return constants.DBDeleteErrorCode, errors.Annotate(err, fmt.Sprintf("list delete error for ID %d", id))
+	}
+
+	return checkListResult(result, id, constants.DBDeleteErrorCode)
+}
+
+// checkListResult determines the outcome of an update or delete of the list identified by
+// 'id'. 'errCode' is returned if the result can't be evaluated.
+func checkListResult(result sql.Result, id int64, errCode constants.ErrCode) (constants.ErrCode, error) {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errCode, errors.Annotate(err, "error getting rows affected")
+	}
+	if n == 0 {
+		return constants.ListNotFoundErrorCode, errors.Errorf("list %d doesn't exist", id)
+	}
+
+	return constants.NoErrorCode, nil
+}
+
+// GetShares returns the shares of the list identified by 'listID' ordered by username
+func (s *PGStore) GetShares(ctx context.Context, listID int64) ([]Share, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	results, err := s.db.QueryContext(ctx, getSharesQuery, listID)
+	if err != nil {
+		return nil, errors.Annotate(err, "error querying DB")
+	}
+	defer results.Close()
+
+	shares := []Share{}
+	for results.Next() {
+		var sh Share
+		err = results.Scan(&sh.ListID, &sh.Username, &sh.Role)
+		if err != nil {
+			return nil, errors.Annotate(err, "error scanning result set")
+		}
+		shares = append(shares, sh)
+	}
+	if err = results.Err(); err != nil {
+		return nil, errors.Annotate(err, "error iterating result set")
+	}
+
+	return shares, nil
+}
+
+// PutShare grants sh.Username sh.Role on the list identified by sh.ListID, replacing any
+// role they already have. 'created' is true if they didn't have one.
+func (s *PGStore) PutShare(ctx context.Context, sh Share) (bool, constants.ErrCode, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	err := ValidateShare(sh)
+	if err != nil {
+		return false, constants.ListValidationErrorCode, errors.Annotate(err, "share validation failure")
+	}
+
+	var created bool
+	err = s.db.QueryRowContext(ctx, putShareStmt, sh.ListID, sh.Username, sh.Role).Scan(&created)
+	if isForeignKeyError(err) {
+		return false, constants.ListNotFoundErrorCode, errors.Errorf("list %d doesn't exist", sh.ListID)
+	}
+	if err != nil {
+		return false, constants.DBUpSertErrorCode, errors.Annotate(err, fmt.Sprintf("error storing share %+v in the database", sh))
+	}
+
+	return created, constants.NoErrorCode, nil
+}
+
+// DeleteShare revokes 'username's access to the list identified by 'listID'
+func (s *PGStore) DeleteShare(ctx context.Context, listID int64, username string) (constants.ErrCode, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, deleteShareStmt, listID, username)
+	if err != nil {
+		return constants.DBDeleteErrorCode, errors.Annotate(err, fmt.Sprintf("share delete error for list %d", listID))
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return constants.DBDeleteErrorCode, errors.Annotate(err, "error getting rows affected")
+	}
+	if n == 0 {
+		return constants.ListShareNotFoundErrorCode, errors.Errorf("list %d isn't shared with %s", listID, username)
+	}
+
+	return constants.NoErrorCode, nil
+}
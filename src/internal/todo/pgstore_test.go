@@ -0,0 +1,445 @@
+package todo
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/juju/errors"
+	"github.com/lib/pq"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+func TestBuildPageQuery(t *testing.T) {
+	yes := true
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	testcases := []struct {
+		testname      string
+		opts          ListOptions
+		expectedQuery string
+		expectedArgs  []interface{}
+	}{
+		{
+			testname:      "FirstPage",
+			opts:          ListOptions{ListID: DefaultListID},
+			expectedQuery: getAllToDosQuery + " WHERE owner = $1 AND list_id = $2 ORDER BY id ASC LIMIT $3",
+			expectedArgs:  []interface{}{"", DefaultListID, DefaultPageLimit + 1},
+		},
+		{
+			testname:      "AllLists",
+			opts:          ListOptions{Owner: "ryoungkin"},
+			expectedQuery: getAllToDosQuery + " WHERE owner = $1 ORDER BY id ASC LIMIT $2",
+			expectedArgs:  []interface{}{"ryoungkin", DefaultPageLimit + 1},
+		},
+		{
+			testname:      "AllOwners",
+			opts:          ListOptions{Owner: "ryoungkin", AllOwners: true},
+			expectedQuery: getAllToDosQuery + " ORDER BY id ASC LIMIT $1",
+			expectedArgs:  []interface{}{DefaultPageLimit + 1},
+		},
+		{
+			testname:      "NextPage",
+			opts:          ListOptions{After: 5, Limit: 10, AllOwners: true},
+			expectedQuery: getAllToDosQuery + " WHERE id > $1 ORDER BY id ASC LIMIT $2",
+			expectedArgs:  []interface{}{int64(5), 11},
+		},
+		{
+			testname:      "AllFilters",
+			opts:          ListOptions{After: 5, Limit: 10, Completed: &yes, Repeat: &yes, DueBefore: date, DueAfter: date, AllOwners: true},
+			expectedQuery: getAllToDosQuery + " WHERE id > $1 AND completed = $2 AND repeat = $3 AND duedate < $4 AND duedate > $5 ORDER BY id ASC LIMIT $6",
+			expectedArgs:  []interface{}{int64(5), true, true, date, date, 11},
+		},
+		{
+			testname:      "SortByIDDesc",
+			opts:          ListOptions{After: 5, Limit: 10, SortDesc: true, AllOwners: true},
+			expectedQuery: getAllToDosQuery + " WHERE id < $1 ORDER BY id DESC LIMIT $2",
+			expectedArgs:  []interface{}{int64(5), 11},
+		},
+		{
+			testname:      "SortByNoteNextPage",
+			opts:          ListOptions{After: 5, Limit: 10, SortBy: SortByNote, Completed: &yes, AllOwners: true},
+			expectedQuery: getAllToDosQuery + ` WHERE (note COLLATE "C", id) > (SELECT note COLLATE "C", id FROM todo WHERE id = $1) AND completed = $2 ORDER BY note COLLATE "C" ASC, id ASC LIMIT $3`,
+			expectedArgs:  []interface{}{int64(5), true, 11},
+		},
+		{
+			testname:      "OwnersSortByNoteNextPage",
+			opts:          ListOptions{After: 5, Limit: 10, SortBy: SortByNote, Owner: "ryoungkin"},
+			expectedQuery: getAllToDosQuery + ` WHERE owner = $1 AND (note COLLATE "C", id) > (SELECT note COLLATE "C", id FROM todo WHERE id = $2 AND owner = $3) ORDER BY note COLLATE "C" ASC, id ASC LIMIT $4`,
+			expectedArgs:  []interface{}{"ryoungkin", int64(5), "ryoungkin", 11},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testname, func(t *testing.T) {
+			query, args := buildPageQuery(tc.opts)
+			if query != tc.expectedQuery {
+				t.Errorf("expected query %q, got %q", tc.expectedQuery, query)
+			}
+			if !reflect.DeepEqual(tc.expectedArgs, args) {
+				t.Errorf("expected args %v, got %v", tc.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestPGStoreGetToDoList(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+	columns := []string{"id", "list_id", "owner", "note", "duedate", "repeat", "recurrence", "completed", "version"}
+	mock.ExpectQuery(regexp.QuoteMeta(getToDoListQuery)).
+		WithArgs("ryoungkin").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 1, "ryoungkin", "walk the dog", date, false, "", false, 1).
+			AddRow(2, 1, "ryoungkin", "get groceries", date, false, "", false, 1)).
+		RowsWillBeClosed()
+	// The connection is lost while the second row is being read
+	mock.ExpectQuery(regexp.QuoteMeta(getToDoListQuery)).
+		WithArgs("ryoungkin").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(1, 1, "ryoungkin", "walk the dog", date, false, "", false, 1).
+			AddRow(2, 1, "ryoungkin", "get groceries", date, false, "", false, 1).
+			RowError(1, errors.New("connection reset"))).
+		RowsWillBeClosed()
+
+	s, err := NewPGStore(db, 0)
+	if err != nil {
+		t.Fatalf("unexpected error creating PGStore: %s", err)
+	}
+
+	tdl, err := s.GetToDoList(context.Background(), "ryoungkin")
+	if err != nil {
+		t.Fatalf("unexpected error getting the todo list: %s", err)
+	}
+	if len(tdl.Items) != 2 || tdl.Items[1].Note != "get groceries" {
+		t.Errorf("expected 2 todos, got %+v", tdl.Items)
+	}
+
+	_, err = s.GetToDoList(context.Background(), "ryoungkin")
+	if err == nil {
+		t.Error("expected an error when the result set can't be read")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPGStoreInsertToDos(t *testing.T) {
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+	tds := []Item{
+		{Note: "walk the dog", DueDate: date, Repeat: true},
+		{Note: "get groceries", DueDate: date},
+	}
+
+	testcases := []struct {
+		testname    string
+		tds         []Item
+		setup       func(mock sqlmock.Sqlmock)
+		shouldPass  bool
+		expectedIDs []int64
+	}{
+		{
+			testname: "Commit",
+			tds:      tds,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				// Both todos are in the default list, it's only checked once
+				expectList(mock, DefaultListID, "", true)
+				for i, td := range tds {
+					mock.ExpectQuery(regexp.QuoteMeta(insertToDoStmt)).
+						WithArgs(DefaultListID, td.Owner, td.Note, &AnyTime{}, td.Repeat, td.Recurrence, td.Completed).
+						WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(i + 1))
+				}
+				mock.ExpectCommit()
+			},
+			shouldPass:  true,
+			expectedIDs: []int64{1, 2},
+		},
+		{
+			testname: "Rollback",
+			tds:      tds,
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectList(mock, DefaultListID, "", true)
+				mock.ExpectQuery(regexp.QuoteMeta(insertToDoStmt)).
+					WithArgs(DefaultListID, "", tds[0].Note, &AnyTime{}, tds[0].Repeat, tds[0].Recurrence, tds[0].Completed).
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+				mock.ExpectQuery(regexp.QuoteMeta(insertToDoStmt)).
+					WithArgs(DefaultListID, "", tds[1].Note, &AnyTime{}, tds[1].Repeat, tds[1].Recurrence, tds[1].Completed).
+					WillReturnError(errors.New("insert failed"))
+				mock.ExpectRollback()
+			},
+			shouldPass: false,
+		},
+		{
+			// ryoungkin's list 2 isn't visible to jdoe
+			testname: "ListNotVisible",
+			tds:      []Item{{Note: "fix bug", DueDate: date, ListID: 2, Owner: "jdoe"}},
+			setup: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectList(mock, 2, "jdoe", false)
+				mock.ExpectRollback()
+			},
+			shouldPass: false,
+		},
+		{
+			testname:   "InvalidToDo",
+			tds:        []Item{tds[0], {Note: ""}},
+			setup:      func(mock sqlmock.Sqlmock) {},
+			shouldPass: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testname, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+			}
+			defer db.Close()
+			tc.setup(mock)
+
+			s, err := NewPGStore(db, 0)
+			if err != nil {
+				t.Fatalf("unexpected error creating PGStore: %s", err)
+			}
+
+			ids, err := s.InsertToDos(context.Background(), tc.tds)
+			if tc.shouldPass != (err == nil) {
+				t.Errorf("expected success to be %t, got error %v", tc.shouldPass, err)
+			}
+			if !reflect.DeepEqual(tc.expectedIDs, ids) {
+				t.Errorf("expected IDs %v, got %v", tc.expectedIDs, ids)
+			}
+
+			DBCallTeardownHelper(t, mock)
+		})
+	}
+}
+
+// expectList sets the expectation that the list identified by 'listID' is checked for
+// 'owner'. It's found if 'visible' is true.
+func expectList(mock sqlmock.Sqlmock, listID int64, owner string, visible bool) {
+	rows := sqlmock.NewRows([]string{"id", "name", "description", "owner"})
+	if visible {
+		rows.AddRow(listID, "list", "", owner)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(getListQuery)).WithArgs(listID, owner).WillReturnRows(rows)
+}
+
+func TestPGStoreContext(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "description", "owner"}).AddRow(2, "sprint", "", "ryoungkin")
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(getListQuery)).
+		WithArgs(2, "ryoungkin").
+		WillDelayFor(time.Second).
+		WillReturnRows(rows())
+	mock.ExpectQuery(regexp.QuoteMeta(getListQuery)).
+		WithArgs(2, "ryoungkin").
+		WillDelayFor(time.Second).
+		WillReturnRows(rows())
+
+	tcs := []struct {
+		testName string
+		timeout  time.Duration
+		ctx      func() (context.Context, context.CancelFunc)
+	}{
+		{
+			testName: "testTimeout",
+			timeout:  10 * time.Millisecond,
+			ctx:      func() (context.Context, context.CancelFunc) { return context.Background(), func() {} },
+		},
+		{
+			testName: "testCanceled",
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 10*time.Millisecond)
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			s, err := NewPGStore(db, tc.timeout)
+			if err != nil {
+				t.Fatalf("unexpected error creating PGStore: %s", err)
+			}
+			ctx, cancel := tc.ctx()
+			defer cancel()
+
+			start := time.Now()
+			_, err = s.GetList(ctx, "ryoungkin", 2)
+			if err == nil {
+				t.Errorf("expected the query to be canceled")
+			}
+			if elapsed := time.Since(start); elapsed >= time.Second {
+				t.Errorf("expected the query to be abandoned, it took %s", elapsed)
+			}
+		})
+	}
+
+	_, err = NewPGStore(db, -time.Second)
+	if err == nil {
+		t.Errorf("expected an error creating a PGStore with a negative timeout")
+	}
+}
+
+func TestPGStoreLists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(getListQuery)).
+		WithArgs(2, "ryoungkin").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "owner"}).
+			AddRow(2, "sprint", "current sprint", "ryoungkin"))
+	mock.ExpectQuery(regexp.QuoteMeta(getListQuery)).
+		WithArgs(3, "ryoungkin").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "owner"}))
+	mock.ExpectExec(regexp.QuoteMeta(updateListStmt)).
+		WithArgs("backlog", "", 3, "ryoungkin").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(deleteListStmt)).
+		WithArgs(2, "ryoungkin").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(getListQuery)).
+		WithArgs(3, "ryoungkin").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "owner"}).
+			AddRow(3, "backlog", "", "ryoungkin"))
+	mock.ExpectExec(regexp.QuoteMeta(updateToDoStmt)).
+		WithArgs(int64(3), "fix bug", &AnyTime{}, false, "", false, 1, "ryoungkin").
+		WillReturnError(&pq.Error{Code: postgresForeignKeyErrorCode})
+
+	s, err := NewPGStore(db, 0)
+	if err != nil {
+		t.Fatalf("unexpected error creating PGStore: %s", err)
+	}
+
+	l, err := s.GetList(context.Background(), "ryoungkin", 2)
+	if err != nil {
+		t.Fatalf("unexpected error getting list: %s", err)
+	}
+	expected := &ListInfo{ID: 2, Name: "sprint", Description: "current sprint", Owner: "ryoungkin"}
+	if !reflect.DeepEqual(expected, l) {
+		t.Errorf("expected list %+v, got %+v", expected, l)
+	}
+	l, err = s.GetList(context.Background(), "ryoungkin", 3)
+	if err != nil || l != nil {
+		t.Errorf("expected no list and no error, got %+v and %v", l, err)
+	}
+
+	errCode, _ := s.UpdateList(context.Background(), ListInfo{ID: 3, Name: "backlog", Owner: "ryoungkin"})
+	if errCode != constants.ListNotFoundErrorCode {
+		t.Errorf("expected list not found updating list, got error code %d", errCode)
+	}
+	errCode, _ = s.DeleteList(context.Background(), "ryoungkin", DefaultListID)
+	if errCode != constants.ListValidationErrorCode {
+		t.Errorf("expected validation error deleting the default list, got error code %d", errCode)
+	}
+	_, err = s.DeleteList(context.Background(), "ryoungkin", 2)
+	if err != nil {
+		t.Errorf("unexpected error deleting list: %s", err)
+	}
+
+	errCode, _ = s.UpdateToDo(context.Background(), Item{ID: 1, ListID: 3, Owner: "ryoungkin", Note: "fix bug", DueDate: time.Now()})
+	if errCode != constants.ListNotFoundErrorCode {
+		t.Errorf("expected list not found moving todo to a missing list, got error code %d", errCode)
+	}
+
+	DBCallTeardownHelper(t, mock)
+}
+
+func TestPGStoreShares(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(getListRoleQuery)).
+		WithArgs(2, "jdoe").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "owner", "role"}).
+			AddRow(2, "sprint", "current sprint", "ryoungkin", "viewer"))
+	mock.ExpectQuery(regexp.QuoteMeta(getToDoRoleQuery)).
+		WithArgs(4, "jdoe").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "list_id", "owner", "note", "duedate", "repeat", "recurrence", "completed", "version", "role"}).
+			AddRow(4, 2, "ryoungkin", "fix bug", date, false, "", false, 1, "viewer"))
+	mock.ExpectQuery(regexp.QuoteMeta(getToDoRoleQuery)).
+		WithArgs(5, "jdoe").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "list_id", "owner", "note", "duedate", "repeat", "recurrence", "completed", "version", "role"}))
+	mock.ExpectQuery(regexp.QuoteMeta(putShareStmt)).
+		WithArgs(2, "jdoe", RoleEditor).
+		WillReturnRows(sqlmock.NewRows([]string{"created"}).AddRow(false))
+	mock.ExpectQuery(regexp.QuoteMeta(putShareStmt)).
+		WithArgs(3, "jdoe", RoleViewer).
+		WillReturnError(&pq.Error{Code: postgresForeignKeyErrorCode})
+	mock.ExpectQuery(regexp.QuoteMeta(getSharesQuery)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"list_id", "username", "role"}).
+			AddRow(2, "jdoe", "editor"))
+	mock.ExpectExec(regexp.QuoteMeta(deleteShareStmt)).
+		WithArgs(2, "asmith").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	s, err := NewPGStore(db, 0)
+	if err != nil {
+		t.Fatalf("unexpected error creating PGStore: %s", err)
+	}
+
+	l, role, err := s.GetListRole(context.Background(), "jdoe", 2)
+	if err != nil || l == nil || l.Owner != "ryoungkin" || role != RoleViewer {
+		t.Errorf("expected viewer of ryoungkin's list, got %+v, %q, %v", l, role, err)
+	}
+	td, role, err := s.GetToDoItemRole(context.Background(), "jdoe", 4)
+	if err != nil || td == nil || td.Owner != "ryoungkin" || role != RoleViewer {
+		t.Errorf("expected viewer of ryoungkin's todo, got %+v, %q, %v", td, role, err)
+	}
+	td, role, err = s.GetToDoItemRole(context.Background(), "jdoe", 5)
+	if err != nil || td != nil || role != "" {
+		t.Errorf("expected no todo and no error, got %+v, %q, %v", td, role, err)
+	}
+
+	created, _, err := s.PutShare(context.Background(), Share{ListID: 2, Username: "jdoe", Role: RoleEditor})
+	if err != nil || created {
+		t.Errorf("expected the share to be replaced, got %t, %v", created, err)
+	}
+	_, errCode, _ := s.PutShare(context.Background(), Share{ListID: 3, Username: "jdoe", Role: RoleViewer})
+	if errCode != constants.ListNotFoundErrorCode {
+		t.Errorf("expected list not found sharing a missing list, got error code %d", errCode)
+	}
+	_, errCode, _ = s.PutShare(context.Background(), Share{ListID: DefaultListID, Username: "jdoe", Role: RoleViewer})
+	if errCode != constants.ListValidationErrorCode {
+		t.Errorf("expected validation error sharing the default list, got error code %d", errCode)
+	}
+
+	shares, err := s.GetShares(context.Background(), 2)
+	expected := []Share{{ListID: 2, Username: "jdoe", Role: RoleEditor}}
+	if err != nil || !reflect.DeepEqual(expected, shares) {
+		t.Errorf("expected shares %+v, got %+v, %v", expected, shares, err)
+	}
+
+	errCode, _ = s.DeleteShare(context.Background(), 2, "asmith")
+	if errCode != constants.ListShareNotFoundErrorCode {
+		t.Errorf("expected share not found, got error code %d", errCode)
+	}
+
+	DBCallTeardownHelper(t, mock)
+}
@@ -0,0 +1,197 @@
+package todo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// Frequency is how often a recurring todo repeats
+type Frequency string
+
+const (
+	// Daily repeats every 'Interval' days
+	Daily Frequency = "DAILY"
+	// Weekly repeats every 'Interval' weeks
+	Weekly Frequency = "WEEKLY"
+	// Monthly repeats every 'Interval' months on the same day of the month. Months that
+	// don't have that day, e.g., February 30th, are skipped.
+	Monthly Frequency = "MONTHLY"
+)
+
+// DailyRule is the recurrence rule of a todo that has Repeat set but no Recurrence
+const DailyRule = "FREQ=DAILY"
+
+// untilLayouts are the accepted formats of a rule's UNTIL date
+var untilLayouts = []string{"20060102T150405Z", "20060102"}
+
+// Rule describes when a recurring todo repeats. It's parsed from, and formatted as, a
+// subset of an RFC 5545 RRULE, e.g., 'FREQ=WEEKLY;INTERVAL=2;UNTIL=20201231T000000Z'.
+// FREQ is required and must be DAILY, WEEKLY, or MONTHLY. INTERVAL defaults to 1. There
+// are no occurrences after UNTIL, if it's provided.
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	Until    time.Time
+}
+
+// ParseRule parses a recurrence rule, an optional 'RRULE:' prefix is allowed
+func ParseRule(s string) (Rule, error) {
+	r := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if len(s) == 0 {
+		return Rule{}, errors.New("recurrence rule is empty")
+	}
+
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return Rule{}, errors.Errorf("expected NAME=VALUE in recurrence rule, got %q", part)
+		}
+		name, value := strings.ToUpper(kv[0]), kv[1]
+
+		switch name {
+		case "FREQ":
+			r.Freq = Frequency(strings.ToUpper(value))
+			if r.Freq != Daily && r.Freq != Weekly && r.Freq != Monthly {
+				return Rule{}, errors.Errorf("expected FREQ of DAILY, WEEKLY, or MONTHLY, got %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return Rule{}, errors.Errorf("expected positive numeric INTERVAL, got %q", value)
+			}
+			r.Interval = n
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return Rule{}, err
+			}
+			r.Until = until
+		default:
+			return Rule{}, errors.Errorf("unsupported recurrence rule part %q, expected FREQ, INTERVAL, or UNTIL", name)
+		}
+	}
+
+	if len(r.Freq) == 0 {
+		return Rule{}, errors.Errorf("recurrence rule %q is missing FREQ", s)
+	}
+	return r, nil
+}
+
+func parseUntil(v string) (time.Time, error) {
+	for _, layout := range untilLayouts {
+		t, err := time.Parse(layout, v)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.Errorf("expected UNTIL like 20201231T000000Z or 20201231, got %q", v)
+}
+
+// String formats 'r' as an RRULE, without the 'RRULE:' prefix
+func (r Rule) String() string {
+	s := "FREQ=" + string(r.Freq)
+	if r.Interval > 1 {
+		s += fmt.Sprintf(";INTERVAL=%d", r.Interval)
+	}
+	if !r.Until.IsZero() {
+		s += ";UNTIL=" + r.Until.UTC().Format(untilLayouts[0])
+	}
+	return s
+}
+
+// Next returns the first occurrence of 'r', counting from 'start', that's after both 'start'
+// and 'after'. 'ok' is false if there are no more occurrences.
+func (r Rule) Next(start time.Time, after time.Time) (next time.Time, ok bool) {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	if start.After(after) {
+		after = start
+	}
+
+	next = start
+	for i := 1; !next.After(after); i++ {
+		switch r.Freq {
+		case Daily:
+			next = start.AddDate(0, 0, i*interval)
+		case Weekly:
+			next = start.AddDate(0, 0, 7*i*interval)
+		case Monthly:
+			next = start.AddDate(0, i*interval, 0)
+			if next.Day() != start.Day() {
+				// The month doesn't have this day, e.g., April 31st
+				next = start
+			}
+		default:
+			return time.Time{}, false
+		}
+	}
+
+	if !r.Until.IsZero() && next.After(r.Until) {
+		return time.Time{}, false
+	}
+	return next, true
+}
+
+// RecurrenceRule returns the rule describing when 'td' repeats. 'ok' is false if 'td'
+// doesn't repeat. A todo with Repeat set but no Recurrence repeats daily.
+func (td Item) RecurrenceRule() (r Rule, ok bool, err error) {
+	if !td.Repeat {
+		return Rule{}, false, nil
+	}
+	rule := td.Recurrence
+	if len(rule) == 0 {
+		rule = DailyRule
+	}
+	r, err = ParseRule(rule)
+	if err != nil {
+		return Rule{}, false, err
+	}
+	return r, true, nil
+}
+
+// NextOccurrence returns 'td' rolled forward to its first occurrence after 'after', it's
+// due on that date and isn't completed. 'ok' is false if 'td' doesn't repeat, or if it has
+// no more occurrences. A todo without a due date repeats from 'after'.
+func NextOccurrence(td Item, after time.Time) (next Item, ok bool) {
+	r, ok, err := td.RecurrenceRule()
+	if err != nil || !ok {
+		return td, false
+	}
+
+	start := td.DueDate
+	if start.IsZero() {
+		start = after
+	}
+	due, ok := r.Next(start, after)
+	if !ok {
+		return td, false
+	}
+
+	td.DueDate = due
+	td.Completed = false
+	return td, true
+}
+
+// now is replaced in tests to get predictable occurrences
+var now = time.Now
+
+// completeOccurrence is applied to updated todos. If 'td' is a completed recurring todo
+// it's rolled forward to its next occurrence, otherwise it's returned unchanged. A
+// recurring todo with no more occurrences stays completed.
+func completeOccurrence(td Item) Item {
+	if !td.Completed {
+		return td
+	}
+	next, ok := NextOccurrence(td, now())
+	if !ok {
+		return td
+	}
+	return next
+}
@@ -0,0 +1,177 @@
+package todo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	testcases := []struct {
+		testname   string
+		rule       string
+		shouldPass bool
+		expected   Rule
+	}{
+		{
+			testname:   "Daily",
+			rule:       "FREQ=DAILY",
+			shouldPass: true,
+			expected:   Rule{Freq: Daily, Interval: 1},
+		},
+		{
+			testname:   "WeeklyWithInterval",
+			rule:       "RRULE:FREQ=WEEKLY;INTERVAL=2",
+			shouldPass: true,
+			expected:   Rule{Freq: Weekly, Interval: 2},
+		},
+		{
+			testname:   "MonthlyUntil",
+			rule:       "freq=monthly;UNTIL=20201231T000000Z",
+			shouldPass: true,
+			expected:   Rule{Freq: Monthly, Interval: 1, Until: time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC)},
+		},
+		{
+			testname:   "UntilDate",
+			rule:       "FREQ=DAILY;UNTIL=20201231",
+			shouldPass: true,
+			expected:   Rule{Freq: Daily, Interval: 1, Until: time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC)},
+		},
+		{testname: "Empty", rule: ""},
+		{testname: "MissingFreq", rule: "INTERVAL=2"},
+		{testname: "UnsupportedFreq", rule: "FREQ=HOURLY"},
+		{testname: "BadInterval", rule: "FREQ=DAILY;INTERVAL=0"},
+		{testname: "BadUntil", rule: "FREQ=DAILY;UNTIL=tomorrow"},
+		{testname: "UnsupportedPart", rule: "FREQ=DAILY;COUNT=3"},
+		{testname: "Malformed", rule: "FREQ"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testname, func(t *testing.T) {
+			r, err := ParseRule(tc.rule)
+			if tc.shouldPass != (err == nil) {
+				t.Fatalf("expected success to be %t, got error %v", tc.shouldPass, err)
+			}
+			if r != tc.expected {
+				t.Errorf("expected %+v, got %+v", tc.expected, r)
+			}
+			if tc.shouldPass {
+				rt, err := ParseRule(r.String())
+				if err != nil || rt != r {
+					t.Errorf("expected %q to round trip, got %+v, %v", r.String(), rt, err)
+				}
+			}
+		})
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	due := time.Date(2020, 1, 31, 9, 0, 0, 0, time.UTC)
+
+	testcases := []struct {
+		testname    string
+		td          Item
+		after       time.Time
+		expectedOK  bool
+		expectedDue time.Time
+	}{
+		{
+			testname:   "NotRepeating",
+			td:         Item{Note: "pay bills", DueDate: due, Completed: true},
+			after:      due,
+			expectedOK: false,
+		},
+		{
+			testname:    "RepeatIsDaily",
+			td:          Item{Note: "walk the dog", DueDate: due, Repeat: true, Completed: true},
+			after:       due,
+			expectedOK:  true,
+			expectedDue: time.Date(2020, 2, 1, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			testname:    "CompletedEarly",
+			td:          Item{Note: "walk the dog", DueDate: due, Repeat: true, Completed: true},
+			after:       due.AddDate(0, 0, -3),
+			expectedOK:  true,
+			expectedDue: time.Date(2020, 2, 1, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			testname:    "CompletedLate",
+			td:          Item{Note: "water plants", DueDate: due, Repeat: true, Recurrence: "FREQ=WEEKLY;INTERVAL=2", Completed: true},
+			after:       due.AddDate(0, 0, 20),
+			expectedOK:  true,
+			expectedDue: time.Date(2020, 2, 28, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			testname:    "MonthlySkipsShortMonths",
+			td:          Item{Note: "pay rent", DueDate: due, Repeat: true, Recurrence: "FREQ=MONTHLY", Completed: true},
+			after:       due,
+			expectedOK:  true,
+			expectedDue: time.Date(2020, 3, 31, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			testname:   "PastUntil",
+			td:         Item{Note: "pay rent", DueDate: due, Repeat: true, Recurrence: "FREQ=MONTHLY;UNTIL=20200301", Completed: true},
+			after:      due,
+			expectedOK: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.testname, func(t *testing.T) {
+			next, ok := NextOccurrence(tc.td, tc.after)
+			if ok != tc.expectedOK {
+				t.Fatalf("expected ok to be %t, got %t", tc.expectedOK, ok)
+			}
+			if !ok {
+				if next != tc.td {
+					t.Errorf("expected unchanged todo, got %+v", next)
+				}
+				return
+			}
+			if !next.DueDate.Equal(tc.expectedDue) {
+				t.Errorf("expected due date %s, got %s", tc.expectedDue, next.DueDate)
+			}
+			if next.Completed {
+				t.Error("expected next occurrence to not be completed")
+			}
+		})
+	}
+}
+
+func TestMemStoreCompleteRepeating(t *testing.T) {
+	due := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+	now = func() time.Time { return due }
+	defer func() { now = time.Now }()
+
+	s := NewMemStore()
+	id, err := s.InsertToDo(context.Background(), Item{Note: "walk the dog", DueDate: due, Repeat: true, Recurrence: "FREQ=WEEKLY"})
+	if err != nil {
+		t.Fatalf("unexpected error inserting todo: %s", err)
+	}
+
+	_, err = s.UpdateToDo(context.Background(), Item{ID: id, Note: "walk the dog", DueDate: due, Repeat: true, Recurrence: "FREQ=WEEKLY", Completed: true})
+	if err != nil {
+		t.Fatalf("unexpected error updating todo: %s", err)
+	}
+	td, err := s.GetToDoItem(context.Background(), "", int(id))
+	if err != nil {
+		t.Fatalf("unexpected error getting todo: %s", err)
+	}
+	if td.Completed || !td.DueDate.Equal(due.AddDate(0, 0, 7)) {
+		t.Errorf("expected todo rolled forward a week and not completed, got %+v", td)
+	}
+
+	td, _, err = s.PatchToDo(context.Background(), "", int(id), []byte(`{"completed":true}`), 0)
+	if err != nil {
+		t.Fatalf("unexpected error patching todo: %s", err)
+	}
+	if td.Completed || !td.DueDate.Equal(due.AddDate(0, 0, 14)) {
+		t.Errorf("expected todo rolled forward two weeks and not completed, got %+v", td)
+	}
+
+	_, err = s.InsertToDo(context.Background(), Item{Note: "walk the dog", Recurrence: "FREQ=DAILY"})
+	if err == nil {
+		t.Error("expected validation error inserting todo with recurrence but not repeat")
+	}
+}
@@ -0,0 +1,54 @@
+package todo
+
+import (
+	"github.com/juju/errors"
+)
+
+// Role is a user's level of access to a list and its todos
+type Role string
+
+const (
+	// RoleOwner can do anything to a list, its todos, and its shares. It's the role of a
+	// list's owner and of every user of the default list, it can't be granted.
+	RoleOwner Role = "owner"
+	// RoleEditor can view, create, update, and delete a list's todos. It can't change the
+	// list itself or its shares.
+	RoleEditor Role = "editor"
+	// RoleViewer can only view a list and its todos
+	RoleViewer Role = "viewer"
+)
+
+// CanEdit indicates if 'r' allows a list's todos to be created, updated, and deleted
+func (r Role) CanEdit() bool {
+	return r == RoleOwner || r == RoleEditor
+}
+
+// Share grants a user, other than its owner, access to a list
+type Share struct {
+	ListID   int64  `json:"listid"`
+	Username string `json:"username"`
+	Role     Role   `json:"role"`
+}
+
+// ValidateShare returns an error describing what's wrong with 's's data, if anything
+func ValidateShare(s Share) error {
+	if s.ListID == DefaultListID {
+		return errors.New("the default list can't be shared")
+	}
+	if len(s.Username) == 0 {
+		return errors.New("share username must be populated")
+	}
+	if s.Role != RoleEditor && s.Role != RoleViewer {
+		return errors.Errorf("share role must be %q or %q, got %q", RoleEditor, RoleViewer, s.Role)
+	}
+	return nil
+}
+
+// ItemOwner returns the owner of the todos in 'l' that 'requester' can access. That's the
+// list's owner, except for the default list where every user has their own todos.
+func (l ListInfo) ItemOwner(requester string) string {
+	if l.Owner == "" {
+		return requester
+	}
+	return l.Owner
+}
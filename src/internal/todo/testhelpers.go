@@ -8,6 +8,7 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
+	"regexp"
 	"testing"
 	"time"
 
@@ -32,9 +33,9 @@ func DBCallSetupHelper(t *testing.T) (*sql.DB, sqlmock.Sqlmock, List) {
 
 	now := time.Now()
 
-	rows := sqlmock.NewRows([]string{"id", "note", "duedate", "repeat", "completed"}).
-		AddRow(1, "Get groceries", now, false, false).
-		AddRow(2, "Walk Dog", now, true, false)
+	rows := sqlmock.NewRows([]string{"id", "list_id", "owner", "note", "duedate", "repeat", "recurrence", "completed", "version"}).
+		AddRow(1, 1, "", "Get groceries", now, false, "", false, 1).
+		AddRow(2, 1, "", "Walk Dog", now, true, "", false, 1)
 
 	mock.ExpectQuery(getAllToDosQuery).
 		WillReturnRows(rows)
@@ -44,18 +45,22 @@ func DBCallSetupHelper(t *testing.T) (*sql.DB, sqlmock.Sqlmock, List) {
 			{
 				ID:        1,
 				SelfRef:   "/todos/1",
+				ListID:    1,
 				Note:      "Get groceries",
 				DueDate:   now,
 				Repeat:    false,
 				Completed: false,
+				Version:   1,
 			},
 			{
 				ID:        2,
 				SelfRef:   "/todos/2",
+				ListID:    1,
 				Note:      "Walk Dog",
 				DueDate:   now,
 				Repeat:    true,
 				Completed: false,
+				Version:   1,
 			},
 		},
 	}
@@ -63,6 +68,186 @@ func DBCallSetupHelper(t *testing.T) (*sql.DB, sqlmock.Sqlmock, List) {
 	return db, mock, expected
 }
 
+// DBCallPageSetupHelper encapsulates common code needed to setup mock DB access to the first
+// page, of size 2, of todo data
+func DBCallPageSetupHelper(t *testing.T) (*sql.DB, sqlmock.Sqlmock, List) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+
+	now := time.Now()
+
+	// One more row than the page size is returned to indicate there's a next page
+	rows := sqlmock.NewRows([]string{"id", "list_id", "owner", "note", "duedate", "repeat", "recurrence", "completed", "version"}).
+		AddRow(1, 1, "", "Get groceries", now, false, "", false, 1).
+		AddRow(2, 1, "", "Walk Dog", now, true, "", false, 1).
+		AddRow(3, 1, "", "Pay bills", now, false, "", false, 1)
+
+	query := getAllToDosQuery + " WHERE owner = $1 AND list_id = $2 ORDER BY id ASC LIMIT $3"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs("", DefaultListID, 3).
+		WillReturnRows(rows)
+
+	expected := List{
+		Items: []*Item{
+			{
+				ID:        1,
+				SelfRef:   "/todos/1",
+				ListID:    1,
+				Note:      "Get groceries",
+				DueDate:   now,
+				Repeat:    false,
+				Completed: false,
+				Version:   1,
+			},
+			{
+				ID:        2,
+				SelfRef:   "/todos/2",
+				ListID:    1,
+				Note:      "Walk Dog",
+				DueDate:   now,
+				Repeat:    true,
+				Completed: false,
+				Version:   1,
+			},
+		},
+		Next: "/todos?after=2&limit=2",
+	}
+
+	return db, mock, expected
+}
+
+// DBCallFilterSetupHelper encapsulates common code needed to setup mock DB access to todo
+// data filtered by 'completed=false' and 'due_before'
+func DBCallFilterSetupHelper(t *testing.T) (*sql.DB, sqlmock.Sqlmock, List) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{"id", "list_id", "owner", "note", "duedate", "repeat", "recurrence", "completed", "version"}).
+		AddRow(2, 1, "", "Walk Dog", date, true, "", false, 1)
+
+	query := getAllToDosQuery + " WHERE owner = $1 AND list_id = $2 AND completed = $3 AND duedate < $4 ORDER BY id ASC LIMIT $5"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs("", DefaultListID, false, &AnyTime{}, DefaultPageLimit+1).
+		WillReturnRows(rows)
+
+	expected := List{
+		Items: []*Item{
+			{
+				ID:        2,
+				SelfRef:   "/todos/2",
+				ListID:    1,
+				Note:      "Walk Dog",
+				DueDate:   date,
+				Repeat:    true,
+				Completed: false,
+				Version:   1,
+			},
+		},
+	}
+
+	return db, mock, expected
+}
+
+// DBCallSortSetupHelper encapsulates common code needed to setup mock DB access to the
+// page following todo 1 when sorted by descending due date
+func DBCallSortSetupHelper(t *testing.T) (*sql.DB, sqlmock.Sqlmock, List) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+
+	date := time.Date(2020, 4, 2, 13, 13, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{"id", "list_id", "owner", "note", "duedate", "repeat", "recurrence", "completed", "version"}).
+		AddRow(3, 1, "", "Pay bills", date, false, "", false, 1).
+		AddRow(2, 1, "", "Walk Dog", date.AddDate(0, 0, -1), true, "", false, 1)
+
+	mock.ExpectQuery(regexp.QuoteMeta(getOwnedCursorQuery)).
+		WithArgs(1, "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	query := getAllToDosQuery + " WHERE owner = $1 AND list_id = $2 AND (duedate, id) < (SELECT duedate, id FROM todo WHERE id = $3 AND owner = $4) ORDER BY duedate DESC, id DESC LIMIT $5"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs("", DefaultListID, 1, "", 3).
+		WillReturnRows(rows)
+
+	expected := List{
+		Items: []*Item{
+			{
+				ID:        3,
+				SelfRef:   "/todos/3",
+				ListID:    1,
+				Note:      "Pay bills",
+				DueDate:   date,
+				Repeat:    false,
+				Completed: false,
+				Version:   1,
+			},
+			{
+				ID:        2,
+				SelfRef:   "/todos/2",
+				ListID:    1,
+				Note:      "Walk Dog",
+				DueDate:   date.AddDate(0, 0, -1),
+				Repeat:    true,
+				Completed: false,
+				Version:   1,
+			},
+		},
+	}
+
+	return db, mock, expected
+}
+
+// DBCallEmptyFilterSetupHelper encapsulates common code needed to setup mock DB access to
+// todo data filtered by 'completed=true' when none of the todos are completed
+func DBCallEmptyFilterSetupHelper(t *testing.T) (*sql.DB, sqlmock.Sqlmock, List) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "list_id", "owner", "note", "duedate", "repeat", "recurrence", "completed", "version"})
+
+	query := getAllToDosQuery + " WHERE owner = $1 AND list_id = $2 AND completed = $3 ORDER BY id ASC LIMIT $4"
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs("", DefaultListID, true, DefaultPageLimit+1).
+		WillReturnRows(rows)
+
+	return db, mock, List{Items: []*Item{}}
+}
+
+// DBCallUnknownCursorSetupHelper encapsulates common code needed to setup mock DB access to
+// the page following todo 4, which doesn't exist or belongs to another user
+func DBCallUnknownCursorSetupHelper(t *testing.T) (*sql.DB, sqlmock.Sqlmock, List) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(getOwnedCursorQuery)).
+		WithArgs(4, "").
+		WillReturnError(sql.ErrNoRows)
+
+	return db, mock, List{}
+}
+
+// DBCallNoListExpectationsSetupHelper encapsulates common code needed when no expectations
+// are present for a todo list request
+func DBCallNoListExpectationsSetupHelper(t *testing.T) (*sql.DB, sqlmock.Sqlmock, List) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+
+	return db, mock, List{}
+}
+
 // DBCallTeardownHelper encapsulates common code needed to finalize processing of mock DB access to todo data
 func DBCallTeardownHelper(t *testing.T, mock sqlmock.Sqlmock) {
 	// we make sure that all expectations were met
@@ -109,19 +294,22 @@ func GetItemSetupHelper(t *testing.T) (*sql.DB, sqlmock.Sqlmock, *Item) {
 
 	now := time.Now()
 
-	rows := sqlmock.NewRows([]string{"id", "note", "duedate", "repeat", "completed"}).
-		AddRow(1, "Get groceries", now, false, false)
+	rows := sqlmock.NewRows([]string{"id", "list_id", "owner", "note", "duedate", "repeat", "recurrence", "completed", "version", "role"}).
+		AddRow(1, 1, "", "Get groceries", now, false, "", false, 1, RoleOwner)
 
-	mock.ExpectQuery(getAllToDosQuery).
+	mock.ExpectQuery(regexp.QuoteMeta(getToDoRoleQuery)).
+		WithArgs(1, "").
 		WillReturnRows(rows)
 
 	expected := Item{
 		ID:        1,
 		SelfRef:   "/todos/1",
+		ListID:    1,
 		Note:      "Get groceries",
 		DueDate:   now,
 		Repeat:    false,
 		Completed: false,
+		Version:   1,
 	}
 
 	return db, mock, &expected
@@ -157,9 +345,15 @@ func DBInsertSetupHelper(t *testing.T, td Item) (*sql.DB, sqlmock.Sqlmock) {
 	rows := sqlmock.NewRows([]string{"id"}).
 		AddRow(1)
 
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(getListQuery)).
+		WithArgs(DefaultListID, td.Owner).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "owner"}).
+			AddRow(DefaultListID, "default", "", ""))
 	mock.ExpectQuery(insertToDoStmt).
-		WithArgs(td.Note, AnyTime{}, td.Repeat, td.Completed).
+		WithArgs(DefaultListID, td.Owner, td.Note, AnyTime{}, td.Repeat, td.Recurrence, td.Completed).
 		WillReturnRows(rows)
+	mock.ExpectCommit()
 
 	return db, mock
 }
@@ -171,13 +365,28 @@ func DBUpdateSetupHelper(t *testing.T, td Item) (*sql.DB, sqlmock.Sqlmock) {
 		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
 	}
 
-	// mock.ExpectExec("UPDATE todo SET (.+) WHERE (.+)").WithArgs(TODO ADD).
-	// 	WillReturnResult(sqlmock.NewResult(0, 1)) // no insert ID, 1 row affected
-	mock.ExpectExec("UPDATE todo").WithArgs(sqlmock.AnyArg, sqlmock.AnyArg, sqlmock.AnyArg, sqlmock.AnyArg, sqlmock.AnyArg, sqlmock.AnyArg, sqlmock.AnyArg).
+	expectToDoRole(mock, td, true)
+	mock.ExpectExec(regexp.QuoteMeta(updateToDoStmt)).
+		WithArgs(td.ListID, td.Note, &AnyTime{}, td.Repeat, td.Recurrence, td.Completed, td.ID, td.Owner).
 		WillReturnResult(sqlmock.NewResult(0, 1)) // no insert ID, 1 row affected
 	return db, mock
 }
 
+// DBUpdateNotFoundSetupHelper encapsulates the common code needed to setup a mock update
+// of a non-existent Item
+func DBUpdateNotFoundSetupHelper(t *testing.T, td Item) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+
+	expectToDoRole(mock, td, false)
+	mock.ExpectExec(regexp.QuoteMeta(updateToDoStmt)).
+		WithArgs(td.ListID, td.Note, &AnyTime{}, td.Repeat, td.Recurrence, td.Completed, td.ID, td.Owner).
+		WillReturnResult(sqlmock.NewResult(0, 0)) // no insert ID, no rows affected
+	return db, mock
+}
+
 // DBUpdateErrorSetupHelper encapsulates the common code needed to setup a mock Item update error
 func DBUpdateErrorSetupHelper(t *testing.T, td Item) (*sql.DB, sqlmock.Sqlmock) {
 	db, mock, err := sqlmock.New()
@@ -185,7 +394,10 @@ func DBUpdateErrorSetupHelper(t *testing.T, td Item) (*sql.DB, sqlmock.Sqlmock)
 		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
 	}
 
-	mock.ExpectExec("UPDATE todo SET (.+) WHERE (.+)").WithArgs(sqlmock.AnyArg, sqlmock.AnyArg, sqlmock.AnyArg, sqlmock.AnyArg, sqlmock.AnyArg).WillReturnError(sql.ErrConnDone)
+	expectToDoRole(mock, td, true)
+	mock.ExpectExec(regexp.QuoteMeta(updateToDoStmt)).
+		WithArgs(td.ListID, td.Note, &AnyTime{}, td.Repeat, td.Recurrence, td.Completed, td.ID, td.Owner).
+		WillReturnError(sql.ErrConnDone)
 	return db, mock
 }
 
@@ -206,7 +418,68 @@ func DBDeleteSetupHelper(t *testing.T, td Item) (*sql.DB, sqlmock.Sqlmock) {
 		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
 	}
 
-	mock.ExpectExec(deleteToDoStmt).WithArgs(td.ID)
+	expectToDoRole(mock, td, true)
+	mock.ExpectExec(regexp.QuoteMeta(deleteToDoStmt)).
+		WithArgs(td.ID, td.Owner).
+		WillReturnResult(sqlmock.NewResult(0, 1)) // no insert ID, 1 row affected
+
+	return db, mock
+}
+
+// DBDeleteNotFoundSetupHelper encapsulates the common code needed to setup a mock delete
+// of a non-existent Item
+func DBDeleteNotFoundSetupHelper(t *testing.T, td Item) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+
+	expectToDoRole(mock, td, false)
+	mock.ExpectExec(regexp.QuoteMeta(deleteToDoStmt)).
+		WithArgs(td.ID, td.Owner).
+		WillReturnResult(sqlmock.NewResult(0, 0)) // no insert ID, no rows affected
+
+	return db, mock
+}
+
+// DBDeleteErrorSetupHelper encapsulates the common code needed to setup a mock Item delete error
+func DBDeleteErrorSetupHelper(t *testing.T, td Item) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+
+	expectToDoRole(mock, td, true)
+	mock.ExpectExec(regexp.QuoteMeta(deleteToDoStmt)).
+		WithArgs(td.ID, td.Owner).
+		WillReturnError(sql.ErrConnDone)
 
 	return db, mock
 }
+
+// DBGetListNotFoundSetupHelper encapsulates the common code needed to setup a mock lookup
+// of td.ListID, a non-existent list
+func DBGetListNotFoundSetupHelper(t *testing.T, td Item) (*sql.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(getListQuery)).
+		WithArgs(td.ListID, td.Owner).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "owner"}))
+
+	return db, mock
+}
+
+// expectToDoRole sets up the mock lookup, made before 'td' is changed, of the requester's
+// role on 'td'. The requester is td.Owner. If 'exists' is false the todo isn't found.
+func expectToDoRole(mock sqlmock.Sqlmock, td Item, exists bool) {
+	rows := sqlmock.NewRows([]string{"id", "list_id", "owner", "note", "duedate", "repeat", "recurrence", "completed", "version", "role"})
+	if exists {
+		rows.AddRow(td.ID, listID(td), td.Owner, td.Note, td.DueDate, td.Repeat, td.Recurrence, td.Completed, 1, RoleOwner)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(getToDoRoleQuery)).
+		WithArgs(td.ID, td.Owner).
+		WillReturnRows(rows)
+}
@@ -1,133 +1,294 @@
 package todo
 
 import (
-	"database/sql"
-	"fmt"
+	"context"
+	"strings"
 	"time"
 
 	"github.com/juju/errors"
 	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
 )
 
-var (
-	getAllToDosQuery = "SELECT id, note, duedate, repeat, completed FROM todo"
-	getToDoQuery     = "SELECT id, note, duedate, repeat, completed FROM todo WHERE id = $1"
-	insertToDoStmt   = "INSERT INTO todo (note, duedate, repeat, completed) VALUES ($1, $2, $3, $4) RETURNING id"
-	updateToDoStmt   = "UPDATE todo SET note = $1, duedate = $2, repeat = $3, completed = $4 WHERE id = $5"
-	deleteToDoStmt   = "DELETE FROM todo WHERE id = $1"
-)
-
 // Item represents the data about a To Do list item
 type Item struct {
-	ID        int64     `json:"id"`
-	SelfRef   string    `json:"selfref"`
+	ID      int64  `json:"id"`
+	SelfRef string `json:"selfref"`
+	// ListID identifies the list the item belongs to. Zero, when creating an item, means
+	// DefaultListID and, when replacing an item, means the item stays in its current list.
+	ListID int64 `json:"listid"`
+	// Owner is the name of the user the item belongs to. It's set from the authenticated
+	// user and is ignored when provided in a request.
+	Owner     string    `json:"owner,omitempty"`
 	Note      string    `json:"note"`
 	DueDate   time.Time `json:"duedate"`
 	Repeat    bool      `json:"repeat"`
 	Completed bool      `json:"completed"`
+	// Recurrence is the rule describing when a todo with Repeat set repeats, see Rule. If
+	// it's empty the todo repeats daily. Completing a repeating todo rolls it forward to
+	// its next due date.
+	Recurrence string `json:"recurrence,omitempty"`
+	// Version is incremented each time the item is changed. It's maintained by the Store
+	// and is ignored when provided in a request.
+	Version int64 `json:"version"`
 }
 
+// InitialVersion is the Version of a newly inserted Item
+const InitialVersion int64 = 1
+
 // List is a collection ToDo items, i.e., a To Do List
 type List struct {
 	Items []*Item `json:"todolist"`
+	// Next, when populated, is a link to the next page of a paginated To Do List
+	Next string `json:"next,omitempty"`
 }
 
-// GetToDoList will return all ToDo items
-func GetToDoList(db *sql.DB) (List, error) {
-	results, err := db.Query(getAllToDosQuery)
-	if err != nil {
-		return List{}, errors.Annotate(err, "error querying DB")
-	}
+const (
+	// DefaultPageLimit is the number of items returned in a page when a limit isn't specified
+	DefaultPageLimit = 100
+	// MaxPageLimit is the maximum number of items that can be returned in a single page
+	MaxPageLimit = 1000
+)
 
-	tdl := List{}
-	for results.Next() {
-		var td Item
+// ErrUnknownCursor is returned when a page is requested after a todo, ListOptions.After,
+// that doesn't exist or belongs to another user
+var ErrUnknownCursor = errors.New("the todo the page follows doesn't exist")
 
-		err = results.Scan(&td.ID,
-			&td.Note,
-			&td.DueDate,
-			&td.Repeat,
-			&td.Completed)
-		if err != nil {
-			return List{}, errors.Annotate(err, "error scanning result set")
-		}
+// SortField identifies an Item field that a To Do list can be sorted by
+type SortField string
 
-		tdl.Items = append(tdl.Items, &td)
-	}
+const (
+	// SortByCompleted sorts by Item.Completed, false before true
+	SortByCompleted SortField = "completed"
+	// SortByDueDate sorts by Item.DueDate, earliest first
+	SortByDueDate SortField = "duedate"
+	// SortByID sorts by Item.ID, this is the default
+	SortByID SortField = "id"
+	// SortByNote sorts by Item.Note
+	SortByNote SortField = "note"
+)
 
-	return tdl, nil
+// Valid indicates if 'f' is one of the supported SortFields
+func (f SortField) Valid() bool {
+	switch f {
+	case SortByCompleted, SortByDueDate, SortByID, SortByNote:
+		return true
+	}
+	return false
 }
 
-// GetToDoItem will return the todo identified by 'id' or a nil todo if there
-// wasn't a matching todo.
-func GetToDoItem(db *sql.DB, id int) (*Item, error) {
-	row := db.QueryRow(getToDoQuery, id)
-	var td Item
-	err := row.Scan(&td.ID,
-		&td.Note,
-		&td.DueDate,
-		&td.Repeat,
-		&td.Completed)
-	if err != nil && err != sql.ErrNoRows {
-		return nil, errors.Annotate(err, "error scanning todo row")
-	}
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
+// ListOptions specifies which subset of the To Do list is to be returned
+type ListOptions struct {
+	// After is the ID of the last item on the previous page. Only items sorted after
+	// that item will be returned. Zero means start from the beginning.
+	After int64
+	// Limit is the maximum number of items to return. Zero means DefaultPageLimit.
+	Limit int
+	// SortBy is the field the items are ordered by. Items with equal values are ordered
+	// by ID so that the order, and therefore the pages, are stable. Empty means SortByID.
+	SortBy SortField
+	// SortDesc reverses the sort order
+	SortDesc bool
+	// Owner is the user whose items are returned
+	Owner string
+	// AllOwners returns every user's items, Owner is ignored. It's intended for background
+	// processing, e.g., reminders, never for requests made on behalf of a user.
+	AllOwners bool
 
-	return &td, nil
+	//
+	// The following fields filter the returned items. Unset (nil or zero) fields don't filter.
+	//
+
+	// ListID, when non-zero, only returns items in that list
+	ListID int64
+	// Completed, when set, only returns items whose Completed field matches
+	Completed *bool
+	// Repeat, when set, only returns items whose Repeat field matches
+	Repeat *bool
+	// DueBefore only returns items due before this time
+	DueBefore time.Time
+	// DueAfter only returns items due after this time
+	DueAfter time.Time
 }
 
-// InsertToDo takes the provided todo data, inserts it into the db, and returns the newly created todo ID.
-func InsertToDo(db *sql.DB, td Item) (int64, error) {
-	err := validateToDo(td)
-	if err != nil {
-		return 0, errors.Annotate(err, "ToDo validation failure")
+func (o ListOptions) limit() int {
+	if o.Limit <= 0 {
+		return DefaultPageLimit
 	}
+	return o.Limit
+}
 
-	var id int64
-	err = db.QueryRow(insertToDoStmt, td.Note, td.DueDate, td.Repeat, td.Completed).Scan(&id)
-	if err != nil {
-		return 0, errors.Annotate(err, fmt.Sprintf("error inserting todo %+v into DB", td))
+func (o ListOptions) sortBy() SortField {
+	if len(o.SortBy) == 0 {
+		return SortByID
 	}
-
-	return id, nil
+	return o.SortBy
 }
 
-// UpdateToDo takes the provided todo data, inserts it into the db, and returns the newly created todo ID
-func UpdateToDo(db *sql.DB, td Item) (constants.ErrCode, error) {
-	err := validateToDo(td)
-	if err != nil {
-		return constants.DBUpSertErrorCode, errors.Annotate(err, "ToDo validation failure")
+// less indicates if 'a' is ordered before 'b' according to the sort order in 'o'
+func (o ListOptions) less(a, b Item) bool {
+	cmp := 0
+	switch o.sortBy() {
+	case SortByCompleted:
+		if a.Completed != b.Completed {
+			cmp = 1
+			if b.Completed {
+				cmp = -1
+			}
+		}
+	case SortByDueDate:
+		if a.DueDate.Before(b.DueDate) {
+			cmp = -1
+		} else if a.DueDate.After(b.DueDate) {
+			cmp = 1
+		}
+	case SortByNote:
+		// Byte order, the PGStore sorts notes using the "C" collation to match
+		cmp = strings.Compare(a.Note, b.Note)
 	}
-
-	_, err = db.Exec(updateToDoStmt, td.Note, td.DueDate, td.Repeat, td.Completed, td.ID)
-	if err != nil {
-		return constants.DBUpSertErrorCode, errors.Annotate(err, fmt.Sprintf("error updating todo in the database: %+v", td))
+	if cmp == 0 && a.ID != b.ID {
+		cmp = 1
+		if a.ID < b.ID {
+			cmp = -1
+		}
 	}
 
-	return constants.NoErrorCode, nil
+	if o.SortDesc {
+		return cmp > 0
+	}
+	return cmp < 0
 }
 
-// DeleteToDo deletes the todo identified by td.id from the database
-func DeleteToDo(db *sql.DB, id int) (constants.ErrCode, error) {
-	_, err := db.Exec(deleteToDoStmt, id)
-	if err != nil {
-		return constants.DBDeleteErrorCode, errors.Annotate(err, fmt.Sprintf("ToDo delete error for ID %d", id))
+// matches indicates if 'td' satisfies the filters in 'o'
+func (o ListOptions) matches(td Item) bool {
+	if !o.AllOwners && td.Owner != o.Owner {
+		return false
+	}
+	if o.ListID != 0 && td.ListID != o.ListID {
+		return false
+	}
+	if o.Completed != nil && td.Completed != *o.Completed {
+		return false
+	}
+	if o.Repeat != nil && td.Repeat != *o.Repeat {
+		return false
 	}
+	if !o.DueBefore.IsZero() && !td.DueDate.Before(o.DueBefore) {
+		return false
+	}
+	if !o.DueAfter.IsZero() && !td.DueDate.After(o.DueAfter) {
+		return false
+	}
+	return true
+}
+
+// Store defines the operations needed to persist and retrieve To Do items. It allows
+// callers, like the HTTP handlers, to be independent of the underlying storage
+// technology (e.g., Postgres or in-memory).
+//
+// Every operation is scoped to an owner, the user the todos and lists belong to. Todos
+// belonging to other users are treated as if they don't exist. The exceptions are the
+// operations that return a user's role on a todo or list, which include lists shared
+// with the user, and the share operations, which the caller must authorize.
+//
+// Operations that wait, e.g., for a database, give up and return an error if their 'ctx' is
+// done first, e.g., because the client disconnected or the service is shutting down.
+type Store interface {
+	// GetToDoList will return all of the ToDo items belonging to 'owner'
+	GetToDoList(ctx context.Context, owner string) (List, error)
+	// GetToDoListPage will return, in the order specified by 'opts', up to opts.Limit ToDo
+	// items following opts.After that satisfy the filters in 'opts'. 'more' will be true if
+	// there are additional items after the returned page. ErrUnknownCursor is returned if
+	// opts.After doesn't identify one of opts.Owner's todos, e.g., it's been deleted.
+	GetToDoListPage(ctx context.Context, opts ListOptions) (tdl List, more bool, err error)
+	// GetToDoItem will return the todo identified by 'id', and belonging to 'owner', or a
+	// nil todo if there wasn't a matching todo.
+	GetToDoItem(ctx context.Context, owner string, id int) (*Item, error)
+	// GetToDoItemRole returns the todo identified by 'id' and 'username's role on it, or a
+	// nil todo if it doesn't exist or isn't visible to 'username'. A todo is visible to its
+	// owner and to the users its list is shared with.
+	GetToDoItemRole(ctx context.Context, username string, id int) (*Item, Role, error)
+	// InsertToDo takes the provided todo data, stores it, and returns the newly created todo
+	// ID. The todo belongs to td.Owner.
+	InsertToDo(ctx context.Context, td Item) (int64, error)
+	// InsertToDos stores all of the provided todos, or none of them if any of them can't be
+	// stored, and returns the newly created todo IDs in the same order as 'tds'.
+	InsertToDos(ctx context.Context, tds []Item) ([]int64, error)
+	// UpdateToDo takes the provided todo data and replaces the stored todo identified by td.ID,
+	// and belonging to td.Owner.
+	// A completed repeating todo is rolled forward to its next occurrence, see NextOccurrence.
+	// If td.Version is non-zero the todo is only replaced if its version matches, otherwise
+	// ToDoVersionConflictErrorCode is returned. DBInvalidRequestCode is returned if the todo
+	// doesn't exist and ListNotFoundErrorCode if td.ListID doesn't.
+	UpdateToDo(ctx context.Context, td Item) (constants.ErrCode, error)
+	// PatchToDo atomically applies the JSON Merge Patch, 'patch', to the todo identified
+	// by 'id' and returns the updated todo, or a nil todo if there wasn't a matching todo.
+	// See MergePatch for details. Repeating todos are handled as for UpdateToDo. If
+	// 'version' is non-zero the todo is only patched if its version matches, otherwise
+	// ToDoVersionConflictErrorCode is returned.
+	PatchToDo(ctx context.Context, owner string, id int, patch []byte, version int64) (*Item, constants.ErrCode, error)
+	// DeleteToDo deletes the todo identified by 'id', and belonging to 'owner'. If 'version'
+	// is non-zero the todo is only deleted if its version matches, otherwise
+	// ToDoVersionConflictErrorCode is returned. DBInvalidRequestCode is returned if the todo
+	// doesn't exist.
+	DeleteToDo(ctx context.Context, owner string, id int, version int64) (constants.ErrCode, error)
 
-	return constants.NoErrorCode, nil
+	// GetLists returns all of the lists visible to 'owner', those it owns, the default
+	// list, and those shared with it, in ID order
+	GetLists(ctx context.Context, owner string) ([]ListInfo, error)
+	// GetList returns the list identified by 'id' or nil if there isn't one visible to
+	// 'owner'. Lists shared with 'owner' aren't included, see GetListRole.
+	GetList(ctx context.Context, owner string, id int64) (*ListInfo, error)
+	// GetListRole returns the list identified by 'id' and 'username's role on it, or a nil
+	// list if it doesn't exist or isn't visible to 'username'. Users are the owners of their
+	// own lists and the default list.
+	GetListRole(ctx context.Context, username string, id int64) (*ListInfo, Role, error)
+	// InsertList stores 'l' and returns its newly created ID. The list belongs to l.Owner.
+	InsertList(ctx context.Context, l ListInfo) (int64, error)
+	// UpdateList replaces the name and description of the list identified by l.ID, and
+	// owned by l.Owner. ListNotFoundErrorCode is returned if the list doesn't exist.
+	UpdateList(ctx context.Context, l ListInfo) (constants.ErrCode, error)
+	// DeleteList deletes the list identified by 'id', and owned by 'owner', and all of its
+	// todos. The default list can't be deleted, ListValidationErrorCode is returned if it's
+	// requested. ListNotFoundErrorCode is returned if the list doesn't exist.
+	DeleteList(ctx context.Context, owner string, id int64) (constants.ErrCode, error)
+
+	// GetShares returns the shares of the list identified by 'listID' ordered by username
+	GetShares(ctx context.Context, listID int64) ([]Share, error)
+	// PutShare grants s.Username s.Role on the list identified by s.ListID, replacing any
+	// role they already have. 'created' is true if they didn't have one.
+	// ListValidationErrorCode is returned if 's' is invalid, see ValidateShare, and
+	// ListNotFoundErrorCode if the list doesn't exist.
+	PutShare(ctx context.Context, s Share) (created bool, errCode constants.ErrCode, err error)
+	// DeleteShare revokes 'username's access to the list identified by 'listID'.
+	// ListShareNotFoundErrorCode is returned if the list isn't shared with 'username'.
+	DeleteShare(ctx context.Context, listID int64, username string) (constants.ErrCode, error)
 }
 
-func validateToDo(td Item) error {
+// ValidateToDo returns an error describing what's wrong with 'td's data, if anything
+func ValidateToDo(td Item) error {
 	errMsg := ""
 
 	if len(td.Note) == 0 {
 		errMsg = errMsg + "ToDo note must be populated"
 	}
 
+	if len(td.Recurrence) > 0 {
+		if !td.Repeat {
+			errMsg = appendErrMsg(errMsg, "ToDo recurrence requires repeat to be true")
+		} else if _, err := ParseRule(td.Recurrence); err != nil {
+			errMsg = appendErrMsg(errMsg, err.Error())
+		}
+	}
+
 	if len(errMsg) > 0 {
 		return errors.New(errMsg)
 	}
 	return nil
 }
+
+func appendErrMsg(errMsg string, msg string) string {
+	if len(errMsg) > 0 {
+		return errMsg + ", " + msg
+	}
+	return msg
+}
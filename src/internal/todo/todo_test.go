@@ -7,7 +7,7 @@ func TestValidate(t *testing.T) {
 		Note: "",
 	}
 
-	err := validateToDo(item)
+	err := ValidateToDo(item)
 	if err == nil {
 		t.Error("expected error")
 	}
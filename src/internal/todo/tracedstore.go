@@ -0,0 +1,184 @@
+package todo
+
+import (
+	"context"
+
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans recorded by this package
+const tracerName = "github.com/youngkin/todoshaleapps/src/internal/todo"
+
+// tracedStore is a Store that records a span for each call to 'store'
+type tracedStore struct {
+	store Store
+}
+
+// NewTracedStore returns a Store that records each call to 's' as a span, a child of the
+// span in the call's context, e.g., the span of the request the call is made for
+func NewTracedStore(s Store) Store {
+	return tracedStore{store: s}
+}
+
+// start starts the span of a call to 'operation'
+func (s tracedStore) start(ctx context.Context, operation string) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, "todo."+operation,
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(semconv.DBOperationName(operation)))
+}
+
+// end ends 'span' recording 'err', if it isn't nil
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// GetToDoList records a span for Store.GetToDoList
+func (s tracedStore) GetToDoList(ctx context.Context, owner string) (List, error) {
+	ctx, span := s.start(ctx, "GetToDoList")
+	tdl, err := s.store.GetToDoList(ctx, owner)
+	end(span, err)
+	return tdl, err
+}
+
+// GetToDoListPage records a span for Store.GetToDoListPage
+func (s tracedStore) GetToDoListPage(ctx context.Context, opts ListOptions) (List, bool, error) {
+	ctx, span := s.start(ctx, "GetToDoListPage")
+	tdl, more, err := s.store.GetToDoListPage(ctx, opts)
+	end(span, err)
+	return tdl, more, err
+}
+
+// GetToDoItem records a span for Store.GetToDoItem
+func (s tracedStore) GetToDoItem(ctx context.Context, owner string, id int) (*Item, error) {
+	ctx, span := s.start(ctx, "GetToDoItem")
+	td, err := s.store.GetToDoItem(ctx, owner, id)
+	end(span, err)
+	return td, err
+}
+
+// GetToDoItemRole records a span for Store.GetToDoItemRole
+func (s tracedStore) GetToDoItemRole(ctx context.Context, username string, id int) (*Item, Role, error) {
+	ctx, span := s.start(ctx, "GetToDoItemRole")
+	td, role, err := s.store.GetToDoItemRole(ctx, username, id)
+	end(span, err)
+	return td, role, err
+}
+
+// InsertToDo records a span for Store.InsertToDo
+func (s tracedStore) InsertToDo(ctx context.Context, td Item) (int64, error) {
+	ctx, span := s.start(ctx, "InsertToDo")
+	id, err := s.store.InsertToDo(ctx, td)
+	end(span, err)
+	return id, err
+}
+
+// InsertToDos records a span for Store.InsertToDos
+func (s tracedStore) InsertToDos(ctx context.Context, tds []Item) ([]int64, error) {
+	ctx, span := s.start(ctx, "InsertToDos")
+	ids, err := s.store.InsertToDos(ctx, tds)
+	end(span, err)
+	return ids, err
+}
+
+// UpdateToDo records a span for Store.UpdateToDo
+func (s tracedStore) UpdateToDo(ctx context.Context, td Item) (constants.ErrCode, error) {
+	ctx, span := s.start(ctx, "UpdateToDo")
+	errCode, err := s.store.UpdateToDo(ctx, td)
+	end(span, err)
+	return errCode, err
+}
+
+// PatchToDo records a span for Store.PatchToDo
+func (s tracedStore) PatchToDo(ctx context.Context, owner string, id int, patch []byte, version int64) (*Item, constants.ErrCode, error) {
+	ctx, span := s.start(ctx, "PatchToDo")
+	td, errCode, err := s.store.PatchToDo(ctx, owner, id, patch, version)
+	end(span, err)
+	return td, errCode, err
+}
+
+// DeleteToDo records a span for Store.DeleteToDo
+func (s tracedStore) DeleteToDo(ctx context.Context, owner string, id int, version int64) (constants.ErrCode, error) {
+	ctx, span := s.start(ctx, "DeleteToDo")
+	errCode, err := s.store.DeleteToDo(ctx, owner, id, version)
+	end(span, err)
+	return errCode, err
+}
+
+// GetLists records a span for Store.GetLists
+func (s tracedStore) GetLists(ctx context.Context, owner string) ([]ListInfo, error) {
+	ctx, span := s.start(ctx, "GetLists")
+	lists, err := s.store.GetLists(ctx, owner)
+	end(span, err)
+	return lists, err
+}
+
+// GetList records a span for Store.GetList
+func (s tracedStore) GetList(ctx context.Context, owner string, id int64) (*ListInfo, error) {
+	ctx, span := s.start(ctx, "GetList")
+	l, err := s.store.GetList(ctx, owner, id)
+	end(span, err)
+	return l, err
+}
+
+// GetListRole records a span for Store.GetListRole
+func (s tracedStore) GetListRole(ctx context.Context, username string, id int64) (*ListInfo, Role, error) {
+	ctx, span := s.start(ctx, "GetListRole")
+	l, role, err := s.store.GetListRole(ctx, username, id)
+	end(span, err)
+	return l, role, err
+}
+
+// InsertList records a span for Store.InsertList
+func (s tracedStore) InsertList(ctx context.Context, l ListInfo) (int64, error) {
+	ctx, span := s.start(ctx, "InsertList")
+	id, err := s.store.InsertList(ctx, l)
+	end(span, err)
+	return id, err
+}
+
+// UpdateList records a span for Store.UpdateList
+func (s tracedStore) UpdateList(ctx context.Context, l ListInfo) (constants.ErrCode, error) {
+	ctx, span := s.start(ctx, "UpdateList")
+	errCode, err := s.store.UpdateList(ctx, l)
+	end(span, err)
+	return errCode, err
+}
+
+// DeleteList records a span for Store.DeleteList
+func (s tracedStore) DeleteList(ctx context.Context, owner string, id int64) (constants.ErrCode, error) {
+	ctx, span := s.start(ctx, "DeleteList")
+	errCode, err := s.store.DeleteList(ctx, owner, id)
+	end(span, err)
+	return errCode, err
+}
+
+// GetShares records a span for Store.GetShares
+func (s tracedStore) GetShares(ctx context.Context, listID int64) ([]Share, error) {
+	ctx, span := s.start(ctx, "GetShares")
+	shares, err := s.store.GetShares(ctx, listID)
+	end(span, err)
+	return shares, err
+}
+
+// PutShare records a span for Store.PutShare
+func (s tracedStore) PutShare(ctx context.Context, share Share) (bool, constants.ErrCode, error) {
+	ctx, span := s.start(ctx, "PutShare")
+	created, errCode, err := s.store.PutShare(ctx, share)
+	end(span, err)
+	return created, errCode, err
+}
+
+// DeleteShare records a span for Store.DeleteShare
+func (s tracedStore) DeleteShare(ctx context.Context, listID int64, username string) (constants.ErrCode, error) {
+	ctx, span := s.start(ctx, "DeleteShare")
+	errCode, err := s.store.DeleteShare(ctx, listID, username)
+	end(span, err)
+	return errCode, err
+}
@@ -0,0 +1,93 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/juju/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOTLPExporter(t *testing.T) {
+	var received []otlpRequest
+	testSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected Content-Type application/json, got %q", ct)
+		}
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected reading the request", err)
+		}
+		var req otlpRequest
+		err = json.Unmarshal(body, &req)
+		if err != nil {
+			t.Fatalf("an error '%s' was not expected unmarshaling %s", err, body)
+		}
+		received = append(received, req)
+	}))
+	defer testSrv.Close()
+
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(NewOTLPExporter(testSrv.URL)))
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "GET /todos/{id}",
+		trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attribute.Int("http.response.status_code", 500)))
+	_, child := tp.Tracer("test").Start(ctx, "todo.GetToDoItem")
+	child.RecordError(errors.New("connection refused"))
+	child.SetStatus(codes.Error, "connection refused")
+	child.End()
+	parent.End()
+
+	if len(received) != 2 {
+		t.Fatalf("expected 2 export requests, got %d", len(received))
+	}
+	childSpan := received[0].ResourceSpans[0].ScopeSpans[0].Spans[0]
+	parentSpan := received[1].ResourceSpans[0].ScopeSpans[0].Spans[0]
+	if received[1].ResourceSpans[0].ScopeSpans[0].Scope.Name != "test" {
+		t.Errorf("expected scope 'test', got %+v", received[1].ResourceSpans[0].ScopeSpans[0].Scope)
+	}
+
+	if parentSpan.TraceID != parent.SpanContext().TraceID().String() || parentSpan.SpanID != parent.SpanContext().SpanID().String() {
+		t.Errorf("expected trace ID %s and span ID %s, got %+v", parent.SpanContext().TraceID(), parent.SpanContext().SpanID(), parentSpan)
+	}
+	if childSpan.TraceID != parentSpan.TraceID || childSpan.ParentSpanID != parentSpan.SpanID {
+		t.Errorf("expected the child span to be a child of %s, got %+v", parentSpan.SpanID, childSpan)
+	}
+	if parentSpan.Kind != 2 || parentSpan.Name != "GET /todos/{id}" {
+		t.Errorf("expected a server span named 'GET /todos/{id}', got %+v", parentSpan)
+	}
+	if len(parentSpan.Attributes) != 1 || parentSpan.Attributes[0].Key != "http.response.status_code" ||
+		parentSpan.Attributes[0].Value.IntValue != "500" {
+		t.Errorf("expected the http.response.status_code attribute, got %+v", parentSpan.Attributes)
+	}
+	if childSpan.Status != (otlpStatus{Code: otlpStatusError, Message: "connection refused"}) {
+		t.Errorf("expected an error status, got %+v", childSpan.Status)
+	}
+	if len(childSpan.Events) != 1 || childSpan.Events[0].Name != "exception" {
+		t.Errorf("expected an exception event, got %+v", childSpan.Events)
+	}
+}
+
+func TestOTLPExporterRejected(t *testing.T) {
+	testSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer testSrv.Close()
+
+	tp := sdktrace.NewTracerProvider()
+	_, span := tp.Tracer("test").Start(context.Background(), "span")
+	span.End()
+	ro, ok := span.(sdktrace.ReadOnlySpan)
+	if !ok {
+		t.Fatalf("expected a sdktrace.ReadOnlySpan")
+	}
+	err := NewOTLPExporter(testSrv.URL).ExportSpans(context.Background(), []sdktrace.ReadOnlySpan{ro})
+	if err == nil {
+		t.Errorf("expected an error when the collector rejects the spans")
+	}
+}
@@ -0,0 +1,80 @@
+// Package tracing configures OpenTelemetry tracing for todod. Spans are exported to stdout,
+// a file, or an OpenTelemetry collector, and trace context is propagated to and from other
+// services using the W3C Trace Context 'traceparent' and 'tracestate' headers.
+package tracing
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/juju/errors"
+	"github.com/youngkin/todoshaleapps/src/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ServiceName identifies todod's spans
+const ServiceName = "todod"
+
+// Start installs the global tracer provider described by 'c' and the W3C Trace Context
+// propagator. The propagator is installed even if the exporter is 'none' so that callers'
+// trace context is passed on. The returned function exports any buffered spans and stops
+// the exporter, it must be called before todod exits.
+func Start(c config.Tracing) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var (
+		exporter sdktrace.SpanExporter
+		closer   io.Closer
+		err      error
+	)
+	switch c.Exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "file":
+		var f *os.File
+		f, err = os.OpenFile(c.File, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return nil, errors.Annotatef(err, "error opening trace file %s", c.File)
+		}
+		closer = f
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	case "otlp":
+		exporter = NewOTLPExporter(c.Endpoint)
+	default:
+		return nil, errors.Errorf("unknown trace exporter %q, expected 'none', 'stdout', 'file', or 'otlp'", c.Exporter)
+	}
+	if err != nil {
+		return nil, errors.Annotatef(err, "error creating %s trace exporter", c.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName)))
+	if err != nil {
+		return nil, errors.Annotate(err, "error creating trace resource")
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+
+	return func(ctx context.Context) error {
+		err := tp.Shutdown(ctx)
+		if closer != nil {
+			closeErr := closer.Close()
+			if err == nil {
+				err = closeErr
+			}
+		}
+		return errors.Trace(err)
+	}, nil
+}
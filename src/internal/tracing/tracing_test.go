@@ -0,0 +1,54 @@
+package tracing
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/youngkin/todoshaleapps/src/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func TestStartFileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tracing")
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected creating a temp dir", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spans.json")
+
+	stop, err := Start(config.Tracing{Exporter: "file", File: path, SampleRatio: 1})
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected starting tracing", err)
+	}
+	_, span := otel.Tracer("test").Start(context.Background(), "test span")
+	span.End()
+	err = stop(context.Background())
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected stopping tracing", err)
+	}
+
+	spans, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected reading %s", err, path)
+	}
+	for _, expected := range []string{`"Name":"test span"`, span.SpanContext().TraceID().String(), `"Value":"todod"`} {
+		if !strings.Contains(string(spans), expected) {
+			t.Errorf("expected the exported spans to contain %s, got %s", expected, spans)
+		}
+	}
+	if _, ok := otel.GetTextMapPropagator().(propagation.TraceContext); !ok {
+		t.Errorf("expected the W3C Trace Context propagator, got %T", otel.GetTextMapPropagator())
+	}
+}
+
+func TestStartUnknownExporter(t *testing.T) {
+	_, err := Start(config.Tracing{Exporter: "zipkin"})
+	if err == nil {
+		t.Errorf("expected an error for an unknown exporter")
+	}
+}
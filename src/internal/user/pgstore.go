@@ -0,0 +1,84 @@
+package user
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/lib/pq"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+var (
+	getUserQuery   = "SELECT id, username, password_hash FROM users WHERE username = $1"
+	insertUserStmt = "INSERT INTO users (username, password_hash) VALUES ($1, $2) RETURNING id"
+)
+
+// postgresUniqueErrorCode is the Postgres error code for a unique constraint violation.
+// See https://www.postgresql.org/docs/current/errcodes-appendix.html
+const postgresUniqueErrorCode = "23505"
+
+// PGStore is a Store backed by a Postgres database
+type PGStore struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+// NewPGStore returns a *PGStore that will use the provided database connection. Each
+// operation is cancelled if it takes longer than 'timeout', 0 means operations are only
+// limited by their contexts.
+func NewPGStore(db *sql.DB, timeout time.Duration) (*PGStore, error) {
+	if db == nil {
+		return nil, errors.New("non-nil sql.DB connection required")
+	}
+	if timeout < 0 {
+		return nil, errors.New("non-negative timeout required")
+	}
+	return &PGStore{db: db, timeout: timeout}, nil
+}
+
+// withTimeout returns a copy of 'ctx' that's cancelled after the store's timeout, if it has
+// one. 'cancel' must be called when the operation completes.
+func (p *PGStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.timeout > 0 {
+		return context.WithTimeout(ctx, p.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// GetUser returns the user identified by 'username', or nil if there isn't one
+func (p *PGStore) GetUser(ctx context.Context, username string) (*User, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var u User
+	err := p.db.QueryRowContext(ctx, getUserQuery, username).Scan(&u.ID, &u.Username, &u.PasswordHash)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Annotate(err, "error scanning user row")
+	}
+	return &u, nil
+}
+
+// InsertUser stores 'u' and returns its newly created ID
+func (p *PGStore) InsertUser(ctx context.Context, u User) (int64, constants.ErrCode, error) {
+	if len(u.Username) == 0 || len(u.PasswordHash) == 0 {
+		return 0, constants.UserValidationErrorCode, errors.New("username and password hash must be populated")
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	err := p.db.QueryRowContext(ctx, insertUserStmt, u.Username, u.PasswordHash).Scan(&id)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == postgresUniqueErrorCode {
+		return 0, constants.UserExistsErrorCode, errors.Errorf("user %s already exists", u.Username)
+	}
+	if err != nil {
+		return 0, constants.DBUpSertErrorCode, errors.Annotate(err, "error inserting user into DB")
+	}
+	return id, constants.NoErrorCode, nil
+}
@@ -0,0 +1,61 @@
+package user
+
+import (
+	"context"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+// Store defines the operations used to manage users
+type Store interface {
+	// GetUser returns the user identified by 'username', or nil if there isn't one
+	GetUser(ctx context.Context, username string) (*User, error)
+	// InsertUser stores 'u' and returns its newly created ID. UserExistsErrorCode is
+	// returned if u.Username is taken.
+	InsertUser(ctx context.Context, u User) (int64, constants.ErrCode, error)
+}
+
+// MemStore is a Store that keeps users in memory, they're lost when the process exits
+type MemStore struct {
+	mu     sync.RWMutex
+	users  map[string]User
+	lastID int64
+}
+
+// NewMemStore returns an empty *MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{users: map[string]User{}}
+}
+
+// GetUser returns the user identified by 'username', or nil if there isn't one
+func (m *MemStore) GetUser(ctx context.Context, username string) (*User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	u, ok := m.users[username]
+	if !ok {
+		return nil, nil
+	}
+	return &u, nil
+}
+
+// InsertUser stores 'u' and returns its newly created ID
+func (m *MemStore) InsertUser(ctx context.Context, u User) (int64, constants.ErrCode, error) {
+	if len(u.Username) == 0 || len(u.PasswordHash) == 0 {
+		return 0, constants.UserValidationErrorCode, errors.New("username and password hash must be populated")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.users[u.Username]; ok {
+		return 0, constants.UserExistsErrorCode, errors.Errorf("user %s already exists", u.Username)
+	}
+	m.lastID++
+	u.ID = m.lastID
+	m.users[u.Username] = u
+
+	return u.ID, constants.NoErrorCode, nil
+}
@@ -0,0 +1,89 @@
+package user
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/juju/errors"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinPasswordLength is the minimum length of a user's password
+const MinPasswordLength = 8
+
+// usernamePattern describes valid usernames, they're used in URLs and log messages. They
+// can't contain ':' so they're distinct from bearer token principals, e.g., 'svc:reporting'.
+var usernamePattern = regexp.MustCompile(`^[a-zA-Z0-9._-]{1,64}$`)
+
+// User is an account that owns todos and lists
+type User struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	// PasswordHash is the bcrypt hash of the user's password, the password itself is
+	// never stored
+	PasswordHash string `json:"-"`
+}
+
+// Credentials is the request body used to create a user
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ValidateCredentials returns an error describing what's wrong with 'c', if anything
+func ValidateCredentials(c Credentials) error {
+	if !usernamePattern.MatchString(c.Username) {
+		return errors.Errorf("username must be 1 to 64 letters, digits, '.', '_', or '-', got %q", c.Username)
+	}
+	if len(c.Password) < MinPasswordLength {
+		return errors.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	return nil
+}
+
+// NewUser validates 'c' and returns the User it describes, with its password hashed
+func NewUser(c Credentials) (User, error) {
+	err := ValidateCredentials(c)
+	if err != nil {
+		return User{}, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(c.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return User{}, errors.Annotate(err, "error hashing password")
+	}
+	return User{Username: c.Username, PasswordHash: string(hash)}, nil
+}
+
+// Authenticate returns the user identified by 'username' if 'password' is theirs, or nil
+// if the user doesn't exist or the password is wrong. The two cases aren't distinguished
+// so usernames can't be discovered.
+func Authenticate(ctx context.Context, s Store, username, password string) (*User, error) {
+	u, err := s.GetUser(ctx, username)
+	if err != nil {
+		return nil, errors.Annotate(err, "error retrieving user")
+	}
+	if u == nil {
+		return nil, nil
+	}
+	err = bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password))
+	if err != nil {
+		return nil, nil
+	}
+	return u, nil
+}
+
+// contextKey is the type of the key used to store the authenticated user's name in a
+// context.Context. It's unexported so other packages can't collide with it.
+type contextKey struct{}
+
+// NewContext returns a copy of 'ctx' carrying 'username', the authenticated user
+func NewContext(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, contextKey{}, username)
+}
+
+// FromContext returns the name of the authenticated user carried by 'ctx', or "" if there
+// isn't one
+func FromContext(ctx context.Context) string {
+	username, _ := ctx.Value(contextKey{}).(string)
+	return username
+}
@@ -0,0 +1,153 @@
+package user
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+func TestValidateCredentials(t *testing.T) {
+	tcs := []struct {
+		testName    string
+		creds       Credentials
+		shouldError bool
+	}{
+		{
+			testName: "testValid",
+			creds:    Credentials{Username: "r.young-kin_1", Password: "password123"},
+		},
+		{
+			testName:    "testEmptyUsername",
+			creds:       Credentials{Password: "password123"},
+			shouldError: true,
+		},
+		{
+			testName:    "testUsernameWithSpace",
+			creds:       Credentials{Username: "r youngkin", Password: "password123"},
+			shouldError: true,
+		},
+		{
+			testName:    "testBearerPrincipal",
+			creds:       Credentials{Username: "svc:reporting", Password: "password123"},
+			shouldError: true,
+		},
+		{
+			testName:    "testShortPassword",
+			creds:       Credentials{Username: "ryoungkin", Password: "short"},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := ValidateCredentials(tc.creds)
+			if (err != nil) != tc.shouldError {
+				t.Errorf("expected error %t, got %v", tc.shouldError, err)
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	s := NewMemStore()
+	u, err := NewUser(Credentials{Username: "ryoungkin", Password: "password123"})
+	if err != nil {
+		t.Fatalf("unexpected error creating user: %s", err)
+	}
+	if u.PasswordHash == "password123" {
+		t.Error("expected the password to be hashed")
+	}
+	_, _, err = s.InsertUser(context.Background(), u)
+	if err != nil {
+		t.Fatalf("unexpected error inserting user: %s", err)
+	}
+	_, errCode, _ := s.InsertUser(context.Background(), u)
+	if errCode != constants.UserExistsErrorCode {
+		t.Errorf("expected user exists inserting a duplicate user, got error code %d", errCode)
+	}
+
+	tcs := []struct {
+		testName      string
+		username      string
+		password      string
+		authenticated bool
+	}{
+		{testName: "testCorrectPassword", username: "ryoungkin", password: "password123", authenticated: true},
+		{testName: "testWrongPassword", username: "ryoungkin", password: "password456"},
+		{testName: "testUnknownUser", username: "jdoe", password: "password123"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			u, err := Authenticate(context.Background(), s, tc.username, tc.password)
+			if err != nil {
+				t.Fatalf("unexpected error authenticating: %s", err)
+			}
+			if (u != nil) != tc.authenticated {
+				t.Errorf("expected authenticated to be %t, got %+v", tc.authenticated, u)
+			}
+		})
+	}
+}
+
+func TestContext(t *testing.T) {
+	if username := FromContext(context.Background()); username != "" {
+		t.Errorf("expected no user in an empty context, got %q", username)
+	}
+	ctx := NewContext(context.Background(), "ryoungkin")
+	if username := FromContext(ctx); username != "ryoungkin" {
+		t.Errorf("expected user ryoungkin, got %q", username)
+	}
+}
+
+func TestPGStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(insertUserStmt)).
+		WithArgs("ryoungkin", "hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(insertUserStmt)).
+		WithArgs("ryoungkin", "hash").
+		WillReturnError(&pq.Error{Code: postgresUniqueErrorCode})
+	mock.ExpectQuery(regexp.QuoteMeta(getUserQuery)).
+		WithArgs("ryoungkin").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash"}).AddRow(1, "ryoungkin", "hash"))
+	mock.ExpectQuery(regexp.QuoteMeta(getUserQuery)).
+		WithArgs("jdoe").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password_hash"}))
+
+	s, err := NewPGStore(db, 0)
+	if err != nil {
+		t.Fatalf("unexpected error creating PGStore: %s", err)
+	}
+
+	id, _, err := s.InsertUser(context.Background(), User{Username: "ryoungkin", PasswordHash: "hash"})
+	if err != nil || id != 1 {
+		t.Errorf("expected user 1 to be inserted, got %d, %v", id, err)
+	}
+	_, errCode, _ := s.InsertUser(context.Background(), User{Username: "ryoungkin", PasswordHash: "hash"})
+	if errCode != constants.UserExistsErrorCode {
+		t.Errorf("expected user exists inserting a duplicate user, got error code %d", errCode)
+	}
+
+	u, err := s.GetUser(context.Background(), "ryoungkin")
+	if err != nil || u == nil || u.ID != 1 || u.PasswordHash != "hash" {
+		t.Errorf("expected user ryoungkin, got %+v, %v", u, err)
+	}
+	u, err = s.GetUser(context.Background(), "jdoe")
+	if err != nil || u != nil {
+		t.Errorf("expected no user and no error, got %+v and %v", u, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// Destinations decides which hosts webhooks can be delivered to. Anyone can subscribe to
+// webhooks, so deliveries to internal addresses, i.e., loopback, link-local (e.g., a cloud
+// provider's metadata service), private, and unspecified addresses, are refused unless the
+// host is allowed by the operator. Otherwise webhooks, and their delivery logs, could be
+// used to probe the services todod can reach.
+type Destinations struct {
+	hosts    map[string]bool
+	networks []*net.IPNet
+	lookup   func(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// NewDestinations returns a *Destinations that allows deliveries to the hosts in 'allowed',
+// even if they're internal, in addition to any external host. Each entry is a host name,
+// an IP address, or a CIDR, e.g., 'hooks.internal', '10.0.0.5', or '10.1.0.0/16'.
+func NewDestinations(allowed []string) (*Destinations, error) {
+	d := &Destinations{hosts: map[string]bool{}, lookup: net.DefaultResolver.LookupIPAddr}
+	for _, a := range allowed {
+		a = strings.ToLower(strings.TrimSpace(a))
+		switch {
+		case len(a) == 0:
+			continue
+		case strings.Contains(a, "/"):
+			_, network, err := net.ParseCIDR(a)
+			if err != nil {
+				return nil, errors.Annotatef(err, "invalid allowed webhook network %q", a)
+			}
+			d.networks = append(d.networks, network)
+		default:
+			d.hosts[a] = true
+		}
+	}
+	return d, nil
+}
+
+// Check returns an error if webhooks can't be delivered to 'rawURL's host, i.e., it's
+// internal, or resolves to an internal address, and it isn't allowed. It's also an error
+// if the host can't be resolved.
+func (d *Destinations) Check(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Annotate(err, "invalid webhook url")
+	}
+	host := u.Hostname()
+	if d.allowedHost(host) {
+		return nil
+	}
+
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		addrs, err := d.lookup(ctx, host)
+		if err != nil {
+			return errors.Errorf("webhook url host %s can't be resolved", host)
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+	for _, ip := range ips {
+		if !d.allowedIP(ip) {
+			return errors.Errorf("webhook url host %s is an internal address", host)
+		}
+	}
+	return nil
+}
+
+// Client returns an *http.Client, with 'timeout', that refuses to connect to internal
+// addresses that aren't allowed. Addresses are checked when they're dialed, after they've
+// been resolved, so a host can't pass Check and then resolve to an internal address.
+func (d *Destinations) Client(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           d.dialContext,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       90 * time.Second,
+		},
+	}
+}
+
+// dialContext connects to 'addr', refusing internal addresses unless its host is allowed
+func (d *Destinations) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, errors.Trace(err)
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !d.allowedHost(host) {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			ipHost, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return errors.Trace(err)
+			}
+			ip := net.ParseIP(ipHost)
+			if ip == nil || !d.allowedIP(ip) {
+				return errors.Errorf("webhook host %s is an internal address", host)
+			}
+			return nil
+		}
+	}
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// allowedHost returns true if 'host', a name or an IP address, was allowed by the operator
+func (d *Destinations) allowedHost(host string) bool {
+	if d.hosts[strings.ToLower(host)] {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && d.inAllowedNetwork(ip)
+}
+
+// allowedIP returns true if webhooks can be delivered to 'ip', i.e., it's external or it's
+// in an allowed network
+func (d *Destinations) allowedIP(ip net.IP) bool {
+	return !internal(ip) || d.hosts[ip.String()] || d.inAllowedNetwork(ip)
+}
+
+// inAllowedNetwork returns true if 'ip' is in one of the allowed networks
+func (d *Destinations) inAllowedNetwork(ip net.IP) bool {
+	for _, n := range d.networks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// internal returns true if 'ip' is a loopback, link-local, private, unspecified, or
+// multicast address
+func internal(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsPrivate() || ip.IsUnspecified()
+}
@@ -0,0 +1,212 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+const (
+	// DefaultMaxAttempts is the number of times delivery of an event is attempted
+	DefaultMaxAttempts = 5
+	// DefaultBackoff is the delay before the first retry, it doubles for each subsequent retry
+	DefaultBackoff = 2 * time.Second
+	// eventQueueSize is the number of published events that can be waiting for delivery
+	eventQueueSize = 100
+)
+
+// Dispatcher delivers published events to the subscriptions in its Store. Failed
+// deliveries are retried, with exponential backoff, up to MaxAttempts times. Every
+// attempt is recorded in the subscription's delivery log.
+type Dispatcher struct {
+	store  Store
+	client *http.Client
+	logger *log.Entry
+	events chan Event
+	done   chan interface{}
+	wg     sync.WaitGroup
+
+	// MaxAttempts is the number of times delivery is attempted before giving up
+	MaxAttempts int
+	// Backoff is the delay before the first retry
+	Backoff time.Duration
+}
+
+// NewDispatcher returns a *Dispatcher that delivers events to the subscriptions in 'store'
+// using 'client', e.g., a Destinations' client. If 'client' is nil a client that refuses to
+// deliver to any internal address is used.
+func NewDispatcher(store Store, client *http.Client, logger *log.Entry) (*Dispatcher, error) {
+	if store == nil {
+		return nil, errors.New("non-nil webhook.Store required")
+	}
+	if logger == nil {
+		return nil, errors.New("non-nil log.Entry  required")
+	}
+	if client == nil {
+		destinations, err := NewDestinations(nil)
+		if err != nil {
+			return nil, errors.Trace(err)
+		}
+		client = destinations.Client(10 * time.Second)
+	}
+
+	return &Dispatcher{
+		store:       store,
+		client:      client,
+		logger:      logger,
+		events:      make(chan Event, eventQueueSize),
+		MaxAttempts: DefaultMaxAttempts,
+		Backoff:     DefaultBackoff,
+	}, nil
+}
+
+// Publish queues 'e' for delivery, it doesn't wait for the event to be delivered. If the
+// queue is full the event is dropped, and logged, rather than delaying the caller.
+func (d *Dispatcher) Publish(e Event) {
+	select {
+	case d.events <- e:
+	default:
+		d.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.WebhookDeliveryErrorCode,
+			constants.ErrorDetail: "event queue full, dropping " + string(e.Type) + " event " + e.ID,
+		}).Error(constants.WebhookDeliveryError)
+	}
+}
+
+// Run delivers published events until 'done' is closed. Deliveries in progress when
+// 'done' is closed aren't retried.
+func (d *Dispatcher) Run(done chan interface{}) {
+	d.done = done
+	// The deliveries' store operations use 'ctx'. It's only cancelled once the deliveries in
+	// progress have finished so their last attempts are still recorded.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	d.logger.Debug("Webhook dispatcher starting...")
+	for {
+		select {
+		case e := <-d.events:
+			d.dispatch(ctx, e)
+		case <-done:
+			d.wg.Wait()
+			d.logger.Info("Webhook dispatcher exiting...")
+			return
+		}
+	}
+}
+
+// dispatch starts delivering 'e' to each subscription that receives it
+func (d *Dispatcher) dispatch(ctx context.Context, e Event) {
+	subs, err := d.store.GetSubscriptionsByOwner(ctx, e.ToDo.Owner)
+	if err != nil {
+		d.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.DBQueryErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Error(constants.DBQueryError)
+		return
+	}
+
+	body, err := json.Marshal(e)
+	if err != nil {
+		d.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.JSONMarshalingErrorCode,
+			constants.ErrorDetail: err.Error(),
+		}).Error(constants.JSONMarshalingError)
+		return
+	}
+
+	for _, s := range subs {
+		if !s.Receives(e) {
+			continue
+		}
+		d.wg.Add(1)
+		go func(s Subscription) {
+			defer d.wg.Done()
+			d.deliver(ctx, s, e, body)
+		}(s)
+	}
+}
+
+// deliver POSTs 'body', the JSON encoded 'e', to 's' until it succeeds or MaxAttempts is reached
+func (d *Dispatcher) deliver(ctx context.Context, s Subscription, e Event, body []byte) {
+	backoff := d.Backoff
+	for attempt := 1; attempt <= d.MaxAttempts; attempt++ {
+		delivery := d.post(s, e, body)
+		delivery.Attempt = attempt
+
+		err := d.store.InsertDelivery(ctx, delivery)
+		if err != nil {
+			d.logger.WithFields(log.Fields{
+				constants.ErrorCode:   constants.DBUpSertErrorCode,
+				constants.ErrorDetail: err.Error(),
+			}).Error(constants.DBUpSertError)
+		}
+		if delivery.Succeeded() {
+			return
+		}
+
+		d.logger.WithFields(log.Fields{
+			constants.ErrorCode:   constants.WebhookDeliveryErrorCode,
+			constants.HTTPStatus:  delivery.HTTPStatus,
+			constants.ErrorDetail: delivery.Err,
+		}).Warnf("delivery attempt %d of %d of %s event %s to webhook %d failed", attempt, d.MaxAttempts, e.Type, e.ID, s.ID)
+		if attempt == d.MaxAttempts {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-d.done:
+			return
+		}
+	}
+
+	d.logger.WithFields(log.Fields{
+		constants.ErrorCode:   constants.WebhookDeliveryErrorCode,
+		constants.ErrorDetail: "giving up on " + string(e.Type) + " event " + e.ID + " to " + s.URL,
+	}).Error(constants.WebhookDeliveryError)
+}
+
+// post makes a single delivery attempt
+func (d *Dispatcher) post(s Subscription, e Event, body []byte) Delivery {
+	delivery := Delivery{
+		SubscriptionID: s.ID,
+		EventID:        e.ID,
+		EventType:      e.Type,
+		DeliveredAt:    time.Now().UTC(),
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		delivery.Err = err.Error()
+		return delivery
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(e.Type))
+	req.Header.Set(EventIDHeader, e.ID)
+	req.Header.Set(SignatureHeader, Sign(s.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		delivery.Err = err.Error()
+		return delivery
+	}
+	defer resp.Body.Close()
+	// Drain the body so the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+
+	delivery.HTTPStatus = resp.StatusCode
+	if !delivery.Succeeded() {
+		delivery.Err = "unexpected response status " + resp.Status
+	}
+	return delivery
+}
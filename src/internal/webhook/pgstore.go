@@ -0,0 +1,226 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+var (
+	getSubscriptionsByOwnerQuery = "SELECT id, owner, url, events, secret FROM webhook WHERE owner = $1 ORDER BY id ASC"
+	getSubscriptionQuery         = "SELECT id, owner, url, events, secret FROM webhook WHERE id = $1"
+	insertSubscriptionStmt       = "INSERT INTO webhook (owner, url, events, secret) VALUES ($1, $2, $3, $4) RETURNING id"
+	updateSubscriptionStmt       = "UPDATE webhook SET url = $1, events = $2, secret = COALESCE(NULLIF($3, ''), secret) WHERE id = $4"
+	deleteSubscriptionStmt       = "DELETE FROM webhook WHERE id = $1"
+	insertDeliveryStmt           = "INSERT INTO webhook_delivery (webhook_id, event_id, event, attempt, status, error, delivered) VALUES ($1, $2, $3, $4, $5, $6, $7)"
+	getDeliveriesQuery           = "SELECT id, webhook_id, event_id, event, attempt, status, error, delivered FROM webhook_delivery WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2"
+)
+
+// PGStore is a Store backed by a Postgres database
+type PGStore struct {
+	db      *sql.DB
+	timeout time.Duration
+}
+
+// NewPGStore returns a *PGStore that will use the provided database connection. Each
+// operation is cancelled if it takes longer than 'timeout', 0 means operations are only
+// limited by their contexts.
+func NewPGStore(db *sql.DB, timeout time.Duration) (*PGStore, error) {
+	if db == nil {
+		return nil, errors.New("non-nil sql.DB connection required")
+	}
+	if timeout < 0 {
+		return nil, errors.New("non-negative timeout required")
+	}
+	return &PGStore{db: db, timeout: timeout}, nil
+}
+
+// withTimeout returns a copy of 'ctx' that's cancelled after the store's timeout, if it has
+// one. 'cancel' must be called when the operation completes.
+func (p *PGStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.timeout > 0 {
+		return context.WithTimeout(ctx, p.timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// joinEvents and splitEvents convert between a subscription's events and their
+// representation in the 'events' column, a comma separated list.
+func joinEvents(events []EventType) string {
+	strs := make([]string, 0, len(events))
+	for _, e := range events {
+		strs = append(strs, string(e))
+	}
+	return strings.Join(strs, ",")
+}
+
+func splitEvents(events string) []EventType {
+	var ets []EventType
+	for _, e := range strings.Split(events, ",") {
+		if len(e) > 0 {
+			ets = append(ets, EventType(e))
+		}
+	}
+	return ets
+}
+
+// GetSubscriptionsByOwner returns the subscriptions belonging to 'owner' in ID order
+func (p *PGStore) GetSubscriptionsByOwner(ctx context.Context, owner string) ([]Subscription, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	results, err := p.db.QueryContext(ctx, getSubscriptionsByOwnerQuery, owner)
+	if err != nil {
+		return nil, errors.Annotate(err, "error querying DB")
+	}
+	defer results.Close()
+
+	subs := []Subscription{}
+	for results.Next() {
+		var (
+			s      Subscription
+			events string
+		)
+		err = results.Scan(&s.ID, &s.Owner, &s.URL, &events, &s.Secret)
+		if err != nil {
+			return nil, errors.Annotate(err, "error scanning result set")
+		}
+		s.Events = splitEvents(events)
+		subs = append(subs, s)
+	}
+	err = results.Err()
+	if err != nil {
+		return nil, errors.Annotate(err, "error iterating result set")
+	}
+
+	return subs, nil
+}
+
+// GetSubscription returns the subscription identified by 'id', or nil if there isn't one
+func (p *PGStore) GetSubscription(ctx context.Context, id int64) (*Subscription, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var (
+		s      Subscription
+		events string
+	)
+	err := p.db.QueryRowContext(ctx, getSubscriptionQuery, id).Scan(&s.ID, &s.Owner, &s.URL, &events, &s.Secret)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Annotate(err, fmt.Sprintf("error getting webhook %d", id))
+	}
+	s.Events = splitEvents(events)
+
+	return &s, nil
+}
+
+// InsertSubscription stores 's' and returns its newly created ID
+func (p *PGStore) InsertSubscription(ctx context.Context, s Subscription) (int64, error) {
+	err := ValidateSubscription(s)
+	if err != nil {
+		return 0, errors.Annotate(err, "webhook validation failure")
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	var id int64
+	err = p.db.QueryRowContext(ctx, insertSubscriptionStmt, s.Owner, s.URL, joinEvents(s.Events), s.Secret).Scan(&id)
+	if err != nil {
+		return 0, errors.Annotate(err, fmt.Sprintf("error inserting webhook for %s into DB", s.URL))
+	}
+
+	return id, nil
+}
+
+// UpdateSubscription replaces the subscription identified by s.ID, the secret is only
+// replaced if s.Secret is populated. The subscription's owner isn't changed.
+func (p *PGStore) UpdateSubscription(ctx context.Context, s Subscription) (constants.ErrCode, error) {
+	err := ValidateSubscription(s)
+	if err != nil {
+		return constants.WebhookValidationErrorCode, errors.Annotate(err, "webhook validation failure")
+	}
+
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.ExecContext(ctx, updateSubscriptionStmt, s.URL, joinEvents(s.Events), s.Secret, s.ID)
+	if err != nil {
+		return constants.DBUpSertErrorCode, errors.Annotate(err, fmt.Sprintf("error updating webhook %d in the database", s.ID))
+	}
+
+	return checkResult(result, s.ID, constants.DBUpSertErrorCode)
+}
+
+// DeleteSubscription deletes the subscription identified by 'id'. Its delivery log is
+// deleted by the database, 'ON DELETE CASCADE'.
+func (p *PGStore) DeleteSubscription(ctx context.Context, id int64) (constants.ErrCode, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	result, err := p.db.ExecContext(ctx, deleteSubscriptionStmt, id)
+	if err != nil {
+		return constants.DBDeleteErrorCode, errors.Annotate(err, fmt.Sprintf("webhook delete error for ID %d", id))
+	}
+
+	return checkResult(result, id, constants.DBDeleteErrorCode)
+}
+
+func checkResult(result sql.Result, id int64, errCode constants.ErrCode) (constants.ErrCode, error) {
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return errCode, errors.Annotate(err, "error getting rows affected")
+	}
+	if rows == 0 {
+		return constants.DBInvalidRequestCode, errors.Errorf("webhook %d doesn't exist", id)
+	}
+	return constants.NoErrorCode, nil
+}
+
+// InsertDelivery adds 'd' to its subscription's delivery log
+func (p *PGStore) InsertDelivery(ctx context.Context, d Delivery) error {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	_, err := p.db.ExecContext(ctx, insertDeliveryStmt, d.SubscriptionID, d.EventID, d.EventType, d.Attempt, d.HTTPStatus, d.Err, d.DeliveredAt)
+	if err != nil {
+		return errors.Annotate(err, fmt.Sprintf("error inserting delivery for webhook %d into DB", d.SubscriptionID))
+	}
+	return nil
+}
+
+// GetDeliveries returns the subscription's most recent deliveries, most recent first
+func (p *PGStore) GetDeliveries(ctx context.Context, id int64) ([]Delivery, error) {
+	ctx, cancel := p.withTimeout(ctx)
+	defer cancel()
+
+	results, err := p.db.QueryContext(ctx, getDeliveriesQuery, id, MaxDeliveries)
+	if err != nil {
+		return nil, errors.Annotate(err, "error querying DB")
+	}
+	defer results.Close()
+
+	deliveries := []Delivery{}
+	for results.Next() {
+		var d Delivery
+		err = results.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Attempt, &d.HTTPStatus, &d.Err, &d.DeliveredAt)
+		if err != nil {
+			return nil, errors.Annotate(err, "error scanning result set")
+		}
+		deliveries = append(deliveries, d)
+	}
+	err = results.Err()
+	if err != nil {
+		return nil, errors.Annotate(err, "error iterating result set")
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,163 @@
+package webhook
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/juju/errors"
+	"github.com/youngkin/todoshaleapps/src/internal/platform/constants"
+)
+
+// MaxDeliveries is the maximum number of deliveries returned by Store.GetDeliveries
+const MaxDeliveries = 100
+
+// Store defines the operations used to manage subscriptions and their delivery log
+type Store interface {
+	// GetSubscriptionsByOwner returns the subscriptions belonging to 'owner', including
+	// their secrets, in ID order
+	GetSubscriptionsByOwner(ctx context.Context, owner string) ([]Subscription, error)
+	// GetSubscription returns the subscription identified by 'id', or nil if there isn't one
+	GetSubscription(ctx context.Context, id int64) (*Subscription, error)
+	// InsertSubscription stores 's' and returns its newly created ID
+	InsertSubscription(ctx context.Context, s Subscription) (int64, error)
+	// UpdateSubscription replaces the subscription identified by s.ID. If s.Secret is
+	// empty the existing secret is kept. The subscription's owner isn't changed.
+	// DBInvalidRequestCode is returned if the subscription doesn't exist.
+	UpdateSubscription(ctx context.Context, s Subscription) (constants.ErrCode, error)
+	// DeleteSubscription deletes the subscription identified by 'id', and its delivery log.
+	// DBInvalidRequestCode is returned if the subscription doesn't exist.
+	DeleteSubscription(ctx context.Context, id int64) (constants.ErrCode, error)
+	// InsertDelivery adds 'd' to its subscription's delivery log
+	InsertDelivery(ctx context.Context, d Delivery) error
+	// GetDeliveries returns the most recent deliveries, up to MaxDeliveries, to the
+	// subscription identified by 'id', most recent first
+	GetDeliveries(ctx context.Context, id int64) ([]Delivery, error)
+}
+
+// MemStore is a Store that keeps subscriptions in memory, they're lost when the process exits
+type MemStore struct {
+	mu             sync.RWMutex
+	subs           map[int64]Subscription
+	deliveries     map[int64][]Delivery
+	lastID         int64
+	lastDeliveryID int64
+}
+
+// NewMemStore returns an empty *MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{subs: map[int64]Subscription{}, deliveries: map[int64][]Delivery{}}
+}
+
+// GetSubscriptionsByOwner returns the subscriptions belonging to 'owner' in ID order
+func (m *MemStore) GetSubscriptionsByOwner(ctx context.Context, owner string) ([]Subscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	subs := []Subscription{}
+	for _, s := range m.subs {
+		if s.Owner == owner {
+			subs = append(subs, s)
+		}
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].ID < subs[j].ID })
+	return subs, nil
+}
+
+// GetSubscription returns the subscription identified by 'id', or nil if there isn't one
+func (m *MemStore) GetSubscription(ctx context.Context, id int64) (*Subscription, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	s, ok := m.subs[id]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+// InsertSubscription stores 's' and returns its newly created ID
+func (m *MemStore) InsertSubscription(ctx context.Context, s Subscription) (int64, error) {
+	err := ValidateSubscription(s)
+	if err != nil {
+		return 0, errors.Annotate(err, "webhook validation failure")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.lastID++
+	s.ID = m.lastID
+	s.SelfRef = ""
+	m.subs[s.ID] = s
+	return s.ID, nil
+}
+
+// UpdateSubscription replaces the subscription identified by s.ID
+func (m *MemStore) UpdateSubscription(ctx context.Context, s Subscription) (constants.ErrCode, error) {
+	err := ValidateSubscription(s)
+	if err != nil {
+		return constants.WebhookValidationErrorCode, errors.Annotate(err, "webhook validation failure")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cur, ok := m.subs[s.ID]
+	if !ok {
+		return constants.DBInvalidRequestCode, errors.Errorf("webhook %d doesn't exist", s.ID)
+	}
+	if len(s.Secret) == 0 {
+		s.Secret = cur.Secret
+	}
+	s.Owner = cur.Owner
+	s.SelfRef = ""
+	m.subs[s.ID] = s
+	return constants.NoErrorCode, nil
+}
+
+// DeleteSubscription deletes the subscription identified by 'id', and its delivery log
+func (m *MemStore) DeleteSubscription(ctx context.Context, id int64) (constants.ErrCode, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.subs[id]; !ok {
+		return constants.DBInvalidRequestCode, errors.Errorf("webhook %d doesn't exist", id)
+	}
+	delete(m.subs, id)
+	delete(m.deliveries, id)
+	return constants.NoErrorCode, nil
+}
+
+// InsertDelivery adds 'd' to its subscription's delivery log, the oldest deliveries are
+// discarded once there are more than MaxDeliveries
+func (m *MemStore) InsertDelivery(ctx context.Context, d Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.subs[d.SubscriptionID]; !ok {
+		// The subscription was deleted while the event was being delivered
+		return errors.Errorf("webhook %d doesn't exist", d.SubscriptionID)
+	}
+	m.lastDeliveryID++
+	d.ID = m.lastDeliveryID
+	log := append(m.deliveries[d.SubscriptionID], d)
+	if len(log) > MaxDeliveries {
+		log = log[len(log)-MaxDeliveries:]
+	}
+	m.deliveries[d.SubscriptionID] = log
+	return nil
+}
+
+// GetDeliveries returns the subscription's most recent deliveries, most recent first
+func (m *MemStore) GetDeliveries(ctx context.Context, id int64) ([]Delivery, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	log := m.deliveries[id]
+	deliveries := make([]Delivery, 0, len(log))
+	for i := len(log) - 1; i >= 0; i-- {
+		deliveries = append(deliveries, log[i])
+	}
+	return deliveries, nil
+}
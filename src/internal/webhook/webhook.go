@@ -0,0 +1,162 @@
+// Package webhook notifies subscribers, via HTTP POSTs, when todos are created, updated,
+// completed, or deleted.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+)
+
+// EventType identifies what happened to a todo
+type EventType string
+
+const (
+	// ToDoCreated is sent when a todo is created
+	ToDoCreated EventType = "todo.created"
+	// ToDoUpdated is sent when a todo is updated, including when it's completed
+	ToDoUpdated EventType = "todo.updated"
+	// ToDoCompleted is sent, in addition to ToDoUpdated, when an update marks a todo that
+	// wasn't completed as completed
+	ToDoCompleted EventType = "todo.completed"
+	// ToDoDeleted is sent when a todo is deleted, it contains the todo as it was before it was deleted
+	ToDoDeleted EventType = "todo.deleted"
+)
+
+// EventTypes are all of the event types a Subscription can subscribe to
+var EventTypes = []EventType{ToDoCreated, ToDoUpdated, ToDoCompleted, ToDoDeleted}
+
+// Valid returns true if 'e' is a known event type
+func (e EventType) Valid() bool {
+	for _, et := range EventTypes {
+		if e == et {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	// EventHeader is the request header containing the delivered event's type
+	EventHeader = "X-Todo-Event"
+	// EventIDHeader is the request header containing the delivered event's ID. Retries of
+	// the same event have the same ID.
+	EventIDHeader = "X-Todo-Event-Id"
+	// SignatureHeader is the request header containing the signature of the request body,
+	// 'sha256=' followed by the hex encoded HMAC-SHA256 of the body keyed by the
+	// subscription's secret.
+	SignatureHeader = "X-Todo-Signature"
+)
+
+// Event describes something that happened to a todo
+type Event struct {
+	ID         string    `json:"id"`
+	Type       EventType `json:"type"`
+	OccurredAt time.Time `json:"occurredat"`
+	ToDo       todo.Item `json:"todo"`
+}
+
+// NewEvent returns an Event of type 'et' about 'td' that occurred now
+func NewEvent(et EventType, td todo.Item) Event {
+	return Event{ID: randomHex(16), Type: et, OccurredAt: time.Now().UTC(), ToDo: td}
+}
+
+// Subscription is a request to be sent events of the subscribed types
+type Subscription struct {
+	ID      int64  `json:"id"`
+	SelfRef string `json:"selfref"`
+	// Owner is the user that created the subscription, it's only sent events about their
+	// todos. It's set by the server.
+	Owner  string      `json:"owner,omitempty"`
+	URL    string      `json:"url"`
+	Events []EventType `json:"events"`
+	// Secret is the key used to sign deliveries. It's generated if it isn't provided when
+	// the subscription is created and is only returned at that time.
+	Secret string `json:"secret,omitempty"`
+}
+
+// Receives returns true if 'e' should be delivered to 's', i.e., 's' is subscribed to the
+// event's type and the event is about one of its owner's todos
+func (s Subscription) Receives(e Event) bool {
+	return s.Owner == e.ToDo.Owner && s.Subscribes(e.Type)
+}
+
+// Subscribes returns true if 's' is subscribed to events of type 'et'
+func (s Subscription) Subscribes(et EventType) bool {
+	for _, e := range s.Events {
+		if e == et {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateSubscription returns an error describing what's wrong with 's', if anything
+func ValidateSubscription(s Subscription) error {
+	var errMsgs []string
+
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		errMsgs = append(errMsgs, "webhook url must be an absolute http or https URL")
+	}
+	if len(s.Events) == 0 {
+		errMsgs = append(errMsgs, "webhook events must be populated")
+	}
+	for _, e := range s.Events {
+		if !e.Valid() {
+			errMsgs = append(errMsgs, "unknown webhook event "+string(e))
+		}
+	}
+
+	if len(errMsgs) > 0 {
+		return errors.New(strings.Join(errMsgs, ", "))
+	}
+	return nil
+}
+
+// NewSecret returns a random secret for signing deliveries
+func NewSecret() string {
+	return randomHex(32)
+}
+
+// Sign returns the value of the SignatureHeader for 'body' signed with 'secret'
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Delivery records an attempt to deliver an event to a subscription
+type Delivery struct {
+	ID             int64     `json:"id"`
+	SubscriptionID int64     `json:"subscriptionid"`
+	EventID        string    `json:"eventid"`
+	EventType      EventType `json:"eventtype"`
+	Attempt        int       `json:"attempt"`
+	// HTTPStatus is the subscriber's response status, 0 if there wasn't a response
+	HTTPStatus  int       `json:"httpStatus"`
+	Err         string    `json:"error,omitempty"`
+	DeliveredAt time.Time `json:"deliveredat"`
+}
+
+// Succeeded returns true if the subscriber accepted the delivery
+func (d Delivery) Succeeded() bool {
+	return d.HTTPStatus >= 200 && d.HTTPStatus <= 299
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		// crypto/rand failing means the system is unusable anyway
+		panic(errors.Annotate(err, "unable to generate random bytes"))
+	}
+	return hex.EncodeToString(b)
+}
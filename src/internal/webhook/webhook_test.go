@@ -0,0 +1,343 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/youngkin/todoshaleapps/src/internal/logging"
+	"github.com/youngkin/todoshaleapps/src/internal/todo"
+)
+
+var logger = logging.GetLogger()
+
+func TestValidateSubscription(t *testing.T) {
+	tcs := []struct {
+		testName    string
+		sub         Subscription
+		shouldError bool
+	}{
+		{
+			testName: "testValid",
+			sub:      Subscription{URL: "https://example.com/hook", Events: []EventType{ToDoCreated, ToDoDeleted}},
+		},
+		{
+			testName:    "testRelativeURL",
+			sub:         Subscription{URL: "/hook", Events: []EventType{ToDoCreated}},
+			shouldError: true,
+		},
+		{
+			testName:    "testUnsupportedScheme",
+			sub:         Subscription{URL: "ftp://example.com/hook", Events: []EventType{ToDoCreated}},
+			shouldError: true,
+		},
+		{
+			testName:    "testNoEvents",
+			sub:         Subscription{URL: "https://example.com/hook"},
+			shouldError: true,
+		},
+		{
+			testName:    "testUnknownEvent",
+			sub:         Subscription{URL: "https://example.com/hook", Events: []EventType{"todo.archived"}},
+			shouldError: true,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := ValidateSubscription(tc.sub)
+			if (err != nil) != tc.shouldError {
+				t.Errorf("expected error %t, got %v", tc.shouldError, err)
+			}
+		})
+	}
+}
+
+func TestDestinations(t *testing.T) {
+	d, err := NewDestinations([]string{"hooks.internal", "10.1.0.0/16", " 192.168.1.5 "})
+	if err != nil {
+		t.Fatalf("unexpected error creating destinations: %s", err)
+	}
+	d.lookup = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		switch host {
+		case "example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}}, nil
+		case "rebind.example.com":
+			return []net.IPAddr{{IP: net.ParseIP("93.184.216.34")}, {IP: net.ParseIP("127.0.0.1")}}, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	tcs := []struct {
+		testName   string
+		url        string
+		shouldPass bool
+	}{
+		{testName: "testExternalHost", url: "https://example.com/hook", shouldPass: true},
+		{testName: "testExternalIP", url: "http://93.184.216.34/hook", shouldPass: true},
+		{testName: "testLoopback", url: "http://127.0.0.1:8080/hook"},
+		{testName: "testLoopbackIPv6", url: "http://[::1]/hook"},
+		{testName: "testLocalhost", url: "http://localhost/hook"},
+		{testName: "testMetadataService", url: "http://169.254.169.254/latest/meta-data"},
+		{testName: "testPrivate", url: "http://10.0.0.5:5432/"},
+		{testName: "testUnspecified", url: "http://0.0.0.0/"},
+		{testName: "testResolvesToLoopback", url: "http://rebind.example.com/hook"},
+		{testName: "testUnresolvable", url: "http://unknown.example.com/hook"},
+		{testName: "testAllowedHost", url: "http://hooks.internal/hook", shouldPass: true},
+		{testName: "testAllowedNetwork", url: "http://10.1.2.3/hook", shouldPass: true},
+		{testName: "testAllowedIP", url: "http://192.168.1.5/hook", shouldPass: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.testName, func(t *testing.T) {
+			err := d.Check(context.Background(), tc.url)
+			if (err == nil) != tc.shouldPass {
+				t.Errorf("expected shouldPass = %t, got error %v", tc.shouldPass, err)
+			}
+		})
+	}
+
+	_, err = NewDestinations([]string{"10.1.0.0/33"})
+	if err == nil {
+		t.Error("expected an error creating destinations with an invalid CIDR")
+	}
+}
+
+// TestDispatcherRefusesInternalAddress verifies that the default client won't connect to
+// an internal address, even if the subscription got past validation
+func TestDispatcherRefusesInternalAddress(t *testing.T) {
+	called := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer srv.Close()
+
+	store := NewMemStore()
+	subID, err := store.InsertSubscription(context.Background(), Subscription{URL: srv.URL, Events: []EventType{ToDoCreated}, Secret: "secret"})
+	if err != nil {
+		t.Fatalf("unexpected error creating subscription: %s", err)
+	}
+	d, err := NewDispatcher(store, nil, logger)
+	if err != nil {
+		t.Fatalf("unexpected error creating dispatcher: %s", err)
+	}
+
+	s, _ := store.GetSubscription(context.Background(), subID)
+	delivery := d.post(*s, NewEvent(ToDoCreated, todo.Item{ID: 1}), []byte("{}"))
+	if called || delivery.Succeeded() || len(delivery.Err) == 0 {
+		t.Errorf("expected delivery to %s to be refused, got %+v", srv.URL, delivery)
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		received []*http.Request
+		bodies   [][]byte
+	)
+	// The first delivery attempt fails, the retry succeeds
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+		if len(received) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	store := NewMemStore()
+	subID, err := store.InsertSubscription(context.Background(), Subscription{URL: srv.URL, Events: []EventType{ToDoCompleted}, Secret: "secret"})
+	if err != nil {
+		t.Fatalf("unexpected error creating subscription: %s", err)
+	}
+	// Not subscribed to completed events, shouldn't be called
+	_, err = store.InsertSubscription(context.Background(), Subscription{URL: "http://127.0.0.1:1/hook", Events: []EventType{ToDoCreated}, Secret: "other"})
+	if err != nil {
+		t.Fatalf("unexpected error creating subscription: %s", err)
+	}
+	// Another user's subscription, shouldn't be called
+	otherID, err := store.InsertSubscription(context.Background(), Subscription{Owner: "jdoe", URL: "http://127.0.0.1:1/hook", Events: []EventType{ToDoCompleted}, Secret: "other"})
+	if err != nil {
+		t.Fatalf("unexpected error creating subscription: %s", err)
+	}
+
+	// The test server is on the loopback interface, an internal address
+	destinations, err := NewDestinations([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("unexpected error creating destinations: %s", err)
+	}
+	d, err := NewDispatcher(store, destinations.Client(time.Second), logger)
+	if err != nil {
+		t.Fatalf("unexpected error creating dispatcher: %s", err)
+	}
+	d.Backoff = time.Millisecond
+
+	done := make(chan interface{})
+	go d.Run(done)
+
+	e := NewEvent(ToDoCompleted, todo.Item{ID: 1, Note: "walk the dog", Completed: true})
+	d.Publish(e)
+
+	var deliveries []Delivery
+	for i := 0; i < 100; i++ {
+		deliveries, _ = store.GetDeliveries(context.Background(), subID)
+		if len(deliveries) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(done)
+
+	if len(deliveries) != 2 {
+		t.Fatalf("expected 2 delivery attempts, got %+v", deliveries)
+	}
+	if deliveries[0].Attempt != 2 || !deliveries[0].Succeeded() {
+		t.Errorf("expected successful second attempt first in delivery log, got %+v", deliveries[0])
+	}
+	if deliveries[1].Attempt != 1 || deliveries[1].HTTPStatus != http.StatusServiceUnavailable || deliveries[1].Err == "" {
+		t.Errorf("expected failed first attempt last in delivery log, got %+v", deliveries[1])
+	}
+	if others, _ := store.GetDeliveries(context.Background(), otherID); len(others) != 0 {
+		t.Errorf("expected no deliveries to another user's subscription, got %+v", others)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i, r := range received {
+		if r.Header.Get(EventHeader) != string(ToDoCompleted) || r.Header.Get(EventIDHeader) != e.ID {
+			t.Errorf("expected event headers for %s event %s, got %v", ToDoCompleted, e.ID, r.Header)
+		}
+		if sig := r.Header.Get(SignatureHeader); sig != Sign("secret", bodies[i]) {
+			t.Errorf("expected signature %s, got %s", Sign("secret", bodies[i]), sig)
+		}
+		got := Event{}
+		err = json.Unmarshal(bodies[i], &got)
+		if err != nil || got.ID != e.ID || got.ToDo.ID != 1 {
+			t.Errorf("expected body containing %+v, got %s", e, bodies[i])
+		}
+	}
+}
+
+func TestMemStoreSubscriptionsByOwner(t *testing.T) {
+	store := NewMemStore()
+	for _, owner := range []string{"ryoungkin", "jdoe", "ryoungkin"} {
+		_, err := store.InsertSubscription(context.Background(), Subscription{Owner: owner, URL: "https://example.com/hook", Events: []EventType{ToDoCreated}})
+		if err != nil {
+			t.Fatalf("unexpected error creating subscription: %s", err)
+		}
+	}
+
+	subs, err := store.GetSubscriptionsByOwner(context.Background(), "ryoungkin")
+	if err != nil {
+		t.Fatalf("unexpected error getting subscriptions: %s", err)
+	}
+	if len(subs) != 2 || subs[0].ID != 1 || subs[1].ID != 3 {
+		t.Errorf("expected ryoungkin's subscriptions 1 and 3, got %+v", subs)
+	}
+	subs, err = store.GetSubscriptionsByOwner(context.Background(), "nobody")
+	if err != nil || subs == nil || len(subs) != 0 {
+		t.Errorf("expected no subscriptions and no error, got %+v and %v", subs, err)
+	}
+}
+
+func TestMemStoreDeliveries(t *testing.T) {
+	store := NewMemStore()
+	id, err := store.InsertSubscription(context.Background(), Subscription{URL: "https://example.com/hook", Events: []EventType{ToDoCreated}})
+	if err != nil {
+		t.Fatalf("unexpected error creating subscription: %s", err)
+	}
+
+	for i := 1; i <= MaxDeliveries+5; i++ {
+		err = store.InsertDelivery(context.Background(), Delivery{SubscriptionID: id, Attempt: i})
+		if err != nil {
+			t.Fatalf("unexpected error inserting delivery: %s", err)
+		}
+	}
+	deliveries, err := store.GetDeliveries(context.Background(), id)
+	if err != nil {
+		t.Fatalf("unexpected error getting deliveries: %s", err)
+	}
+	if len(deliveries) != MaxDeliveries {
+		t.Fatalf("expected %d deliveries, got %d", MaxDeliveries, len(deliveries))
+	}
+	if deliveries[0].Attempt != MaxDeliveries+5 || deliveries[MaxDeliveries-1].Attempt != 6 {
+		t.Errorf("expected most recent deliveries first, got %+v ... %+v", deliveries[0], deliveries[MaxDeliveries-1])
+	}
+
+	_, err = store.DeleteSubscription(context.Background(), id)
+	if err != nil {
+		t.Fatalf("unexpected error deleting subscription: %s", err)
+	}
+	err = store.InsertDelivery(context.Background(), Delivery{SubscriptionID: id})
+	if err == nil {
+		t.Error("expected error inserting delivery for deleted subscription")
+	}
+}
+
+func TestPGStore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a mock database connection", err)
+	}
+	defer db.Close()
+
+	delivered := time.Date(2020, 4, 2, 12, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(regexp.QuoteMeta(insertSubscriptionStmt)).
+		WithArgs("ryoungkin", "https://example.com/hook", "todo.created,todo.deleted", "secret").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(getSubscriptionsByOwnerQuery)).
+		WithArgs("ryoungkin").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner", "url", "events", "secret"}).
+			AddRow(1, "ryoungkin", "https://example.com/hook", "todo.created,todo.deleted", "secret"))
+	mock.ExpectExec(regexp.QuoteMeta(updateSubscriptionStmt)).
+		WithArgs("https://example.com/hook", "todo.updated", "", 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(insertDeliveryStmt)).
+		WithArgs(1, "abc", ToDoCreated, 1, 200, "", delivered).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	store, err := NewPGStore(db, 0)
+	if err != nil {
+		t.Fatalf("unexpected error creating PGStore: %s", err)
+	}
+
+	id, err := store.InsertSubscription(context.Background(), Subscription{Owner: "ryoungkin", URL: "https://example.com/hook", Events: []EventType{ToDoCreated, ToDoDeleted}, Secret: "secret"})
+	if err != nil || id != 1 {
+		t.Errorf("expected subscription 1 to be inserted, got %d, %v", id, err)
+	}
+
+	subs, err := store.GetSubscriptionsByOwner(context.Background(), "ryoungkin")
+	if err != nil {
+		t.Fatalf("unexpected error getting subscriptions: %s", err)
+	}
+	if len(subs) != 1 || !subs[0].Subscribes(ToDoDeleted) || subs[0].Subscribes(ToDoUpdated) {
+		t.Errorf("expected subscription to todo.created and todo.deleted, got %+v", subs)
+	}
+
+	_, err = store.UpdateSubscription(context.Background(), Subscription{ID: 2, URL: "https://example.com/hook", Events: []EventType{ToDoUpdated}})
+	if err == nil {
+		t.Error("expected error updating a subscription that doesn't exist")
+	}
+
+	err = store.InsertDelivery(context.Background(), Delivery{SubscriptionID: 1, EventID: "abc", EventType: ToDoCreated, Attempt: 1, HTTPStatus: 200, DeliveredAt: delivered})
+	if err != nil {
+		t.Errorf("unexpected error inserting delivery: %s", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}